@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/businesslogic/user"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage/mongodb"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// runEventsCommand runs the events subcommand with the given arguments, it stops the application on failure
+func runEventsCommand(args []string) {
+	if len(args) == 0 || args[0] != "backfill" {
+		logger.Log.Fatalf("usage: users-service events backfill [flags]")
+	}
+
+	runBackfill(args[1:])
+}
+
+// runBackfill emits a snapshot event for each current user through the configured events emitter,
+// seeding new consumers. The backfill stops on interrupt and resumes from its checkpoint file when run again.
+func runBackfill(args []string) {
+	flags := flag.NewFlagSet("events backfill", flag.ExitOnError)
+	firstName := flags.String("first-name", "", "backfill only users with the given first name")
+	lastName := flags.String("last-name", "", "backfill only users with the given last name")
+	country := flags.String("country", "", "backfill only users with the given country")
+	pageSize := flags.Int("page-size", 0, "number of users listed at once, the maximum page size if not set")
+	eventsRate := flags.Float64("rate", 0, "maximum number of events emitted per second, unlimited if not set")
+	checkpointFile := flags.String("checkpoint-file", "", "file of the backfill checkpoint, the backfill is not resumable if not set")
+	dryRun := flags.Bool("dry-run", false, "list the backfilled users without emitting their events")
+	_ = flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	mongoDbStorage, mongodbErr := mongodb.NewMongoDB(
+		nil,
+		os.Getenv("MONGODB_DATABASE"),
+		mongodb.Config{SoftDelete: getEnvBool("MONGODB_SOFT_DELETE", false)},
+	)
+	if mongodbErr != nil {
+		logger.Log.Fatalf("could not initialize MongoDB storage: %v", mongodbErr)
+	}
+	defer func() {
+		if err := mongoDbStorage.Close(context.Background()); err != nil {
+			logger.Log.Errorf("could not close MongoDB storage: %v", err)
+		}
+	}()
+
+	// Snapshot events are emitted straight to the events emitter, no event emitter is created for a dry run
+	var eventEmitter events.EventEmitter = events.NopEventEmitter{}
+	userEventEmitters := &eventEmitters{ctx: ctx, webhookStorage: mongoDbStorage}
+	defer userEventEmitters.close()
+	if !*dryRun {
+		eventsEmitter := os.Getenv("EVENTS_EMITTER")
+		if eventsEmitter == "" {
+			eventsEmitter = "kafka"
+		}
+		eventEmitter = userEventEmitters.newEventEmitter(eventsEmitter)
+	}
+
+	var checkpointStore user.CheckpointStore
+	if *checkpointFile != "" {
+		checkpointStore = user.NewFileCheckpointStore(*checkpointFile)
+	}
+
+	backfiller := user.NewBackfiller(
+		mongoDbStorage,
+		eventEmitter,
+		checkpointStore,
+		user.BackfillConfig{
+			Filter: businesslogic.UserFilter{
+				FirstName: optionalFlag(*firstName),
+				LastName:  optionalFlag(*lastName),
+				Country:   optionalFlag(*country),
+			},
+			PageSize: *pageSize,
+			Rate:     *eventsRate,
+			DryRun:   *dryRun,
+		},
+	)
+
+	backfilled, errBackfill := backfiller.Backfill(ctx)
+	summary := fmt.Sprintf("%d users backfilled", backfilled)
+	if *dryRun {
+		summary = fmt.Sprintf("Dry run, %d users would be backfilled", backfilled)
+	}
+	if errBackfill != nil {
+		logger.Log.Fatalf("backfill failed, %s: %v", summary, errBackfill)
+	}
+	logger.Log.Info(summary)
+}
+
+// optionalFlag returns a pointer to the given flag value, nil if it is empty
+func optionalFlag(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
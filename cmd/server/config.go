@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/alenalato/users-service/internal/logger"
+)
+
+// getEnvBool returns the boolean value of the given environment variable, or the fallback value if it is not set.
+// It stops the application if the value is not a valid boolean.
+func getEnvBool(name string, fallback bool) bool {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Log.Fatalf("invalid boolean value for %s: %v", name, err)
+	}
+
+	return parsed
+}
+
+// getEnvInt returns the integer value of the given environment variable, or the fallback value if it is not set.
+// It stops the application if the value is not a valid positive integer.
+func getEnvInt(name string, fallback int) int {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Log.Fatalf("invalid integer value for %s: %v", name, err)
+	}
+	if parsed <= 0 {
+		logger.Log.Fatalf("invalid integer value for %s: must be positive", name)
+	}
+
+	return parsed
+}
+
+// getEnvDuration returns the duration value of the given environment variable, or the fallback value if it is not set.
+// It stops the application if the value is not a valid positive duration.
+func getEnvDuration(name string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Log.Fatalf("invalid duration value for %s: %v", name, err)
+	}
+	if parsed <= 0 {
+		logger.Log.Fatalf("invalid duration value for %s: must be positive", name)
+	}
+
+	return parsed
+}
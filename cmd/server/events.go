@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/events/fanout"
+	"github.com/alenalato/users-service/internal/events/kafka"
+	natsevents "github.com/alenalato/users-service/internal/events/nats"
+	"github.com/alenalato/users-service/internal/events/redaction"
+	"github.com/alenalato/users-service/internal/events/schemaregistry"
+	"github.com/alenalato/users-service/internal/events/webhook"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"os"
+	"strings"
+	"time"
+)
+
+// deadLetterEmitterName is the name of the dead letter event emitter, naming its redaction policy
+const deadLetterEmitterName = "dead_letter"
+
+// eventEmitters creates the event emitters publishing user events by name, and closes them on shutdown
+type eventEmitters struct {
+	// ctx is the context of the startup verifications of the event emitters
+	ctx context.Context
+	// webhookStorage is the storage of the webhook subscriptions
+	webhookStorage storage.WebhookStorage
+	// kafkaConfig is the configuration of the Kafka event emitters, set once a Kafka event emitter is created
+	kafkaConfig *kafka.Config
+	// kafkaMetrics records the emissions of the Kafka event emitters, if set
+	kafkaMetrics *kafka.Metrics
+	// schemaRegistryClient is the schema registry client of the Kafka event emitters, if configured
+	schemaRegistryClient *schemaregistry.Client
+	// redacted are the names of the created event emitters redacting personal data
+	redacted []string
+	// webhooks is set once a webhook event emitter is created
+	webhooks bool
+	// closers close the created event emitters
+	closers []func()
+}
+
+// newEventEmitter creates the event emitter publishing user events from a list of route specifications.
+// A single route without event types is the named event emitter itself,
+// multiple routes are served by a fan out event emitter.
+// It stops the application if the specifications are not valid.
+func (e *eventEmitters) newEventEmitter(value string) events.EventEmitter {
+	specs, errSpecs := fanout.ParseRouteSpecs(value)
+	if errSpecs != nil {
+		logger.Log.Fatalf("invalid events emitter: %v", errSpecs)
+	}
+	if len(specs) == 1 && len(specs[0].EventTypes) == 0 && specs[0].Policy == fanout.PolicyRequired {
+		return e.get(specs[0].Name)
+	}
+
+	routes := make([]fanout.Route, 0, len(specs))
+	for _, spec := range specs {
+		routes = append(routes, fanout.Route{
+			Name:         spec.Name,
+			EventEmitter: e.get(spec.Name),
+			EventTypes:   spec.EventTypes,
+			Policy:       spec.Policy,
+		})
+	}
+	fanoutEventEmitter, fanoutErr := fanout.NewEventEmitter(routes...)
+	if fanoutErr != nil {
+		logger.Log.Fatalf("could not initialize fan out event emitter: %v", fanoutErr)
+	}
+	logger.Log.Infof("Fan out event emitter initialized with %d routes", len(routes))
+
+	return fanoutEventEmitter
+}
+
+// get creates the event emitter with the given name, redacting the personal data of the user events
+// according to its EVENTS_REDACTION_POLICY_<NAME> redaction policy, if any.
+// It stops the application if the name is unknown or the redaction policy is not valid.
+func (e *eventEmitters) get(name string) events.EventEmitter {
+	return e.redact(name, e.create(name))
+}
+
+// deadLetter returns the dead letter event emitter of the events failing every async emission attempt, redacting
+// the personal data of the user events according to the EVENTS_REDACTION_POLICY_DEAD_LETTER redaction policy, if any.
+// Dead lettered events are the original events, so it stops the application if an event emitter redacts
+// personal data and the dead letter redaction policy is not set.
+func (e *eventEmitters) deadLetter(eventEmitter events.EventEmitter) events.EventEmitter {
+	if len(e.redacted) > 0 && os.Getenv("EVENTS_REDACTION_POLICY_"+strings.ToUpper(deadLetterEmitterName)) == "" {
+		logger.Log.Fatalf(
+			"events emitters %s redact personal data, EVENTS_REDACTION_POLICY_DEAD_LETTER is required",
+			strings.Join(e.redacted, ", "),
+		)
+	}
+
+	return e.redact(deadLetterEmitterName, eventEmitter)
+}
+
+// redact wraps the event emitter with the given name in a redaction event emitter,
+// according to its EVENTS_REDACTION_POLICY_<NAME> redaction policy, if any.
+// It stops the application if the redaction policy is not valid.
+func (e *eventEmitters) redact(name string, eventEmitter events.EventEmitter) events.EventEmitter {
+	policyValue := os.Getenv("EVENTS_REDACTION_POLICY_" + strings.ToUpper(name))
+	if policyValue == "" {
+		return eventEmitter
+	}
+	policy, errPolicy := redaction.ParsePolicy(policyValue)
+	if errPolicy != nil {
+		logger.Log.Fatalf("invalid redaction policy of %s events emitter: %v", name, errPolicy)
+	}
+	// The same key hashes the fields of every events emitter, so hashed values can be joined across them
+	redactionEventEmitter, redactionErr := redaction.NewEventEmitter(
+		eventEmitter,
+		policy,
+		[]byte(os.Getenv("EVENTS_REDACTION_KEY")),
+	)
+	if redactionErr != nil {
+		logger.Log.Fatalf("could not initialize redaction of %s events emitter: %v", name, redactionErr)
+	}
+	e.redacted = append(e.redacted, name)
+	logger.Log.Infof("Events emitter %s redacts personal data", name)
+
+	return redactionEventEmitter
+}
+
+// create creates the event emitter with the given name, it stops the application if the name is unknown
+func (e *eventEmitters) create(name string) events.EventEmitter {
+	switch name {
+	case "kafka":
+		return e.kafka(os.Getenv("KAFKA_EVENT_EMITTER_TOPIC_NAME"))
+	case "compliance":
+		// The compliance topic receives full user events, it is usually routed deletions only
+		complianceTopicName := os.Getenv("KAFKA_EVENT_EMITTER_COMPLIANCE_TOPIC_NAME")
+		if complianceTopicName == "" {
+			logger.Log.Fatalf("compliance events emitter requires KAFKA_EVENT_EMITTER_COMPLIANCE_TOPIC_NAME")
+		}
+
+		return e.kafka(complianceTopicName)
+	case "nats":
+		natsEventEmitter, natsErr := natsevents.NewEventEmitter(natsevents.Config{
+			URL:           os.Getenv("NATS_URL"),
+			SubjectPrefix: os.Getenv("NATS_SUBJECT_PREFIX"),
+			Stream:        os.Getenv("NATS_STREAM"),
+			Timeout:       getEnvDuration("NATS_PUBLISH_TIMEOUT", 10*time.Second),
+		})
+		if natsErr != nil {
+			logger.Log.Fatalf("could not initialize nats emitter: %v", natsErr)
+		}
+		e.closers = append(e.closers, func() {
+			if err := natsEventEmitter.Close(); err != nil {
+				logger.Log.Errorf("could not close nats emitter: %v", err)
+			}
+		})
+		logger.Log.Infof("NATS event emitter initialized")
+
+		return natsEventEmitter
+	case "webhook":
+		// Events are posted to the webhooks subscribed through the Webhooks service
+		webhookEventEmitter, webhookErr := webhook.NewEventEmitter(
+			e.webhookStorage,
+			webhook.Config{
+				Timeout:              getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+				MaxAttempts:          getEnvInt("WEBHOOK_MAX_ATTEMPTS", 3),
+				MinBackoff:           getEnvDuration("WEBHOOK_MIN_BACKOFF", time.Second),
+				MaxBackoff:           getEnvDuration("WEBHOOK_MAX_BACKOFF", 30*time.Second),
+				AllowHTTP:            getEnvBool("WEBHOOK_ALLOW_HTTP", false),
+				AllowPrivateNetworks: getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+			},
+		)
+		if webhookErr != nil {
+			logger.Log.Fatalf("could not initialize webhook emitter: %v", webhookErr)
+		}
+		e.webhooks = true
+		logger.Log.Infof("Webhook event emitter initialized")
+
+		return webhookEventEmitter
+	default:
+		logger.Log.Fatalf("unknown events emitter: %s", name)
+
+		return nil
+	}
+}
+
+// kafka creates a Kafka event emitter writing to the given topic, events are plain JSON, CloudEvents or protobuf
+func (e *eventEmitters) kafka(topicName string) *kafka.EventEmitter {
+	if e.kafkaConfig == nil {
+		e.kafkaConfig, e.schemaRegistryClient = newKafkaConfig()
+		e.kafkaConfig.Metrics = e.kafkaMetrics
+	}
+
+	kafkaEventEmitter := newKafkaEventEmitter(e.ctx, topicName, *e.kafkaConfig, e.schemaRegistryClient)
+	e.closers = append(e.closers, func() {
+		if err := kafkaEventEmitter.Close(); err != nil {
+			logger.Log.Errorf("could not close kafka emitter of topic %s: %v", topicName, err)
+		}
+	})
+
+	return kafkaEventEmitter
+}
+
+// close closes the created event emitters, in reverse creation order
+func (e *eventEmitters) close() {
+	for i := len(e.closers) - 1; i >= 0; i-- {
+		e.closers[i]()
+	}
+}
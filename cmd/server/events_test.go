@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/events/async"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestEventEmitters_DeadLetter_Redacted(t *testing.T) {
+	t.Setenv("EVENTS_REDACTION_POLICY_KAFKA", "*:drop")
+	t.Setenv("EVENTS_REDACTION_POLICY_DEAD_LETTER", "*:drop")
+
+	ctrl := gomock.NewController(t)
+	kafkaEventEmitter := events.NewMockEventEmitter(ctrl)
+	deadLetterEventEmitter := events.NewMockEventEmitter(ctrl)
+
+	userEvent := events.UserEvent{
+		EventId:   "event-id",
+		EventType: events.EventTypeCreated,
+		UserId:    "user-id",
+		FirstName: "John",
+		LastName:  "Doe",
+		Nickname:  "johndoe",
+		Email:     "john.doe@example.com",
+		Country:   "uk",
+	}
+	redactedEvent := events.UserEvent{
+		EventId:   "event-id",
+		EventType: events.EventTypeCreated,
+		UserId:    "user-id",
+	}
+
+	// The redacted route fails every attempt, its event is dead lettered redacted
+	kafkaEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), redactedEvent).
+		Return(common.NewError(nil, common.ErrTypeInternal)).Times(2)
+	deadLetterEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), redactedEvent).Return(nil)
+
+	userEventEmitters := &eventEmitters{ctx: context.Background()}
+	eventEmitter := userEventEmitters.redact("kafka", kafkaEventEmitter)
+	asyncEventEmitter, err := async.NewEventEmitter(
+		eventEmitter,
+		userEventEmitters.deadLetter(deadLetterEventEmitter),
+		async.Config{QueueSize: 1, Workers: 1, MaxAttempts: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+		prometheus.NewRegistry(),
+	)
+	require.NoError(t, err)
+
+	assert.NoError(t, asyncEventEmitter.EmitUserEvent(context.Background(), userEvent))
+	assert.NoError(t, asyncEventEmitter.Drain(context.Background()))
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/events/kafka"
+	"github.com/alenalato/users-service/internal/events/schemaregistry"
+	"github.com/alenalato/users-service/internal/logger"
+	"os"
+	"strings"
+	"time"
+)
+
+// newKafkaConfig returns the configuration of the Kafka event emitters from the environment,
+// along with the schema registry client if configured.
+// It stops the application if the configuration is not valid.
+func newKafkaConfig() (*kafka.Config, *schemaregistry.Client) {
+	// Events are plain JSON, CloudEvents or protobuf
+	kafkaConfig := kafka.Config{
+		Addresses:    strings.Split(os.Getenv("KAFKA_ADDRESSES"), ","),
+		Encoding:     kafka.Encoding(os.Getenv("KAFKA_EVENT_EMITTER_ENCODING")),
+		Source:       os.Getenv("KAFKA_EVENT_EMITTER_CLOUDEVENTS_SOURCE"),
+		RequiredAcks: kafka.Acks(os.Getenv("KAFKA_REQUIRED_ACKS")),
+		Compression:  kafka.Compression(os.Getenv("KAFKA_COMPRESSION")),
+		BatchSize:    getEnvInt("KAFKA_BATCH_SIZE", 100),
+		BatchTimeout: getEnvDuration("KAFKA_BATCH_TIMEOUT", time.Second),
+		WriteTimeout: getEnvDuration("KAFKA_WRITE_TIMEOUT", 10*time.Second),
+	}
+	// Broker connections are encrypted with TLS and authenticated with SASL, if configured
+	if getEnvBool("KAFKA_TLS_ENABLED", false) {
+		kafkaConfig.TLS = &kafka.TLSConfig{
+			CAFile:   os.Getenv("KAFKA_TLS_CA_FILE"),
+			CertFile: os.Getenv("KAFKA_TLS_CERT_FILE"),
+			KeyFile:  os.Getenv("KAFKA_TLS_KEY_FILE"),
+		}
+	}
+	if saslMechanism := os.Getenv("KAFKA_SASL_MECHANISM"); saslMechanism != "" {
+		kafkaConfig.SASL = &kafka.SASLConfig{
+			Mechanism: kafka.SASLMechanism(saslMechanism),
+			Username:  os.Getenv("KAFKA_SASL_USERNAME"),
+			Password:  os.Getenv("KAFKA_SASL_PASSWORD"),
+		}
+	}
+	// Protobuf events are written in the schema registry wire format, if a schema registry is configured
+	var schemaRegistryClient *schemaregistry.Client
+	if schemaRegistryUrl := os.Getenv("SCHEMA_REGISTRY_URL"); schemaRegistryUrl != "" {
+		var schemaRegistryErr error
+		schemaRegistryClient, schemaRegistryErr = schemaregistry.NewClient(schemaregistry.Config{
+			URL:      schemaRegistryUrl,
+			Username: os.Getenv("SCHEMA_REGISTRY_USERNAME"),
+			Password: os.Getenv("SCHEMA_REGISTRY_PASSWORD"),
+			Timeout:  getEnvDuration("SCHEMA_REGISTRY_TIMEOUT", 10*time.Second),
+		})
+		if schemaRegistryErr != nil {
+			logger.Log.Fatalf("could not initialize schema registry client: %v", schemaRegistryErr)
+		}
+		kafkaConfig.SchemaRegistry = schemaRegistryClient
+		logger.Log.Infof("Schema registry client initialized")
+	}
+
+	return &kafkaConfig, schemaRegistryClient
+}
+
+// newKafkaEventEmitter creates a Kafka event emitter writing to the given topic.
+// The topic is verified on startup, creating it if configured, so that a misconfigured topic fails fast.
+// The user event schema must be compatible with the schema registered for the topic, if a schema registry is given.
+// It stops the application if the topic or its schema are not valid.
+func newKafkaEventEmitter(
+	ctx context.Context,
+	topicName string,
+	kafkaConfig kafka.Config,
+	schemaRegistryClient *schemaregistry.Client,
+) *kafka.EventEmitter {
+	if schemaRegistryClient != nil {
+		compatible, compatibilityErr := schemaRegistryClient.CheckCompatibility(
+			ctx,
+			kafka.SchemaSubject(topicName),
+			kafka.UserEventSchema(),
+		)
+		if compatibilityErr != nil {
+			logger.Log.Fatalf("could not check user event schema compatibility of topic %s: %v", topicName, compatibilityErr)
+		}
+		if !compatible {
+			logger.Log.Fatalf("user event schema is not compatible with the schema registered for topic %s", topicName)
+		}
+	}
+
+	topicManager, topicManagerErr := kafka.NewTopicManager(kafkaConfig)
+	if topicManagerErr != nil {
+		logger.Log.Fatalf("could not initialize kafka topic manager: %v", topicManagerErr)
+	}
+	topicCtx, cancelTopic := context.WithTimeout(ctx, 30*time.Second)
+	defer cancelTopic()
+	topicErr := topicManager.EnsureTopic(topicCtx, topicName, kafka.TopicConfig{
+		Partitions:        getEnvInt("KAFKA_TOPIC_PARTITIONS", 0),
+		Create:            getEnvBool("KAFKA_TOPIC_CREATE", false),
+		ReplicationFactor: getEnvInt("KAFKA_TOPIC_REPLICATION_FACTOR", 0),
+		Retention:         getEnvDuration("KAFKA_TOPIC_RETENTION", 0),
+	})
+	if topicErr != nil {
+		logger.Log.Fatalf("could not verify kafka topic %s: %v", topicName, topicErr)
+	}
+	logger.Log.Infof("Kafka topic %s verified", topicName)
+
+	kafkaEventEmitter, kafkaErr := kafka.NewEventEmitter(topicName, kafkaConfig)
+	if kafkaErr != nil {
+		logger.Log.Fatalf("could not initialize kafka emitter of topic %s: %v", topicName, kafkaErr)
+	}
+	logger.Log.Infof("Kafka event emitter of topic %s initialized", topicName)
+
+	return kafkaEventEmitter
+}
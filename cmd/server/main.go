@@ -3,19 +3,32 @@ package main
 import (
 	"context"
 	"fmt"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/auth/jwt"
+	"github.com/alenalato/users-service/internal/auth/mtls"
 	"github.com/alenalato/users-service/internal/businesslogic/password"
 	"github.com/alenalato/users-service/internal/businesslogic/user"
+	webhookLogic "github.com/alenalato/users-service/internal/businesslogic/webhook"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/events/async"
 	"github.com/alenalato/users-service/internal/events/kafka"
+	"github.com/alenalato/users-service/internal/events/outbox"
+	"github.com/alenalato/users-service/internal/ratelimit"
 	"github.com/alenalato/users-service/internal/storage/mongodb"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/reflection"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
-	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	servicegrpc "github.com/alenalato/users-service/internal/grpc"
 	"github.com/alenalato/users-service/internal/logger"
@@ -28,8 +41,19 @@ func main() {
 		_ = Log.Sync()
 	}(logger.Log)
 
+	// The events subcommand runs an events operation instead of the server, like users-service events backfill
+	if len(os.Args) > 1 && os.Args[1] == "events" {
+		runEventsCommand(os.Args[2:])
+
+		return
+	}
+
 	ctx := context.Background()
 
+	// Background workers are stopped after the gRPC server
+	workersCtx, stopWorkers := context.WithCancel(ctx)
+	var workers sync.WaitGroup
+
 	grpcListenAddress := fmt.Sprintf(
 		"%s:%s",
 		os.Getenv("GRPC_LISTEN_HOST"),
@@ -43,10 +67,36 @@ func main() {
 	}
 	logger.Log.Infof("TCP listener initialized on %s", grpcListenAddress)
 
+	// Initialize metrics of the gRPC server, MongoDB commands, Kafka event emissions and password hashing
+	grpcMetrics, metricsErr := servicegrpc.NewMetrics(prometheus.DefaultRegisterer)
+	if metricsErr != nil {
+		logger.Log.Fatalf("could not initialize gRPC metrics: %v", metricsErr)
+	}
+	mongoDbMetrics, metricsErr := mongodb.NewMetrics(prometheus.DefaultRegisterer)
+	if metricsErr != nil {
+		logger.Log.Fatalf("could not initialize MongoDB metrics: %v", metricsErr)
+	}
+	kafkaMetrics, metricsErr := kafka.NewMetrics(prometheus.DefaultRegisterer)
+	if metricsErr != nil {
+		logger.Log.Fatalf("could not initialize Kafka metrics: %v", metricsErr)
+	}
+	passwordMetrics, metricsErr := password.NewMetrics(prometheus.DefaultRegisterer)
+	if metricsErr != nil {
+		logger.Log.Fatalf("could not initialize password metrics: %v", metricsErr)
+	}
+
 	// Initialize MongoDB storage
+	softDelete := getEnvBool("MONGODB_SOFT_DELETE", false)
+	changeStream := getEnvBool("EVENTS_CHANGE_STREAM_ENABLED", false)
 	mongoDbStorage, mongodbErr := mongodb.NewMongoDB(
 		nil,
 		os.Getenv("MONGODB_DATABASE"),
+		mongodb.Config{
+			SoftDelete:                softDelete,
+			ReleaseDeletedIdentifiers: getEnvBool("MONGODB_SOFT_DELETE_RELEASE_IDENTIFIERS", false),
+			ChangeStreamPreImages:     changeStream,
+			Metrics:                   mongoDbMetrics,
+		},
 	)
 	if mongodbErr != nil {
 		logger.Log.Fatalf("could not initialize MongoDB storage: %v", mongodbErr)
@@ -62,39 +112,266 @@ func main() {
 	}(mongoDbStorage, ctx)
 
 	// Initialize password manager
-	passwordManager := password.NewBcrypt()
+	passwordManager := password.NewBcrypt(passwordMetrics)
 
-	// Initialize Kafka event emitter
-	kafkaEventEmitter, kafkaErr := kafka.NewEventEmitter(
-		os.Getenv("KAFKA_EVENT_EMITTER_TOPIC_NAME"),
-		kafka.Config{
-			Addresses: strings.Split(os.Getenv("KAFKA_ADDRESSES"), ","),
-		},
-	)
-	if kafkaErr != nil {
-		logger.Log.Fatalf("could not initialize kafka emitter: %v", kafkaErr)
-	} else {
-		logger.Log.Infof("Kafka event emitter initialized")
+	// Initialize the event emitter publishing user events, to Kafka, NATS JetStream or the subscribed webhooks.
+	// Events can be routed to multiple event emitters by event type.
+	userEventEmitters := &eventEmitters{ctx: ctx, webhookStorage: mongoDbStorage, kafkaMetrics: kafkaMetrics}
+	// Defer closing event emitters
+	defer userEventEmitters.close()
+	eventsEmitter := os.Getenv("EVENTS_EMITTER")
+	if eventsEmitter == "" {
+		eventsEmitter = "kafka"
 	}
-	// Defer closing Kafka event emitter
-	defer func(kafkaEventEmitter *kafka.EventEmitter) {
-		err := kafkaEventEmitter.Close()
-		if err != nil {
-			logger.Log.Errorf("could not close kafka emitter: %v", err)
+	eventEmitter := userEventEmitters.newEventEmitter(eventsEmitter)
+
+	// Events are either emitted along with user changes, stored in the transactional outbox and relayed,
+	// or published from the changes observed on the storage
+	userConfig := user.Config{
+		RestoreGracePeriod:        getEnvDuration("USERS_RESTORE_GRACE_PERIOD", 72*time.Hour),
+		PurgeRetention:            getEnvDuration("USERS_PURGE_RETENTION", 30*24*time.Hour),
+		PurgeInterval:             getEnvDuration("USERS_PURGE_INTERVAL", time.Hour),
+		TransactionalOutbox:       getEnvBool("EVENTS_OUTBOX_ENABLED", false),
+		ChangeStreamRetryInterval: getEnvDuration("EVENTS_CHANGE_STREAM_RETRY_INTERVAL", 5*time.Second),
+	}
+	if changeStream && userConfig.TransactionalOutbox {
+		logger.Log.Fatalf("events outbox and change stream cannot be both enabled")
+	}
+
+	asyncEvents := getEnvBool("EVENTS_ASYNC_ENABLED", false)
+	if asyncEvents && (changeStream || userConfig.TransactionalOutbox) {
+		logger.Log.Fatalf("async events cannot be enabled along with events outbox or change stream")
+	}
+	// Webhook deliveries wait for their retries, so they are kept out of the user requests
+	if userEventEmitters.webhooks && !asyncEvents && !changeStream && !userConfig.TransactionalOutbox {
+		logger.Log.Fatalf("webhook events emitter requires async events, events outbox or change stream to be enabled")
+	}
+
+	var userEventEmitter events.EventEmitter = eventEmitter
+	// Emitted events are queued and emitted in background, they are drained on shutdown
+	var asyncEventEmitter *async.EventEmitter
+	if asyncEvents {
+		// Events failing every attempt are emitted to the Kafka dead letter topic, if any and Kafka is used,
+		// redacted by the dead letter redaction policy
+		var deadLetterEmitter events.EventEmitter
+		deadLetterTopicName := os.Getenv("KAFKA_EVENT_EMITTER_DEAD_LETTER_TOPIC_NAME")
+		if userEventEmitters.kafkaConfig != nil && deadLetterTopicName != "" {
+			deadLetterEmitter = userEventEmitters.deadLetter(userEventEmitters.kafka(deadLetterTopicName))
+		}
+
+		var asyncErr error
+		asyncEventEmitter, asyncErr = async.NewEventEmitter(
+			eventEmitter,
+			deadLetterEmitter,
+			async.Config{
+				QueueSize:   getEnvInt("EVENTS_ASYNC_QUEUE_SIZE", 10000),
+				Workers:     getEnvInt("EVENTS_ASYNC_WORKERS", 8),
+				MaxAttempts: getEnvInt("EVENTS_ASYNC_MAX_ATTEMPTS", 5),
+				MinBackoff:  getEnvDuration("EVENTS_ASYNC_MIN_BACKOFF", 100*time.Millisecond),
+				MaxBackoff:  getEnvDuration("EVENTS_ASYNC_MAX_BACKOFF", 10*time.Second),
+			},
+			prometheus.DefaultRegisterer,
+		)
+		if asyncErr != nil {
+			logger.Log.Fatalf("could not initialize async event emitter: %v", asyncErr)
 		}
-	}(kafkaEventEmitter)
+		userEventEmitter = asyncEventEmitter
+		logger.Log.Infof("Async event emitter initialized")
+	}
+	if userConfig.TransactionalOutbox {
+		userEventEmitter = outbox.NewEventEmitter(mongoDbStorage)
+
+		// Start outbox relay in background, stored events are published by the event emitter
+		relay := outbox.NewRelay(
+			mongoDbStorage,
+			eventEmitter,
+			outbox.RelayConfig{
+				Interval:      getEnvDuration("EVENTS_OUTBOX_RELAY_INTERVAL", time.Second),
+				BatchSize:     getEnvInt("EVENTS_OUTBOX_RELAY_BATCH_SIZE", 100),
+				MinBackoff:    getEnvDuration("EVENTS_OUTBOX_RELAY_MIN_BACKOFF", time.Second),
+				MaxBackoff:    getEnvDuration("EVENTS_OUTBOX_RELAY_MAX_BACKOFF", 5*time.Minute),
+				MaxAttempts:   getEnvInt("EVENTS_OUTBOX_RELAY_MAX_ATTEMPTS", 20),
+				LeaseDuration: getEnvDuration("EVENTS_OUTBOX_RELAY_LEASE_DURATION", 30*time.Second),
+			},
+		)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			relay.Run(workersCtx)
+		}()
+		logger.Log.Infof("Events outbox relay started")
+	}
+	if changeStream {
+		userEventEmitter = events.NopEventEmitter{}
+
+		// Start change publisher in background, user changes are published by the event emitter
+		changePublisher := user.NewChangePublisher(
+			mongoDbStorage,
+			eventEmitter,
+			userConfig,
+		)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			changePublisher.Run(workersCtx)
+		}()
+		logger.Log.Infof("User change publisher started")
+	}
 
 	// Initialize user manager, the business logic layer
-	userManager := user.NewLogic(passwordManager, mongoDbStorage, kafkaEventEmitter)
+	userManager := user.NewLogic(
+		passwordManager,
+		mongoDbStorage,
+		userEventEmitter,
+		userConfig,
+	)
+
+	// Start deleted users purger in background, soft deleted users are purged after the retention window
+	if softDelete {
+		purger := user.NewPurger(
+			mongoDbStorage,
+			userEventEmitter,
+			userConfig,
+		)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			purger.Run(workersCtx)
+		}()
+		logger.Log.Infof("Deleted users purger started")
+	}
 
 	// Initialize gRPC users server
 	usersServer := servicegrpc.NewUsersServer(userManager)
 
-	// Initialize gRPC server
-	grpcServer := grpc.NewServer()
+	// Initialize gRPC webhooks server, managing the webhook subscriptions to user events, HTTPS URLs are required by default
+	webhooksServer := servicegrpc.NewWebhooksServer(
+		webhookLogic.NewLogic(mongoDbStorage, webhookLogic.Config{AllowHTTP: getEnvBool("WEBHOOK_ALLOW_HTTP", false)}),
+	)
+
+	// The gRPC listener is secured with TLS if a certificate is configured, certificates are reloaded when they change.
+	// Client certificates are required and verified if a client CA is configured, their SPIFFE ID identifies the callers.
+	// The calls are recorded first, so that the calls rejected by the other interceptors are recorded too.
+	var serverOptions []grpc.ServerOption
+	unaryInterceptors := []grpc.UnaryServerInterceptor{grpcMetrics.UnaryInterceptor}
+	streamInterceptors := []grpc.StreamServerInterceptor{grpcMetrics.StreamInterceptor}
+	clientCertificates := false
+	if certFile := os.Getenv("GRPC_TLS_CERT_FILE"); certFile != "" {
+		tlsCredentials, tlsErr := mtls.NewCredentials(mtls.Config{
+			CertFile:     certFile,
+			KeyFile:      os.Getenv("GRPC_TLS_KEY_FILE"),
+			ClientCAFile: os.Getenv("GRPC_TLS_CLIENT_CA_FILE"),
+		})
+		if tlsErr != nil {
+			logger.Log.Fatalf("could not load TLS certificates: %v", tlsErr)
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			tlsCredentials.Run(workersCtx, getEnvDuration("GRPC_TLS_RELOAD_INTERVAL", 10*time.Second))
+		}()
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsCredentials.TLSConfig())))
+
+		clientCertificates = os.Getenv("GRPC_TLS_CLIENT_CA_FILE") != ""
+		if clientCertificates {
+			unaryInterceptors = append(unaryInterceptors, servicegrpc.PeerIdentityUnaryInterceptor)
+			streamInterceptors = append(streamInterceptors, servicegrpc.PeerIdentityStreamInterceptor)
+		}
+		logger.Log.Infof("TLS initialized, client certificates verified: %t", clientCertificates)
+	} else {
+		logger.Log.Warnf("TLS is disabled, GRPC_TLS_CERT_FILE is not set")
+	}
+
+	// Callers are authenticated by their bearer token if a JSON Web Key Set is configured, or by their client certificate,
+	// then authorized if an authorization policy is configured, the health service is public
+	jwksSource := os.Getenv("AUTH_JWKS")
+	if jwksSource != "" {
+		keySet, keySetErr := jwt.NewKeySet(ctx, jwksSource, getEnvDuration("AUTH_JWKS_TIMEOUT", 10*time.Second))
+		if keySetErr != nil {
+			logger.Log.Fatalf("could not load JSON Web Key Set: %v", keySetErr)
+		}
+		verifier, verifierErr := jwt.NewVerifier(
+			keySet,
+			jwt.Config{
+				Issuer:   os.Getenv("AUTH_ISSUER"),
+				Audience: os.Getenv("AUTH_AUDIENCE"),
+				Leeway:   getEnvDuration("AUTH_LEEWAY", 30*time.Second),
+			},
+		)
+		if verifierErr != nil {
+			logger.Log.Fatalf("could not initialize token verifier: %v", verifierErr)
+		}
+
+		// Refresh the JSON Web Key Set in background, so that rotated keys are picked up
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			keySet.Run(workersCtx, getEnvDuration("AUTH_JWKS_REFRESH_INTERVAL", 5*time.Minute))
+		}()
+
+		authenticator := servicegrpc.NewAuthenticator(verifier, "/grpc.health.v1.Health/")
+		unaryInterceptors = append(unaryInterceptors, authenticator.UnaryInterceptor)
+		streamInterceptors = append(streamInterceptors, authenticator.StreamInterceptor)
+		logger.Log.Infof("Authentication initialized")
+	} else {
+		logger.Log.Warnf("Authentication is disabled, AUTH_JWKS is not set")
+	}
+
+	// Calls are rate limited per client if limits are configured, clients are identified once authenticated.
+	// Token buckets are kept in memory, so limits apply to each instance.
+	if rateLimitFile := os.Getenv("RATE_LIMIT_FILE"); rateLimitFile != "" {
+		rateLimitConfig, rateLimitErr := ratelimit.LoadConfig(rateLimitFile)
+		if rateLimitErr != nil {
+			logger.Log.Fatalf("could not load rate limit configuration: %v", rateLimitErr)
+		}
+		memoryLimiter := ratelimit.NewMemoryLimiter()
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			memoryLimiter.Run(workersCtx, time.Minute)
+		}()
+
+		rateLimiter := servicegrpc.NewRateLimiter(memoryLimiter, rateLimitConfig, "/grpc.health.v1.Health/")
+		unaryInterceptors = append(unaryInterceptors, rateLimiter.UnaryInterceptor)
+		streamInterceptors = append(streamInterceptors, rateLimiter.StreamInterceptor)
+		logger.Log.Infof("Rate limiting initialized")
+	}
+
+	// Authenticated callers are authorized by the policy, if any
+	policyFile := os.Getenv("AUTH_POLICY_FILE")
+	if policyFile != "" {
+		if jwksSource == "" && !clientCertificates {
+			logger.Log.Fatalf("authorization requires authentication, neither AUTH_JWKS nor GRPC_TLS_CLIENT_CA_FILE is set")
+		}
+		policy, policyErr := auth.LoadPolicy(policyFile)
+		if policyErr != nil {
+			logger.Log.Fatalf("could not load authorization policy: %v", policyErr)
+		}
+		authorizer := servicegrpc.NewAuthorizer(policy, "/grpc.health.v1.Health/")
+		unaryInterceptors = append(unaryInterceptors, authorizer.UnaryInterceptor)
+		streamInterceptors = append(streamInterceptors, authorizer.StreamInterceptor)
+		logger.Log.Infof("Authorization initialized")
+	} else {
+		logger.Log.Warnf("Authorization is disabled, AUTH_POLICY_FILE is not set")
+	}
+
+	// Initialize gRPC server, the actor performing the changes is recorded in the user history
+	unaryInterceptors = append(unaryInterceptors, servicegrpc.ActorUnaryInterceptor)
+	serverOptions = append(
+		serverOptions,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+	grpcServer := grpc.NewServer(serverOptions...)
 
-	// Register users server
+	// Register users and webhooks servers, webhooks are only managed by authorized callers,
+	// since the service posts user events to their endpoints
 	protogrpc.RegisterUsersServer(grpcServer, usersServer)
+	if policyFile != "" {
+		protogrpc.RegisterWebhooksServer(grpcServer, webhooksServer)
+	} else {
+		logger.Log.Warnf("Webhooks service is disabled, AUTH_POLICY_FILE is not set")
+	}
 	reflection.Register(grpcServer)
 
 	// Register health service
@@ -103,6 +380,26 @@ func main() {
 
 	closeServer := make(chan struct{})
 
+	// Start metrics HTTP server asynchronously, if a port is configured, metrics are exposed on /metrics
+	var metricsServer *http.Server
+	if metricsListenPort := os.Getenv("METRICS_LISTEN_PORT"); metricsListenPort != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", promhttp.Handler())
+		metricsServer = &http.Server{
+			Addr:              fmt.Sprintf("%s:%s", os.Getenv("METRICS_LISTEN_HOST"), metricsListenPort),
+			Handler:           metricsMux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			if srvErr := metricsServer.ListenAndServe(); srvErr != nil && srvErr != http.ErrServerClosed {
+				logger.Log.Errorf("could not listen HTTP metrics(%s): %v", metricsServer.Addr, srvErr)
+			}
+		}()
+		logger.Log.Infof("Metrics exposed on %s/metrics", metricsServer.Addr)
+	} else {
+		logger.Log.Warnf("Metrics are not exposed, METRICS_LISTEN_PORT is not set")
+	}
+
 	// Start gRPC server asynchronously
 	go func() {
 		if srvErr := grpcServer.Serve(listener); srvErr != nil {
@@ -124,4 +421,29 @@ func main() {
 	logger.Log.Infof("Waiting for gRPC server to close")
 
 	grpcServer.GracefulStop()
+
+	if metricsServer != nil {
+		logger.Log.Infof("Waiting for metrics server to close")
+
+		shutdownCtx, cancelShutdown := context.WithTimeout(ctx, 5*time.Second)
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Log.Errorf("could not close metrics server: %v", err)
+		}
+		cancelShutdown()
+	}
+
+	logger.Log.Infof("Waiting for background workers to stop")
+
+	stopWorkers()
+	workers.Wait()
+
+	if asyncEventEmitter != nil {
+		logger.Log.Infof("Waiting for queued events to be emitted")
+
+		drainCtx, cancelDrain := context.WithTimeout(ctx, getEnvDuration("EVENTS_ASYNC_DRAIN_TIMEOUT", 10*time.Second))
+		defer cancelDrain()
+		if err := asyncEventEmitter.Drain(drainCtx); err != nil {
+			logger.Log.Errorf("could not emit every queued event: %v", err)
+		}
+	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/alenalato/users-service/internal/businesslogic/password"
 	"github.com/alenalato/users-service/internal/businesslogic/user"
@@ -14,11 +15,13 @@ import (
 	"github.com/ory/dockertest/v3/docker"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/types/known/fieldmaskpb"
@@ -43,20 +46,29 @@ func TestMain(m *testing.M) {
 	defer mongoDbCloser()
 
 	// Initialize password manager
-	passwordManager := password.NewBcrypt()
+	passwordManager := password.NewBcrypt(nil)
 
 	// Initialize mocked Kafka event emitter
 	mockEventEmitter := events.NewMockEventEmitter(gomock.NewController(nil))
 	mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
 	// Initialize user manager, the business logic layer
-	userManager := user.NewLogic(passwordManager, mongoDbStorage, mockEventEmitter)
+	userManager := user.NewLogic(
+		passwordManager,
+		mongoDbStorage,
+		mockEventEmitter,
+		user.Config{
+			RestoreGracePeriod: time.Hour,
+		},
+	)
 
 	// Initialize gRPC users server
 	usersServer := servicegrpc.NewUsersServer(userManager)
 
 	// Initialize gRPC server
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(servicegrpc.ActorUnaryInterceptor),
+	)
 
 	// Register users server
 	protogrpc.RegisterUsersServer(grpcServer, usersServer)
@@ -106,9 +118,11 @@ func getTestStorage() (testMongoStorage *mongodb.MongoDB, closer func()) {
 	}
 
 	// pull mongodb docker image
+	// run as a single node replica set, required by transactions
 	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
 		Repository: "mongo",
 		Tag:        "4.4",
+		Cmd:        []string{"--replSet", "rs0"},
 	}, func(config *docker.HostConfig) {
 		config.AutoRemove = true
 		config.RestartPolicy = docker.RestartPolicy{
@@ -124,21 +138,24 @@ func getTestStorage() (testMongoStorage *mongodb.MongoDB, closer func()) {
 	// exponential backoff-retry
 	err = pool.Retry(func() error {
 		dbClient, err = mongodb.NewMongoDBClient(fmt.Sprintf(
-			"mongodb://%s:%s",
+			"mongodb://%s:%s/?directConnection=true",
 			resource.Container.NetworkSettings.Gateway,
 			resource.GetPort("27017/tcp"),
-		))
+		), nil)
 		if err != nil {
 			return err
 		}
-		return dbClient.Ping(context.TODO(), nil)
+		if err = dbClient.Ping(context.TODO(), nil); err != nil {
+			return err
+		}
+		return initiateReplicaSet(dbClient)
 	})
 
 	if err != nil {
 		log.Fatalf("Could not connect to docker: %s", err)
 	}
 
-	testMongoStorage, err = mongodb.NewMongoDB(dbClient, testDbName)
+	testMongoStorage, err = mongodb.NewMongoDB(dbClient, testDbName, mongodb.Config{SoftDelete: true})
 
 	return testMongoStorage, func() {
 		// kill and remove the container
@@ -152,6 +169,32 @@ func getTestStorage() (testMongoStorage *mongodb.MongoDB, closer func()) {
 	}
 }
 
+// initiateReplicaSet initiates the single node replica set of the test container,
+// it returns an error until the node is elected primary
+func initiateReplicaSet(client *mongo.Client) error {
+	admin := client.Database("admin")
+
+	if errStatus := admin.RunCommand(context.TODO(), bson.D{{Key: "replSetGetStatus", Value: 1}}).Err(); errStatus != nil {
+		errInit := admin.RunCommand(context.TODO(), bson.D{{Key: "replSetInitiate", Value: bson.D{}}}).Err()
+		if errInit != nil {
+			return errInit
+		}
+	}
+
+	var isMaster struct {
+		IsMaster bool `bson:"ismaster"`
+	}
+	errIsMaster := admin.RunCommand(context.TODO(), bson.D{{Key: "isMaster", Value: 1}}).Decode(&isMaster)
+	if errIsMaster != nil {
+		return errIsMaster
+	}
+	if !isMaster.IsMaster {
+		return errors.New("replica set primary not elected yet")
+	}
+
+	return nil
+}
+
 // Test_Integration tests the integration of all server components using gRPC operations
 // against an actual gRPC server instance and a real MongoDB database, event emitter is mocked for now.
 // In this first version database is shared thus test cases are not isolated and can affect each other.
@@ -315,6 +358,7 @@ func Test_Integration(t *testing.T) {
 				assert.WithinDuration(t, now, res.GetUser().GetCreatedAt().AsTime(), time.Minute)
 				assert.WithinDuration(t, now, res.GetUser().GetUpdatedAt().AsTime(), time.Minute)
 				assert.Equal(t, res.GetUser().GetCreatedAt().AsTime(), res.GetUser().GetUpdatedAt().AsTime())
+				assert.Equal(t, int64(1), res.GetUser().GetVersion())
 			}
 		})
 	}
@@ -358,7 +402,8 @@ func Test_Integration(t *testing.T) {
 					Email:     "alicia@smythe.com",
 					Country:   "UK",
 				},
-				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"first_name", "last_name", "nickname", "email", "country"}},
+				Precondition: &protogrpc.UserPrecondition{Version: getTestUser(0).GetVersion()},
+				UpdateMask:   &fieldmaskpb.FieldMask{Paths: []string{"first_name", "last_name", "nickname", "email", "country"}},
 			},
 			expectedRes: &protogrpc.UpdateUserResponse{
 				User: &protogrpc.User{
@@ -368,8 +413,21 @@ func Test_Integration(t *testing.T) {
 					Email:     "alicia@smythe.com",
 					Country:   "UK",
 					CreatedAt: getTestUser(0).GetCreatedAt(),
+					Version:   getTestUser(0).GetVersion() + 1,
+				},
+			},
+		},
+		{
+			name: "Failed precondition error - stale version",
+			req: &protogrpc.UpdateUserRequest{
+				UserId: getTestUser(0).GetId(),
+				Update: &protogrpc.UpdateUserRequest_Update{
+					FirstName: "Alice",
 				},
+				Precondition: &protogrpc.UserPrecondition{Version: getTestUser(0).GetVersion()}, // already updated
+				UpdateMask:   &fieldmaskpb.FieldMask{Paths: []string{"first_name"}},
 			},
+			expectedStatusCode: codes.FailedPrecondition,
 		},
 		{
 			name: "Already exists error - duplicate nickname",
@@ -411,6 +469,7 @@ func Test_Integration(t *testing.T) {
 				assert.Equal(t, test.expectedRes.GetUser().GetEmail(), res.GetUser().GetEmail())
 				assert.Equal(t, test.expectedRes.GetUser().GetCountry(), res.GetUser().GetCountry())
 				assert.Equal(t, test.expectedRes.GetUser().GetCreatedAt(), res.GetUser().GetCreatedAt())
+				assert.Equal(t, test.expectedRes.GetUser().GetVersion(), res.GetUser().GetVersion())
 				assert.NotEmpty(t, res.GetUser().GetId())
 				assert.WithinDuration(t, now, res.GetUser().GetUpdatedAt().AsTime(), time.Minute)
 			}
@@ -530,8 +589,15 @@ func Test_Integration(t *testing.T) {
 	deleteUserTests := []struct {
 		name               string
 		userId             string
+		precondition       *protogrpc.UserPrecondition
 		expectedStatusCode codes.Code
 	}{
+		{
+			name:               "Delete user with stale version",
+			userId:             testUsers[1].GetId(), // Bob Johnson
+			precondition:       &protogrpc.UserPrecondition{Version: testUsers[1].GetVersion() + 1},
+			expectedStatusCode: codes.FailedPrecondition,
+		},
 		{
 			name:   "Delete existing user",
 			userId: testUsers[1].GetId(), // Bob Johnson
@@ -550,8 +616,8 @@ func Test_Integration(t *testing.T) {
 
 	for _, test := range deleteUserTests {
 		t.Run(test.name, func(t *testing.T) {
-			req := &protogrpc.DeleteUserRequest{UserId: test.userId}
-			_, err := testGrpcClient.DeleteUser(context.Background(), req)
+			req := &protogrpc.DeleteUserRequest{UserId: test.userId, Precondition: test.precondition}
+			resp, err := testGrpcClient.DeleteUser(context.Background(), req)
 			if test.expectedStatusCode != 0 {
 				require.Error(t, err)
 				errStatus, ok := status.FromError(err)
@@ -559,7 +625,68 @@ func Test_Integration(t *testing.T) {
 				assert.Equal(t, test.expectedStatusCode, errStatus.Code())
 			} else {
 				require.NoError(t, err)
+				assert.Equal(t, test.userId, resp.GetUser().GetId())
 			}
 		})
 	}
+
+	undeleteUserTests := []struct {
+		name               string
+		userId             string
+		expectedStatusCode codes.Code
+	}{
+		{
+			name:   "Undelete deleted user",
+			userId: testUsers[1].GetId(), // Bob Johnson
+		},
+		{
+			name:               "Undelete not deleted user",
+			userId:             testUsers[1].GetId(),
+			expectedStatusCode: codes.FailedPrecondition,
+		},
+		{
+			name:               "Undelete non-existent user",
+			userId:             "nonexistentid",
+			expectedStatusCode: codes.NotFound,
+		},
+	}
+
+	for _, test := range undeleteUserTests {
+		t.Run(test.name, func(t *testing.T) {
+			req := &protogrpc.UndeleteUserRequest{UserId: test.userId}
+			// an actor declared by unauthenticated callers is not recorded in the user history
+			ctx := metadata.AppendToOutgoingContext(context.Background(), "x-actor", "admin")
+			res, err := testGrpcClient.UndeleteUser(ctx, req)
+			if test.expectedStatusCode != 0 {
+				require.Error(t, err)
+				errStatus, ok := status.FromError(err)
+				require.True(t, ok)
+				assert.Equal(t, test.expectedStatusCode, errStatus.Code())
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, test.userId, res.GetUser().GetId())
+				assert.Equal(t, testUsers[1].GetNickname(), res.GetUser().GetNickname())
+				assert.Equal(t, testUsers[1].GetEmail(), res.GetUser().GetEmail())
+			}
+		})
+	}
+
+	t.Run("Get user history", func(t *testing.T) {
+		req := &protogrpc.GetUserHistoryRequest{UserId: testUsers[1].GetId()}
+		res, err := testGrpcClient.GetUserHistory(context.Background(), req)
+		require.NoError(t, err)
+		assert.Empty(t, res.GetNextPageToken())
+
+		revisions := res.GetRevisions()
+		require.GreaterOrEqual(t, len(revisions), 3)
+		assert.Equal(t, "created", revisions[0].GetOperation())
+		assert.Equal(t, "deleted", revisions[len(revisions)-2].GetOperation())
+
+		lastRevision := revisions[len(revisions)-1]
+		assert.Equal(t, "restored", lastRevision.GetOperation())
+		assert.Empty(t, lastRevision.GetActor())
+		for i := 1; i < len(revisions); i++ {
+			assert.Greater(t, revisions[i].GetVersion(), revisions[i-1].GetVersion())
+		}
+	})
 }
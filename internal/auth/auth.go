@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+//go:generate mockgen -destination=auth_mock.go -package=auth github.com/alenalato/users-service/internal/auth TokenVerifier
+
+// TokenVerifier is an interface for verifying the bearer tokens of the callers
+type TokenVerifier interface {
+	// Verify returns the claims of a valid token, or an unauthenticated error
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// Claims represents the verified claims of an authenticated caller
+// Subject identifies the caller
+// Scopes and Roles are the permissions granted to the caller, for authorization
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	Scopes    []string
+	Roles     []string
+}
+
+// HasScope tells if the given scope is granted to the caller
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// HasRole tells if the given role is granted to the caller
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of the context carrying the verified claims of the caller
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the verified claims carried by the context, or nil if the caller is not authenticated
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsContextKey{}).(*Claims)
+
+	return claims
+}
+
+type peerIdentityContextKey struct{}
+
+// WithPeerIdentity returns a copy of the context carrying the identity of the caller verified by its client certificate,
+// like the SPIFFE ID spiffe://example.org/ns/default/sa/billing
+func WithPeerIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, peerIdentityContextKey{}, identity)
+}
+
+// PeerIdentityFromContext returns the identity of the caller verified by its client certificate,
+// or an empty string if the caller presented none
+func PeerIdentityFromContext(ctx context.Context) string {
+	identity, _ := ctx.Value(peerIdentityContextKey{}).(string)
+
+	return identity
+}
@@ -0,0 +1,230 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxKeySetSize is the maximum size of a JSON Web Key Set document
+const maxKeySetSize = 1 << 20
+
+// jsonWebKey is a JSON Web Key, as defined by RFC 7517, holding an RSA or EC public key
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jsonWebKeySet is a JSON Web Key Set document
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// KeySet holds the public keys verifying the token signatures, by key ID,
+// loaded from a JSON Web Key Set file or URL and refreshed periodically
+type KeySet struct {
+	mu sync.RWMutex
+	// source is the path or the http(s) URL of the JSON Web Key Set
+	source string
+	// client is the HTTP client fetching the JSON Web Key Set from a URL
+	client *http.Client
+	// keys are the public keys by key ID
+	keys map[string]crypto.PublicKey
+}
+
+// Key returns the public key with the given ID
+func (s *KeySet) Key(kid string) (crypto.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	key, ok := s.keys[kid]
+
+	return key, ok
+}
+
+// Run refreshes the keys periodically until the given context is done,
+// the current keys are kept if a refresh fails
+func (s *KeySet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if errLoad := s.Load(ctx); errLoad != nil {
+			logger.Log.Errorf("Error refreshing JSON Web Key Set, keeping current keys: %v", errLoad)
+		}
+	}
+}
+
+// Load loads the keys from the JSON Web Key Set, replacing the current ones.
+// Keys not used for signatures and keys of unsupported types are skipped.
+func (s *KeySet) Load(ctx context.Context) error {
+	content, errRead := s.read(ctx)
+	if errRead != nil {
+		logger.Log.Errorf("Error reading JSON Web Key Set %s: %v", s.source, errRead)
+
+		return common.NewError(errRead, common.ErrTypeInternal)
+	}
+
+	var keySet jsonWebKeySet
+	if errUnmarshal := json.Unmarshal(content, &keySet); errUnmarshal != nil {
+		logger.Log.Errorf("Error decoding JSON Web Key Set %s: %v", s.source, errUnmarshal)
+
+		return common.NewError(errUnmarshal, common.ErrTypeInternal)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(keySet.Keys))
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, errKey := jwk.publicKey()
+		if errKey != nil {
+			logger.Log.Warnf("Skipping key %q of JSON Web Key Set %s: %v", jwk.Kid, s.source, errKey)
+
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	if len(keys) == 0 {
+		err := fmt.Errorf("no signature keys in JSON Web Key Set %s", s.source)
+		logger.Log.Error(err)
+
+		return common.NewError(err, common.ErrTypeInternal)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	logger.Log.Debugf("Loaded %d keys from JSON Web Key Set %s", len(keys), s.source)
+
+	return nil
+}
+
+// read reads the JSON Web Key Set document from its file or URL
+func (s *KeySet) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(s.source, "http://") && !strings.HasPrefix(s.source, "https://") {
+		return os.ReadFile(s.source)
+	}
+
+	req, errReq := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if errReq != nil {
+		return nil, errReq
+	}
+	res, errDo := s.client.Do(req)
+	if errDo != nil {
+		return nil, errDo
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(res.Body, maxKeySetSize))
+}
+
+// publicKey returns the RSA or EC public key of a JSON Web Key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, errN := decodeBigInt(k.N)
+		e, errE := decodeBigInt(k.E)
+		if err := errors.Join(errN, errE); err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Crv)
+		}
+
+		x, errX := base64.RawURLEncoding.DecodeString(k.X)
+		y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+		if err := errors.Join(errX, errY); err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC coordinates length")
+		}
+		// The point is validated by parsing its uncompressed encoding
+		point := append(append([]byte{4}, x...), y...)
+		if _, errPoint := ecdhCurve.NewPublicKey(point); errPoint != nil {
+			return nil, errPoint
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// decodeBigInt decodes a base64url encoded big endian unsigned integer
+func decodeBigInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) == 0 {
+		return nil, errors.New("empty integer")
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
+
+// NewKeySet creates a new KeySet instance loading the keys from the given JSON Web Key Set path or http(s) URL,
+// the timeout applies to the requests to the URL
+func NewKeySet(ctx context.Context, source string, timeout time.Duration) (*KeySet, error) {
+	keySet := &KeySet{
+		source: source,
+		client: &http.Client{Timeout: timeout},
+	}
+	if errLoad := keySet.Load(ctx); errLoad != nil {
+		return nil, errLoad
+	}
+
+	return keySet, nil
+}
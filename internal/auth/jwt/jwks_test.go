@@ -0,0 +1,173 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testKeys are the private keys of the test tokens
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return testKeys{rsa: rsaKey, ec: ecKey}
+}
+
+// jwks returns the JSON Web Key Set of the public test keys, with key IDs rsa-key and ec-key
+func (k testKeys) jwks(t *testing.T) []byte {
+	encode := func(b []byte) string {
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	ecPublicKey := k.ec.PublicKey
+
+	content, err := json.Marshal(jsonWebKeySet{Keys: []jsonWebKey{
+		{
+			Kty: "RSA",
+			Kid: "rsa-key",
+			Use: "sig",
+			N:   encode(k.rsa.N.Bytes()),
+			E:   encode(big.NewInt(int64(k.rsa.E)).Bytes()),
+		},
+		{
+			Kty: "EC",
+			Kid: "ec-key",
+			Crv: "P-256",
+			X:   encode(ecPublicKey.X.FillBytes(make([]byte, 32))),
+			Y:   encode(ecPublicKey.Y.FillBytes(make([]byte, 32))),
+		},
+		// Encryption keys and unsupported keys are skipped
+		{Kty: "RSA", Kid: "enc-key", Use: "enc", N: encode(k.rsa.N.Bytes()), E: "AQAB"},
+		{Kty: "oct", Kid: "hmac-key"},
+		{Kty: "EC", Kid: "bad-point", Crv: "P-256", X: encode(make([]byte, 32)), Y: encode(make([]byte, 32))},
+	}})
+	require.NoError(t, err)
+
+	return content
+}
+
+func TestNewKeySet_File(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, keys.jwks(t), 0o600))
+
+	keySet, err := NewKeySet(context.Background(), path, time.Second)
+	require.NoError(t, err)
+
+	rsaKey, ok := keySet.Key("rsa-key")
+	assert.True(t, ok)
+	assert.True(t, keys.rsa.PublicKey.Equal(rsaKey))
+
+	ecKey, ok := keySet.Key("ec-key")
+	assert.True(t, ok)
+	assert.True(t, keys.ec.PublicKey.Equal(ecKey))
+
+	for _, kid := range []string{"enc-key", "hmac-key", "bad-point", "unknown"} {
+		_, ok = keySet.Key(kid)
+		assert.False(t, ok, kid)
+	}
+}
+
+func TestNewKeySet_URL(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		// The keys are rotated after the first request, the third request fails
+		switch requests.Add(1) {
+		case 1:
+			_, _ = w.Write(keys.jwks(t))
+		case 2:
+			_, _ = w.Write(otherKeys.jwks(t))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	keySet, err := NewKeySet(context.Background(), server.URL, time.Second)
+	require.NoError(t, err)
+	rsaKey, _ := keySet.Key("rsa-key")
+	assert.True(t, keys.rsa.PublicKey.Equal(rsaKey))
+
+	require.NoError(t, keySet.Load(context.Background()))
+	rsaKey, _ = keySet.Key("rsa-key")
+	assert.True(t, otherKeys.rsa.PublicKey.Equal(rsaKey))
+
+	// The current keys are kept when a refresh fails
+	assert.Error(t, keySet.Load(context.Background()))
+	rsaKey, _ = keySet.Key("rsa-key")
+	assert.True(t, otherKeys.rsa.PublicKey.Equal(rsaKey))
+}
+
+func TestKeySet_Run(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, keys.jwks(t), 0o600))
+
+	keySet, err := NewKeySet(context.Background(), path, time.Second)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		keySet.Run(ctx, 10*time.Millisecond)
+	}()
+
+	require.NoError(t, os.WriteFile(path, otherKeys.jwks(t), 0o600))
+	assert.Eventually(t, func() bool {
+		rsaKey, _ := keySet.Key("rsa-key")
+		return otherKeys.rsa.PublicKey.Equal(rsaKey)
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestNewKeySet_Error(t *testing.T) {
+	dir := t.TempDir()
+	invalidPath := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("not json"), 0o600))
+	emptyPath := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(emptyPath, []byte(`{"keys":[{"kty":"oct","kid":"hmac-key"}]}`), 0o600))
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "Missing file", source: filepath.Join(dir, "missing.json")},
+		{name: "Invalid document", source: invalidPath},
+		{name: "No signature keys", source: emptyPath},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keySet, err := NewKeySet(context.Background(), tt.source, time.Second)
+			assert.Error(t, err)
+			assert.Nil(t, keySet)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"strings"
+	"time"
+)
+
+// validMethods are the accepted signature algorithms of the tokens
+var validMethods = []string{jwtlib.SigningMethodRS256.Alg(), jwtlib.SigningMethodES256.Alg()}
+
+// Config holds the configuration of the token verifier
+type Config struct {
+	// Issuer is the required issuer of the tokens
+	Issuer string
+	// Audience is the audience the tokens must be issued to
+	Audience string
+	// Leeway is the tolerated clock skew when checking the validity period of the tokens
+	Leeway time.Duration
+}
+
+// KeyProvider is an interface for retrieving the public keys verifying the token signatures, by key ID
+type KeyProvider interface {
+	Key(kid string) (crypto.PublicKey, bool)
+}
+
+// tokenClaims are the claims of a token
+// Scope holds the space separated scopes granted to the token, as defined by RFC 8693
+type tokenClaims struct {
+	jwtlib.RegisteredClaims
+	Scope string   `json:"scope,omitempty"`
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Verifier is a struct that implements the TokenVerifier interface for JSON Web Tokens signed with RS256 or ES256
+type Verifier struct {
+	// keyProvider provides the public keys verifying the token signatures
+	keyProvider KeyProvider
+	// parser parses and validates the tokens
+	parser *jwtlib.Parser
+}
+
+var _ auth.TokenVerifier = new(Verifier)
+
+// Verify verifies the signature, issuer, audience and validity period of a token and returns its claims
+func (v *Verifier) Verify(_ context.Context, token string) (*auth.Claims, error) {
+	var claims tokenClaims
+	if _, errParse := v.parser.ParseWithClaims(token, &claims, v.key); errParse != nil {
+		logger.Log.Debugf("Invalid token: %v", errParse)
+
+		return nil, common.NewError(errParse, common.ErrTypeUnauthenticated)
+	}
+	if claims.Subject == "" {
+		err := errors.New("token has no subject")
+		logger.Log.Debugf("Invalid token: %v", err)
+
+		return nil, common.NewError(err, common.ErrTypeUnauthenticated)
+	}
+
+	return &auth.Claims{
+		Subject:   claims.Subject,
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		ExpiresAt: claims.ExpiresAt.Time,
+		Scopes:    strings.Fields(claims.Scope),
+		Roles:     claims.Roles,
+	}, nil
+}
+
+// key returns the public key verifying the signature of a token, identified by the kid header
+func (v *Verifier) key(token *jwtlib.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := v.keyProvider.Key(kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key: %q", kid)
+	}
+
+	return key, nil
+}
+
+// NewVerifier creates a new Verifier instance, verifying the token signatures with the keys of the given provider
+func NewVerifier(keyProvider KeyProvider, config Config) (*Verifier, error) {
+	if keyProvider == nil || config.Issuer == "" || config.Audience == "" {
+		err := errors.New("key provider, issuer and audience are required")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	return &Verifier{
+		keyProvider: keyProvider,
+		parser: jwtlib.NewParser(
+			jwtlib.WithValidMethods(validMethods),
+			jwtlib.WithIssuer(config.Issuer),
+			jwtlib.WithAudience(config.Audience),
+			jwtlib.WithExpirationRequired(),
+			jwtlib.WithIssuedAt(),
+			jwtlib.WithLeeway(config.Leeway),
+		),
+	}, nil
+}
@@ -0,0 +1,161 @@
+package jwt
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+const testAudience = "users-service"
+
+// newTestVerifier creates a verifier of the tokens signed with the given test keys
+func newTestVerifier(t *testing.T, keys testKeys) *Verifier {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, keys.jwks(t), 0o600))
+	keySet, err := NewKeySet(context.Background(), path, time.Second)
+	require.NoError(t, err)
+
+	verifier, err := NewVerifier(keySet, Config{Issuer: testIssuer, Audience: testAudience, Leeway: time.Second})
+	require.NoError(t, err)
+
+	return verifier
+}
+
+// validClaims returns valid claims of a test token
+func validClaims() jwtlib.MapClaims {
+	return jwtlib.MapClaims{
+		"sub":   "user-123",
+		"iss":   testIssuer,
+		"aud":   []string{testAudience, "other-service"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"scope": "users:read users:write",
+		"roles": []string{"admin"},
+	}
+}
+
+// sign returns a token with the given claims, signed with the given method, key and key ID
+func sign(t *testing.T, method jwtlib.SigningMethod, key interface{}, kid string, claims jwtlib.MapClaims) string {
+	token := jwtlib.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	keys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "RS256", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "rsa-key", validClaims())},
+		{name: "ES256", token: sign(t, jwtlib.SigningMethodES256, keys.ec, "ec-key", validClaims())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token)
+			require.NoError(t, err)
+			assert.Equal(t, "user-123", claims.Subject)
+			assert.Equal(t, testIssuer, claims.Issuer)
+			assert.Equal(t, []string{testAudience, "other-service"}, claims.Audience)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), claims.ExpiresAt, 2*time.Second)
+			assert.Equal(t, []string{"users:read", "users:write"}, claims.Scopes)
+			assert.Equal(t, []string{"admin"}, claims.Roles)
+		})
+	}
+}
+
+func TestVerifier_Verify_Invalid(t *testing.T) {
+	keys := newTestKeys(t)
+	otherKeys := newTestKeys(t)
+	verifier := newTestVerifier(t, keys)
+
+	withClaim := func(name string, value interface{}) jwtlib.MapClaims {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+
+		return claims
+	}
+
+	hs256Token := jwtlib.NewWithClaims(jwtlib.SigningMethodHS256, validClaims())
+	hs256Token.Header["kid"] = "rsa-key"
+	hs256Signed, err := hs256Token.SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	noneSigned, err := jwtlib.NewWithClaims(jwtlib.SigningMethodNone, validClaims()).
+		SignedString(jwtlib.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "Malformed", token: "not-a-token"},
+		{name: "Expired", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "rsa-key",
+			withClaim("exp", time.Now().Add(-time.Minute).Unix()))},
+		{name: "Missing expiry", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "rsa-key", withClaim("exp", nil))},
+		{name: "Not yet valid", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "rsa-key",
+			withClaim("nbf", time.Now().Add(time.Minute).Unix()))},
+		{name: "Wrong issuer", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "rsa-key",
+			withClaim("iss", "https://other.example.com"))},
+		{name: "Wrong audience", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "rsa-key",
+			withClaim("aud", "other-service"))},
+		{name: "Missing subject", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "rsa-key", withClaim("sub", nil))},
+		{name: "Unknown key", token: sign(t, jwtlib.SigningMethodRS256, keys.rsa, "other-key", validClaims())},
+		{name: "Wrong key", token: sign(t, jwtlib.SigningMethodRS256, otherKeys.rsa, "rsa-key", validClaims())},
+		{name: "Key of another algorithm", token: sign(t, jwtlib.SigningMethodES256, keys.ec, "rsa-key", validClaims())},
+		{name: "RS384", token: sign(t, jwtlib.SigningMethodRS384, keys.rsa, "rsa-key", validClaims())},
+		{name: "HS256", token: hs256Signed},
+		{name: "None", token: noneSigned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token)
+			assert.Nil(t, claims)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeUnauthenticated, errCommon.Type())
+		})
+	}
+}
+
+func TestNewVerifier_Error(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, keys.jwks(t), 0o600))
+	keySet, err := NewKeySet(context.Background(), path, time.Second)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		keyProvider KeyProvider
+		config      Config
+	}{
+		{name: "Missing key provider", config: Config{Issuer: testIssuer, Audience: testAudience}},
+		{name: "Missing issuer", keyProvider: keySet, config: Config{Audience: testAudience}},
+		{name: "Missing audience", keyProvider: keySet, config: Config{Issuer: testIssuer}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := NewVerifier(tt.keyProvider, tt.config)
+			assert.Error(t, err)
+			assert.Nil(t, verifier)
+		})
+	}
+}
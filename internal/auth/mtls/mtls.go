@@ -0,0 +1,205 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config holds the TLS configuration of the server
+// CertFile and KeyFile are the PEM files of the server certificate and key
+// ClientCAFile is the PEM file of the certificate authorities verifying the client certificates,
+// client certificates are required and verified if set, otherwise they are not requested
+type Config struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+}
+
+// fileState is the state of a file, telling if it changed since it was loaded
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Credentials holds the server certificate and the client certificate authorities, loaded from their files
+// and reloaded when they change, so that certificates are rotated without restarting the server
+type Credentials struct {
+	mu sync.RWMutex
+	// config is the TLS configuration of the server
+	config Config
+	// certificate is the current server certificate
+	certificate *tls.Certificate
+	// clientCAs are the current certificate authorities verifying the client certificates, nil if not verified
+	clientCAs *x509.CertPool
+	// files are the states of the loaded files, by path
+	files map[string]fileState
+}
+
+// TLSConfig returns the TLS configuration of the server, each handshake uses the current certificates
+func (c *Credentials) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return c.handshakeConfig(), nil
+		},
+	}
+}
+
+// handshakeConfig returns the TLS configuration of a handshake with the current certificates
+func (c *Credentials) handshakeConfig() *tls.Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	config := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{*c.certificate},
+		// gRPC requires HTTP/2 to be negotiated
+		NextProtos: []string{"h2"},
+		ClientAuth: tls.NoClientCert,
+	}
+	if c.clientCAs != nil {
+		config.ClientCAs = c.clientCAs
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config
+}
+
+// Run reloads the certificates when their files change, checking them periodically until the given context is done,
+// the current certificates are kept if a reload fails
+func (c *Credentials) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !c.changed() {
+			continue
+		}
+		if errLoad := c.Load(); errLoad != nil {
+			logger.Log.Errorf("Error reloading TLS certificates, keeping current ones: %v", errLoad)
+
+			continue
+		}
+		logger.Log.Infof("TLS certificates reloaded")
+	}
+}
+
+// changed tells if any file changed since the certificates were loaded
+func (c *Credentials) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for path, loaded := range c.files {
+		current, errStat := statFile(path)
+		if errStat != nil || current != loaded {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Load loads the certificates from their files, replacing the current ones
+func (c *Credentials) Load() error {
+	paths := []string{c.config.CertFile, c.config.KeyFile}
+	if c.config.ClientCAFile != "" {
+		paths = append(paths, c.config.ClientCAFile)
+	}
+	// Files are stat before being read, so that a change while loading is detected by the next check
+	files := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		state, errStat := statFile(path)
+		if errStat != nil {
+			logger.Log.Errorf("Error reading TLS file %s: %v", path, errStat)
+
+			return common.NewError(errStat, common.ErrTypeInternal)
+		}
+		files[path] = state
+	}
+
+	certificate, errLoad := tls.LoadX509KeyPair(c.config.CertFile, c.config.KeyFile)
+	if errLoad != nil {
+		err := fmt.Errorf("could not load server certificate: %w", errLoad)
+		logger.Log.Error(err)
+
+		return common.NewError(err, common.ErrTypeInternal)
+	}
+
+	var clientCAs *x509.CertPool
+	if c.config.ClientCAFile != "" {
+		caPem, errRead := os.ReadFile(c.config.ClientCAFile)
+		if errRead != nil {
+			err := fmt.Errorf("could not read client CA file: %w", errRead)
+			logger.Log.Error(err)
+
+			return common.NewError(err, common.ErrTypeInternal)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPem) {
+			err := errors.New("no certificate found in client CA file")
+			logger.Log.Error(err)
+
+			return common.NewError(err, common.ErrTypeInternal)
+		}
+	}
+
+	c.mu.Lock()
+	c.certificate = &certificate
+	c.clientCAs = clientCAs
+	c.files = files
+	c.mu.Unlock()
+
+	return nil
+}
+
+// statFile returns the state of a file
+func statFile(path string) (fileState, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}, err
+	}
+
+	return fileState{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// SPIFFEID returns the SPIFFE ID of a certificate, its spiffe URI SAN, or an empty string if it has none
+func SPIFFEID(certificate *x509.Certificate) string {
+	for _, uri := range certificate.URIs {
+		if uri.Scheme == "spiffe" && uri.Host != "" {
+			return uri.String()
+		}
+	}
+
+	return ""
+}
+
+// NewCredentials creates a new Credentials instance loading the certificates of the given configuration
+func NewCredentials(config Config) (*Credentials, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		err := errors.New("certificate and key files are required")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	credentials := &Credentials{config: config}
+	if errLoad := credentials.Load(); errLoad != nil {
+		return nil, errLoad
+	}
+
+	return credentials, nil
+}
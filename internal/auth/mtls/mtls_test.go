@@ -0,0 +1,299 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a certificate authority issuing test certificates
+type testCA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+func newTestCA(t *testing.T) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return testCA{
+		certificate: certificate,
+		key:         key,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns the PEM certificate and key of a leaf certificate with the given common name and SPIFFE ID, if any
+func (ca testCA) issue(t *testing.T, commonName string, spiffeID string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if spiffeID != "" {
+		uri, errParse := url.Parse(spiffeID)
+		require.NoError(t, errParse)
+		template.URIs = []*url.URL{uri}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+// writeFiles writes the given contents to the files of a directory, by file name
+func writeFiles(t *testing.T, dir string, contents map[string][]byte) {
+	for name, content := range contents {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), content, 0o600))
+	}
+}
+
+// handshake performs a TLS handshake with the server configuration and returns the client certificate verified by the server
+func handshake(t *testing.T, serverConfig *tls.Config, clientConfig *tls.Config) (*x509.Certificate, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() {
+		_ = listener.Close()
+	}()
+
+	go func() {
+		clientConn, errDial := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+		if errDial != nil {
+			return
+		}
+		// The client waits for the server to verify its certificate
+		_, _ = clientConn.Read(make([]byte, 1))
+		_ = clientConn.Close()
+	}()
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	server := tls.Server(conn, serverConfig)
+	defer func() {
+		_ = server.Close()
+	}()
+	if errHandshake := server.Handshake(); errHandshake != nil {
+		return nil, errHandshake
+	}
+	state := server.ConnectionState()
+	if len(state.VerifiedChains) == 0 {
+		return nil, nil
+	}
+
+	return state.VerifiedChains[0][0], nil
+}
+
+func TestCredentials_TLSConfig(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "users-service", "", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, "billing", "spiffe://example.org/ns/default/sa/billing", x509.ExtKeyUsageClientAuth)
+	otherClientCert, otherClientKey := otherCA.issue(t, "billing", "spiffe://example.org/billing", x509.ExtKeyUsageClientAuth)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey, "ca.crt": ca.pem})
+
+	clientCertificate, err := tls.X509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	otherClientCertificate, err := tls.X509KeyPair(otherClientCert, otherClientKey)
+	require.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.certificate)
+
+	tests := []struct {
+		name         string
+		clientCAFile string
+		certificates []tls.Certificate
+		wantErr      bool
+		wantIdentity string
+	}{
+		{
+			name:         "Verified client certificate",
+			clientCAFile: filepath.Join(dir, "ca.crt"),
+			certificates: []tls.Certificate{clientCertificate},
+			wantIdentity: "spiffe://example.org/ns/default/sa/billing",
+		},
+		{
+			name:         "Client certificate of another CA",
+			clientCAFile: filepath.Join(dir, "ca.crt"),
+			certificates: []tls.Certificate{otherClientCertificate},
+			wantErr:      true,
+		},
+		{
+			name:         "Missing client certificate",
+			clientCAFile: filepath.Join(dir, "ca.crt"),
+			wantErr:      true,
+		},
+		{
+			name: "Client certificates not verified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentials, errNew := NewCredentials(Config{
+				CertFile:     filepath.Join(dir, "server.crt"),
+				KeyFile:      filepath.Join(dir, "server.key"),
+				ClientCAFile: tt.clientCAFile,
+			})
+			require.NoError(t, errNew)
+
+			peerCertificate, errHandshake := handshake(t, credentials.TLSConfig(), &tls.Config{
+				ServerName:   "users-service",
+				RootCAs:      rootCAs,
+				Certificates: tt.certificates,
+				NextProtos:   []string{"h2"},
+			})
+			if tt.wantErr {
+				assert.Error(t, errHandshake)
+
+				return
+			}
+			require.NoError(t, errHandshake)
+			if tt.wantIdentity == "" {
+				assert.Nil(t, peerCertificate)
+
+				return
+			}
+			assert.Equal(t, tt.wantIdentity, SPIFFEID(peerCertificate))
+		})
+	}
+}
+
+func TestCredentials_Run(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "users-service", "", x509.ExtKeyUsageServerAuth)
+	renewedCert, renewedKey := ca.issue(t, "users-service", "", x509.ExtKeyUsageServerAuth)
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey})
+
+	credentials, err := NewCredentials(Config{
+		CertFile: filepath.Join(dir, "server.crt"),
+		KeyFile:  filepath.Join(dir, "server.key"),
+	})
+	require.NoError(t, err)
+	currentCertificate := func() []byte {
+		return credentials.handshakeConfig().Certificates[0].Certificate[0]
+	}
+	initialCertificate := currentCertificate()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		credentials.Run(ctx, 10*time.Millisecond)
+	}()
+
+	// A certificate not matching the key is not loaded, the current one is kept until the key is renewed too
+	writeFiles(t, dir, map[string][]byte{"server.crt": renewedCert})
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, initialCertificate, currentCertificate())
+
+	writeFiles(t, dir, map[string][]byte{"server.key": renewedKey})
+	renewed, err := tls.X509KeyPair(renewedCert, renewedKey)
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return string(currentCertificate()) == string(renewed.Certificate[0])
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestNewCredentials_Error(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "users-service", "", x509.ExtKeyUsageServerAuth)
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{"server.crt": serverCert, "server.key": serverKey, "invalid.crt": []byte("invalid")})
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "Missing key file", config: Config{CertFile: filepath.Join(dir, "server.crt")}},
+		{name: "Certificate not found", config: Config{
+			CertFile: filepath.Join(dir, "missing.crt"),
+			KeyFile:  filepath.Join(dir, "server.key"),
+		}},
+		{name: "Invalid certificate", config: Config{
+			CertFile: filepath.Join(dir, "invalid.crt"),
+			KeyFile:  filepath.Join(dir, "server.key"),
+		}},
+		{name: "Invalid client CA", config: Config{
+			CertFile:     filepath.Join(dir, "server.crt"),
+			KeyFile:      filepath.Join(dir, "server.key"),
+			ClientCAFile: filepath.Join(dir, "invalid.crt"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentials, err := NewCredentials(tt.config)
+			assert.Error(t, err)
+			assert.Nil(t, credentials)
+		})
+	}
+}
+
+func TestSPIFFEID(t *testing.T) {
+	parse := func(rawURL string) *url.URL {
+		uri, err := url.Parse(rawURL)
+		require.NoError(t, err)
+
+		return uri
+	}
+
+	tests := []struct {
+		name string
+		uris []*url.URL
+		want string
+	}{
+		{name: "SPIFFE ID", uris: []*url.URL{parse("https://example.org"), parse("spiffe://example.org/billing")}, want: "spiffe://example.org/billing"},
+		{name: "No SPIFFE ID", uris: []*url.URL{parse("https://example.org")}, want: ""},
+		{name: "No URIs", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, SPIFFEID(&x509.Certificate{URIs: tt.uris}))
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Rule grants a method to the callers holding any of its roles and all of its scopes,
+// a rule without roles and scopes grants the method to every authenticated caller.
+// A rule with identities only grants the method to the callers verified by a client certificate of any of them.
+// An owner rule only grants the method on the resources owned by the caller.
+type Rule struct {
+	Roles      []string `json:"roles,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+	Identities []string `json:"identities,omitempty"`
+	Owner      bool     `json:"owner,omitempty"`
+}
+
+// allows tells if the rule grants a method to the caller with the given claims and peer identity
+func (r Rule) allows(claims *Claims, identity string, owner bool) bool {
+	if r.Owner && !owner {
+		return false
+	}
+	if len(r.Identities) > 0 && (identity == "" || !slices.Contains(r.Identities, identity)) {
+		return false
+	}
+
+	hasRole := len(r.Roles) == 0
+	for _, role := range r.Roles {
+		if claims.HasRole(role) {
+			hasRole = true
+
+			break
+		}
+	}
+	if !hasRole {
+		return false
+	}
+
+	for _, scope := range r.Scopes {
+		if !claims.HasScope(scope) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Policy is the authorization policy of the methods, by full method name like /users.v1.Users/DeleteUser.
+// A method is granted if any of its rules allows the caller, methods not in the policy are denied.
+type Policy struct {
+	Methods map[string][]Rule `json:"methods"`
+}
+
+// Authorize checks that the caller with the given claims, or peer identity, is granted the given method,
+// owner tells if the caller owns the resource of the call.
+// Callers authenticated by their client certificate only, without claims, hold no roles and scopes.
+func (p *Policy) Authorize(claims *Claims, identity string, method string, owner bool) error {
+	if claims == nil && identity == "" {
+		return common.NewError(errors.New("caller is not authenticated"), common.ErrTypePermissionDenied)
+	}
+	caller := identity
+	if claims == nil {
+		claims = &Claims{}
+	} else {
+		caller = claims.Subject
+	}
+
+	for _, rule := range p.Methods[method] {
+		if rule.allows(claims, identity, owner) {
+			return nil
+		}
+	}
+
+	logger.Log.Debugf("Caller %s denied method %s", caller, method)
+
+	return common.NewError(fmt.Errorf("caller is not allowed to call %s", method), common.ErrTypePermissionDenied)
+}
+
+// ParsePolicy parses a JSON authorization policy, like
+// {"methods": {"/users.v1.Users/UpdateUser": [{"roles": ["admin"]}, {"owner": true}]}}
+func ParsePolicy(content []byte) (*Policy, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// Misspelled fields are rejected, since they would silently change the granted methods
+	decoder.DisallowUnknownFields()
+
+	var policy Policy
+	if errDecode := decoder.Decode(&policy); errDecode != nil {
+		return nil, policyError(errDecode)
+	}
+	for method := range policy.Methods {
+		service, name, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+		if !strings.HasPrefix(method, "/") || !ok || service == "" || name == "" || strings.Contains(name, "/") {
+			return nil, policyError(fmt.Errorf("invalid method %q, expected /<service>/<method>", method))
+		}
+	}
+
+	return &policy, nil
+}
+
+// LoadPolicy reads and parses the JSON authorization policy of the given file
+func LoadPolicy(path string) (*Policy, error) {
+	content, errRead := os.ReadFile(path)
+	if errRead != nil {
+		logger.Log.Errorf("Error reading authorization policy %s: %v", path, errRead)
+
+		return nil, common.NewError(errRead, common.ErrTypeInternal)
+	}
+
+	return ParsePolicy(content)
+}
+
+// policyError returns the invalid argument error of an invalid authorization policy
+func policyError(err error) error {
+	err = fmt.Errorf("invalid authorization policy: %w", err)
+	logger.Log.Error(err)
+
+	return common.NewError(err, common.ErrTypeInvalidArgument)
+}
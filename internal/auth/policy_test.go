@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPolicy_Authorize(t *testing.T) {
+	policy, err := ParsePolicy([]byte(`{"methods": {
+		"/users.v1.Users/UpdateUser": [{"roles": ["admin", "support"]}, {"owner": true, "scopes": ["users:write"]}],
+		"/users.v1.Users/ListUsers": [{"scopes": ["users:read", "users:list"]}],
+		"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo": [{}],
+		"/users.v1.Users/DeleteUser": [{"roles": ["admin"], "identities": ["spiffe://example.org/admin-console"]}],
+		"/users.v1.Webhooks/ListWebhooks": [{"identities": ["spiffe://example.org/notifier"]}]
+	}}`))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		claims   *Claims
+		identity string
+		method   string
+		owner    bool
+		allowed  bool
+	}{
+		{name: "Any role", claims: &Claims{Roles: []string{"support"}}, method: "/users.v1.Users/UpdateUser", allowed: true},
+		{name: "Other role", claims: &Claims{Roles: []string{"auditor"}}, method: "/users.v1.Users/UpdateUser"},
+		{
+			name:    "Owner with scope",
+			claims:  &Claims{Scopes: []string{"users:write"}},
+			method:  "/users.v1.Users/UpdateUser",
+			owner:   true,
+			allowed: true,
+		},
+		{name: "Owner without scope", claims: &Claims{}, method: "/users.v1.Users/UpdateUser", owner: true},
+		{name: "Scope without ownership", claims: &Claims{Scopes: []string{"users:write"}}, method: "/users.v1.Users/UpdateUser"},
+		{
+			name:    "All scopes",
+			claims:  &Claims{Scopes: []string{"users:list", "users:read"}},
+			method:  "/users.v1.Users/ListUsers",
+			allowed: true,
+		},
+		{name: "Some scopes", claims: &Claims{Scopes: []string{"users:read"}}, method: "/users.v1.Users/ListUsers"},
+		{
+			name:    "Any authenticated caller",
+			claims:  &Claims{},
+			method:  "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+			allowed: true,
+		},
+		{
+			name:     "Any caller verified by client certificate",
+			identity: "spiffe://example.org/notifier",
+			method:   "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+			allowed:  true,
+		},
+		{
+			name:     "Identity and role",
+			claims:   &Claims{Roles: []string{"admin"}},
+			identity: "spiffe://example.org/admin-console",
+			method:   "/users.v1.Users/DeleteUser",
+			allowed:  true,
+		},
+		{name: "Role without identity", claims: &Claims{Roles: []string{"admin"}}, method: "/users.v1.Users/DeleteUser"},
+		{
+			name:     "Identity without role",
+			identity: "spiffe://example.org/admin-console",
+			method:   "/users.v1.Users/DeleteUser",
+		},
+		{
+			name:     "Identity",
+			identity: "spiffe://example.org/notifier",
+			method:   "/users.v1.Webhooks/ListWebhooks",
+			allowed:  true,
+		},
+		{
+			name:     "Other identity",
+			claims:   &Claims{Roles: []string{"admin"}},
+			identity: "spiffe://example.org/billing",
+			method:   "/users.v1.Webhooks/ListWebhooks",
+		},
+		{name: "Method not in policy", claims: &Claims{Roles: []string{"admin"}}, method: "/users.v1.Users/CreateUser"},
+		{name: "Not authenticated", method: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errAuthorize := policy.Authorize(tt.claims, tt.identity, tt.method, tt.owner)
+			if tt.allowed {
+				assert.NoError(t, errAuthorize)
+
+				return
+			}
+			var errCommon common.Error
+			assert.ErrorAs(t, errAuthorize, &errCommon)
+			assert.Equal(t, common.ErrTypePermissionDenied, errCommon.Type())
+		})
+	}
+}
+
+func TestParsePolicy_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "Invalid JSON", content: `{"methods":`},
+		{name: "Unknown field", content: `{"methods": {"/users.v1.Users/DeleteUser": [{"role": ["admin"]}]}}`},
+		{name: "Invalid rules", content: `{"methods": {"/users.v1.Users/DeleteUser": {"roles": ["admin"]}}}`},
+		{name: "Method without service", content: `{"methods": {"DeleteUser": [{"roles": ["admin"]}]}}`},
+		{name: "Method prefix", content: `{"methods": {"/users.v1.Users/": [{"roles": ["admin"]}]}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy([]byte(tt.content))
+			assert.Nil(t, policy)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"methods": {"/users.v1.Users/DeleteUser": [{"roles": ["admin"]}]}}`), 0o600))
+
+	policy, err := LoadPolicy(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]Rule{"/users.v1.Users/DeleteUser": {{Roles: []string{"admin"}}}}, policy.Methods)
+
+	policy, err = LoadPolicy(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Nil(t, policy)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
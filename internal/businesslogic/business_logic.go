@@ -9,9 +9,11 @@ import (
 // UserManager is an interface for business logic operations related to user management
 type UserManager interface {
 	CreateUser(ctx context.Context, userDetails UserDetails) (*User, error)
-	UpdateUser(ctx context.Context, userId string, userUpdate UserUpdate) (*User, error)
-	DeleteUser(ctx context.Context, userId string) error
+	UpdateUser(ctx context.Context, userId string, userUpdate UserUpdate, precondition *UserPrecondition) (*User, error)
+	DeleteUser(ctx context.Context, userId string, precondition *UserPrecondition) (*User, error)
+	UndeleteUser(ctx context.Context, userId string) (*User, error)
 	ListUsers(ctx context.Context, userFilter UserFilter, pageSize int, pageToken string) ([]User, string, error)
+	GetUserHistory(ctx context.Context, userId string, pageSize int, pageToken string) ([]UserRevision, string, error)
 }
 
 //go:generate mockgen -destination=password_manager_mock.go -package=businesslogic github.com/alenalato/users-service/internal/businesslogic PasswordManager
@@ -21,3 +23,12 @@ type PasswordManager interface {
 	GeneratePasswordHash(ctx context.Context, passwordDetails *PasswordDetails) error
 	VerifyPassword(ctx context.Context, passwordDetails *PasswordDetails) error
 }
+
+//go:generate mockgen -destination=webhook_manager_mock.go -package=businesslogic github.com/alenalato/users-service/internal/businesslogic WebhookManager
+
+// WebhookManager is an interface for business logic operations related to webhook subscriptions
+type WebhookManager interface {
+	RegisterWebhook(ctx context.Context, webhookDetails WebhookDetails) (*Webhook, error)
+	ListWebhooks(ctx context.Context, eventType string) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookId string) (*Webhook, error)
+}
@@ -56,17 +56,34 @@ func (mr *MockUserManagerMockRecorder) CreateUser(ctx, userDetails any) *gomock.
 }
 
 // DeleteUser mocks base method.
-func (m *MockUserManager) DeleteUser(ctx context.Context, userId string) error {
+func (m *MockUserManager) DeleteUser(ctx context.Context, userId string, precondition *UserPrecondition) (*User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", ctx, userId)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, userId, precondition)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockUserManagerMockRecorder) DeleteUser(ctx, userId any) *gomock.Call {
+func (mr *MockUserManagerMockRecorder) DeleteUser(ctx, userId, precondition any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserManager)(nil).DeleteUser), ctx, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserManager)(nil).DeleteUser), ctx, userId, precondition)
+}
+
+// GetUserHistory mocks base method.
+func (m *MockUserManager) GetUserHistory(ctx context.Context, userId string, pageSize int, pageToken string) ([]UserRevision, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserHistory", ctx, userId, pageSize, pageToken)
+	ret0, _ := ret[0].([]UserRevision)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetUserHistory indicates an expected call of GetUserHistory.
+func (mr *MockUserManagerMockRecorder) GetUserHistory(ctx, userId, pageSize, pageToken any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserHistory", reflect.TypeOf((*MockUserManager)(nil).GetUserHistory), ctx, userId, pageSize, pageToken)
 }
 
 // ListUsers mocks base method.
@@ -85,17 +102,32 @@ func (mr *MockUserManagerMockRecorder) ListUsers(ctx, userFilter, pageSize, page
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUserManager)(nil).ListUsers), ctx, userFilter, pageSize, pageToken)
 }
 
+// UndeleteUser mocks base method.
+func (m *MockUserManager) UndeleteUser(ctx context.Context, userId string) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UndeleteUser", ctx, userId)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UndeleteUser indicates an expected call of UndeleteUser.
+func (mr *MockUserManagerMockRecorder) UndeleteUser(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UndeleteUser", reflect.TypeOf((*MockUserManager)(nil).UndeleteUser), ctx, userId)
+}
+
 // UpdateUser mocks base method.
-func (m *MockUserManager) UpdateUser(ctx context.Context, userId string, userUpdate UserUpdate) (*User, error) {
+func (m *MockUserManager) UpdateUser(ctx context.Context, userId string, userUpdate UserUpdate, precondition *UserPrecondition) (*User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", ctx, userId, userUpdate)
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, userId, userUpdate, precondition)
 	ret0, _ := ret[0].(*User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockUserManagerMockRecorder) UpdateUser(ctx, userId, userUpdate any) *gomock.Call {
+func (mr *MockUserManagerMockRecorder) UpdateUser(ctx, userId, userUpdate, precondition any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserManager)(nil).UpdateUser), ctx, userId, userUpdate)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserManager)(nil).UpdateUser), ctx, userId, userUpdate, precondition)
 }
@@ -31,6 +31,7 @@ type User struct {
 	Country   string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int64
 }
 
 // UserUpdate represents the input details of a user to be updated
@@ -44,6 +45,12 @@ type UserUpdate struct {
 	UpdateMask []string `validate:"required"`
 }
 
+// UserPrecondition represents the expected state of a user for a write operation to be applied
+// Version must match the current version of the user
+type UserPrecondition struct {
+	Version int64
+}
+
 // UserFilter represents the input filter criteria for listing users
 // If a field is nil, it will not be used in the filter
 type UserFilter struct {
@@ -53,3 +60,41 @@ type UserFilter struct {
 	Email     *string
 	Country   *string
 }
+
+// UserRevision represents a recorded change of a user
+// Operation is one of the following: created, updated, deleted, restored, purged
+// Version is the user version resulting from the change
+type UserRevision struct {
+	ID        string
+	UserID    string
+	Version   int64
+	Operation string
+	Changes   []UserFieldChange
+	Actor     string
+	ChangedAt time.Time
+}
+
+// UserFieldChange represents the change of a user field, with its values before and after the change
+type UserFieldChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// WebhookDetails represents the input details of a webhook subscription to be registered
+// URL is a required HTTPS URL receiving the user events, HTTP URLs are only accepted if allowed
+// EventTypes is the list of user event types delivered to the webhook, all of them if empty
+type WebhookDetails struct {
+	URL        string   `validate:"required,http_url"`
+	EventTypes []string `validate:"dive,oneof=created updated deleted restored purged snapshot"`
+}
+
+// Webhook represents a webhook subscription to user events
+// Secret is the key signing the deliveries to the webhook, it is only returned on registration
+type Webhook struct {
+	ID         string
+	URL        string
+	EventTypes []string
+	Secret     string
+	CreatedAt  time.Time
+}
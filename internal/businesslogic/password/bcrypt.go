@@ -6,16 +6,21 @@ import (
 	"github.com/alenalato/users-service/internal/common"
 	"github.com/alenalato/users-service/internal/logger"
 	"golang.org/x/crypto/bcrypt"
+	"time"
 )
 
 // Bcrypt is a struct that implements the PasswordManager interface
 type Bcrypt struct {
+	// metrics records the duration of the password hashing, if set
+	metrics *Metrics
 }
 
 var _ businesslogic.PasswordManager = new(Bcrypt)
 
 // GeneratePasswordHash generates a password hash using bcrypt and stores it in the given PasswordDetails struct
 func (s *Bcrypt) GeneratePasswordHash(_ context.Context, passwordDetails *businesslogic.PasswordDetails) error {
+	defer s.metrics.observe(operationGenerate, time.Now())
+
 	bytes, bcryptErr := bcrypt.GenerateFromPassword([]byte(passwordDetails.Text), 14)
 	if bcryptErr != nil {
 		logger.Log.Errorf("bcrypt error: %v", bcryptErr)
@@ -33,6 +38,8 @@ func (s *Bcrypt) VerifyPassword(
 	_ context.Context,
 	passwordDetails *businesslogic.PasswordDetails,
 ) error {
+	defer s.metrics.observe(operationVerify, time.Now())
+
 	bcryptErr := bcrypt.CompareHashAndPassword([]byte(passwordDetails.Hash), []byte(passwordDetails.Text))
 	if bcryptErr != nil {
 		logger.Log.Errorf("bcrypt error: %v", bcryptErr)
@@ -43,7 +50,7 @@ func (s *Bcrypt) VerifyPassword(
 	return nil
 }
 
-// NewBcrypt creates a new Bcrypt instance
-func NewBcrypt() *Bcrypt {
-	return &Bcrypt{}
+// NewBcrypt creates a new Bcrypt instance, recording the duration of the password hashing in the given metrics, if any
+func NewBcrypt(metrics *Metrics) *Bcrypt {
+	return &Bcrypt{metrics: metrics}
 }
@@ -6,24 +6,30 @@ import (
 	"testing"
 
 	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGeneratePasswordHash(t *testing.T) {
-	bcrypt := NewBcrypt()
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	bcrypt := NewBcrypt(metrics)
 	ctx := context.Background()
 
 	passwordDetails := &businesslogic.PasswordDetails{
 		Text: "securepassword",
 	}
 
-	err := bcrypt.GeneratePasswordHash(ctx, passwordDetails)
+	err = bcrypt.GeneratePasswordHash(ctx, passwordDetails)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, passwordDetails.Hash)
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.duration))
 }
 
 func TestVerifyPassword(t *testing.T) {
-	bcrypt := NewBcrypt()
+	bcrypt := NewBcrypt(nil)
 	ctx := context.Background()
 
 	passwordDetails := &businesslogic.PasswordDetails{
@@ -40,7 +46,7 @@ func TestVerifyPassword(t *testing.T) {
 }
 
 func TestVerifyPassword_Invalid(t *testing.T) {
-	bcrypt := NewBcrypt()
+	bcrypt := NewBcrypt(nil)
 	ctx := context.Background()
 
 	passwordDetails := &businesslogic.PasswordDetails{
@@ -0,0 +1,46 @@
+package password
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Password hashing operations
+const (
+	operationGenerate = "generate"
+	operationVerify   = "verify"
+)
+
+// Metrics holds the metrics of the password hashing
+type Metrics struct {
+	// duration is the duration of the password hashing, by operation
+	duration *prometheus.HistogramVec
+}
+
+// observe records the duration of a password hashing operation, metrics are not recorded if nil
+func (m *Metrics) observe(operation string, start time.Time) {
+	if m == nil {
+		return
+	}
+
+	m.duration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// NewMetrics creates the metrics of the password hashing and registers them with the given registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	metrics := &Metrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "users",
+			Subsystem: "password",
+			Name:      "hash_duration_seconds",
+			Help:      "Duration of the password hashing, by operation, generate or verify.",
+			// bcrypt hashing is slow by design, it takes around a second with the cost in use
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 4, 8},
+		}, []string{"operation"}),
+	}
+	if errRegister := registerer.Register(metrics.duration); errRegister != nil {
+		return nil, errRegister
+	}
+
+	return metrics, nil
+}
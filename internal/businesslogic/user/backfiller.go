@@ -0,0 +1,173 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+	"golang.org/x/time/rate"
+	"strconv"
+)
+
+// snapshotEventIdNamespace is the namespace of the event IDs derived from user snapshots
+var snapshotEventIdNamespace = uuid.MustParse("9d3c6e21-7b4a-4f08-b5e2-1a6f0c8d4e57")
+
+// BackfillConfig holds the configuration of a backfill of user events
+type BackfillConfig struct {
+	// Filter restricts the backfilled users, every user is backfilled if empty
+	Filter businesslogic.UserFilter
+	// PageSize is the number of users listed at once, storage.MaxPageSize if not set or greater
+	PageSize int
+	// Rate is the maximum number of events emitted per second, unlimited if not set
+	Rate float64
+	// DryRun lists the backfilled users without emitting their events nor saving checkpoints
+	DryRun bool
+}
+
+// Backfiller emits a snapshot event for each current user, seeding new consumers with the state of the users.
+// Users are listed page by page in creation order, and a checkpoint with the key of the last user of each page
+// is saved after the page, so that an interrupted backfill resumes after it; events of the page being emitted
+// when the backfill was interrupted may then be emitted again.
+// Keys are not shifted by the users deleted meanwhile, so that no current user is skipped.
+type Backfiller struct {
+	// time is a time provider used for generating event timestamps
+	time common.TimeProvider
+	// converter is a model converter used for converting between different models
+	converter modelConverter
+	// userStorage is a user storage used for listing users
+	userStorage storage.UserStorage
+	// eventEmitter is an event emitter used for emitting snapshot user events
+	eventEmitter events.EventEmitter
+	// checkpointStore is the store of the backfill progress, the backfill is not resumable if nil
+	checkpointStore CheckpointStore
+	// config is the backfill configuration
+	config BackfillConfig
+}
+
+// Backfill emits a snapshot event for each user matching the filter, resuming from the saved checkpoint if any,
+// and returns how many users were backfilled by this run
+func (b *Backfiller) Backfill(ctx context.Context) (int, error) {
+	filter := b.converter.fromModelUserFilterToStorage(ctx, b.config.Filter)
+
+	checkpoint := BackfillCheckpoint{Filter: filter}
+	if b.checkpointStore != nil {
+		saved, errLoad := b.checkpointStore.Load(ctx)
+		if errLoad != nil {
+			return 0, errLoad
+		}
+		if saved != nil {
+			if !equalUserFilters(saved.Filter, filter) {
+				err := errors.New("checkpoint was saved by a backfill with a different filter")
+				logger.Log.Error(err)
+
+				return 0, common.NewError(err, common.ErrTypeInvalidArgument)
+			}
+			if saved.Completed {
+				logger.Log.Infof("Backfill already completed with %d users", saved.Users)
+
+				return 0, nil
+			}
+			checkpoint = *saved
+			logger.Log.Infof("Resuming backfill after %d users", checkpoint.Users)
+		}
+	}
+
+	limit := rate.Inf
+	if b.config.Rate > 0 {
+		limit = rate.Limit(b.config.Rate)
+	}
+	limiter := rate.NewLimiter(limit, 1)
+
+	// Coerce the page size to the one of the storage, a shorter page is the last one
+	pageSize := b.config.PageSize
+	if pageSize <= 0 || pageSize > storage.MaxPageSize {
+		pageSize = storage.MaxPageSize
+	}
+
+	backfilled := 0
+	for {
+		storageUsers, errList := b.userStorage.ListUsersAfter(ctx, filter, checkpoint.After, pageSize)
+		if errList != nil {
+			return backfilled, errList
+		}
+
+		for _, storageUser := range storageUsers {
+			if b.config.DryRun {
+				logger.Log.Infof("Dry run, user %s would be backfilled", storageUser.ID)
+				backfilled++
+
+				continue
+			}
+
+			if errWait := limiter.Wait(ctx); errWait != nil {
+				return backfilled, common.NewError(errWait, common.ErrTypeInternal)
+			}
+			if errEmit := b.eventEmitter.EmitUserEvent(ctx, b.snapshotUserEvent(ctx, storageUser)); errEmit != nil {
+				return backfilled, errEmit
+			}
+			backfilled++
+		}
+
+		if len(storageUsers) > 0 {
+			lastUser := storageUsers[len(storageUsers)-1]
+			checkpoint.After = &storage.UserKey{CreatedAt: lastUser.CreatedAt, ID: lastUser.ID}
+		}
+		checkpoint.Users += len(storageUsers)
+		checkpoint.Completed = len(storageUsers) < pageSize
+		if b.checkpointStore != nil && !b.config.DryRun {
+			if errSave := b.checkpointStore.Save(ctx, checkpoint); errSave != nil {
+				return backfilled, errSave
+			}
+		}
+
+		if checkpoint.Completed {
+			return backfilled, nil
+		}
+	}
+}
+
+// snapshotUserEvent returns the snapshot event for the given user,
+// with an event ID derived from the user ID and version, so that a snapshot emitted again keeps its event ID
+func (b *Backfiller) snapshotUserEvent(ctx context.Context, storageUser storage.User) events.UserEvent {
+	user := b.converter.fromStorageUserToModel(ctx, storageUser)
+
+	userEvent := b.converter.fromModelUserToEvent(ctx, user)
+	userEvent.EventId = uuid.NewSHA1(
+		snapshotEventIdNamespace,
+		[]byte(storageUser.ID+":"+strconv.FormatInt(storageUser.Version, 10)+":snapshot"),
+	).String()
+	userEvent.EventType = events.EventTypeSnapshot
+	userEvent.EventTime = b.time.Now().UTC()
+
+	return userEvent
+}
+
+// equalUserFilters tells if the given user filters have the same criteria
+func equalUserFilters(a, b storage.UserFilter) bool {
+	equal := func(x, y *string) bool {
+		return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+	}
+
+	return equal(a.FirstName, b.FirstName) && equal(a.LastName, b.LastName) && equal(a.Country, b.Country)
+}
+
+// NewBackfiller creates a new Backfiller instance, checkpointStore may be nil for a backfill that is not resumable
+func NewBackfiller(
+	userStorage storage.UserStorage,
+	eventEmitter events.EventEmitter,
+	checkpointStore CheckpointStore,
+	config BackfillConfig,
+) *Backfiller {
+	return &Backfiller{
+		time:            common.NewTime(),
+		converter:       newBusinessLogicModelConverter(),
+		userStorage:     userStorage,
+		eventEmitter:    eventEmitter,
+		checkpointStore: checkpointStore,
+		config:          config,
+	}
+}
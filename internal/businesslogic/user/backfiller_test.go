@@ -0,0 +1,289 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func newTestBackfiller(ts *testSuite, checkpointStore CheckpointStore, config BackfillConfig) *Backfiller {
+	backfiller := NewBackfiller(ts.mockUserStorage, ts.mockEventEmitter, checkpointStore, config)
+	backfiller.time = ts.mockTimeProvider
+	backfiller.converter = ts.mockModelConverter
+
+	return backfiller
+}
+
+func expectBackfillConversions(ts *testSuite, filter businesslogic.UserFilter, storageFilter storage.UserFilter) {
+	ts.mockModelConverter.EXPECT().fromModelUserFilterToStorage(gomock.Any(), filter).Return(storageFilter)
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user storage.User) businesslogic.User {
+			return businesslogic.User{ID: user.ID, Version: user.Version}
+		}).AnyTimes()
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user businesslogic.User) events.UserEvent {
+			return events.UserEvent{UserId: user.ID, Sequence: user.Version}
+		}).AnyTimes()
+}
+
+func TestBackfiller_Backfill_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockCheckpointStore := NewMockCheckpointStore(ts.mockCtrl)
+
+	country := "uk"
+	filter := businesslogic.UserFilter{Country: &country}
+	storageFilter := storage.UserFilter{Country: &country}
+	backfiller := newTestBackfiller(ts, mockCheckpointStore, BackfillConfig{Filter: filter, PageSize: 2})
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+	expectBackfillConversions(ts, filter, storageFilter)
+
+	secondUserKey := &storage.UserKey{CreatedAt: now, ID: "user-2"}
+
+	mockCheckpointStore.EXPECT().Load(gomock.Any()).Return(nil, nil)
+	gomock.InOrder(
+		ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storageFilter, nil, 2).
+			Return([]storage.User{
+				{ID: "user-1", Version: 3, CreatedAt: now},
+				{ID: "user-2", Version: 1, CreatedAt: now},
+			}, nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{events.UserEvent{
+			UserId:    "user-1",
+			Sequence:  3,
+			EventType: events.EventTypeSnapshot,
+			EventTime: now,
+		}}).Return(nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{events.UserEvent{
+			UserId:    "user-2",
+			Sequence:  1,
+			EventType: events.EventTypeSnapshot,
+			EventTime: now,
+		}}).Return(nil),
+		mockCheckpointStore.EXPECT().Save(gomock.Any(), BackfillCheckpoint{
+			Filter: storageFilter,
+			After:  secondUserKey,
+			Users:  2,
+		}).Return(nil),
+		ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storageFilter, secondUserKey, 2).
+			Return([]storage.User{{ID: "user-3", Version: 7, CreatedAt: now.Add(time.Second)}}, nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{events.UserEvent{
+			UserId:    "user-3",
+			Sequence:  7,
+			EventType: events.EventTypeSnapshot,
+			EventTime: now,
+		}}).Return(nil),
+		mockCheckpointStore.EXPECT().Save(gomock.Any(), BackfillCheckpoint{
+			Filter:    storageFilter,
+			After:     &storage.UserKey{CreatedAt: now.Add(time.Second), ID: "user-3"},
+			Users:     3,
+			Completed: true,
+		}).Return(nil),
+	)
+
+	backfilled, err := backfiller.Backfill(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, backfilled)
+}
+
+func TestBackfiller_Backfill_EventIds(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	backfiller := newTestBackfiller(ts, nil, BackfillConfig{})
+
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+
+	// The same users are backfilled twice, then after a change of the first user
+	storageUsers := []storage.User{{ID: "user-1", Version: 1}, {ID: "user-2", Version: 1}}
+	changedUsers := []storage.User{{ID: "user-1", Version: 2}, {ID: "user-2", Version: 1}}
+	gomock.InOrder(
+		ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, nil, storage.MaxPageSize).
+			Return(storageUsers, nil).Times(2),
+		ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, nil, storage.MaxPageSize).
+			Return(changedUsers, nil),
+	)
+
+	var eventIds []string
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, userEvent events.UserEvent) {
+			assert.NotEmpty(t, userEvent.EventId)
+			eventIds = append(eventIds, userEvent.EventId)
+		}).Return(nil).Times(6)
+
+	for i := 0; i < 3; i++ {
+		expectBackfillConversions(ts, businesslogic.UserFilter{}, storage.UserFilter{})
+		backfilled, err := backfiller.Backfill(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, backfilled)
+	}
+
+	require.Len(t, eventIds, 6)
+	// A snapshot emitted again keeps its event ID
+	assert.NotEqual(t, eventIds[0], eventIds[1])
+	assert.Equal(t, eventIds[0:2], eventIds[2:4])
+	// A snapshot of another version of the user has another event ID
+	assert.NotEqual(t, eventIds[0], eventIds[4])
+	assert.Equal(t, eventIds[1], eventIds[5])
+}
+
+func TestBackfiller_Backfill_Resume(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockCheckpointStore := NewMockCheckpointStore(ts.mockCtrl)
+	backfiller := newTestBackfiller(ts, mockCheckpointStore, BackfillConfig{})
+
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+	expectBackfillConversions(ts, businesslogic.UserFilter{}, storage.UserFilter{})
+
+	// The backfill resumes after the last backfilled user, whatever the users deleted meanwhile
+	createdAt := time.Now().UTC()
+	after := &storage.UserKey{CreatedAt: createdAt, ID: "user-100"}
+	mockCheckpointStore.EXPECT().Load(gomock.Any()).Return(&BackfillCheckpoint{After: after, Users: 100}, nil)
+	ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, after, storage.MaxPageSize).
+		Return([]storage.User{{ID: "user-101", CreatedAt: createdAt}}, nil)
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).Return(nil)
+	mockCheckpointStore.EXPECT().Save(gomock.Any(), BackfillCheckpoint{
+		After:     &storage.UserKey{CreatedAt: createdAt, ID: "user-101"},
+		Users:     101,
+		Completed: true,
+	}).Return(nil)
+
+	backfilled, err := backfiller.Backfill(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, backfilled)
+}
+
+func TestBackfiller_Backfill_Completed(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockCheckpointStore := NewMockCheckpointStore(ts.mockCtrl)
+	backfiller := newTestBackfiller(ts, mockCheckpointStore, BackfillConfig{})
+
+	expectBackfillConversions(ts, businesslogic.UserFilter{}, storage.UserFilter{})
+
+	mockCheckpointStore.EXPECT().Load(gomock.Any()).Return(&BackfillCheckpoint{Users: 10, Completed: true}, nil)
+
+	backfilled, err := backfiller.Backfill(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, backfilled)
+}
+
+func TestBackfiller_Backfill_FilterMismatch(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockCheckpointStore := NewMockCheckpointStore(ts.mockCtrl)
+
+	country := "uk"
+	otherCountry := "it"
+	filter := businesslogic.UserFilter{Country: &country}
+	backfiller := newTestBackfiller(ts, mockCheckpointStore, BackfillConfig{Filter: filter})
+
+	expectBackfillConversions(ts, filter, storage.UserFilter{Country: &country})
+
+	mockCheckpointStore.EXPECT().Load(gomock.Any()).
+		Return(&BackfillCheckpoint{Filter: storage.UserFilter{Country: &otherCountry}, Users: 10}, nil)
+
+	backfilled, err := backfiller.Backfill(context.Background())
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+	assert.Equal(t, 0, backfilled)
+}
+
+func TestBackfiller_Backfill_DryRun(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockCheckpointStore := NewMockCheckpointStore(ts.mockCtrl)
+	backfiller := newTestBackfiller(ts, mockCheckpointStore, BackfillConfig{PageSize: 2, DryRun: true})
+
+	expectBackfillConversions(ts, businesslogic.UserFilter{}, storage.UserFilter{})
+
+	// The checkpoint is read to preview the resumed backfill, but never saved
+	mockCheckpointStore.EXPECT().Load(gomock.Any()).Return(nil, nil)
+	gomock.InOrder(
+		ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, nil, 2).
+			Return([]storage.User{{ID: "user-1"}, {ID: "user-2"}}, nil),
+		ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, &storage.UserKey{ID: "user-2"}, 2).
+			Return([]storage.User{{ID: "user-3"}}, nil),
+	)
+
+	backfilled, err := backfiller.Backfill(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, backfilled)
+}
+
+func TestBackfiller_Backfill_EmitError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockCheckpointStore := NewMockCheckpointStore(ts.mockCtrl)
+	backfiller := newTestBackfiller(ts, mockCheckpointStore, BackfillConfig{})
+
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+	expectBackfillConversions(ts, businesslogic.UserFilter{}, storage.UserFilter{})
+
+	// The checkpoint is not saved, the page is backfilled again on resume
+	mockCheckpointStore.EXPECT().Load(gomock.Any()).Return(nil, nil)
+	ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, nil, storage.MaxPageSize).
+		Return([]storage.User{{ID: "user-1"}, {ID: "user-2"}}, nil)
+	gomock.InOrder(
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).Return(nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).
+			Return(common.NewError(nil, common.ErrTypeInternal)),
+	)
+
+	backfilled, err := backfiller.Backfill(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 1, backfilled)
+}
+
+func TestBackfiller_Backfill_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	backfiller := newTestBackfiller(ts, nil, BackfillConfig{})
+
+	expectBackfillConversions(ts, businesslogic.UserFilter{}, storage.UserFilter{})
+
+	ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, nil, storage.MaxPageSize).
+		Return(nil, common.NewError(nil, common.ErrTypeInternal))
+
+	backfilled, err := backfiller.Backfill(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, backfilled)
+}
+
+func TestBackfiller_Backfill_RateLimit(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	backfiller := newTestBackfiller(ts, nil, BackfillConfig{Rate: 20})
+
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+	expectBackfillConversions(ts, businesslogic.UserFilter{}, storage.UserFilter{})
+
+	ts.mockUserStorage.EXPECT().ListUsersAfter(gomock.Any(), storage.UserFilter{}, nil, storage.MaxPageSize).
+		Return([]storage.User{{ID: "user-1"}, {ID: "user-2"}, {ID: "user-3"}}, nil)
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).Return(nil).Times(3)
+
+	// The first event is emitted right away, the others one every 50ms
+	start := time.Now()
+	backfilled, err := backfiller.Backfill(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, backfilled)
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
@@ -0,0 +1,160 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+	"time"
+)
+
+// changePublisherWatcherId identifies the change publisher as watcher of user changes, to resume where it stopped
+const changePublisherWatcherId = "user-events"
+
+// changeEventIdNamespace is the namespace of the event IDs derived from user change IDs
+var changeEventIdNamespace = uuid.MustParse("5b0f4a86-3f1e-4d52-9a0c-8e6b2f7d1c39")
+
+// changeEventTypes maps the operations of user changes to the types of the user events published for them
+var changeEventTypes = map[storage.UserOperation]events.UserEventType{
+	storage.OperationCreated:  events.EventTypeCreated,
+	storage.OperationUpdated:  events.EventTypeUpdated,
+	storage.OperationDeleted:  events.EventTypeDeleted,
+	storage.OperationRestored: events.EventTypeRestored,
+	storage.OperationPurged:   events.EventTypePurged,
+}
+
+// ChangePublisher publishes user events from the changes observed on the user storage,
+// as an alternative to emitting them along with the changes.
+// Changes are published in order, at least once: a change whose event emission fails is published again.
+// Event IDs are derived from the change IDs, so that a change published again keeps its event ID.
+// A lease ensures that a single change publisher is running among service instances,
+// another one takes over where it stopped, from the resume token saved along with the lease.
+type ChangePublisher struct {
+	// owner identifies the change publisher as owner of the user changes lease
+	owner string
+	// time is a time provider used for leases
+	time common.TimeProvider
+	// converter is a model converter used for converting between different models
+	converter modelConverter
+	// userChangeStorage is a user change storage used for watching user changes
+	userChangeStorage storage.UserChangeStorage
+	// eventEmitter is an event emitter used for publishing user events
+	eventEmitter events.EventEmitter
+	// config is the user management logic configuration, defining the retry interval and the lease renewal period
+	config Config
+}
+
+// Run publishes user changes until the given context is done, resuming after errors
+func (p *ChangePublisher) Run(ctx context.Context) {
+	for {
+		errPublish := p.Publish(ctx)
+		if errPublish != nil {
+			logger.Log.Errorf("Error publishing user changes: %v", errPublish)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.ChangeStreamRetryInterval):
+		}
+	}
+}
+
+// Publish watches user changes and publishes their events until the given context is done or an error occurs.
+// Nothing is published if the lease of the user changes is held by another change publisher,
+// the lease is renewed while watching and the watch stops if it is lost.
+func (p *ChangePublisher) Publish(ctx context.Context) error {
+	acquired, errLease := p.acquireLease(ctx)
+	if errLease != nil {
+		return errLease
+	}
+	if !acquired {
+		logger.Log.Debugf("User changes lease held by another change publisher")
+
+		return nil
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	renewed := make(chan struct{})
+	defer func() {
+		stopWatch()
+		<-renewed
+	}()
+	go func() {
+		defer close(renewed)
+		p.renewLease(watchCtx, stopWatch)
+	}()
+
+	return p.userChangeStorage.WatchUserChanges(watchCtx, changePublisherWatcherId, p.owner, p.publishChange)
+}
+
+// acquireLease acquires or renews the lease of the user changes,
+// it outlives a few renewals so that it is kept while the change publisher is running
+func (p *ChangePublisher) acquireLease(ctx context.Context) (bool, error) {
+	now := p.time.Now().UTC()
+
+	return p.userChangeStorage.AcquireUserChangesLease(
+		ctx,
+		changePublisherWatcherId,
+		p.owner,
+		now,
+		now.Add(3*p.config.ChangeStreamRetryInterval),
+	)
+}
+
+// renewLease renews the lease of the user changes periodically until the given context is done,
+// it stops the watch if the lease cannot be renewed, since another change publisher may take over
+func (p *ChangePublisher) renewLease(ctx context.Context, stopWatch context.CancelFunc) {
+	ticker := time.NewTicker(p.config.ChangeStreamRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		acquired, errLease := p.acquireLease(ctx)
+		if errLease != nil && ctx.Err() != nil {
+			return
+		}
+		if errLease != nil || !acquired {
+			logger.Log.Warnf("User changes lease not renewed, stopping the watch: %v", errLease)
+			stopWatch()
+
+			return
+		}
+	}
+}
+
+// publishChange emits the user event of a user change, an emission error stops the watch
+func (p *ChangePublisher) publishChange(ctx context.Context, change storage.UserChange) error {
+	user := p.converter.fromStorageUserToModel(ctx, change.User)
+
+	userEvent := p.converter.fromModelUserToEvent(ctx, user)
+	userEvent.EventId = uuid.NewSHA1(changeEventIdNamespace, []byte(change.ID)).String()
+	userEvent.EventType = changeEventTypes[change.Operation]
+	userEvent.EventMask = change.ChangedFields
+	userEvent.EventTime = change.ChangedAt
+
+	return p.eventEmitter.EmitUserEvent(ctx, userEvent)
+}
+
+// NewChangePublisher creates a new ChangePublisher instance
+func NewChangePublisher(
+	userChangeStorage storage.UserChangeStorage,
+	eventEmitter events.EventEmitter,
+	config Config,
+) *ChangePublisher {
+	return &ChangePublisher{
+		owner:             uuid.New().String(),
+		time:              common.NewTime(),
+		converter:         newBusinessLogicModelConverter(),
+		userChangeStorage: userChangeStorage,
+		eventEmitter:      eventEmitter,
+		config:            config,
+	}
+}
@@ -0,0 +1,235 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func newTestChangePublisher(ts *testSuite) (*ChangePublisher, *storage.MockUserChangeStorage) {
+	mockUserChangeStorage := storage.NewMockUserChangeStorage(ts.mockCtrl)
+
+	changePublisher := NewChangePublisher(
+		mockUserChangeStorage,
+		ts.mockEventEmitter,
+		Config{ChangeStreamRetryInterval: time.Second},
+	)
+	changePublisher.owner = "owner-id"
+	changePublisher.time = ts.mockTimeProvider
+	changePublisher.converter = ts.mockModelConverter
+
+	return changePublisher, mockUserChangeStorage
+}
+
+// expectLease expects the user changes lease to be acquired with the given result
+func expectLease(ts *testSuite, mockUserChangeStorage *storage.MockUserChangeStorage, acquired bool, err error) {
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+	mockUserChangeStorage.EXPECT().
+		AcquireUserChangesLease(gomock.Any(), changePublisherWatcherId, "owner-id", now, now.Add(3*time.Second)).
+		Return(acquired, err)
+}
+
+// changeEventId returns the event ID expected for the event of a user change
+func changeEventId(change storage.UserChange) string {
+	return uuid.NewSHA1(changeEventIdNamespace, []byte(change.ID)).String()
+}
+
+func TestChangePublisher_Publish_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	changePublisher, mockUserChangeStorage := newTestChangePublisher(ts)
+
+	changedAt := time.Now().UTC()
+	changes := []storage.UserChange{
+		{
+			ID:        "change-1",
+			Operation: storage.OperationCreated,
+			User:      storage.User{ID: "user-id", FirstName: "John"},
+			ChangedAt: changedAt,
+		},
+		{
+			ID:            "change-2",
+			Operation:     storage.OperationUpdated,
+			User:          storage.User{ID: "user-id", FirstName: "Johnny"},
+			ChangedFields: []string{"first_name"},
+			ChangedAt:     changedAt.Add(time.Second),
+		},
+		{
+			ID:        "change-3",
+			Operation: storage.OperationPurged,
+			User:      storage.User{ID: "user-id"},
+			ChangedAt: changedAt.Add(2 * time.Second),
+		},
+	}
+
+	expectLease(ts, mockUserChangeStorage, true, nil)
+	mockUserChangeStorage.EXPECT().WatchUserChanges(gomock.Any(), changePublisherWatcherId, "owner-id", gomock.Any()).
+		DoAndReturn(func(
+			ctx context.Context,
+			_ string,
+			_ string,
+			handle func(ctx context.Context, change storage.UserChange) error,
+		) error {
+			for _, change := range changes {
+				if err := handle(ctx, change); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user storage.User) businesslogic.User {
+			return businesslogic.User{ID: user.ID, FirstName: user.FirstName}
+		}).Times(len(changes))
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user businesslogic.User) events.UserEvent {
+			return events.UserEvent{UserId: user.ID, FirstName: user.FirstName}
+		}).Times(len(changes))
+
+	gomock.InOrder(
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), events.UserEvent{
+			EventId:   changeEventId(changes[0]),
+			EventType: events.EventTypeCreated,
+			EventTime: changedAt,
+			UserId:    "user-id",
+			FirstName: "John",
+		}).Return(nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), events.UserEvent{
+			EventId:   changeEventId(changes[1]),
+			EventType: events.EventTypeUpdated,
+			EventTime: changedAt.Add(time.Second),
+			UserId:    "user-id",
+			FirstName: "Johnny",
+			EventMask: []string{"first_name"},
+		}).Return(nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), events.UserEvent{
+			EventId:   changeEventId(changes[2]),
+			EventType: events.EventTypePurged,
+			EventTime: changedAt.Add(2 * time.Second),
+			UserId:    "user-id",
+		}).Return(nil),
+	)
+
+	err := changePublisher.Publish(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestChangePublisher_Publish_EventEmitterError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	changePublisher, mockUserChangeStorage := newTestChangePublisher(ts)
+
+	change := storage.UserChange{
+		ID:        "change-id",
+		Operation: storage.OperationDeleted,
+		User:      storage.User{ID: "user-id"},
+		ChangedAt: time.Now().UTC(),
+	}
+
+	// The emission error stops the watch, so the change is published again on resume
+	expectLease(ts, mockUserChangeStorage, true, nil)
+	mockUserChangeStorage.EXPECT().WatchUserChanges(gomock.Any(), changePublisherWatcherId, "owner-id", gomock.Any()).
+		DoAndReturn(func(
+			ctx context.Context,
+			_ string,
+			_ string,
+			handle func(ctx context.Context, change storage.UserChange) error,
+		) error {
+			return handle(ctx, change)
+		})
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), change.User).
+		Return(businesslogic.User{ID: "user-id"})
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), businesslogic.User{ID: "user-id"}).
+		Return(events.UserEvent{UserId: "user-id"})
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), events.UserEvent{
+		EventId:   changeEventId(change),
+		EventType: events.EventTypeDeleted,
+		EventTime: change.ChangedAt,
+		UserId:    "user-id",
+	}).Return(common.NewError(nil, common.ErrTypeInternal))
+
+	err := changePublisher.Publish(context.Background())
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestChangePublisher_Publish_LeaseHeld(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	changePublisher, mockUserChangeStorage := newTestChangePublisher(ts)
+
+	// Another change publisher holds the lease, user changes are not watched
+	expectLease(ts, mockUserChangeStorage, false, nil)
+
+	err := changePublisher.Publish(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestChangePublisher_Publish_LeaseError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	changePublisher, mockUserChangeStorage := newTestChangePublisher(ts)
+
+	expectLease(ts, mockUserChangeStorage, false, common.NewError(nil, common.ErrTypeInternal))
+
+	err := changePublisher.Publish(context.Background())
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestChangePublisher_Publish_LeaseLost(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	changePublisher, mockUserChangeStorage := newTestChangePublisher(ts)
+	changePublisher.config.ChangeStreamRetryInterval = 10 * time.Millisecond
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).Times(2)
+	// The lease is acquired, then taken over by another change publisher before its renewal
+	gomock.InOrder(
+		mockUserChangeStorage.EXPECT().
+			AcquireUserChangesLease(gomock.Any(), changePublisherWatcherId, "owner-id", now, gomock.Any()).
+			Return(true, nil),
+		mockUserChangeStorage.EXPECT().
+			AcquireUserChangesLease(gomock.Any(), changePublisherWatcherId, "owner-id", now, gomock.Any()).
+			Return(false, nil),
+	)
+	// The watch stops once the lease is lost
+	mockUserChangeStorage.EXPECT().WatchUserChanges(gomock.Any(), changePublisherWatcherId, "owner-id", gomock.Any()).
+		DoAndReturn(func(
+			ctx context.Context,
+			_ string,
+			_ string,
+			_ func(ctx context.Context, change storage.UserChange) error,
+		) error {
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+				t.Error("watch not stopped after losing the lease")
+			}
+
+			return nil
+		})
+
+	err := changePublisher.Publish(context.Background())
+	assert.NoError(t, err)
+}
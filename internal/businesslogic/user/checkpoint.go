@@ -0,0 +1,104 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"os"
+	"path/filepath"
+)
+
+// BackfillCheckpoint records the progress of a backfill
+// Filter is the filter of the backfilled users, a checkpoint only resumes a backfill with the same filter
+// After is the key of the last backfilled user, the backfill resumes with the following users
+// Users is the number of users backfilled so far
+// Completed is set once every user has been backfilled
+type BackfillCheckpoint struct {
+	Filter    storage.UserFilter `json:"filter"`
+	After     *storage.UserKey   `json:"after,omitempty"`
+	Users     int                `json:"users"`
+	Completed bool               `json:"completed"`
+}
+
+//go:generate mockgen -source=./checkpoint.go -destination=./checkpoint_mock.go -package=user CheckpointStore
+
+// CheckpointStore stores the checkpoint of a backfill
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, nil if none was saved
+	Load(ctx context.Context) (*BackfillCheckpoint, error)
+	Save(ctx context.Context, checkpoint BackfillCheckpoint) error
+}
+
+// FileCheckpointStore is a CheckpointStore keeping the checkpoint in a JSON file
+type FileCheckpointStore struct {
+	// path is the path of the checkpoint file
+	path string
+}
+
+var _ CheckpointStore = new(FileCheckpointStore)
+
+// Load reads the checkpoint from the file, it returns nil if the file does not exist
+func (s *FileCheckpointStore) Load(_ context.Context) (*BackfillCheckpoint, error) {
+	content, errRead := os.ReadFile(s.path)
+	if errors.Is(errRead, os.ErrNotExist) {
+		return nil, nil
+	}
+	if errRead != nil {
+		logger.Log.Errorf("Error reading checkpoint file %s: %v", s.path, errRead)
+
+		return nil, common.NewError(errRead, common.ErrTypeInternal)
+	}
+
+	var checkpoint BackfillCheckpoint
+	if errUnmarshal := json.Unmarshal(content, &checkpoint); errUnmarshal != nil {
+		logger.Log.Errorf("Error decoding checkpoint file %s: %v", s.path, errUnmarshal)
+
+		return nil, common.NewError(errUnmarshal, common.ErrTypeInternal)
+	}
+
+	return &checkpoint, nil
+}
+
+// Save writes the checkpoint to a temporary file and renames it over the checkpoint file,
+// so that an interrupted save never leaves a partial checkpoint
+func (s *FileCheckpointStore) Save(_ context.Context, checkpoint BackfillCheckpoint) error {
+	content, errMarshal := json.Marshal(checkpoint)
+	if errMarshal != nil {
+		logger.Log.Errorf("Error encoding checkpoint: %v", errMarshal)
+
+		return common.NewError(errMarshal, common.ErrTypeInternal)
+	}
+
+	tmpFile, errCreate := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if errCreate != nil {
+		logger.Log.Errorf("Error creating checkpoint file %s: %v", s.path, errCreate)
+
+		return common.NewError(errCreate, common.ErrTypeInternal)
+	}
+	defer func() {
+		_ = os.Remove(tmpFile.Name())
+	}()
+
+	_, errWrite := tmpFile.Write(content)
+	errWrite = errors.Join(errWrite, tmpFile.Close())
+	if errWrite == nil {
+		errWrite = os.Rename(tmpFile.Name(), s.path)
+	}
+	if errWrite != nil {
+		logger.Log.Errorf("Error writing checkpoint file %s: %v", s.path, errWrite)
+
+		return common.NewError(errWrite, common.ErrTypeInternal)
+	}
+
+	return nil
+}
+
+// NewFileCheckpointStore creates a new FileCheckpointStore instance keeping the checkpoint in the given file
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{
+		path: path,
+	}
+}
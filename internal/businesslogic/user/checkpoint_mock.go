@@ -0,0 +1,70 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./checkpoint.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./checkpoint.go -destination=./checkpoint_mock.go -package=user CheckpointStore
+//
+
+// Package user is a generated GoMock package.
+package user
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockCheckpointStore is a mock of CheckpointStore interface.
+type MockCheckpointStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockCheckpointStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockCheckpointStoreMockRecorder is the mock recorder for MockCheckpointStore.
+type MockCheckpointStoreMockRecorder struct {
+	mock *MockCheckpointStore
+}
+
+// NewMockCheckpointStore creates a new mock instance.
+func NewMockCheckpointStore(ctrl *gomock.Controller) *MockCheckpointStore {
+	mock := &MockCheckpointStore{ctrl: ctrl}
+	mock.recorder = &MockCheckpointStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCheckpointStore) EXPECT() *MockCheckpointStoreMockRecorder {
+	return m.recorder
+}
+
+// Load mocks base method.
+func (m *MockCheckpointStore) Load(ctx context.Context) (*BackfillCheckpoint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Load", ctx)
+	ret0, _ := ret[0].(*BackfillCheckpoint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Load indicates an expected call of Load.
+func (mr *MockCheckpointStoreMockRecorder) Load(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockCheckpointStore)(nil).Load), ctx)
+}
+
+// Save mocks base method.
+func (m *MockCheckpointStore) Save(ctx context.Context, checkpoint BackfillCheckpoint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, checkpoint)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockCheckpointStoreMockRecorder) Save(ctx, checkpoint any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockCheckpointStore)(nil).Save), ctx, checkpoint)
+}
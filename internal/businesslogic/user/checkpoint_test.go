@@ -0,0 +1,55 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileCheckpointStore_LoadMissing(t *testing.T) {
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+	checkpoint, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Nil(t, checkpoint)
+}
+
+func TestFileCheckpointStore_SaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileCheckpointStore(filepath.Join(dir, "checkpoint.json"))
+
+	country := "uk"
+	checkpoint := BackfillCheckpoint{
+		Filter: storage.UserFilter{Country: &country},
+		After:  &storage.UserKey{CreatedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC), ID: "user-42"},
+		Users:  42,
+	}
+	require.NoError(t, store.Save(context.Background(), checkpoint))
+
+	// A later save replaces the checkpoint
+	checkpoint.Users = 84
+	require.NoError(t, store.Save(context.Background(), checkpoint))
+
+	loaded, err := store.Load(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, &checkpoint, loaded)
+
+	// No temporary file is left behind
+	entries, errDir := os.ReadDir(dir)
+	require.NoError(t, errDir)
+	assert.Len(t, entries, 1)
+}
+
+func TestFileCheckpointStore_LoadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	checkpoint, err := NewFileCheckpointStore(path).Load(context.Background())
+	assert.Error(t, err)
+	assert.Nil(t, checkpoint)
+}
@@ -16,7 +16,12 @@ type modelConverter interface {
 	fromModelUserDetailsToStorage(ctx context.Context, userDetails businesslogic.UserDetails) storage.UserDetails
 	fromModelUserUpdateToStorage(ctx context.Context, userUpdate businesslogic.UserUpdate) (storage.UserUpdate, error)
 	fromModelUserFilterToStorage(ctx context.Context, userFilter businesslogic.UserFilter) storage.UserFilter
+	fromModelUserPreconditionToStorage(
+		ctx context.Context,
+		precondition *businesslogic.UserPrecondition,
+	) *storage.UserPrecondition
 	fromStorageUserToModel(ctx context.Context, user storage.User) businesslogic.User
+	fromStorageUserRevisionToModel(ctx context.Context, revision storage.UserRevision) businesslogic.UserRevision
 	fromModelUserToEvent(ctx context.Context, user businesslogic.User) events.UserEvent
 }
 
@@ -98,6 +103,21 @@ func (c *businessLogicModelConverter) fromModelUserFilterToStorage(
 	return storageUserFilter
 }
 
+// fromModelUserPreconditionToStorage converts a businesslogic.UserPrecondition to a storage.UserPrecondition
+// A nil precondition is converted to nil
+func (c *businessLogicModelConverter) fromModelUserPreconditionToStorage(
+	_ context.Context,
+	precondition *businesslogic.UserPrecondition,
+) *storage.UserPrecondition {
+	if precondition == nil {
+		return nil
+	}
+
+	return &storage.UserPrecondition{
+		Version: precondition.Version,
+	}
+}
+
 // fromStorageUserToModel converts a storage.User to a businesslogic.User
 func (c *businessLogicModelConverter) fromStorageUserToModel(_ context.Context, user storage.User) businesslogic.User {
 	return businesslogic.User{
@@ -109,15 +129,43 @@ func (c *businessLogicModelConverter) fromStorageUserToModel(_ context.Context,
 		Country:   user.Country,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
+		Version:   user.Version,
+	}
+}
+
+// fromStorageUserRevisionToModel converts a storage.UserRevision to a businesslogic.UserRevision
+func (c *businessLogicModelConverter) fromStorageUserRevisionToModel(
+	_ context.Context,
+	revision storage.UserRevision,
+) businesslogic.UserRevision {
+	changes := make([]businesslogic.UserFieldChange, 0, len(revision.Changes))
+	for _, change := range revision.Changes {
+		changes = append(changes, businesslogic.UserFieldChange{
+			Field:  change.Field,
+			Before: change.Before,
+			After:  change.After,
+		})
+	}
+
+	return businesslogic.UserRevision{
+		ID:        revision.ID,
+		UserID:    revision.UserID,
+		Version:   revision.Version,
+		Operation: string(revision.Operation),
+		Changes:   changes,
+		Actor:     revision.Actor,
+		ChangedAt: revision.ChangedAt,
 	}
 }
 
 // fromModelUserToEvent converts a businesslogic.User to an events.UserEvent
+// Every change bumps the user version, so the version is the sequence number of the last change
 func (c *businessLogicModelConverter) fromModelUserToEvent(
 	_ context.Context,
 	user businesslogic.User,
 ) events.UserEvent {
 	return events.UserEvent{
+		Sequence:  user.Version,
 		UserId:    user.ID,
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
@@ -120,6 +120,16 @@ func TestFromModelUserFilterToStorage(t *testing.T) {
 	assert.Equal(t, expected, result)
 }
 
+func TestFromModelUserPreconditionToStorage(t *testing.T) {
+	converter := newBusinessLogicModelConverter()
+	ctx := context.Background()
+
+	result := converter.fromModelUserPreconditionToStorage(ctx, &businesslogic.UserPrecondition{Version: 7})
+	assert.Equal(t, &storage.UserPrecondition{Version: 7}, result)
+
+	assert.Nil(t, converter.fromModelUserPreconditionToStorage(ctx, nil))
+}
+
 func TestFromStorageUserToModel(t *testing.T) {
 	converter := newBusinessLogicModelConverter()
 	ctx := context.Background()
@@ -133,6 +143,7 @@ func TestFromStorageUserToModel(t *testing.T) {
 		Country:   "US",
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
+		Version:   4,
 	}
 
 	expected := businesslogic.User{
@@ -144,6 +155,7 @@ func TestFromStorageUserToModel(t *testing.T) {
 		Country:   "US",
 		CreatedAt: storageUser.CreatedAt,
 		UpdatedAt: storageUser.UpdatedAt,
+		Version:   4,
 	}
 
 	result := converter.fromStorageUserToModel(ctx, storageUser)
@@ -163,9 +175,11 @@ func TestFromModelUserToEvent(t *testing.T) {
 		Country:   "US",
 		CreatedAt: time.Now().UTC(),
 		UpdatedAt: time.Now().UTC(),
+		Version:   6,
 	}
 
 	expected := events.UserEvent{
+		Sequence:  6,
 		UserId:    "123",
 		FirstName: "John",
 		LastName:  "Doe",
@@ -179,3 +193,37 @@ func TestFromModelUserToEvent(t *testing.T) {
 	result := converter.fromModelUserToEvent(ctx, model)
 	assert.Equal(t, expected, result)
 }
+
+func TestFromStorageUserRevisionToModel(t *testing.T) {
+	converter := newBusinessLogicModelConverter()
+	ctx := context.Background()
+
+	storageRevision := storage.UserRevision{
+		ID:        "revision-id",
+		UserID:    "123",
+		Version:   2,
+		Operation: storage.OperationUpdated,
+		Changes: []storage.UserFieldChange{
+			{Field: "email", Before: "john.doe@example.com", After: "john@example.com"},
+			{Field: "country", Before: "US"},
+		},
+		Actor:     "admin",
+		ChangedAt: time.Now().UTC(),
+	}
+
+	expected := businesslogic.UserRevision{
+		ID:        "revision-id",
+		UserID:    "123",
+		Version:   2,
+		Operation: "updated",
+		Changes: []businesslogic.UserFieldChange{
+			{Field: "email", Before: "john.doe@example.com", After: "john@example.com"},
+			{Field: "country", Before: "US"},
+		},
+		Actor:     "admin",
+		ChangedAt: storageRevision.ChangedAt,
+	}
+
+	result := converter.fromStorageUserRevisionToModel(ctx, storageRevision)
+	assert.Equal(t, expected, result)
+}
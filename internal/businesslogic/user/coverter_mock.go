@@ -71,6 +71,20 @@ func (mr *MockmodelConverterMockRecorder) fromModelUserFilterToStorage(ctx, user
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromModelUserFilterToStorage", reflect.TypeOf((*MockmodelConverter)(nil).fromModelUserFilterToStorage), ctx, userFilter)
 }
 
+// fromModelUserPreconditionToStorage mocks base method.
+func (m *MockmodelConverter) fromModelUserPreconditionToStorage(ctx context.Context, precondition *businesslogic.UserPrecondition) *storage.UserPrecondition {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "fromModelUserPreconditionToStorage", ctx, precondition)
+	ret0, _ := ret[0].(*storage.UserPrecondition)
+	return ret0
+}
+
+// fromModelUserPreconditionToStorage indicates an expected call of fromModelUserPreconditionToStorage.
+func (mr *MockmodelConverterMockRecorder) fromModelUserPreconditionToStorage(ctx, precondition any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromModelUserPreconditionToStorage", reflect.TypeOf((*MockmodelConverter)(nil).fromModelUserPreconditionToStorage), ctx, precondition)
+}
+
 // fromModelUserToEvent mocks base method.
 func (m *MockmodelConverter) fromModelUserToEvent(ctx context.Context, user businesslogic.User) events.UserEvent {
 	m.ctrl.T.Helper()
@@ -100,6 +114,20 @@ func (mr *MockmodelConverterMockRecorder) fromModelUserUpdateToStorage(ctx, user
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromModelUserUpdateToStorage", reflect.TypeOf((*MockmodelConverter)(nil).fromModelUserUpdateToStorage), ctx, userUpdate)
 }
 
+// fromStorageUserRevisionToModel mocks base method.
+func (m *MockmodelConverter) fromStorageUserRevisionToModel(ctx context.Context, revision storage.UserRevision) businesslogic.UserRevision {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "fromStorageUserRevisionToModel", ctx, revision)
+	ret0, _ := ret[0].(businesslogic.UserRevision)
+	return ret0
+}
+
+// fromStorageUserRevisionToModel indicates an expected call of fromStorageUserRevisionToModel.
+func (mr *MockmodelConverterMockRecorder) fromStorageUserRevisionToModel(ctx, revision any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromStorageUserRevisionToModel", reflect.TypeOf((*MockmodelConverter)(nil).fromStorageUserRevisionToModel), ctx, revision)
+}
+
 // fromStorageUserToModel mocks base method.
 func (m *MockmodelConverter) fromStorageUserToModel(ctx context.Context, user storage.User) businesslogic.User {
 	m.ctrl.T.Helper()
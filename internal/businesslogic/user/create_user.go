@@ -28,34 +28,40 @@ func (l *Logic) CreateUser(ctx context.Context, userDetails businesslogic.UserDe
 	// Prepare storage user details
 	storageUserDetails := l.converter.fromModelUserDetailsToStorage(ctx, userDetails)
 
-	// Generate ID and timestamps
+	// Generate ID, timestamps and version
 	storageUserDetails.ID = uuid.New().String()
 	now := l.time.Now().UTC()
 	storageUserDetails.CreatedAt = now
 	storageUserDetails.UpdatedAt = now
+	// A new user starts from the first version, its creation is its first change
+	storageUserDetails.Version = 1
 
-	// Create user in storage
-	storageUser, errCreate := l.userStorage.CreateUser(ctx, storageUserDetails)
-	if errCreate != nil {
-		return nil, errCreate
-	}
-	if storageUser == nil {
-		err := errors.New("unexpected nil storage user")
-		logger.Log.Error(err)
+	var user businesslogic.User
 
-		return nil, common.NewError(err, common.ErrTypeInternal)
-	}
+	// Create user in storage and emit user event
+	errCreate := l.writeAndEmit(ctx, func(ctx context.Context) ([]events.UserEvent, error) {
+		storageUser, errCreate := l.userStorage.CreateUser(ctx, storageUserDetails)
+		if errCreate != nil {
+			return nil, errCreate
+		}
+		if storageUser == nil {
+			err := errors.New("unexpected nil storage user")
+			logger.Log.Error(err)
 
-	// Convert storage user to model user
-	user := l.converter.fromStorageUserToModel(ctx, *storageUser)
+			return nil, common.NewError(err, common.ErrTypeInternal)
+		}
 
-	// Emit user event
-	userEvent := l.converter.fromModelUserToEvent(ctx, user)
-	userEvent.EventType = events.EventTypeCreated
-	userEvent.EventTime = now
-	errEmit := l.eventEmitter.EmitUserEvent(ctx, userEvent)
-	if errEmit != nil {
-		logger.Log.Warn("User created without event emission: %v", userEvent)
+		// Convert storage user to model user
+		user = l.converter.fromStorageUserToModel(ctx, *storageUser)
+
+		userEvent := l.converter.fromModelUserToEvent(ctx, user)
+		userEvent.EventType = events.EventTypeCreated
+		userEvent.EventTime = now
+
+		return []events.UserEvent{userEvent}, nil
+	})
+	if errCreate != nil {
+		return nil, errCreate
 	}
 
 	return &user, nil
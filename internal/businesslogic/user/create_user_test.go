@@ -115,6 +115,7 @@ func TestLogic_CreateUser_StorageError(t *testing.T) {
 	storageUserDetailsWitTimestamps := storageUserDetails
 	storageUserDetailsWitTimestamps.CreatedAt = now
 	storageUserDetailsWitTimestamps.UpdatedAt = now
+	storageUserDetailsWitTimestamps.Version = 1
 
 	ts.mockUserStorage.EXPECT().CreateUser(gomock.Any(), storageUserDetailsMatcher{storageUserDetailsWitTimestamps}).
 		Return(nil, common.NewError(nil, common.ErrTypeAlreadyExists))
@@ -169,6 +170,7 @@ func TestLogic_CreateUser_StorageNilUserError(t *testing.T) {
 	storageUserDetailsWitTimestamps := storageUserDetails
 	storageUserDetailsWitTimestamps.CreatedAt = now
 	storageUserDetailsWitTimestamps.UpdatedAt = now
+	storageUserDetailsWitTimestamps.Version = 1
 
 	ts.mockUserStorage.EXPECT().CreateUser(gomock.Any(), storageUserDetailsMatcher{storageUserDetailsWitTimestamps}).
 		Return(nil, nil)
@@ -233,6 +235,7 @@ func TestLogic_CreateUser_EventEmitterError(t *testing.T) {
 	storageUserDetailsWitTimestamps := storageUserDetails
 	storageUserDetailsWitTimestamps.CreatedAt = now
 	storageUserDetailsWitTimestamps.UpdatedAt = now
+	storageUserDetailsWitTimestamps.Version = 1
 
 	var generatedUserID string
 	ts.mockUserStorage.EXPECT().CreateUser(gomock.Any(), storageUserDetailsMatcher{storageUserDetailsWitTimestamps}).
@@ -271,7 +274,7 @@ func TestLogic_CreateUser_EventEmitterError(t *testing.T) {
 
 	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
 
-	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).
 		Return(common.NewError(nil, common.ErrTypeInternal))
 
 	res, err := ts.userManager.CreateUser(context.Background(), userDetails)
@@ -331,6 +334,7 @@ func TestLogic_CreateUser_Success(t *testing.T) {
 	storageUserDetailsWitTimestamps := storageUserDetails
 	storageUserDetailsWitTimestamps.CreatedAt = now
 	storageUserDetailsWitTimestamps.UpdatedAt = now
+	storageUserDetailsWitTimestamps.Version = 1
 
 	var generatedUserID string
 	ts.mockUserStorage.EXPECT().CreateUser(gomock.Any(), storageUserDetailsMatcher{storageUserDetailsWitTimestamps}).
@@ -369,7 +373,7 @@ func TestLogic_CreateUser_Success(t *testing.T) {
 
 	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
 
-	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(nil)
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).Return(nil)
 
 	res, err := ts.userManager.CreateUser(context.Background(), userDetails)
 	assert.NoError(t, err)
@@ -2,27 +2,52 @@ package user
 
 import (
 	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
 	"github.com/alenalato/users-service/internal/events"
 	"github.com/alenalato/users-service/internal/logger"
 )
 
-func (l *Logic) DeleteUser(ctx context.Context, userId string) error {
-	// Delete user in storage
-	errDelete := l.userStorage.DeleteUser(ctx, userId)
-	if errDelete != nil {
-		return errDelete
-	}
+func (l *Logic) DeleteUser(
+	ctx context.Context,
+	userId string,
+	precondition *businesslogic.UserPrecondition,
+) (*businesslogic.User, error) {
+	now := l.time.Now().UTC()
 
-	// Emit user event
-	userEvent := events.UserEvent{
-		UserId:    userId,
-		EventType: events.EventTypeDeleted,
-		EventTime: l.time.Now().UTC(),
-	}
-	errEmit := l.eventEmitter.EmitUserEvent(ctx, userEvent)
-	if errEmit != nil {
-		logger.Log.Warn("User deleted without event emission: %v", userEvent)
+	var user businesslogic.User
+
+	// Delete user in storage and emit user event
+	errDelete := l.writeAndEmit(ctx, func(ctx context.Context) ([]events.UserEvent, error) {
+		storageUser, errDelete := l.userStorage.DeleteUser(
+			ctx,
+			userId,
+			now,
+			l.converter.fromModelUserPreconditionToStorage(ctx, precondition),
+		)
+		if errDelete != nil {
+			return nil, errDelete
+		}
+		if storageUser == nil {
+			err := errors.New("unexpected nil storage user")
+			logger.Log.Error(err)
+
+			return nil, common.NewError(err, common.ErrTypeInternal)
+		}
+
+		// Convert storage user to model user
+		user = l.converter.fromStorageUserToModel(ctx, *storageUser)
+
+		userEvent := l.converter.fromModelUserToEvent(ctx, user)
+		userEvent.EventType = events.EventTypeDeleted
+		userEvent.EventTime = now
+
+		return []events.UserEvent{userEvent}, nil
+	})
+	if errDelete != nil {
+		return nil, errDelete
 	}
 
-	return nil
+	return &user, nil
 }
@@ -2,8 +2,10 @@ package user
 
 import (
 	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
 	"github.com/alenalato/users-service/internal/common"
 	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"testing"
@@ -16,38 +18,118 @@ func TestLogic_DeleteUser_StorageError(t *testing.T) {
 
 	userId := "user-id"
 
-	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId).
-		Return(common.NewError(nil, common.ErrTypeNotFound))
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, gomock.Nil()).
+		Return(nil, common.NewError(nil, common.ErrTypeNotFound))
 
-	err := ts.userManager.DeleteUser(context.Background(), userId)
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, nil)
+	assert.Nil(t, res)
 	assert.Error(t, err)
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
 	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
 }
 
-func TestLogic_DeleteUser_EventEmitterError(t *testing.T) {
+func TestLogic_DeleteUser_PreconditionError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userId := "user-id"
+	precondition := &businesslogic.UserPrecondition{Version: 2}
+	storagePrecondition := &storage.UserPrecondition{Version: 2}
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), precondition).
+		Return(storagePrecondition)
+
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, storagePrecondition).
+		Return(nil, common.NewError(nil, common.ErrTypeFailedPrecondition))
+
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, precondition)
+	assert.Nil(t, res)
+	assert.Error(t, err)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+}
+
+func TestLogic_DeleteUser_StorageNilError(t *testing.T) {
 	ts := newTestSuite(t)
 	defer ts.mockCtrl.Finish()
 
 	userId := "user-id"
 
-	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId).Return(nil)
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, gomock.Nil()).Return(nil, nil)
+
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, nil)
+	assert.Nil(t, res)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestLogic_DeleteUser_EventEmitterError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userId := "user-id"
 
 	now := time.Now().UTC()
 	ts.mockTimeProvider.EXPECT().Now().Return(now)
 
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	storageUser := &storage.User{
+		ID:        userId,
+		Nickname:  "nickname",
+		Email:     "email",
+		CreatedAt: now.Add(-time.Hour),
+		UpdatedAt: now.Add(-time.Hour),
+		Version:   1,
+	}
+
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, gomock.Nil()).Return(storageUser, nil)
+
+	expectedUser := businesslogic.User{
+		ID:        storageUser.ID,
+		Nickname:  storageUser.Nickname,
+		Email:     storageUser.Email,
+		CreatedAt: storageUser.CreatedAt,
+		UpdatedAt: storageUser.UpdatedAt,
+		Version:   storageUser.Version,
+	}
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
+
 	userEvent := events.UserEvent{
-		UserId:    userId,
-		EventType: events.EventTypeDeleted,
-		EventTime: now,
+		UserId:   userId,
+		Nickname: storageUser.Nickname,
+		Email:    storageUser.Email,
 	}
 
-	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
+
+	userEvent.EventType = events.EventTypeDeleted
+	userEvent.EventTime = now
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).
 		Return(common.NewError(nil, common.ErrTypeInternal))
 
-	err := ts.userManager.DeleteUser(context.Background(), userId)
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, nil)
 	assert.NoError(t, err) // Deletion should succeed even if event emission fails
+	assert.NotNil(t, res)
+	assert.Equal(t, expectedUser, *res)
 }
 
 func TestLogic_DeleteUser_Success(t *testing.T) {
@@ -56,19 +138,60 @@ func TestLogic_DeleteUser_Success(t *testing.T) {
 
 	userId := "user-id"
 
-	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId).Return(nil)
-
 	now := time.Now().UTC()
 	ts.mockTimeProvider.EXPECT().Now().Return(now)
 
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	storageUser := &storage.User{
+		ID:        userId,
+		FirstName: "John",
+		LastName:  "Doe",
+		Nickname:  "nickname",
+		Email:     "email",
+		Country:   "uk",
+		CreatedAt: now.Add(-time.Hour),
+		UpdatedAt: now.Add(-time.Hour),
+		Version:   1,
+	}
+
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, gomock.Nil()).Return(storageUser, nil)
+
+	expectedUser := businesslogic.User{
+		ID:        storageUser.ID,
+		FirstName: storageUser.FirstName,
+		LastName:  storageUser.LastName,
+		Nickname:  storageUser.Nickname,
+		Email:     storageUser.Email,
+		Country:   storageUser.Country,
+		CreatedAt: storageUser.CreatedAt,
+		UpdatedAt: storageUser.UpdatedAt,
+		Version:   storageUser.Version,
+	}
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
+
 	userEvent := events.UserEvent{
 		UserId:    userId,
-		EventType: events.EventTypeDeleted,
-		EventTime: now,
+		FirstName: storageUser.FirstName,
+		LastName:  storageUser.LastName,
+		Nickname:  storageUser.Nickname,
+		Email:     storageUser.Email,
+		Country:   storageUser.Country,
+		CreatedAt: storageUser.CreatedAt,
+		UpdatedAt: storageUser.UpdatedAt,
 	}
 
-	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(nil)
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
+
+	// The deleted event carries the deleted user data
+	userEvent.EventType = events.EventTypeDeleted
+	userEvent.EventTime = now
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).Return(nil)
 
-	err := ts.userManager.DeleteUser(context.Background(), userId)
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, nil)
 	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, expectedUser, *res)
 }
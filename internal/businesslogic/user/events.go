@@ -0,0 +1,64 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// writeAndEmit runs a storage write and emits the user events resulting from it, each with a new event ID.
+// Without transactional outbox, events are emitted after the write and emission errors are not blocking,
+// events returned along with a write error are emitted anyway, since their changes are already applied.
+// With transactional outbox, the write and the emission run in the same storage transaction,
+// so that an error of either of them rolls back both, the write function may be run again on transient errors.
+func writeAndEmit(
+	ctx context.Context,
+	userStorage storage.UserStorage,
+	eventEmitter events.EventEmitter,
+	transactionalOutbox bool,
+	write func(ctx context.Context) ([]events.UserEvent, error),
+) error {
+	if transactionalOutbox {
+		return userStorage.WithTransaction(ctx, func(ctx context.Context) error {
+			userEvents, errWrite := write(ctx)
+			if errWrite != nil {
+				return errWrite
+			}
+			for _, userEvent := range withEventIds(userEvents) {
+				if errEmit := eventEmitter.EmitUserEvent(ctx, userEvent); errEmit != nil {
+					return errEmit
+				}
+			}
+
+			return nil
+		})
+	}
+
+	userEvents, errWrite := write(ctx)
+	for _, userEvent := range withEventIds(userEvents) {
+		if errEmit := eventEmitter.EmitUserEvent(ctx, userEvent); errEmit != nil {
+			logger.Log.Warnf(
+				"User event %s of type %s of user %s not emitted: %v",
+				userEvent.EventId, userEvent.EventType, userEvent.UserId, errEmit,
+			)
+		}
+	}
+
+	return errWrite
+}
+
+// withEventIds sets a new event ID to each of the given user events
+func withEventIds(userEvents []events.UserEvent) []events.UserEvent {
+	for i := range userEvents {
+		userEvents[i].EventId = uuid.New().String()
+	}
+
+	return userEvents
+}
+
+// writeAndEmit runs a storage write and emits the user events resulting from it, according to the logic configuration
+func (l *Logic) writeAndEmit(ctx context.Context, write func(ctx context.Context) ([]events.UserEvent, error)) error {
+	return writeAndEmit(ctx, l.userStorage, l.eventEmitter, l.config.TransactionalOutbox, write)
+}
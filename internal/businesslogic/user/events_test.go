@@ -0,0 +1,136 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// expectTransaction expects a storage transaction running the given function with the given context
+func expectTransaction(ts *testSuite) {
+	ts.mockUserStorage.EXPECT().WithTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		})
+}
+
+// userEventMatcher matches a user event regardless of its event ID, as long as it has one
+type userEventMatcher struct {
+	expected events.UserEvent
+}
+
+func (m userEventMatcher) Matches(x interface{}) bool {
+	actual, ok := x.(events.UserEvent)
+	if !ok {
+		return false
+	}
+
+	return cmp.Equal(m.expected, actual,
+		cmpopts.IgnoreFields(events.UserEvent{}, "EventId")) &&
+		actual.EventId != ""
+}
+
+func (m userEventMatcher) String() string {
+	return "matches events.UserEvent"
+}
+
+func TestLogic_DeleteUser_TransactionalOutbox_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.userManager.config.TransactionalOutbox = true
+
+	userId := "user-id"
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	expectTransaction(ts)
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	storageUser := &storage.User{ID: userId, Nickname: "nickname", Version: 1}
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, gomock.Nil()).Return(storageUser, nil)
+
+	expectedUser := businesslogic.User{ID: userId, Nickname: "nickname", Version: 1}
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
+
+	userEvent := events.UserEvent{UserId: userId, Nickname: "nickname"}
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
+
+	userEvent.EventType = events.EventTypeDeleted
+	userEvent.EventTime = now
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).Return(nil)
+
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, expectedUser, *res)
+}
+
+func TestLogic_DeleteUser_TransactionalOutbox_EventEmitterError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.userManager.config.TransactionalOutbox = true
+
+	userId := "user-id"
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	expectTransaction(ts)
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	storageUser := &storage.User{ID: userId, Version: 1}
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, gomock.Nil()).Return(storageUser, nil)
+
+	expectedUser := businesslogic.User{ID: userId, Version: 1}
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).
+		Return(events.UserEvent{UserId: userId})
+
+	// Storing the event in the outbox fails, so the deletion is rolled back
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).
+		Return(common.NewError(nil, common.ErrTypeInternal))
+
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, nil)
+	assert.Nil(t, res)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestLogic_DeleteUser_TransactionalOutbox_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.userManager.config.TransactionalOutbox = true
+
+	userId := "user-id"
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	expectTransaction(ts)
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	ts.mockUserStorage.EXPECT().DeleteUser(gomock.Any(), userId, now, gomock.Nil()).
+		Return(nil, common.NewError(nil, common.ErrTypeNotFound))
+
+	res, err := ts.userManager.DeleteUser(context.Background(), userId, nil)
+	assert.Nil(t, res)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+}
@@ -0,0 +1,44 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+)
+
+func (l *Logic) GetUserHistory(
+	ctx context.Context,
+	userId string,
+	pageSize int,
+	pageToken string,
+) ([]businesslogic.UserRevision, string, error) {
+	// Validate input
+	errValidate := errors.Join(
+		validate.Var(userId, "required"),
+		validate.Var(pageSize, "gte=0"),
+		validate.Var(pageSize, fmt.Sprintf("lte=%d", storage.MaxPageSize)),
+	)
+	if errValidate != nil {
+		logger.Log.Errorf("validation error: %v", errValidate)
+
+		return nil, "", common.NewError(errValidate, common.ErrTypeInvalidArgument)
+	}
+
+	// Get user history from storage
+	storageRevisions, nextPageToken, errHistory := l.userStorage.GetUserHistory(ctx, userId, pageSize, pageToken)
+	if errHistory != nil {
+		return nil, "", errHistory
+	}
+
+	// Convert storage revisions to model revisions
+	var revisions []businesslogic.UserRevision
+	for _, storageRevision := range storageRevisions {
+		revisions = append(revisions, l.converter.fromStorageUserRevisionToModel(ctx, storageRevision))
+	}
+
+	return revisions, nextPageToken, nil
+}
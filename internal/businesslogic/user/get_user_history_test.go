@@ -0,0 +1,113 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestLogic_GetUserHistory_ValidationError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	tests := []struct {
+		name     string
+		userId   string
+		pageSize int
+	}{
+		{
+			name:     "Missing user ID",
+			userId:   "",
+			pageSize: 10,
+		},
+		{
+			name:     "Negative page size",
+			userId:   "user-id",
+			pageSize: -1,
+		},
+		{
+			name:     "Page size too large",
+			userId:   "user-id",
+			pageSize: storage.MaxPageSize + 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revisions, nextPageToken, err := ts.userManager.GetUserHistory(context.Background(), tt.userId, tt.pageSize, "")
+
+			assert.Nil(t, revisions)
+			assert.Empty(t, nextPageToken)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+		})
+	}
+}
+
+func TestLogic_GetUserHistory_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockUserStorage.EXPECT().GetUserHistory(gomock.Any(), "user-id", 10, "token").
+		Return(nil, "", common.NewError(errors.New("storage error"), common.ErrTypeInvalidArgument))
+
+	revisions, nextPageToken, err := ts.userManager.GetUserHistory(context.Background(), "user-id", 10, "token")
+
+	assert.Nil(t, revisions)
+	assert.Empty(t, nextPageToken)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+}
+
+func TestLogic_GetUserHistory_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	storageRevisions := []storage.UserRevision{
+		{
+			ID:        "revision-1",
+			UserID:    "user-id",
+			Version:   1,
+			Operation: storage.OperationCreated,
+			Changes: []storage.UserFieldChange{
+				{Field: "nickname", After: "johndoe"},
+			},
+			Actor:     "admin",
+			ChangedAt: now.Add(-time.Hour),
+		},
+		{
+			ID:        "revision-2",
+			UserID:    "user-id",
+			Version:   2,
+			Operation: storage.OperationUpdated,
+			Changes: []storage.UserFieldChange{
+				{Field: "nickname", Before: "johndoe", After: "johnd"},
+			},
+			Actor:     "admin",
+			ChangedAt: now,
+		},
+	}
+
+	ts.mockUserStorage.EXPECT().GetUserHistory(gomock.Any(), "user-id", 0, "").
+		Return(storageRevisions, "next-token", nil)
+
+	ts.mockModelConverter.EXPECT().fromStorageUserRevisionToModel(gomock.Any(), storageRevisions[0]).
+		Return(businesslogic.UserRevision{ID: "revision-1"})
+	ts.mockModelConverter.EXPECT().fromStorageUserRevisionToModel(gomock.Any(), storageRevisions[1]).
+		Return(businesslogic.UserRevision{ID: "revision-2"})
+
+	revisions, nextPageToken, err := ts.userManager.GetUserHistory(context.Background(), "user-id", 0, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "next-token", nextPageToken)
+	assert.Equal(t, []businesslogic.UserRevision{{ID: "revision-1"}, {ID: "revision-2"}}, revisions)
+}
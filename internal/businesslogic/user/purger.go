@@ -0,0 +1,119 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"time"
+)
+
+// purgeBatchSize is the maximum number of users purged in a single storage call
+const purgeBatchSize = 100
+
+// Purger permanently removes soft deleted users after their retention window,
+// emitting a purged event for each of them
+type Purger struct {
+	// time is a time provider used for computing the retention window
+	time common.TimeProvider
+	// converter is a model converter used for converting between different models
+	converter modelConverter
+	// userStorage is a user storage used for purging deleted users
+	userStorage storage.UserStorage
+	// eventEmitter is an event emitter used for emitting purged user events
+	eventEmitter events.EventEmitter
+	// config is the user management logic configuration, defining purge retention and interval
+	config Config
+}
+
+// Run purges deleted users periodically until the given context is done
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.config.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, errPurge := p.Purge(ctx)
+		if errPurge != nil {
+			logger.Log.Errorf("Error purging deleted users: %v", errPurge)
+		} else if purged > 0 {
+			logger.Log.Infof("Purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge permanently removes all users deleted before the retention window and returns how many were purged
+func (p *Purger) Purge(ctx context.Context) (int, error) {
+	// Purges are performed by the service itself
+	ctx = common.WithActor(ctx, common.SystemActor)
+
+	purgedAt := p.time.Now().UTC()
+	deletedBefore := purgedAt.Add(-p.config.PurgeRetention)
+
+	purged := 0
+	for {
+		batchPurged := 0
+		errPurge := writeAndEmit(
+			ctx,
+			p.userStorage,
+			p.eventEmitter,
+			p.config.TransactionalOutbox,
+			func(ctx context.Context) ([]events.UserEvent, error) {
+				storageUsers, errPurge := p.userStorage.PurgeDeletedUsers(ctx, deletedBefore, purgedAt, purgeBatchSize)
+				batchPurged = len(storageUsers)
+
+				// Users purged before an error are returned too, their events are emitted unless rolled back
+				userEvents := make([]events.UserEvent, 0, len(storageUsers))
+				for _, storageUser := range storageUsers {
+					userEvents = append(userEvents, p.purgedUserEvent(ctx, storageUser))
+				}
+
+				return userEvents, errPurge
+			},
+		)
+		if errPurge != nil {
+			// The whole batch is rolled back with the transactional outbox
+			if !p.config.TransactionalOutbox {
+				purged += batchPurged
+			}
+
+			return purged, errPurge
+		}
+		purged += batchPurged
+		if batchPurged < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// purgedUserEvent returns the purged event for the given user
+func (p *Purger) purgedUserEvent(ctx context.Context, storageUser storage.User) events.UserEvent {
+	user := p.converter.fromStorageUserToModel(ctx, storageUser)
+
+	userEvent := p.converter.fromModelUserToEvent(ctx, user)
+	userEvent.EventType = events.EventTypePurged
+	userEvent.EventTime = p.time.Now().UTC()
+
+	return userEvent
+}
+
+// NewPurger creates a new Purger instance
+func NewPurger(
+	userStorage storage.UserStorage,
+	eventEmitter events.EventEmitter,
+	config Config,
+) *Purger {
+	return &Purger{
+		time:         common.NewTime(),
+		converter:    newBusinessLogicModelConverter(),
+		userStorage:  userStorage,
+		eventEmitter: eventEmitter,
+		config:       config,
+	}
+}
@@ -0,0 +1,131 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func newTestPurger(ts *testSuite) *Purger {
+	purger := NewPurger(
+		ts.mockUserStorage,
+		ts.mockEventEmitter,
+		Config{PurgeRetention: 24 * time.Hour, PurgeInterval: time.Hour},
+	)
+	purger.time = ts.mockTimeProvider
+	purger.converter = ts.mockModelConverter
+
+	return purger
+}
+
+func TestPurger_Purge_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	purger := newTestPurger(ts)
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	// A full batch is followed by another purge call
+	firstBatch := make([]storage.User, purgeBatchSize)
+	for i := range firstBatch {
+		firstBatch[i] = storage.User{ID: fmt.Sprintf("user-%d", i)}
+	}
+	secondBatch := []storage.User{{ID: "last-user"}}
+
+	gomock.InOrder(
+		ts.mockUserStorage.EXPECT().PurgeDeletedUsers(gomock.Any(), now.Add(-24*time.Hour), now, purgeBatchSize).
+			Return(firstBatch, nil),
+		ts.mockUserStorage.EXPECT().PurgeDeletedUsers(gomock.Any(), now.Add(-24*time.Hour), now, purgeBatchSize).
+			Return(secondBatch, nil),
+	)
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user storage.User) businesslogic.User {
+			return businesslogic.User{ID: user.ID}
+		}).Times(purgeBatchSize + 1)
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, user businesslogic.User) events.UserEvent {
+			return events.UserEvent{UserId: user.ID}
+		}).Times(purgeBatchSize + 1)
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{events.UserEvent{
+		UserId:    "last-user",
+		EventType: events.EventTypePurged,
+		EventTime: now,
+	}}).Return(nil)
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).Return(nil).Times(purgeBatchSize)
+
+	purged, err := purger.Purge(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, purgeBatchSize+1, purged)
+}
+
+func TestPurger_Purge_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	purger := newTestPurger(ts)
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	// Users purged before the error still get their events
+	storageUser := storage.User{ID: "user-id"}
+	ts.mockUserStorage.EXPECT().PurgeDeletedUsers(gomock.Any(), now.Add(-24*time.Hour), now, purgeBatchSize).
+		Return([]storage.User{storageUser}, common.NewError(nil, common.ErrTypeInternal))
+
+	user := businesslogic.User{ID: storageUser.ID}
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), storageUser).Return(user)
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), user).
+		Return(events.UserEvent{UserId: user.ID})
+
+	// Emission errors are not blocking
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{events.UserEvent{
+		UserId:    user.ID,
+		EventType: events.EventTypePurged,
+		EventTime: now,
+	}}).Return(common.NewError(nil, common.ErrTypeInternal))
+
+	purged, err := purger.Purge(context.Background())
+	assert.Equal(t, 1, purged)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestPurger_Purge_TransactionalOutbox_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	purger := newTestPurger(ts)
+	purger.config.TransactionalOutbox = true
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	expectTransaction(ts)
+
+	// The whole batch is rolled back, so no event is emitted and no user is counted as purged
+	ts.mockUserStorage.EXPECT().PurgeDeletedUsers(gomock.Any(), now.Add(-24*time.Hour), now, purgeBatchSize).
+		Return([]storage.User{{ID: "user-id"}}, common.NewError(nil, common.ErrTypeInternal))
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), gomock.Any()).
+		Return(businesslogic.User{ID: "user-id"})
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), gomock.Any()).
+		Return(events.UserEvent{UserId: "user-id"})
+
+	purged, err := purger.Purge(context.Background())
+	assert.Equal(t, 0, purged)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
@@ -0,0 +1,49 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+)
+
+func (l *Logic) UndeleteUser(ctx context.Context, userId string) (*businesslogic.User, error) {
+	now := l.time.Now().UTC()
+
+	var user businesslogic.User
+
+	// Restore user in storage, only users deleted within the grace period can be restored, and emit user event
+	errUndelete := l.writeAndEmit(ctx, func(ctx context.Context) ([]events.UserEvent, error) {
+		storageUser, errUndelete := l.userStorage.UndeleteUser(
+			ctx,
+			userId,
+			now.Add(-l.config.RestoreGracePeriod),
+			now,
+		)
+		if errUndelete != nil {
+			return nil, errUndelete
+		}
+		if storageUser == nil {
+			err := errors.New("unexpected nil storage user")
+			logger.Log.Error(err)
+
+			return nil, common.NewError(err, common.ErrTypeInternal)
+		}
+
+		// Convert storage user to model user
+		user = l.converter.fromStorageUserToModel(ctx, *storageUser)
+
+		userEvent := l.converter.fromModelUserToEvent(ctx, user)
+		userEvent.EventType = events.EventTypeRestored
+		userEvent.EventTime = now
+
+		return []events.UserEvent{userEvent}, nil
+	})
+	if errUndelete != nil {
+		return nil, errUndelete
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,156 @@
+package user
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestLogic_UndeleteUser_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userId := "user-id"
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockUserStorage.EXPECT().UndeleteUser(gomock.Any(), userId, now.Add(-time.Hour), now).
+		Return(nil, common.NewError(nil, common.ErrTypeFailedPrecondition))
+
+	res, err := ts.userManager.UndeleteUser(context.Background(), userId)
+	assert.Nil(t, res)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+}
+
+func TestLogic_UndeleteUser_StorageNilError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userId := "user-id"
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockUserStorage.EXPECT().UndeleteUser(gomock.Any(), userId, now.Add(-time.Hour), now).Return(nil, nil)
+
+	res, err := ts.userManager.UndeleteUser(context.Background(), userId)
+	assert.Nil(t, res)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestLogic_UndeleteUser_EventEmitterError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userId := "user-id"
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	storageUser := &storage.User{
+		ID:        userId,
+		Nickname:  "nickname",
+		Email:     "email",
+		CreatedAt: now.Add(-time.Hour),
+		UpdatedAt: now,
+		Version:   3,
+	}
+
+	ts.mockUserStorage.EXPECT().UndeleteUser(gomock.Any(), userId, now.Add(-time.Hour), now).
+		Return(storageUser, nil)
+
+	expectedUser := businesslogic.User{
+		ID:        storageUser.ID,
+		Nickname:  storageUser.Nickname,
+		Email:     storageUser.Email,
+		CreatedAt: storageUser.CreatedAt,
+		UpdatedAt: storageUser.UpdatedAt,
+		Version:   storageUser.Version,
+	}
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
+
+	userEvent := events.UserEvent{
+		UserId:   userId,
+		Nickname: storageUser.Nickname,
+		Email:    storageUser.Email,
+	}
+
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
+
+	userEvent.EventType = events.EventTypeRestored
+	userEvent.EventTime = now
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).
+		Return(common.NewError(nil, common.ErrTypeInternal))
+
+	res, err := ts.userManager.UndeleteUser(context.Background(), userId)
+	// Restore should succeed even if event emission fails
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, expectedUser, *res)
+}
+
+func TestLogic_UndeleteUser_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userId := "user-id"
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	storageUser := &storage.User{
+		ID:        userId,
+		Nickname:  "nickname",
+		Email:     "email",
+		CreatedAt: now.Add(-time.Hour),
+		UpdatedAt: now,
+		Version:   3,
+	}
+
+	ts.mockUserStorage.EXPECT().UndeleteUser(gomock.Any(), userId, now.Add(-time.Hour), now).
+		Return(storageUser, nil)
+
+	expectedUser := businesslogic.User{
+		ID:        storageUser.ID,
+		Nickname:  storageUser.Nickname,
+		Email:     storageUser.Email,
+		CreatedAt: storageUser.CreatedAt,
+		UpdatedAt: storageUser.UpdatedAt,
+		Version:   storageUser.Version,
+	}
+
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
+
+	userEvent := events.UserEvent{
+		UserId:    userId,
+		Nickname:  storageUser.Nickname,
+		Email:     storageUser.Email,
+		CreatedAt: storageUser.CreatedAt,
+		UpdatedAt: storageUser.UpdatedAt,
+	}
+
+	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
+
+	userEvent.EventType = events.EventTypeRestored
+	userEvent.EventTime = now
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).Return(nil)
+
+	res, err := ts.userManager.UndeleteUser(context.Background(), userId)
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, expectedUser, *res)
+}
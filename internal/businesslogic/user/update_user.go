@@ -13,6 +13,7 @@ func (l *Logic) UpdateUser(
 	ctx context.Context,
 	userId string,
 	userUpdate businesslogic.UserUpdate,
+	precondition *businesslogic.UserPrecondition,
 ) (*businesslogic.User, error) {
 	// Validate input
 	errValidate := validate.Struct(userUpdate)
@@ -32,30 +33,65 @@ func (l *Logic) UpdateUser(
 	now := l.time.Now().UTC()
 	storageUserUpdate.UpdatedAt = &now
 
-	// Update user in storage
-	storageUser, errUpdate := l.userStorage.UpdateUser(ctx, userId, storageUserUpdate)
+	var user businesslogic.User
+
+	// Update user in storage and emit user event
+	errUpdate := l.writeAndEmit(ctx, func(ctx context.Context) ([]events.UserEvent, error) {
+		storageUser, storagePrevious, errUpdate := l.userStorage.UpdateUser(
+			ctx,
+			userId,
+			storageUserUpdate,
+			l.converter.fromModelUserPreconditionToStorage(ctx, precondition),
+		)
+		if errUpdate != nil {
+			return nil, errUpdate
+		}
+		if storageUser == nil || storagePrevious == nil {
+			err := errors.New("unexpected nil storage user")
+			logger.Log.Error(err)
+
+			return nil, common.NewError(err, common.ErrTypeInternal)
+		}
+
+		// Convert storage user to model user
+		user = l.converter.fromStorageUserToModel(ctx, *storageUser)
+
+		// An update changing nothing is not applied, so it has no event
+		previous := previousValues(l.converter.fromStorageUserToModel(ctx, *storagePrevious), user)
+		if len(previous) == 0 {
+			return nil, nil
+		}
+
+		userEvent := l.converter.fromModelUserToEvent(ctx, user)
+		userEvent.EventType = events.EventTypeUpdated
+		userEvent.EventMask = userUpdate.UpdateMask
+		userEvent.EventTime = now
+		userEvent.Previous = previous
+
+		return []events.UserEvent{userEvent}, nil
+	})
 	if errUpdate != nil {
 		return nil, errUpdate
 	}
-	if storageUser == nil {
-		err := errors.New("unexpected nil storage user")
-		logger.Log.Error(err)
 
-		return nil, common.NewError(err, common.ErrTypeInternal)
-	}
+	return &user, nil
+}
 
-	// Convert storage user to model user
-	user := l.converter.fromStorageUserToModel(ctx, *storageUser)
-
-	// Emit user event
-	userEvent := l.converter.fromModelUserToEvent(ctx, user)
-	userEvent.EventType = events.EventTypeUpdated
-	userEvent.EventMask = userUpdate.UpdateMask
-	userEvent.EventTime = now
-	errEmit := l.eventEmitter.EmitUserEvent(ctx, userEvent)
-	if errEmit != nil {
-		logger.Log.Warn("User updated without event emission: %v", userEvent)
+// previousValues returns the values of the updatable user fields changed between the previous and the updated user,
+// as they were before the update
+func previousValues(previous businesslogic.User, user businesslogic.User) map[string]string {
+	values := make(map[string]string)
+	addChanged := func(field string, before string, after string) {
+		if before != after {
+			values[field] = before
+		}
 	}
 
-	return &user, nil
+	addChanged("first_name", previous.FirstName, user.FirstName)
+	addChanged("last_name", previous.LastName, user.LastName)
+	addChanged("nickname", previous.Nickname, user.Nickname)
+	addChanged("email", previous.Email, user.Email)
+	addChanged("country", previous.Country, user.Country)
+
+	return values
 }
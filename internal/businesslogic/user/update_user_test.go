@@ -18,7 +18,7 @@ func TestLogic_UpdateUser_ValidationError(t *testing.T) {
 
 	userUpdate := businesslogic.UserUpdate{}
 
-	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate)
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, nil)
 	assert.Nil(t, res)
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
@@ -38,7 +38,7 @@ func TestLogic_UpdateUser_ConverterError(t *testing.T) {
 	ts.mockModelConverter.EXPECT().fromModelUserUpdateToStorage(gomock.Any(), userUpdate).
 		Return(storage.UserUpdate{}, common.NewError(nil, common.ErrTypeInternal))
 
-	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate)
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, nil)
 	assert.Nil(t, res)
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
@@ -67,16 +67,53 @@ func TestLogic_UpdateUser_StorageError(t *testing.T) {
 
 	storageUserUpdate.UpdatedAt = &now
 
-	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate).
-		Return(nil, common.NewError(nil, common.ErrTypeNotFound))
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
 
-	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate)
+	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate, gomock.Nil()).
+		Return(nil, nil, common.NewError(nil, common.ErrTypeNotFound))
+
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, nil)
 	assert.Nil(t, res)
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
 	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
 }
 
+func TestLogic_UpdateUser_PreconditionError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userUpdate := businesslogic.UserUpdate{
+		FirstName:  "John",
+		UpdateMask: []string{"first_name"},
+	}
+	precondition := &businesslogic.UserPrecondition{Version: 3}
+
+	storageUserUpdate := storage.UserUpdate{
+		FirstName: &userUpdate.FirstName,
+	}
+	storagePrecondition := &storage.UserPrecondition{Version: 3}
+
+	ts.mockModelConverter.EXPECT().fromModelUserUpdateToStorage(gomock.Any(), userUpdate).Return(storageUserUpdate, nil)
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	storageUserUpdate.UpdatedAt = &now
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), precondition).
+		Return(storagePrecondition)
+
+	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate, storagePrecondition).
+		Return(nil, nil, common.NewError(nil, common.ErrTypeFailedPrecondition))
+
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, precondition)
+	assert.Nil(t, res)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+}
+
 func TestLogic_UpdateUser_StorageNilError(t *testing.T) {
 	ts := newTestSuite(t)
 	defer ts.mockCtrl.Finish()
@@ -99,10 +136,12 @@ func TestLogic_UpdateUser_StorageNilError(t *testing.T) {
 
 	storageUserUpdate.UpdatedAt = &now
 
-	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate).
-		Return(nil, nil)
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate, gomock.Nil()).
+		Return(nil, nil, nil)
 
-	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate)
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, nil)
 	assert.Nil(t, res)
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
@@ -142,7 +181,14 @@ func TestLogic_UpdateUser_EventEmitterError(t *testing.T) {
 		UpdatedAt: now,
 	}
 
-	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate).Return(storageUser, nil)
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	previousStorageUser := *storageUser
+	previousStorageUser.FirstName = "Jack"
+	previousStorageUser.LastName = "Doe"
+
+	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate, gomock.Nil()).
+		Return(storageUser, &previousStorageUser, nil)
 
 	expectedUser := businesslogic.User{
 		ID:        storageUser.ID,
@@ -157,6 +203,10 @@ func TestLogic_UpdateUser_EventEmitterError(t *testing.T) {
 
 	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
 
+	previousUser := expectedUser
+	previousUser.FirstName = previousStorageUser.FirstName
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), previousStorageUser).Return(previousUser)
+
 	userEvent := events.UserEvent{
 		UserId:    storageUser.ID,
 		EventType: events.EventTypeUpdated,
@@ -166,10 +216,13 @@ func TestLogic_UpdateUser_EventEmitterError(t *testing.T) {
 
 	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
 
-	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+	// Only the first name actually changed
+	userEvent.Previous = map[string]string{"first_name": "Jack"}
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).
 		Return(common.NewError(nil, common.ErrTypeInternal))
 
-	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate)
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, nil)
 	// Update should succeed even if event emission fails
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
@@ -210,7 +263,14 @@ func TestLogic_UpdateUser_Success(t *testing.T) {
 		UpdatedAt: now,
 	}
 
-	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate).Return(storageUser, nil)
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	previousStorageUser := *storageUser
+	previousStorageUser.FirstName = "Jack"
+	previousStorageUser.LastName = "Doe"
+
+	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate, gomock.Nil()).
+		Return(storageUser, &previousStorageUser, nil)
 
 	expectedUser := businesslogic.User{
 		ID:        storageUser.ID,
@@ -225,6 +285,10 @@ func TestLogic_UpdateUser_Success(t *testing.T) {
 
 	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser)
 
+	previousUser := expectedUser
+	previousUser.FirstName = previousStorageUser.FirstName
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), previousStorageUser).Return(previousUser)
+
 	userEvent := events.UserEvent{
 		UserId:    storageUser.ID,
 		EventType: events.EventTypeUpdated,
@@ -241,10 +305,73 @@ func TestLogic_UpdateUser_Success(t *testing.T) {
 
 	ts.mockModelConverter.EXPECT().fromModelUserToEvent(gomock.Any(), expectedUser).Return(userEvent)
 
-	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(nil)
+	// Only the first name actually changed
+	userEvent.Previous = map[string]string{"first_name": "Jack"}
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEventMatcher{userEvent}).Return(nil)
 
-	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate)
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, nil)
 	assert.NoError(t, err)
 	assert.NotNil(t, res)
 	assert.Equal(t, expectedUser, *res)
 }
+
+func TestLogic_UpdateUser_NoChanges(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userUpdate := businesslogic.UserUpdate{
+		FirstName:  "John",
+		UpdateMask: []string{"first_name"},
+	}
+
+	storageUserUpdate := storage.UserUpdate{
+		FirstName: &userUpdate.FirstName,
+	}
+
+	ts.mockModelConverter.EXPECT().fromModelUserUpdateToStorage(gomock.Any(), userUpdate).Return(storageUserUpdate, nil)
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	storageUserUpdate.UpdatedAt = &now
+
+	ts.mockModelConverter.EXPECT().fromModelUserPreconditionToStorage(gomock.Any(), gomock.Nil()).Return(nil)
+
+	// The update is not applied, so the current user is returned as both updated and previous user
+	storageUser := &storage.User{ID: "user-id", FirstName: "John", Version: 2}
+	ts.mockUserStorage.EXPECT().UpdateUser(gomock.Any(), "user-id", storageUserUpdate, gomock.Nil()).
+		Return(storageUser, storageUser, nil)
+
+	expectedUser := businesslogic.User{ID: "user-id", FirstName: "John", Version: 2}
+	ts.mockModelConverter.EXPECT().fromStorageUserToModel(gomock.Any(), *storageUser).Return(expectedUser).Times(2)
+
+	// No event is emitted
+	res, err := ts.userManager.UpdateUser(context.Background(), "user-id", userUpdate, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, res)
+	assert.Equal(t, expectedUser, *res)
+}
+
+func TestPreviousValues(t *testing.T) {
+	previous := businesslogic.User{
+		ID:        "user-id",
+		FirstName: "John",
+		LastName:  "Doe",
+		Nickname:  "johnd",
+		Email:     "john.doe@example.com",
+		Version:   1,
+	}
+
+	user := previous
+	user.Email = "john@example.com"
+	user.Country = "IT"
+	user.Version = 2
+
+	// Fields set from empty values are reported with their empty previous value
+	assert.Equal(t, map[string]string{
+		"email":   "john.doe@example.com",
+		"country": "",
+	}, previousValues(previous, user))
+	assert.Empty(t, previousValues(previous, previous))
+}
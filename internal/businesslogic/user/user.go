@@ -6,12 +6,31 @@ import (
 	"github.com/alenalato/users-service/internal/events"
 	"github.com/alenalato/users-service/internal/storage"
 	"github.com/go-playground/validator/v10"
+	"time"
 )
 
 var validate = validator.New(validator.WithRequiredStructEnabled())
 
+// Config holds the configuration for the user management logic
+type Config struct {
+	// RestoreGracePeriod is the period after a deletion in which a soft deleted user can be restored
+	RestoreGracePeriod time.Duration
+	// PurgeRetention is the period a soft deleted user is kept before being purged
+	PurgeRetention time.Duration
+	// PurgeInterval is the period between purge runs
+	PurgeInterval time.Duration
+	// TransactionalOutbox makes user changes and their events atomic, emitting events in the storage transaction.
+	// It requires an event emitter storing events in the outbox, instead of publishing them.
+	TransactionalOutbox bool
+	// ChangeStreamRetryInterval is the delay before the change publisher resumes watching user changes after an error,
+	// or tries again to acquire their lease. The lease is renewed at the same period and expires after three periods.
+	ChangeStreamRetryInterval time.Duration
+}
+
 // Logic is a struct that implements the UserManager interface
 type Logic struct {
+	// config is the user management logic configuration
+	config Config
 	// time is a time provider used for generating timestamps
 	time common.TimeProvider
 	// converter is a model converter used for converting between different models
@@ -31,8 +50,10 @@ func NewLogic(
 	passwordManager businesslogic.PasswordManager,
 	userStorage storage.UserStorage,
 	eventEmitter events.EventEmitter,
+	config Config,
 ) *Logic {
 	return &Logic{
+		config:          config,
 		time:            common.NewTime(),
 		converter:       newBusinessLogicModelConverter(),
 		passwordManager: passwordManager,
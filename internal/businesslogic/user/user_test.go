@@ -7,6 +7,7 @@ import (
 	"github.com/alenalato/users-service/internal/storage"
 	"go.uber.org/mock/gomock"
 	"testing"
+	"time"
 )
 
 type testSuite struct {
@@ -32,6 +33,9 @@ func newTestSuite(t *testing.T) *testSuite {
 		mockPasswordManager,
 		mockUserStorage,
 		mockEventEmitter,
+		Config{
+			RestoreGracePeriod: time.Hour,
+		},
 	)
 	userManager.time = mockTimeProvider
 	userManager.converter = mockModelConverter
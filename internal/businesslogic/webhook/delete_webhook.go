@@ -0,0 +1,35 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+)
+
+// DeleteWebhook deletes the webhook subscription with the given ID, its endpoint stops receiving events
+func (l *Logic) DeleteWebhook(ctx context.Context, webhookId string) (*businesslogic.Webhook, error) {
+	// Validate input
+	errValidate := validate.Var(webhookId, "required")
+	if errValidate != nil {
+		logger.Log.Errorf("validation error: %v", errValidate)
+
+		return nil, common.NewError(errValidate, common.ErrTypeInvalidArgument)
+	}
+
+	subscription, errDelete := l.webhookStorage.DeleteWebhookSubscription(ctx, webhookId)
+	if errDelete != nil {
+		return nil, errDelete
+	}
+	if subscription == nil {
+		err := errors.New("unexpected nil storage webhook subscription")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	webhook := fromStorageSubscriptionToModel(*subscription)
+
+	return &webhook, nil
+}
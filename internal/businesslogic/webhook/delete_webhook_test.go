@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+func TestLogic_DeleteWebhook_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockWebhookStorage.EXPECT().DeleteWebhookSubscription(gomock.Any(), "webhook-id").
+		Return(&storage.WebhookSubscription{ID: "webhook-id", URL: "https://example.com/hook", Secret: "secret"}, nil)
+
+	webhook, err := ts.webhookManager.DeleteWebhook(context.Background(), "webhook-id")
+	require.NoError(t, err)
+	assert.Equal(t, "webhook-id", webhook.ID)
+	assert.Empty(t, webhook.Secret)
+}
+
+func TestLogic_DeleteWebhook_NotFound(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockWebhookStorage.EXPECT().DeleteWebhookSubscription(gomock.Any(), "webhook-id").
+		Return(nil, common.NewError(nil, common.ErrTypeNotFound))
+
+	webhook, err := ts.webhookManager.DeleteWebhook(context.Background(), "webhook-id")
+	assert.Nil(t, webhook)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+}
+
+func TestLogic_DeleteWebhook_ValidationError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	webhook, err := ts.webhookManager.DeleteWebhook(context.Background(), "")
+	assert.Nil(t, webhook)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+}
@@ -0,0 +1,31 @@
+package webhook
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+)
+
+// ListWebhooks returns the webhook subscriptions receiving the given event type, all of them if empty
+func (l *Logic) ListWebhooks(ctx context.Context, eventType string) ([]businesslogic.Webhook, error) {
+	// Validate input
+	errValidate := validate.Var(eventType, "omitempty,oneof=created updated deleted restored purged snapshot")
+	if errValidate != nil {
+		logger.Log.Errorf("validation error: %v", errValidate)
+
+		return nil, common.NewError(errValidate, common.ErrTypeInvalidArgument)
+	}
+
+	subscriptions, errList := l.webhookStorage.ListWebhookSubscriptions(ctx, eventType)
+	if errList != nil {
+		return nil, errList
+	}
+
+	var webhooks []businesslogic.Webhook
+	for _, subscription := range subscriptions {
+		webhooks = append(webhooks, fromStorageSubscriptionToModel(subscription))
+	}
+
+	return webhooks, nil
+}
@@ -0,0 +1,49 @@
+package webhook
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestLogic_ListWebhooks_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "deleted").
+		Return([]storage.WebhookSubscription{{
+			ID:         "webhook-id",
+			URL:        "https://example.com/hook",
+			Secret:     "secret",
+			EventTypes: []string{"deleted"},
+			CreatedAt:  now,
+		}}, nil)
+
+	webhooks, err := ts.webhookManager.ListWebhooks(context.Background(), "deleted")
+	require.NoError(t, err)
+	// Secrets are never listed
+	assert.Equal(t, []businesslogic.Webhook{{
+		ID:         "webhook-id",
+		URL:        "https://example.com/hook",
+		EventTypes: []string{"deleted"},
+		CreatedAt:  now,
+	}}, webhooks)
+}
+
+func TestLogic_ListWebhooks_ValidationError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	webhooks, err := ts.webhookManager.ListWebhooks(context.Background(), "renamed")
+	assert.Nil(t, webhooks)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+}
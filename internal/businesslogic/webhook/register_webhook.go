@@ -0,0 +1,78 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+	"net/url"
+	"slices"
+)
+
+// RegisterWebhook registers a webhook subscription and returns it along with the secret signing its deliveries
+func (l *Logic) RegisterWebhook(
+	ctx context.Context,
+	webhookDetails businesslogic.WebhookDetails,
+) (*businesslogic.Webhook, error) {
+	// Validate input
+	errValidate := validate.Struct(webhookDetails)
+	if errValidate != nil {
+		logger.Log.Errorf("validation error: %v", errValidate)
+
+		return nil, common.NewError(errValidate, common.ErrTypeInvalidArgument)
+	}
+	// Events are only posted in clear text if allowed
+	webhookUrl, errParse := url.Parse(webhookDetails.URL)
+	if errParse != nil {
+		logger.Log.Errorf("validation error: %v", errParse)
+
+		return nil, common.NewError(errParse, common.ErrTypeInvalidArgument)
+	}
+	if webhookUrl.Scheme != "https" && !l.config.AllowHTTP {
+		err := errors.New("webhook URL must be an HTTPS URL")
+		logger.Log.Errorf("validation error: %v", err)
+
+		return nil, common.NewError(err, common.ErrTypeInvalidArgument)
+	}
+
+	// Generate the secret signing the deliveries
+	secret := make([]byte, secretSize)
+	if _, errRand := rand.Read(secret); errRand != nil {
+		logger.Log.Errorf("Error generating webhook secret: %v", errRand)
+
+		return nil, common.NewError(errRand, common.ErrTypeInternal)
+	}
+
+	// Event types are stored once and sorted
+	eventTypes := slices.Clone(webhookDetails.EventTypes)
+	slices.Sort(eventTypes)
+	eventTypes = slices.Compact(eventTypes)
+
+	subscription, errCreate := l.webhookStorage.CreateWebhookSubscription(ctx, storage.WebhookSubscription{
+		ID:         uuid.New().String(),
+		URL:        webhookDetails.URL,
+		Secret:     hex.EncodeToString(secret),
+		EventTypes: eventTypes,
+		CreatedAt:  l.time.Now().UTC(),
+	})
+	if errCreate != nil {
+		return nil, errCreate
+	}
+	if subscription == nil {
+		err := errors.New("unexpected nil storage webhook subscription")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	// The secret is only returned on registration
+	webhook := fromStorageSubscriptionToModel(*subscription)
+	webhook.Secret = subscription.Secret
+
+	return &webhook, nil
+}
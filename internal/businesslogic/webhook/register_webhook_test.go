@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestLogic_RegisterWebhook_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockWebhookStorage.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, subscription storage.WebhookSubscription) (*storage.WebhookSubscription, error) {
+			assert.NotEmpty(t, subscription.ID)
+			assert.Equal(t, "https://example.com/hook", subscription.URL)
+			// 32 random bytes, hex encoded
+			assert.Len(t, subscription.Secret, 64)
+			// Event types are sorted and deduplicated
+			assert.Equal(t, []string{"deleted", "purged"}, subscription.EventTypes)
+			assert.Equal(t, now, subscription.CreatedAt)
+
+			return &subscription, nil
+		})
+
+	webhook, err := ts.webhookManager.RegisterWebhook(context.Background(), businesslogic.WebhookDetails{
+		URL:        "https://example.com/hook",
+		EventTypes: []string{"purged", "deleted", "purged"},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, webhook.ID)
+	assert.Len(t, webhook.Secret, 64)
+	assert.Equal(t, []string{"deleted", "purged"}, webhook.EventTypes)
+}
+
+func TestLogic_RegisterWebhook_ValidationError(t *testing.T) {
+	tests := []struct {
+		name           string
+		webhookDetails businesslogic.WebhookDetails
+	}{
+		{
+			name:           "Missing URL",
+			webhookDetails: businesslogic.WebhookDetails{},
+		},
+		{
+			name:           "Not an HTTP URL",
+			webhookDetails: businesslogic.WebhookDetails{URL: "ftp://example.com/hook"},
+		},
+		{
+			name:           "Not an HTTPS URL",
+			webhookDetails: businesslogic.WebhookDetails{URL: "http://example.com/hook"},
+		},
+		{
+			name: "Unknown event type",
+			webhookDetails: businesslogic.WebhookDetails{
+				URL:        "https://example.com/hook",
+				EventTypes: []string{"created", "renamed"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestSuite(t)
+			defer ts.mockCtrl.Finish()
+
+			webhook, err := ts.webhookManager.RegisterWebhook(context.Background(), tt.webhookDetails)
+			assert.Nil(t, webhook)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+		})
+	}
+}
+
+func TestLogic_RegisterWebhook_AllowHTTP(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.webhookManager.config.AllowHTTP = true
+
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now())
+	ts.mockWebhookStorage.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, subscription storage.WebhookSubscription) (*storage.WebhookSubscription, error) {
+			return &subscription, nil
+		})
+
+	webhook, err := ts.webhookManager.RegisterWebhook(context.Background(), businesslogic.WebhookDetails{
+		URL: "http://example.com/hook",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "http://example.com/hook", webhook.URL)
+}
+
+func TestLogic_RegisterWebhook_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now())
+	ts.mockWebhookStorage.EXPECT().CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+		Return(nil, common.NewError(nil, common.ErrTypeInternal))
+
+	webhook, err := ts.webhookManager.RegisterWebhook(context.Background(), businesslogic.WebhookDetails{
+		URL: "https://example.com/hook",
+	})
+	assert.Nil(t, webhook)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = validator.New(validator.WithRequiredStructEnabled())
+
+// secretSize is the size in bytes of the generated webhook secrets
+const secretSize = 32
+
+// Config holds the configuration of the webhook management logic
+type Config struct {
+	// AllowHTTP allows registering plain HTTP webhook URLs, only HTTPS URLs are accepted otherwise
+	AllowHTTP bool
+}
+
+// Logic is a struct that implements the WebhookManager interface
+type Logic struct {
+	// config is the webhook management logic configuration
+	config Config
+	// time is a time provider used for generating timestamps
+	time common.TimeProvider
+	// webhookStorage is a webhook storage used for storing and retrieving webhook subscriptions
+	webhookStorage storage.WebhookStorage
+}
+
+var _ businesslogic.WebhookManager = new(Logic)
+
+// fromStorageSubscriptionToModel converts a storage webhook subscription to a model webhook, without its secret
+func fromStorageSubscriptionToModel(subscription storage.WebhookSubscription) businesslogic.Webhook {
+	return businesslogic.Webhook{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+// NewLogic creates a new Logic instance
+func NewLogic(webhookStorage storage.WebhookStorage, config Config) *Logic {
+	return &Logic{
+		config:         config,
+		time:           common.NewTime(),
+		webhookStorage: webhookStorage,
+	}
+}
@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+type testSuite struct {
+	mockCtrl           *gomock.Controller
+	mockTimeProvider   *common.MockTimeProvider
+	mockWebhookStorage *storage.MockWebhookStorage
+	webhookManager     *Logic
+}
+
+func newTestSuite(t *testing.T) *testSuite {
+	mockCtrl := gomock.NewController(t)
+
+	mockTimeProvider := common.NewMockTimeProvider(mockCtrl)
+	mockWebhookStorage := storage.NewMockWebhookStorage(mockCtrl)
+
+	webhookManager := NewLogic(mockWebhookStorage, Config{})
+	webhookManager.time = mockTimeProvider
+
+	return &testSuite{
+		mockCtrl:           mockCtrl,
+		mockTimeProvider:   mockTimeProvider,
+		mockWebhookStorage: mockWebhookStorage,
+		webhookManager:     webhookManager,
+	}
+}
@@ -0,0 +1,20 @@
+package common
+
+import "context"
+
+// SystemActor is the actor of changes performed by the service itself, e.g. by background workers
+const SystemActor = "system"
+
+type actorContextKey struct{}
+
+// WithActor returns a copy of the context carrying the actor performing the requested changes
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context, or an empty string if not set
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorContextKey{}).(string)
+
+	return actor
+}
@@ -9,6 +9,10 @@ const (
 	ErrTypeAlreadyExists
 	ErrTypeInvalidArgument
 	ErrTypeInternal
+	ErrTypeFailedPrecondition
+	ErrTypeUnauthenticated
+	ErrTypePermissionDenied
+	ErrTypeResourceExhausted
 )
 
 func (e ErrorType) String() string {
@@ -23,6 +27,14 @@ func (e ErrorType) String() string {
 		return "invalid argument"
 	case ErrTypeInternal:
 		return "internal error"
+	case ErrTypeFailedPrecondition:
+		return "failed precondition"
+	case ErrTypeUnauthenticated:
+		return "unauthenticated"
+	case ErrTypePermissionDenied:
+		return "permission denied"
+	case ErrTypeResourceExhausted:
+		return "resource exhausted"
 	default:
 		return "unknown error type"
 	}
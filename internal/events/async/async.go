@@ -0,0 +1,244 @@
+package async
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// Config holds the configuration for the asynchronous event emitter
+type Config struct {
+	// QueueSize is the maximum number of events waiting to be emitted, further events are dropped
+	QueueSize int
+	// Workers is the number of events emitted concurrently, events of the same user are emitted in order
+	Workers int
+	// MaxAttempts is the number of emission attempts of an event before it is dead lettered
+	MaxAttempts int
+	// MinBackoff is the delay before retrying an event after its first failure, it doubles on every failure
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum delay before retrying a failed event
+	MaxBackoff time.Duration
+}
+
+// EventEmitter is a struct that implements the EventEmitter interface queueing events to be emitted in background.
+// Failed emissions are retried with exponential backoff, events failing every attempt are emitted to a dead letter emitter.
+type EventEmitter struct {
+	// config is the asynchronous event emitter configuration
+	config Config
+	// eventEmitter is the event emitter used to emit the queued events
+	eventEmitter events.EventEmitter
+	// deadLetterEmitter is the event emitter used for events failing every attempt, if nil they are dropped
+	deadLetterEmitter events.EventEmitter
+	// queues are the queues of the workers, events are assigned to a worker by user ID
+	queues []chan events.UserEvent
+	// mu guards the queues from being closed while events are queued
+	mu sync.RWMutex
+	// draining is set once the emitter stops accepting events
+	draining bool
+	// ctx is the context of the emissions, it is cancelled if the drain does not complete in time
+	ctx context.Context
+	// cancel cancels the context of the emissions
+	cancel context.CancelFunc
+	// workers tracks the running workers
+	workers sync.WaitGroup
+	// metrics are the metrics of the emitter
+	metrics *metrics
+}
+
+var _ events.EventEmitter = new(EventEmitter)
+
+// EmitUserEvent queues a user event to be emitted in background, it fails if the queue is full or the emitter is draining
+func (e *EventEmitter) EmitUserEvent(_ context.Context, userEvent events.UserEvent) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.draining {
+		e.metrics.dropped.WithLabelValues(dropReasonDraining).Inc()
+
+		return common.NewError(errors.New("event emitter is draining"), common.ErrTypeInternal)
+	}
+
+	select {
+	case e.queue(userEvent.UserId) <- userEvent:
+		return nil
+	default:
+		e.metrics.dropped.WithLabelValues(dropReasonQueueFull).Inc()
+
+		return common.NewError(errors.New("event queue is full"), common.ErrTypeInternal)
+	}
+}
+
+// Drain stops accepting events and waits until the queued events are emitted.
+// If the given context is done before, pending retries are stopped and the remaining events are dropped.
+func (e *EventEmitter) Drain(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.draining {
+		e.draining = true
+		for _, queue := range e.queues {
+			close(queue)
+		}
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		e.cancel()
+		<-done
+
+		return common.NewError(ctx.Err(), common.ErrTypeInternal)
+	}
+}
+
+// queue returns the queue of the worker emitting the events of the given user
+func (e *EventEmitter) queue(userId string) chan events.UserEvent {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(userId))
+
+	return e.queues[hash.Sum32()%uint32(len(e.queues))]
+}
+
+// queueDepth returns the number of queued events
+func (e *EventEmitter) queueDepth() float64 {
+	depth := 0
+	for _, queue := range e.queues {
+		depth += len(queue)
+	}
+
+	return float64(depth)
+}
+
+// work emits the events of the given queue until it is closed
+func (e *EventEmitter) work(queue <-chan events.UserEvent) {
+	defer e.workers.Done()
+
+	for userEvent := range queue {
+		e.emit(userEvent)
+	}
+}
+
+// emit emits a user event, retrying with backoff, and dead letters it if every attempt fails
+func (e *EventEmitter) emit(userEvent events.UserEvent) {
+	var errEmit error
+	for attempt := 1; ; attempt++ {
+		errEmit = e.eventEmitter.EmitUserEvent(e.ctx, userEvent)
+		if errEmit == nil {
+			return
+		}
+		e.metrics.failures.Inc()
+		if attempt >= e.config.MaxAttempts {
+			break
+		}
+		logger.Log.Warnf("User event of user %s not emitted at attempt %d: %v", userEvent.UserId, attempt, errEmit)
+		if !e.wait(e.backoff(attempt)) {
+			break
+		}
+	}
+
+	e.deadLetter(userEvent, errEmit)
+}
+
+// deadLetter emits a user event that could not be emitted to the dead letter emitter, or drops it
+func (e *EventEmitter) deadLetter(userEvent events.UserEvent, errEmit error) {
+	if e.deadLetterEmitter != nil {
+		errDeadLetter := e.deadLetterEmitter.EmitUserEvent(e.ctx, userEvent)
+		if errDeadLetter == nil {
+			e.metrics.deadLettered.Inc()
+			logger.Log.Errorf(
+				"User event %s of type %s of user %s dead lettered: %v",
+				userEvent.EventId, userEvent.EventType, userEvent.UserId, errEmit,
+			)
+
+			return
+		}
+		errEmit = errDeadLetter
+	}
+
+	e.metrics.dropped.WithLabelValues(dropReasonDeadLetterFailed).Inc()
+	logger.Log.Errorf(
+		"User event %s of type %s of user %s dropped: %v",
+		userEvent.EventId, userEvent.EventType, userEvent.UserId, errEmit,
+	)
+}
+
+// wait waits for the given delay, it returns false if retries are stopped before
+func (e *EventEmitter) wait(delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-e.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// backoff returns the delay before retrying an event after the given failed attempt
+func (e *EventEmitter) backoff(attempt int) time.Duration {
+	backoff := e.config.MinBackoff
+	for i := 1; i < attempt && backoff < e.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > e.config.MaxBackoff {
+		backoff = e.config.MaxBackoff
+	}
+
+	return backoff
+}
+
+// NewEventEmitter creates a new EventEmitter instance, starting its workers and registering its metrics.
+// The dead letter emitter is optional.
+func NewEventEmitter(
+	eventEmitter events.EventEmitter,
+	deadLetterEmitter events.EventEmitter,
+	config Config,
+	registerer prometheus.Registerer,
+) (*EventEmitter, error) {
+	if config.QueueSize <= 0 || config.Workers <= 0 || config.MaxAttempts <= 0 {
+		err := errors.New("queue size, workers and max attempts must be positive")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	emitter := &EventEmitter{
+		config:            config,
+		eventEmitter:      eventEmitter,
+		deadLetterEmitter: deadLetterEmitter,
+		queues:            make([]chan events.UserEvent, config.Workers),
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+	emitter.metrics = newMetrics(emitter.queueDepth)
+	if errRegister := emitter.metrics.register(registerer); errRegister != nil {
+		cancel()
+		logger.Log.Errorf("Failed to register event emitter metrics: %v", errRegister)
+
+		return nil, common.NewError(errRegister, common.ErrTypeInternal)
+	}
+
+	// The queue size is shared among workers
+	workerQueueSize := max(config.QueueSize/config.Workers, 1)
+	for i := range emitter.queues {
+		emitter.queues[i] = make(chan events.UserEvent, workerQueueSize)
+		emitter.workers.Add(1)
+		go emitter.work(emitter.queues[i])
+	}
+
+	return emitter, nil
+}
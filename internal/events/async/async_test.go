@@ -0,0 +1,214 @@
+package async
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+type testSuite struct {
+	mockCtrl              *gomock.Controller
+	mockEventEmitter      *events.MockEventEmitter
+	mockDeadLetterEmitter *events.MockEventEmitter
+	eventEmitter          *EventEmitter
+}
+
+func newTestSuite(t *testing.T, config Config) *testSuite {
+	mockCtrl := gomock.NewController(t)
+	mockEventEmitter := events.NewMockEventEmitter(mockCtrl)
+	mockDeadLetterEmitter := events.NewMockEventEmitter(mockCtrl)
+
+	eventEmitter, err := NewEventEmitter(mockEventEmitter, mockDeadLetterEmitter, config, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	return &testSuite{
+		mockCtrl:              mockCtrl,
+		mockEventEmitter:      mockEventEmitter,
+		mockDeadLetterEmitter: mockDeadLetterEmitter,
+		eventEmitter:          eventEmitter,
+	}
+}
+
+func newTestConfig() Config {
+	return Config{
+		QueueSize:   10,
+		Workers:     2,
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	}
+}
+
+func TestEventEmitter_EmitUserEvent_Success(t *testing.T) {
+	ts := newTestSuite(t, newTestConfig())
+	defer ts.mockCtrl.Finish()
+
+	firstEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-id"}
+	secondEvent := events.UserEvent{EventType: events.EventTypeUpdated, UserId: "user-id"}
+
+	// Events of the same user are emitted in order, a failed attempt is retried
+	gomock.InOrder(
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), firstEvent).
+			Return(common.NewError(nil, common.ErrTypeInternal)),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), firstEvent).Return(nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), secondEvent).Return(nil),
+	)
+
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), firstEvent))
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), secondEvent))
+
+	assert.NoError(t, ts.eventEmitter.Drain(context.Background()))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ts.eventEmitter.metrics.failures))
+	assert.Equal(t, float64(0), testutil.ToFloat64(ts.eventEmitter.metrics.deadLettered))
+}
+
+func TestEventEmitter_EmitUserEvent_DeadLetter(t *testing.T) {
+	ts := newTestSuite(t, newTestConfig())
+	defer ts.mockCtrl.Finish()
+
+	userEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-id"}
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+		Return(common.NewError(nil, common.ErrTypeInternal)).Times(3)
+	ts.mockDeadLetterEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(nil)
+
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), userEvent))
+
+	assert.NoError(t, ts.eventEmitter.Drain(context.Background()))
+	assert.Equal(t, float64(3), testutil.ToFloat64(ts.eventEmitter.metrics.failures))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ts.eventEmitter.metrics.deadLettered))
+}
+
+func TestEventEmitter_EmitUserEvent_DeadLetterError(t *testing.T) {
+	ts := newTestSuite(t, newTestConfig())
+	defer ts.mockCtrl.Finish()
+
+	userEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-id"}
+
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+		Return(common.NewError(nil, common.ErrTypeInternal)).Times(3)
+	ts.mockDeadLetterEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+		Return(common.NewError(nil, common.ErrTypeInternal))
+
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), userEvent))
+
+	assert.NoError(t, ts.eventEmitter.Drain(context.Background()))
+	assert.Equal(t, float64(0), testutil.ToFloat64(ts.eventEmitter.metrics.deadLettered))
+	assert.Equal(t, float64(1), testutil.ToFloat64(
+		ts.eventEmitter.metrics.dropped.WithLabelValues(dropReasonDeadLetterFailed),
+	))
+}
+
+func TestEventEmitter_EmitUserEvent_QueueFull(t *testing.T) {
+	config := newTestConfig()
+	config.QueueSize = 1
+	config.Workers = 1
+	ts := newTestSuite(t, config)
+	defer ts.mockCtrl.Finish()
+
+	emitting := make(chan struct{})
+	release := make(chan struct{})
+
+	// The first event blocks the worker, the second one fills the queue
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ events.UserEvent) error {
+			close(emitting)
+			<-release
+
+			return nil
+		})
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).Return(nil)
+
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), events.UserEvent{UserId: "user-1"}))
+	<-emitting
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), events.UserEvent{UserId: "user-2"}))
+	assert.Equal(t, float64(1), testutil.ToFloat64(ts.eventEmitter.metrics.queueDepth))
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), events.UserEvent{UserId: "user-3"})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+	assert.Equal(t, float64(1), testutil.ToFloat64(
+		ts.eventEmitter.metrics.dropped.WithLabelValues(dropReasonQueueFull),
+	))
+
+	close(release)
+	assert.NoError(t, ts.eventEmitter.Drain(context.Background()))
+}
+
+func TestEventEmitter_Drain(t *testing.T) {
+	ts := newTestSuite(t, newTestConfig())
+	defer ts.mockCtrl.Finish()
+
+	assert.NoError(t, ts.eventEmitter.Drain(context.Background()))
+
+	// Events are not accepted once draining
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), events.UserEvent{UserId: "user-id"})
+	assert.Error(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(
+		ts.eventEmitter.metrics.dropped.WithLabelValues(dropReasonDraining),
+	))
+
+	// Draining again has no effect
+	assert.NoError(t, ts.eventEmitter.Drain(context.Background()))
+}
+
+func TestEventEmitter_Drain_Timeout(t *testing.T) {
+	config := newTestConfig()
+	config.MaxAttempts = 100
+	config.MinBackoff = time.Hour
+	config.MaxBackoff = time.Hour
+	ts := newTestSuite(t, config)
+	defer ts.mockCtrl.Finish()
+
+	userEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-id"}
+
+	emitting := make(chan struct{})
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+		DoAndReturn(func(_ context.Context, _ events.UserEvent) error {
+			close(emitting)
+
+			return common.NewError(nil, common.ErrTypeInternal)
+		})
+	// The pending retry is stopped and the event dead lettered, with the emission context already cancelled
+	ts.mockDeadLetterEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).
+		DoAndReturn(func(ctx context.Context, _ events.UserEvent) error {
+			return ctx.Err()
+		})
+
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), userEvent))
+	<-emitting
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := ts.eventEmitter.Drain(ctx)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, float64(1), testutil.ToFloat64(
+		ts.eventEmitter.metrics.dropped.WithLabelValues(dropReasonDeadLetterFailed),
+	))
+}
+
+func TestEventEmitter_Backoff(t *testing.T) {
+	eventEmitter := &EventEmitter{config: Config{MinBackoff: time.Second, MaxBackoff: 5 * time.Second}}
+
+	assert.Equal(t, time.Second, eventEmitter.backoff(1))
+	assert.Equal(t, 2*time.Second, eventEmitter.backoff(2))
+	assert.Equal(t, 4*time.Second, eventEmitter.backoff(3))
+	assert.Equal(t, 5*time.Second, eventEmitter.backoff(4))
+}
+
+func TestNewEventEmitter_ConfigError(t *testing.T) {
+	_, err := NewEventEmitter(nil, nil, Config{}, prometheus.NewRegistry())
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
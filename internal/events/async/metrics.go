@@ -0,0 +1,65 @@
+package async
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Reasons of dropped events
+const (
+	dropReasonQueueFull        = "queue_full"
+	dropReasonDraining         = "draining"
+	dropReasonDeadLetterFailed = "dead_letter_failed"
+)
+
+// metrics holds the metrics of the asynchronous event emitter
+type metrics struct {
+	// queueDepth is the number of events waiting to be emitted
+	queueDepth prometheus.GaugeFunc
+	// failures is the number of failed emission attempts
+	failures prometheus.Counter
+	// deadLettered is the number of events emitted to the dead letter emitter
+	deadLettered prometheus.Counter
+	// dropped is the number of events lost, by reason
+	dropped *prometheus.CounterVec
+}
+
+// register registers the metrics with the given registerer
+func (m *metrics) register(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{m.queueDepth, m.failures, m.deadLettered, m.dropped} {
+		if errRegister := registerer.Register(collector); errRegister != nil {
+			return errRegister
+		}
+	}
+
+	return nil
+}
+
+// newMetrics creates the metrics of the asynchronous event emitter, queue depth is read from the given function
+func newMetrics(queueDepth func() float64) *metrics {
+	return &metrics{
+		queueDepth: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "users",
+			Subsystem: "events",
+			Name:      "queue_depth",
+			Help:      "Number of user events waiting to be emitted.",
+		}, queueDepth),
+		failures: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "users",
+			Subsystem: "events",
+			Name:      "emit_failures_total",
+			Help:      "Number of failed user event emission attempts.",
+		}),
+		deadLettered: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "users",
+			Subsystem: "events",
+			Name:      "dead_lettered_total",
+			Help:      "Number of user events emitted to the dead letter topic.",
+		}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "users",
+			Subsystem: "events",
+			Name:      "dropped_total",
+			Help:      "Number of user events lost without emission, by reason.",
+		}, []string{"reason"}),
+	}
+}
@@ -8,3 +8,13 @@ import "context"
 type EventEmitter interface {
 	EmitUserEvent(ctx context.Context, userEvent UserEvent) error
 }
+
+// NopEventEmitter is an event emitter discarding user events, for when they are emitted by another source
+type NopEventEmitter struct{}
+
+var _ EventEmitter = NopEventEmitter{}
+
+// EmitUserEvent discards the user event
+func (NopEventEmitter) EmitUserEvent(_ context.Context, _ UserEvent) error {
+	return nil
+}
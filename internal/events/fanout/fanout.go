@@ -0,0 +1,121 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"slices"
+	"sync"
+)
+
+// Policy is the failure policy of a route
+type Policy string
+
+// PolicyRequired fails the emission if the route fails
+const PolicyRequired Policy = "required"
+
+// PolicyBestEffort logs the failures of the route without failing the emission
+const PolicyBestEffort Policy = "best-effort"
+
+// Route routes user events to an event emitter
+// Name identifies the route in logs and errors
+// EventTypes are the user event types routed, all of them if empty
+// Policy is the failure policy of the route, PolicyRequired if not set
+type Route struct {
+	Name         string
+	EventEmitter events.EventEmitter
+	EventTypes   []events.UserEventType
+	Policy       Policy
+}
+
+// matches returns whether the route routes the given user event type
+func (r Route) matches(eventType events.UserEventType) bool {
+	return len(r.EventTypes) == 0 || slices.Contains(r.EventTypes, eventType)
+}
+
+// EventEmitter is a struct that implements the EventEmitter interface routing user events to multiple event emitters.
+// Events are emitted concurrently to the routes matching their type,
+// the emission fails if any required route fails, with the errors of all the failed required routes.
+type EventEmitter struct {
+	// routes are the routes of the user events
+	routes []Route
+}
+
+var _ events.EventEmitter = new(EventEmitter)
+
+// EmitUserEvent emits a user event to the routes matching its type
+func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserEvent) error {
+	errs := make([]error, len(e.routes))
+
+	var emissions sync.WaitGroup
+	for i, route := range e.routes {
+		if !route.matches(userEvent.EventType) {
+			continue
+		}
+		emissions.Add(1)
+		go func() {
+			defer emissions.Done()
+			errs[i] = route.EventEmitter.EmitUserEvent(ctx, userEvent)
+		}()
+	}
+	emissions.Wait()
+
+	var errRequired []error
+	for i, route := range e.routes {
+		if errs[i] == nil {
+			continue
+		}
+		if route.Policy == PolicyBestEffort {
+			logger.Log.Warnf("User event %s not emitted to best effort route %s: %v", userEvent.EventId, route.Name, errs[i])
+
+			continue
+		}
+		errRequired = append(errRequired, fmt.Errorf("route %s: %w", route.Name, errs[i]))
+	}
+	if len(errRequired) > 0 {
+		err := errors.Join(errRequired...)
+		logger.Log.Errorf("User event %s not emitted: %v", userEvent.EventId, err)
+
+		return common.NewError(err, common.ErrTypeInternal)
+	}
+
+	return nil
+}
+
+// NewEventEmitter creates a new EventEmitter instance routing user events to the given routes
+func NewEventEmitter(routes ...Route) (*EventEmitter, error) {
+	if len(routes) == 0 {
+		err := errors.New("routes are empty")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	emitterRoutes := make([]Route, len(routes))
+	for i, route := range routes {
+		if route.EventEmitter == nil {
+			err := fmt.Errorf("route %s has no event emitter", route.Name)
+			logger.Log.Error(err)
+
+			return nil, common.NewError(err, common.ErrTypeInternal)
+		}
+		switch route.Policy {
+		case "":
+			route.Policy = PolicyRequired
+		case PolicyRequired, PolicyBestEffort:
+		default:
+			err := fmt.Errorf("route %s has unknown policy: %s", route.Name, route.Policy)
+			logger.Log.Error(err)
+
+			return nil, common.NewError(err, common.ErrTypeInternal)
+		}
+		emitterRoutes[i] = route
+	}
+
+	return &EventEmitter{
+		routes: emitterRoutes,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+)
+
+type testSuite struct {
+	mockCtrl              *gomock.Controller
+	mockKafkaEmitter      *events.MockEventEmitter
+	mockWebhookEmitter    *events.MockEventEmitter
+	mockComplianceEmitter *events.MockEventEmitter
+	eventEmitter          *EventEmitter
+}
+
+func newTestSuite(t *testing.T) *testSuite {
+	mockCtrl := gomock.NewController(t)
+	mockKafkaEmitter := events.NewMockEventEmitter(mockCtrl)
+	mockWebhookEmitter := events.NewMockEventEmitter(mockCtrl)
+	mockComplianceEmitter := events.NewMockEventEmitter(mockCtrl)
+
+	eventEmitter, err := NewEventEmitter(
+		Route{Name: "kafka", EventEmitter: mockKafkaEmitter},
+		Route{Name: "webhook", EventEmitter: mockWebhookEmitter, Policy: PolicyBestEffort},
+		Route{
+			Name:         "compliance",
+			EventEmitter: mockComplianceEmitter,
+			EventTypes:   []events.UserEventType{events.EventTypeDeleted, events.EventTypePurged},
+			Policy:       PolicyRequired,
+		},
+	)
+	require.NoError(t, err)
+
+	return &testSuite{
+		mockCtrl:              mockCtrl,
+		mockKafkaEmitter:      mockKafkaEmitter,
+		mockWebhookEmitter:    mockWebhookEmitter,
+		mockComplianceEmitter: mockComplianceEmitter,
+		eventEmitter:          eventEmitter,
+	}
+}
+
+func TestEventEmitter_EmitUserEvent_Routes(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	createdEvent := events.UserEvent{EventId: "created-id", EventType: events.EventTypeCreated, UserId: "user-id"}
+	deletedEvent := events.UserEvent{EventId: "deleted-id", EventType: events.EventTypeDeleted, UserId: "user-id"}
+
+	// Created events are not routed to the compliance route
+	ts.mockKafkaEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).Return(nil)
+	ts.mockWebhookEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).Return(nil)
+	require.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), createdEvent))
+
+	ts.mockKafkaEmitter.EXPECT().EmitUserEvent(gomock.Any(), deletedEvent).Return(nil)
+	ts.mockWebhookEmitter.EXPECT().EmitUserEvent(gomock.Any(), deletedEvent).Return(nil)
+	ts.mockComplianceEmitter.EXPECT().EmitUserEvent(gomock.Any(), deletedEvent).Return(nil)
+	require.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), deletedEvent))
+}
+
+func TestEventEmitter_EmitUserEvent_BestEffortFailure(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userEvent := events.UserEvent{EventId: "event-id", EventType: events.EventTypeUpdated, UserId: "user-id"}
+
+	// A best effort route failure does not fail the emission
+	ts.mockKafkaEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(nil)
+	ts.mockWebhookEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(errors.New("webhook error"))
+
+	assert.NoError(t, ts.eventEmitter.EmitUserEvent(context.Background(), userEvent))
+}
+
+func TestEventEmitter_EmitUserEvent_RequiredFailures(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	userEvent := events.UserEvent{EventId: "event-id", EventType: events.EventTypePurged, UserId: "user-id"}
+	errKafka := errors.New("kafka error")
+	errCompliance := errors.New("compliance error")
+
+	// Every route is attempted, the errors of the required routes are aggregated
+	ts.mockKafkaEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(errKafka)
+	ts.mockWebhookEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(errors.New("webhook error"))
+	ts.mockComplianceEmitter.EXPECT().EmitUserEvent(gomock.Any(), userEvent).Return(errCompliance)
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	var errCommon common.Error
+	require.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+	assert.ErrorIs(t, err, errKafka)
+	assert.ErrorIs(t, err, errCompliance)
+	assert.NotContains(t, err.Error(), "webhook error")
+	assert.Contains(t, err.Error(), "route kafka")
+	assert.Contains(t, err.Error(), "route compliance")
+}
+
+func TestNewEventEmitter_Error(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name   string
+		routes []Route
+	}{
+		{
+			name: "No routes",
+		},
+		{
+			name:   "No event emitter",
+			routes: []Route{{Name: "kafka"}},
+		},
+		{
+			name:   "Unknown policy",
+			routes: []Route{{Name: "kafka", EventEmitter: events.NewMockEventEmitter(mockCtrl), Policy: "sometimes"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventEmitter, err := NewEventEmitter(tt.routes...)
+			assert.Nil(t, eventEmitter)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+		})
+	}
+}
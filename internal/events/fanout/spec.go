@@ -0,0 +1,73 @@
+package fanout
+
+import (
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"strings"
+)
+
+// RouteSpec is the specification of a route, naming its event emitter
+type RouteSpec struct {
+	Name       string
+	EventTypes []events.UserEventType
+	Policy     Policy
+}
+
+// eventTypes are the known user event types
+var eventTypes = map[events.UserEventType]struct{}{
+	events.EventTypeCreated:  {},
+	events.EventTypeUpdated:  {},
+	events.EventTypeDeleted:  {},
+	events.EventTypeRestored: {},
+	events.EventTypePurged:   {},
+	events.EventTypeSnapshot: {},
+}
+
+// ParseRouteSpecs parses a comma separated list of route specifications.
+// A route specification is the name of its event emitter, optionally followed by a colon and its policy,
+// and by another colon and the routed event types separated by a pipe,
+// like kafka,webhook:best-effort,compliance:required:deleted|purged
+func ParseRouteSpecs(value string) ([]RouteSpec, error) {
+	var specs []RouteSpec
+	names := make(map[string]struct{})
+	for _, specValue := range strings.Split(value, ",") {
+		parts := strings.Split(strings.TrimSpace(specValue), ":")
+		if len(parts) > 3 || parts[0] == "" {
+			return nil, specError(fmt.Errorf("invalid route: %q", specValue))
+		}
+
+		spec := RouteSpec{Name: parts[0], Policy: PolicyRequired}
+		if _, duplicate := names[spec.Name]; duplicate {
+			return nil, specError(fmt.Errorf("duplicate route: %s", spec.Name))
+		}
+		names[spec.Name] = struct{}{}
+
+		if len(parts) > 1 && parts[1] != "" {
+			spec.Policy = Policy(parts[1])
+			if spec.Policy != PolicyRequired && spec.Policy != PolicyBestEffort {
+				return nil, specError(fmt.Errorf("route %s has unknown policy: %s", spec.Name, spec.Policy))
+			}
+		}
+		if len(parts) > 2 {
+			for _, eventType := range strings.Split(parts[2], "|") {
+				if _, known := eventTypes[events.UserEventType(eventType)]; !known {
+					return nil, specError(fmt.Errorf("route %s has unknown event type: %s", spec.Name, eventType))
+				}
+				spec.EventTypes = append(spec.EventTypes, events.UserEventType(eventType))
+			}
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// specError logs and returns an error of the route specifications
+func specError(err error) error {
+	logger.Log.Error(err)
+
+	return common.NewError(err, common.ErrTypeInvalidArgument)
+}
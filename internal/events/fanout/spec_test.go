@@ -0,0 +1,54 @@
+package fanout
+
+import (
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParseRouteSpecs(t *testing.T) {
+	specs, err := ParseRouteSpecs("kafka, webhook:best-effort,compliance:required:deleted|purged")
+	require.NoError(t, err)
+	assert.Equal(t, []RouteSpec{
+		{Name: "kafka", Policy: PolicyRequired},
+		{Name: "webhook", Policy: PolicyBestEffort},
+		{
+			Name:       "compliance",
+			Policy:     PolicyRequired,
+			EventTypes: []events.UserEventType{events.EventTypeDeleted, events.EventTypePurged},
+		},
+	}, specs)
+
+	// The policy can be omitted along with event types
+	specs, err = ParseRouteSpecs("nats::created")
+	require.NoError(t, err)
+	assert.Equal(t, []RouteSpec{
+		{Name: "nats", Policy: PolicyRequired, EventTypes: []events.UserEventType{events.EventTypeCreated}},
+	}, specs)
+}
+
+func TestParseRouteSpecs_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "Empty", value: ""},
+		{name: "Empty route", value: "kafka,"},
+		{name: "Too many parts", value: "kafka:required:created:updated"},
+		{name: "Duplicate route", value: "kafka,kafka:best-effort"},
+		{name: "Unknown policy", value: "kafka:sometimes"},
+		{name: "Unknown event type", value: "kafka:required:renamed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := ParseRouteSpecs(tt.value)
+			assert.Nil(t, specs)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/google/uuid"
+	"github.com/segmentio/kafka-go"
+	"time"
+)
+
+// cloudEventsSpecVersion is the version of the CloudEvents specification of the user events
+const cloudEventsSpecVersion = "1.0"
+
+// cloudEventsContentType is the content type of structured CloudEvents
+const cloudEventsContentType = "application/cloudevents+json"
+
+// userEventDataContentType is the content type of the user event data
+const userEventDataContentType = "application/json"
+
+// cloudEvent is a structured CloudEvent carrying a user event as data
+type cloudEvent struct {
+	SpecVersion     string           `json:"specversion"`
+	ID              string           `json:"id"`
+	Source          string           `json:"source"`
+	Type            string           `json:"type"`
+	Time            string           `json:"time"`
+	Subject         string           `json:"subject"`
+	DataContentType string           `json:"datacontenttype"`
+	Data            events.UserEvent `json:"data"`
+}
+
+// cloudEventType returns the CloudEvents type of a user event, e.g. users.v1.user.created
+func cloudEventType(userEvent events.UserEvent) string {
+	return fmt.Sprintf("users.v1.user.%s", userEvent.EventType)
+}
+
+// newCloudEvent returns the structured CloudEvent of a user event, identified by its event ID or, if missing, a new ID
+func (e *EventEmitter) newCloudEvent(userEvent events.UserEvent) cloudEvent {
+	id := userEvent.EventId
+	if id == "" {
+		id = uuid.New().String()
+	}
+
+	return cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              id,
+		Source:          e.source,
+		Type:            cloudEventType(userEvent),
+		Time:            userEvent.EventTime.UTC().Format(time.RFC3339Nano),
+		Subject:         userEvent.UserId,
+		DataContentType: userEventDataContentType,
+		Data:            userEvent,
+	}
+}
+
+// cloudEventsStructuredMessage returns the message of a user event in CloudEvents structured mode
+func (e *EventEmitter) cloudEventsStructuredMessage(userEvent events.UserEvent) (kafka.Message, error) {
+	cloudEventBytes, err := json.Marshal(e.newCloudEvent(userEvent))
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	return kafka.Message{
+		Key:   []byte(userEvent.UserId),
+		Value: cloudEventBytes,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte(cloudEventsContentType)},
+		},
+	}, nil
+}
+
+// cloudEventsBinaryMessage returns the message of a user event in CloudEvents binary mode,
+// the attributes are in the ce_ prefixed headers and the user event is the value
+func (e *EventEmitter) cloudEventsBinaryMessage(userEvent events.UserEvent) (kafka.Message, error) {
+	userEventBytes, err := json.Marshal(userEvent)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	envelope := e.newCloudEvent(userEvent)
+
+	return kafka.Message{
+		Key:   []byte(userEvent.UserId),
+		Value: userEventBytes,
+		Headers: []kafka.Header{
+			{Key: "ce_specversion", Value: []byte(envelope.SpecVersion)},
+			{Key: "ce_id", Value: []byte(envelope.ID)},
+			{Key: "ce_source", Value: []byte(envelope.Source)},
+			{Key: "ce_type", Value: []byte(envelope.Type)},
+			{Key: "ce_time", Value: []byte(envelope.Time)},
+			{Key: "ce_subject", Value: []byte(envelope.Subject)},
+			{Key: "content-type", Value: []byte(envelope.DataContentType)},
+		},
+	}, nil
+}
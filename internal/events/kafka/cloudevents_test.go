@@ -0,0 +1,156 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func newTestUserEvent() events.UserEvent {
+	return events.UserEvent{
+		EventType: events.EventTypeCreated,
+		EventTime: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		UserId:    "123",
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john.doe@example.com",
+	}
+}
+
+func TestEmitUserEvent_CloudEventsStructured(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.eventEmitter.encoding = EncodingCloudEventsStructured
+	ts.eventEmitter.source = "test-source"
+
+	userEvent := newTestUserEvent()
+
+	ts.mockWriter.EXPECT().WriteMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msgs ...kafka.Message) error {
+			require.Len(t, msgs, 1)
+			assert.Equal(t, []byte(userEvent.UserId), msgs[0].Key)
+			assert.Equal(t, []kafka.Header{
+				{Key: "content-type", Value: []byte("application/cloudevents+json")},
+			}, msgs[0].Headers)
+
+			var envelope cloudEvent
+			require.NoError(t, json.Unmarshal(msgs[0].Value, &envelope))
+			assert.Equal(t, "1.0", envelope.SpecVersion)
+			// Events without an event ID get a new one
+			assert.NotEmpty(t, envelope.ID)
+			assert.Equal(t, "test-source", envelope.Source)
+			assert.Equal(t, "users.v1.user.created", envelope.Type)
+			assert.Equal(t, "2025-01-02T03:04:05Z", envelope.Time)
+			assert.Equal(t, userEvent.UserId, envelope.Subject)
+			assert.Equal(t, "application/json", envelope.DataContentType)
+			assert.Equal(t, userEvent, envelope.Data)
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	assert.NoError(t, err)
+}
+
+func TestEmitUserEvent_CloudEventsBinary(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.eventEmitter.encoding = EncodingCloudEventsBinary
+	ts.eventEmitter.source = "test-source"
+
+	userEvent := newTestUserEvent()
+	userEvent.EventType = events.EventTypeDeleted
+	userEvent.EventId = "event-id"
+	userEventBytes, _ := json.Marshal(userEvent)
+
+	ts.mockWriter.EXPECT().WriteMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msgs ...kafka.Message) error {
+			require.Len(t, msgs, 1)
+			assert.Equal(t, []byte(userEvent.UserId), msgs[0].Key)
+			assert.Equal(t, userEventBytes, msgs[0].Value)
+
+			headers := make(map[string]string)
+			for _, header := range msgs[0].Headers {
+				headers[header.Key] = string(header.Value)
+			}
+			assert.Equal(t, map[string]string{
+				"ce_id":          "event-id",
+				"ce_specversion": "1.0",
+				"ce_source":      "test-source",
+				"ce_type":        "users.v1.user.deleted",
+				"ce_time":        "2025-01-02T03:04:05Z",
+				"ce_subject":     userEvent.UserId,
+				"content-type":   "application/json",
+			}, headers)
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	assert.NoError(t, err)
+}
+
+func TestNewEventEmitter_Encoding(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		wantEncoding Encoding
+		wantSource   string
+		wantErr      bool
+	}{
+		{
+			name:         "Default encoding and source",
+			config:       Config{Addresses: []string{"localhost:9092"}},
+			wantEncoding: EncodingJSON,
+			wantSource:   DefaultSource,
+		},
+		{
+			name: "CloudEvents encoding",
+			config: Config{
+				Addresses: []string{"localhost:9092"},
+				Encoding:  EncodingCloudEventsBinary,
+				Source:    "test-source",
+			},
+			wantEncoding: EncodingCloudEventsBinary,
+			wantSource:   "test-source",
+		},
+		{
+			name: "Schema registry without protobuf encoding",
+			config: Config{
+				Addresses:      []string{"localhost:9092"},
+				SchemaRegistry: NewMockSchemaRegistry(gomock.NewController(t)),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Unknown encoding",
+			config:  Config{Addresses: []string{"localhost:9092"}, Encoding: "avro"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventEmitter, err := NewEventEmitter("test-topic", tt.config)
+			if tt.wantErr {
+				var errCommon common.Error
+				assert.ErrorAs(t, err, &errCommon)
+				assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEncoding, eventEmitter.encoding)
+			assert.Equal(t, tt.wantSource, eventEmitter.source)
+		})
+	}
+}
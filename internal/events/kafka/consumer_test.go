@@ -0,0 +1,66 @@
+package kafka
+
+import (
+	"context"
+	"github.com/alenalato/users-service/pkg/events/consumer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// TestEmitUserEvent_ConsumerDecoding verifies that the published consumer package decodes the user events
+// as written by the event emitter, with every encoding
+func TestEmitUserEvent_ConsumerDecoding(t *testing.T) {
+	userEvent := newTestUserEvent()
+	userEvent.CreatedAt = userEvent.EventTime.Add(-time.Hour)
+	userEvent.UpdatedAt = userEvent.EventTime
+	userEvent.EventMask = []string{"first_name"}
+	userEvent.EventId = "event-id"
+	userEvent.Sequence = 2
+	userEvent.Previous = map[string]string{"first_name": "Jack"}
+
+	tests := []struct {
+		encoding       Encoding
+		schemaRegistry bool
+	}{
+		{EncodingJSON, false},
+		{EncodingCloudEventsStructured, false},
+		{EncodingCloudEventsBinary, false},
+		{EncodingProtobuf, false},
+		{EncodingProtobuf, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.encoding), func(t *testing.T) {
+			ts := newTestSuite(t)
+			defer ts.mockCtrl.Finish()
+
+			ts.eventEmitter.encoding = tt.encoding
+			if tt.schemaRegistry {
+				mockSchemaRegistry := NewMockSchemaRegistry(ts.mockCtrl)
+				mockSchemaRegistry.EXPECT().RegisterSchema(gomock.Any(), gomock.Any(), gomock.Any()).Return(42, nil)
+				ts.eventEmitter.schemaRegistry = mockSchemaRegistry
+			}
+
+			message, err := ts.eventEmitter.userEventMessage(context.Background(), userEvent)
+			require.NoError(t, err)
+			assert.Equal(t, consumer.Encoding(tt.encoding), consumer.MessageEncoding(message))
+
+			decoded, err := consumer.Decode(message)
+			require.NoError(t, err)
+			assert.Equal(t, string(userEvent.EventType), decoded.GetEventType())
+			assert.Equal(t, userEvent.EventTime, decoded.GetEventTime().AsTime())
+			assert.Equal(t, userEvent.UserId, decoded.GetUserId())
+			assert.Equal(t, userEvent.FirstName, decoded.GetFirstName())
+			assert.Equal(t, userEvent.LastName, decoded.GetLastName())
+			assert.Equal(t, userEvent.Email, decoded.GetEmail())
+			assert.Equal(t, userEvent.CreatedAt, decoded.GetCreatedAt().AsTime())
+			assert.Equal(t, userEvent.UpdatedAt, decoded.GetUpdatedAt().AsTime())
+			assert.Equal(t, userEvent.EventMask, decoded.GetEventMask())
+			assert.Equal(t, userEvent.EventId, decoded.GetEventId())
+			assert.Equal(t, userEvent.Sequence, decoded.GetSequence())
+			assert.Equal(t, userEvent.Previous, decoded.GetPrevious())
+		})
+	}
+}
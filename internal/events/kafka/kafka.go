@@ -2,22 +2,71 @@ package kafka
 
 import (
 	"errors"
+	"fmt"
 	"github.com/alenalato/users-service/internal/common"
 	"github.com/alenalato/users-service/internal/logger"
-	"github.com/segmentio/kafka-go"
+	"time"
 )
 
+// Encoding is the encoding of the user events written to Kafka
+type Encoding string
+
+// EncodingJSON writes user events as plain JSON
+const EncodingJSON Encoding = "json"
+
+// EncodingCloudEventsStructured writes user events as JSON CloudEvents, envelope and data in the message value
+const EncodingCloudEventsStructured Encoding = "cloudevents-structured"
+
+// EncodingCloudEventsBinary writes user events as CloudEvents, envelope in the message headers and data in the message value
+const EncodingCloudEventsBinary Encoding = "cloudevents-binary"
+
+// EncodingProtobuf writes user events as protobuf messages, as defined in the published schema
+const EncodingProtobuf Encoding = "protobuf"
+
+// DefaultSource is the default CloudEvents source of user events
+const DefaultSource = "users-service"
+
 // Config holds the configuration for the Kafka event emitter
 type Config struct {
 	Addresses []string
+	// Encoding is the encoding of the user events, EncodingJSON if not set
+	Encoding Encoding
+	// Source is the CloudEvents source of the user events, DefaultSource if not set
+	Source string
+	// SchemaRegistry is the schema registry of the protobuf user events, written in its wire format, if set
+	SchemaRegistry SchemaRegistry
+	// TLS is the TLS configuration of the broker connections, which are plaintext if not set
+	TLS *TLSConfig
+	// SASL is the SASL authentication of the broker connections, which are not authenticated if not set
+	SASL *SASLConfig
+	// RequiredAcks is the acknowledgement required from the brokers for a write, AcksAll if not set
+	RequiredAcks Acks
+	// Compression is the compression codec of the message batches, CompressionNone if not set
+	Compression Compression
+	// BatchSize is the maximum number of messages of a batch, the writer default if not set
+	BatchSize int
+	// BatchTimeout is the maximum time to fill a batch before writing it, the writer default if not set
+	BatchTimeout time.Duration
+	// WriteTimeout is the timeout of a batch write, the writer default if not set
+	WriteTimeout time.Duration
+	// Metrics records the emissions of the user events, they are not recorded if not set
+	Metrics *Metrics
 }
 
 // EventEmitter is a struct that implements the EventEmitter interface using Kafka
 type EventEmitter struct {
 	// topicName is the name of the Kafka topic to which events will be emitted
 	topicName string
+	// encoding is the encoding of the user events
+	encoding Encoding
+	// source is the CloudEvents source of the user events
+	source string
+	// schemaRegistry is the schema registry of the protobuf user events, if any
+	schemaRegistry SchemaRegistry
 	// writer is the Kafka writer used to send messages to the topic
 	writer Writer
+	// metrics records the emissions of the user events, if set
+	metrics *Metrics
 }
 
 // Close closes the Kafka writer for proper resource cleanup
@@ -34,16 +83,43 @@ func NewEventEmitter(topicName string, config Config) (*EventEmitter, error) {
 		return nil, common.NewError(err, common.ErrTypeInternal)
 	}
 
+	encoding := config.Encoding
+	switch encoding {
+	case "":
+		encoding = EncodingJSON
+	case EncodingJSON, EncodingCloudEventsStructured, EncodingCloudEventsBinary, EncodingProtobuf:
+	default:
+		err := fmt.Errorf("unknown encoding: %s", encoding)
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	if config.SchemaRegistry != nil && encoding != EncodingProtobuf {
+		err := fmt.Errorf("schema registry is not supported by encoding: %s", encoding)
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	source := config.Source
+	if source == "" {
+		source = DefaultSource
+	}
+
 	logger.Log.Debugf("Creating new kafka writer: %s@%v", topicName, config.Addresses)
 
-	writer := &kafka.Writer{
-		Addr:     kafka.TCP(config.Addresses...),
-		Topic:    topicName,
-		Balancer: &kafka.Hash{},
+	writer, errWriter := newWriter(topicName, config)
+	if errWriter != nil {
+		return nil, errWriter
 	}
 
 	return &EventEmitter{
-		topicName: topicName,
-		writer:    writer,
+		topicName:      topicName,
+		encoding:       encoding,
+		source:         source,
+		schemaRegistry: config.SchemaRegistry,
+		writer:         writer,
+		metrics:        config.Metrics,
 	}, nil
 }
@@ -1,6 +1,8 @@
 package kafka
 
 import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 	"testing"
 )
@@ -14,10 +16,13 @@ type testSuite struct {
 func newTestSuite(t *testing.T) *testSuite {
 	mockCtrl := gomock.NewController(t)
 	mockWriter := NewMockWriter(mockCtrl)
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
 
 	eventEmitter := &EventEmitter{
 		topicName: "test-topic",
 		writer:    mockWriter,
+		metrics:   metrics,
 	}
 
 	return &testSuite{
@@ -0,0 +1,60 @@
+package kafka
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Results of an event emission
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// Metrics holds the metrics of the Kafka event emitters, shared by the emitters of every topic
+type Metrics struct {
+	// emitted is the number of emitted user events, by topic and result
+	emitted *prometheus.CounterVec
+	// duration is the duration of the user event emissions, by topic
+	duration *prometheus.HistogramVec
+}
+
+// observe records the emission of a user event to a topic, metrics are not recorded if nil
+func (m *Metrics) observe(topicName string, start time.Time, err error) {
+	if m == nil {
+		return
+	}
+
+	result := resultSuccess
+	if err != nil {
+		result = resultFailure
+	}
+	m.emitted.WithLabelValues(topicName, result).Inc()
+	m.duration.WithLabelValues(topicName).Observe(time.Since(start).Seconds())
+}
+
+// NewMetrics creates the metrics of the Kafka event emitters and registers them with the given registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	metrics := &Metrics{
+		emitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "users",
+			Subsystem: "kafka",
+			Name:      "emitted_events_total",
+			Help:      "Number of user events emitted to Kafka, by topic and result.",
+		}, []string{"topic", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "users",
+			Subsystem: "kafka",
+			Name:      "emit_duration_seconds",
+			Help:      "Duration of the user event emissions to Kafka, by topic.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"topic"}),
+	}
+	for _, collector := range []prometheus.Collector{metrics.emitted, metrics.duration} {
+		if errRegister := registerer.Register(collector); errRegister != nil {
+			return nil, errRegister
+		}
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/events"
+	pkgevents "github.com/alenalato/users-service/pkg/events"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// protobufContentType is the content type of protobuf user events, naming their message type
+const protobufContentType = "application/x-protobuf; messageType=users.events.UserEvent"
+
+// toProtoUserEvent converts an events.UserEvent to its published protobuf message
+func toProtoUserEvent(userEvent events.UserEvent) *pkgevents.UserEvent {
+	return &pkgevents.UserEvent{
+		EventType: string(userEvent.EventType),
+		EventTime: timestamppb.New(userEvent.EventTime),
+		UserId:    userEvent.UserId,
+		FirstName: userEvent.FirstName,
+		LastName:  userEvent.LastName,
+		Nickname:  userEvent.Nickname,
+		Email:     userEvent.Email,
+		Country:   userEvent.Country,
+		CreatedAt: timestamppb.New(userEvent.CreatedAt),
+		UpdatedAt: timestamppb.New(userEvent.UpdatedAt),
+		EventMask: userEvent.EventMask,
+		EventId:   userEvent.EventId,
+		Sequence:  userEvent.Sequence,
+		Previous:  userEvent.Previous,
+	}
+}
+
+// protobufMessage returns the message of a user event serialized as protobuf,
+// in the schema registry wire format if a schema registry is configured
+func (e *EventEmitter) protobufMessage(ctx context.Context, userEvent events.UserEvent) (kafka.Message, error) {
+	userEventBytes, err := proto.Marshal(toProtoUserEvent(userEvent))
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	if e.schemaRegistry != nil {
+		// The schema is registered once, its ID is cached by the schema registry client
+		schemaId, errRegister := e.schemaRegistry.RegisterSchema(ctx, SchemaSubject(e.topicName), userEventSchema)
+		if errRegister != nil {
+			return kafka.Message{}, errRegister
+		}
+		userEventBytes = wireFormat(schemaId, userEventBytes)
+	}
+
+	return kafka.Message{
+		Key:   []byte(userEvent.UserId),
+		Value: userEventBytes,
+		Headers: []kafka.Header{
+			{Key: "content-type", Value: []byte(protobufContentType)},
+		},
+	}, nil
+}
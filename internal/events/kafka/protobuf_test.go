@@ -0,0 +1,123 @@
+package kafka
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	pkgevents "github.com/alenalato/users-service/pkg/events"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmitUserEvent_Protobuf(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.eventEmitter.encoding = EncodingProtobuf
+
+	userEvent := newTestUserEvent()
+	userEvent.EventType = events.EventTypeUpdated
+	userEvent.CreatedAt = userEvent.EventTime.Add(-time.Hour)
+	userEvent.UpdatedAt = userEvent.EventTime
+	userEvent.EventMask = []string{"first_name"}
+	userEvent.EventId = "event-id"
+	userEvent.Sequence = 2
+	userEvent.Previous = map[string]string{"first_name": "Jack"}
+
+	ts.mockWriter.EXPECT().WriteMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msgs ...kafka.Message) error {
+			require.Len(t, msgs, 1)
+			assert.Equal(t, []byte(userEvent.UserId), msgs[0].Key)
+			assert.Equal(t, []kafka.Header{
+				{Key: "content-type", Value: []byte("application/x-protobuf; messageType=users.events.UserEvent")},
+			}, msgs[0].Headers)
+
+			var protoUserEvent pkgevents.UserEvent
+			require.NoError(t, proto.Unmarshal(msgs[0].Value, &protoUserEvent))
+			assert.Equal(t, "updated", protoUserEvent.GetEventType())
+			assert.Equal(t, userEvent.EventTime, protoUserEvent.GetEventTime().AsTime())
+			assert.Equal(t, userEvent.UserId, protoUserEvent.GetUserId())
+			assert.Equal(t, userEvent.FirstName, protoUserEvent.GetFirstName())
+			assert.Equal(t, userEvent.LastName, protoUserEvent.GetLastName())
+			assert.Equal(t, userEvent.Email, protoUserEvent.GetEmail())
+			assert.Equal(t, userEvent.CreatedAt, protoUserEvent.GetCreatedAt().AsTime())
+			assert.Equal(t, userEvent.UpdatedAt, protoUserEvent.GetUpdatedAt().AsTime())
+			assert.Equal(t, userEvent.EventMask, protoUserEvent.GetEventMask())
+			assert.Equal(t, userEvent.EventId, protoUserEvent.GetEventId())
+			assert.Equal(t, userEvent.Sequence, protoUserEvent.GetSequence())
+			assert.Equal(t, userEvent.Previous, protoUserEvent.GetPrevious())
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	assert.NoError(t, err)
+}
+
+func TestToProtoUserEvent_Fields(t *testing.T) {
+	// Every user event field is part of the published schema, with the same name
+	fields := (&pkgevents.UserEvent{}).ProtoReflect().Descriptor().Fields()
+
+	userEventType := reflect.TypeOf(events.UserEvent{})
+	for i := 0; i < userEventType.NumField(); i++ {
+		name := strings.Split(userEventType.Field(i).Tag.Get("json"), ",")[0]
+		assert.NotNil(t, fields.ByName(protoreflect.Name(name)), "field %s missing in the published schema", name)
+	}
+}
+
+func TestEmitUserEvent_ProtobufSchemaRegistry(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockSchemaRegistry := NewMockSchemaRegistry(ts.mockCtrl)
+	ts.eventEmitter.encoding = EncodingProtobuf
+	ts.eventEmitter.schemaRegistry = mockSchemaRegistry
+
+	userEvent := newTestUserEvent()
+
+	mockSchemaRegistry.EXPECT().RegisterSchema(gomock.Any(), "test-topic-value", UserEventSchema()).Return(258, nil)
+
+	ts.mockWriter.EXPECT().WriteMessages(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msgs ...kafka.Message) error {
+			require.Len(t, msgs, 1)
+
+			// Magic byte, schema ID and message indexes precede the protobuf message
+			value := msgs[0].Value
+			require.Greater(t, len(value), 6)
+			assert.Equal(t, []byte{0, 0, 0, 1, 2, 0}, value[:6])
+
+			var protoUserEvent pkgevents.UserEvent
+			require.NoError(t, proto.Unmarshal(value[6:], &protoUserEvent))
+			assert.Equal(t, userEvent.UserId, protoUserEvent.GetUserId())
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	assert.NoError(t, err)
+}
+
+func TestEmitUserEvent_ProtobufSchemaRegistryError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	mockSchemaRegistry := NewMockSchemaRegistry(ts.mockCtrl)
+	ts.eventEmitter.encoding = EncodingProtobuf
+	ts.eventEmitter.schemaRegistry = mockSchemaRegistry
+
+	mockSchemaRegistry.EXPECT().RegisterSchema(gomock.Any(), "test-topic-value", UserEventSchema()).
+		Return(0, common.NewError(nil, common.ErrTypeInternal))
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), newTestUserEvent())
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
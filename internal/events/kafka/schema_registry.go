@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/alenalato/users-service/internal/events/schemaregistry"
+	pkgevents "github.com/alenalato/users-service/pkg/events"
+)
+
+//go:generate mockgen -destination=schema_registry_mock.go -package=kafka github.com/alenalato/users-service/internal/events/kafka SchemaRegistry
+
+// SchemaRegistry is an interface for registering the schema of the user events in a schema registry
+type SchemaRegistry interface {
+	RegisterSchema(ctx context.Context, subject string, schema schemaregistry.Schema) (int, error)
+}
+
+// wireFormatMagicByte is the first byte of the messages in the schema registry wire format
+const wireFormatMagicByte = 0
+
+// userEventSchema is the schema of the protobuf user events
+var userEventSchema = schemaregistry.Schema{
+	Type:   schemaregistry.SchemaTypeProtobuf,
+	Schema: pkgevents.UserEventSchema,
+}
+
+// SchemaSubject returns the schema registry subject of the messages of a topic, following the topic name strategy
+func SchemaSubject(topicName string) string {
+	return topicName + "-value"
+}
+
+// UserEventSchema returns the schema of the protobuf user events, as registered in the schema registry
+func UserEventSchema() schemaregistry.Schema {
+	return userEventSchema
+}
+
+// wireFormat prefixes a protobuf user event with the schema registry wire format header:
+// the magic byte, the schema ID as a 4 bytes big endian integer and the message indexes of the UserEvent message,
+// which is the first message of its schema, so its indexes are encoded as a single zero byte.
+func wireFormat(schemaId int, userEventBytes []byte) []byte {
+	message := make([]byte, 0, 6+len(userEventBytes))
+	message = append(message, wireFormatMagicByte)
+	message = binary.BigEndian.AppendUint32(message, uint32(schemaId))
+	message = append(message, 0)
+
+	return append(message, userEventBytes...)
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/alenalato/users-service/internal/events/kafka (interfaces: SchemaRegistry)
+//
+// Generated by this command:
+//
+//	mockgen -destination=schema_registry_mock.go -package=kafka github.com/alenalato/users-service/internal/events/kafka SchemaRegistry
+//
+
+// Package kafka is a generated GoMock package.
+package kafka
+
+import (
+	context "context"
+	reflect "reflect"
+
+	schemaregistry "github.com/alenalato/users-service/internal/events/schemaregistry"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSchemaRegistry is a mock of SchemaRegistry interface.
+type MockSchemaRegistry struct {
+	ctrl     *gomock.Controller
+	recorder *MockSchemaRegistryMockRecorder
+	isgomock struct{}
+}
+
+// MockSchemaRegistryMockRecorder is the mock recorder for MockSchemaRegistry.
+type MockSchemaRegistryMockRecorder struct {
+	mock *MockSchemaRegistry
+}
+
+// NewMockSchemaRegistry creates a new mock instance.
+func NewMockSchemaRegistry(ctrl *gomock.Controller) *MockSchemaRegistry {
+	mock := &MockSchemaRegistry{ctrl: ctrl}
+	mock.recorder = &MockSchemaRegistryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSchemaRegistry) EXPECT() *MockSchemaRegistryMockRecorder {
+	return m.recorder
+}
+
+// RegisterSchema mocks base method.
+func (m *MockSchemaRegistry) RegisterSchema(ctx context.Context, subject string, schema schemaregistry.Schema) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterSchema", ctx, subject, schema)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterSchema indicates an expected call of RegisterSchema.
+func (mr *MockSchemaRegistryMockRecorder) RegisterSchema(ctx, subject, schema any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterSchema", reflect.TypeOf((*MockSchemaRegistry)(nil).RegisterSchema), ctx, subject, schema)
+}
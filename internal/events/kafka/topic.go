@@ -0,0 +1,153 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/segmentio/kafka-go"
+	"strconv"
+	"time"
+)
+
+//go:generate mockgen -destination=client_mock.go -package=kafka github.com/alenalato/users-service/internal/events/kafka Client
+
+// Client is an interface for the Kafka admin requests used to manage topics
+// It abstracts the underlying Kafka client implementation
+// to allow for easier testing and mocking
+type Client interface {
+	Metadata(ctx context.Context, req *kafka.MetadataRequest) (*kafka.MetadataResponse, error)
+	CreateTopics(ctx context.Context, req *kafka.CreateTopicsRequest) (*kafka.CreateTopicsResponse, error)
+}
+
+// TopicConfig holds the expected configuration of a topic
+// Partitions is the expected number of partitions, any number is accepted if not set
+// Create enables the creation of a missing topic with the configured partitions, replication factor and retention,
+// a missing topic is an error otherwise
+// ReplicationFactor is the replication factor of a created topic, the broker default if not set
+// Retention is the retention of the messages of a created topic, the broker default if not set
+type TopicConfig struct {
+	Partitions        int
+	Create            bool
+	ReplicationFactor int
+	Retention         time.Duration
+}
+
+// TopicManager verifies and provisions the Kafka topics of the event emitters
+type TopicManager struct {
+	// client sends the admin requests to the brokers, topic creations to the controller broker
+	client Client
+}
+
+// EnsureTopic verifies that a topic exists with the expected number of partitions.
+// If the topic is missing and its creation is enabled, it is created through the controller broker.
+// The topic metadata is requested without allowing its auto creation, so that a missing topic
+// is not created by brokers auto creating topics.
+func (m *TopicManager) EnsureTopic(ctx context.Context, topicName string, topicConfig TopicConfig) error {
+	metadata, errMetadata := m.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topicName}})
+	if errMetadata != nil {
+		return topicError(fmt.Errorf("could not read metadata of topic %s: %w", topicName, errMetadata))
+	}
+
+	var topic *kafka.Topic
+	for i := range metadata.Topics {
+		if metadata.Topics[i].Name == topicName {
+			topic = &metadata.Topics[i]
+
+			break
+		}
+	}
+	if topic == nil || errors.Is(topic.Error, kafka.UnknownTopicOrPartition) {
+		if !topicConfig.Create {
+			return topicError(fmt.Errorf("topic %s does not exist", topicName))
+		}
+
+		return m.createTopic(ctx, topicName, topicConfig)
+	}
+	if topic.Error != nil {
+		return topicError(fmt.Errorf("could not read partitions of topic %s: %w", topicName, topic.Error))
+	}
+
+	if topicConfig.Partitions > 0 && len(topic.Partitions) != topicConfig.Partitions {
+		return topicError(fmt.Errorf(
+			"topic %s has %d partitions, %d expected",
+			topicName,
+			len(topic.Partitions),
+			topicConfig.Partitions,
+		))
+	}
+	logger.Log.Debugf("Topic %s verified with %d partitions", topicName, len(topic.Partitions))
+
+	return nil
+}
+
+// createTopic creates a topic, the request is sent to the controller broker, which is the only one allowed to create topics
+func (m *TopicManager) createTopic(ctx context.Context, topicName string, topicConfig TopicConfig) error {
+	topic := kafka.TopicConfig{
+		Topic:             topicName,
+		NumPartitions:     -1,
+		ReplicationFactor: -1,
+	}
+	if topicConfig.Partitions > 0 {
+		topic.NumPartitions = topicConfig.Partitions
+	}
+	if topicConfig.ReplicationFactor > 0 {
+		topic.ReplicationFactor = topicConfig.ReplicationFactor
+	}
+	if topicConfig.Retention > 0 {
+		topic.ConfigEntries = []kafka.ConfigEntry{{
+			ConfigName:  "retention.ms",
+			ConfigValue: strconv.FormatInt(topicConfig.Retention.Milliseconds(), 10),
+		}}
+	}
+
+	created, errCreate := m.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: []kafka.TopicConfig{topic}})
+	if errCreate == nil {
+		errCreate = created.Errors[topicName]
+	}
+	if errCreate != nil && !errors.Is(errCreate, kafka.TopicAlreadyExists) {
+		return topicError(fmt.Errorf("could not create topic %s: %w", topicName, errCreate))
+	}
+	logger.Log.Infof("Topic %s created", topicName)
+
+	return nil
+}
+
+// topicError logs and returns an error of the topic verification or provisioning
+func topicError(err error) error {
+	logger.Log.Error(err)
+
+	return common.NewError(err, common.ErrTypeInternal)
+}
+
+// NewTopicManager creates a new TopicManager instance, connecting to the brokers as the event emitters do
+func NewTopicManager(config Config) (*TopicManager, error) {
+	if len(config.Addresses) == 0 {
+		return nil, configError(errors.New("addresses are empty"))
+	}
+
+	transport := &kafka.Transport{DialTimeout: 10 * time.Second}
+	if config.TLS != nil {
+		tlsConfig, errTLS := newTLSConfig(*config.TLS)
+		if errTLS != nil {
+			return nil, configError(errTLS)
+		}
+		transport.TLS = tlsConfig
+	}
+	if config.SASL != nil {
+		mechanism, errSASL := newSASLMechanism(*config.SASL)
+		if errSASL != nil {
+			return nil, configError(errSASL)
+		}
+		transport.SASL = mechanism
+	}
+
+	return &TopicManager{
+		client: &kafka.Client{
+			Addr:      kafka.TCP(config.Addresses...),
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+	}, nil
+}
@@ -0,0 +1,195 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"net"
+	"testing"
+	"time"
+)
+
+// metadataRequest matches the metadata request of the given topic
+func metadataRequest(topicName string) *kafka.MetadataRequest {
+	return &kafka.MetadataRequest{Topics: []string{topicName}}
+}
+
+// missingTopicMetadata returns the metadata of a missing topic, as returned by the brokers
+func missingTopicMetadata(topicName string) *kafka.MetadataResponse {
+	return &kafka.MetadataResponse{Topics: []kafka.Topic{{Name: topicName, Error: kafka.UnknownTopicOrPartition}}}
+}
+
+func TestTopicManager_EnsureTopic_Exists(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := NewMockClient(mockCtrl)
+	mockClient.EXPECT().Metadata(gomock.Any(), metadataRequest("users")).Return(&kafka.MetadataResponse{
+		Topics: []kafka.Topic{{Name: "users", Partitions: []kafka.Partition{{ID: 0}, {ID: 1}, {ID: 2}}}},
+	}, nil)
+
+	topicManager := &TopicManager{client: mockClient}
+
+	err := topicManager.EnsureTopic(context.Background(), "users", TopicConfig{Partitions: 3})
+	assert.NoError(t, err)
+}
+
+func TestTopicManager_EnsureTopic_UnexpectedPartitions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := NewMockClient(mockCtrl)
+	mockClient.EXPECT().Metadata(gomock.Any(), metadataRequest("users")).Return(&kafka.MetadataResponse{
+		Topics: []kafka.Topic{{Name: "users", Partitions: []kafka.Partition{{ID: 0}}}},
+	}, nil)
+
+	topicManager := &TopicManager{client: mockClient}
+
+	err := topicManager.EnsureTopic(context.Background(), "users", TopicConfig{Partitions: 3})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestTopicManager_EnsureTopic_Missing(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := NewMockClient(mockCtrl)
+	mockClient.EXPECT().Metadata(gomock.Any(), metadataRequest("userz")).Return(missingTopicMetadata("userz"), nil)
+
+	topicManager := &TopicManager{client: mockClient}
+
+	// A missing topic is not created if its creation is not enabled
+	err := topicManager.EnsureTopic(context.Background(), "userz", TopicConfig{Partitions: 3})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestTopicManager_EnsureTopic_Create(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := NewMockClient(mockCtrl)
+	gomock.InOrder(
+		mockClient.EXPECT().Metadata(gomock.Any(), metadataRequest("users")).Return(missingTopicMetadata("users"), nil),
+		mockClient.EXPECT().CreateTopics(gomock.Any(), &kafka.CreateTopicsRequest{Topics: []kafka.TopicConfig{{
+			Topic:             "users",
+			NumPartitions:     6,
+			ReplicationFactor: 3,
+			ConfigEntries:     []kafka.ConfigEntry{{ConfigName: "retention.ms", ConfigValue: "604800000"}},
+		}}}).Return(&kafka.CreateTopicsResponse{Errors: map[string]error{"users": nil}}, nil),
+	)
+
+	topicManager := &TopicManager{client: mockClient}
+
+	err := topicManager.EnsureTopic(context.Background(), "users", TopicConfig{
+		Partitions:        6,
+		Create:            true,
+		ReplicationFactor: 3,
+		Retention:         7 * 24 * time.Hour,
+	})
+	assert.NoError(t, err)
+}
+
+func TestTopicManager_EnsureTopic_CreateBrokerDefaults(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := NewMockClient(mockCtrl)
+	mockClient.EXPECT().Metadata(gomock.Any(), metadataRequest("users")).Return(missingTopicMetadata("users"), nil)
+
+	// A topic created concurrently by another instance is fine
+	mockClient.EXPECT().CreateTopics(gomock.Any(), &kafka.CreateTopicsRequest{Topics: []kafka.TopicConfig{{
+		Topic:             "users",
+		NumPartitions:     -1,
+		ReplicationFactor: -1,
+	}}}).Return(&kafka.CreateTopicsResponse{Errors: map[string]error{"users": kafka.TopicAlreadyExists}}, nil)
+
+	topicManager := &TopicManager{client: mockClient}
+
+	err := topicManager.EnsureTopic(context.Background(), "users", TopicConfig{Create: true})
+	assert.NoError(t, err)
+}
+
+func TestTopicManager_EnsureTopic_CreateError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := NewMockClient(mockCtrl)
+	mockClient.EXPECT().Metadata(gomock.Any(), metadataRequest("users")).Return(missingTopicMetadata("users"), nil)
+	mockClient.EXPECT().CreateTopics(gomock.Any(), gomock.Any()).
+		Return(&kafka.CreateTopicsResponse{Errors: map[string]error{"users": kafka.InvalidReplicationFactor}}, nil)
+
+	topicManager := &TopicManager{client: mockClient}
+
+	err := topicManager.EnsureTopic(context.Background(), "users", TopicConfig{Create: true, ReplicationFactor: 5})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+	assert.True(t, errors.Is(err, kafka.InvalidReplicationFactor))
+}
+
+func TestTopicManager_EnsureTopic_Unreachable(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockClient := NewMockClient(mockCtrl)
+	mockClient.EXPECT().Metadata(gomock.Any(), metadataRequest("users")).Return(nil, errors.New("connection refused"))
+
+	topicManager := &TopicManager{client: mockClient}
+
+	err := topicManager.EnsureTopic(context.Background(), "users", TopicConfig{})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+// roundTripperFunc is a Kafka round tripper answering the requests with a function
+type roundTripperFunc func(ctx context.Context, addr net.Addr, req protocol.Message) (protocol.Message, error)
+
+func (f roundTripperFunc) RoundTrip(ctx context.Context, addr net.Addr, req protocol.Message) (protocol.Message, error) {
+	return f(ctx, addr, req)
+}
+
+func TestTopicManager_EnsureTopic_NoAutoCreation(t *testing.T) {
+	// The requests are the ones sent to the brokers by the Kafka client
+	var requests []protocol.Message
+	topicManager := &TopicManager{client: &kafka.Client{
+		Addr: kafka.TCP("broker-1:9092"),
+		Transport: roundTripperFunc(func(_ context.Context, _ net.Addr, req protocol.Message) (protocol.Message, error) {
+			requests = append(requests, req)
+
+			return &metadata.Response{
+				Topics: []metadata.ResponseTopic{{Name: "userz", ErrorCode: int16(kafka.UnknownTopicOrPartition)}},
+			}, nil
+		}),
+	}}
+
+	err := topicManager.EnsureTopic(context.Background(), "userz", TopicConfig{})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+
+	// Brokers auto creating topics do not create the missing topic on the metadata request
+	require.Len(t, requests, 1)
+	metadataReq, isMetadata := requests[0].(*metadata.Request)
+	require.True(t, isMetadata)
+	assert.Equal(t, []string{"userz"}, metadataReq.TopicNames)
+	assert.False(t, metadataReq.AllowAutoTopicCreation)
+}
+
+func TestNewTopicManager_Error(t *testing.T) {
+	topicManager, err := NewTopicManager(Config{})
+	assert.Nil(t, topicManager)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
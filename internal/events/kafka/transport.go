@@ -0,0 +1,178 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"os"
+)
+
+// SASLMechanism is the SASL mechanism authenticating the broker connections
+type SASLMechanism string
+
+const SASLMechanismPlain SASLMechanism = "plain"
+const SASLMechanismScramSHA256 SASLMechanism = "scram-sha-256"
+const SASLMechanismScramSHA512 SASLMechanism = "scram-sha-512"
+
+// Acks is the acknowledgement required from the brokers for a write to succeed
+type Acks string
+
+// AcksNone does not wait for any acknowledgement
+const AcksNone Acks = "none"
+
+// AcksLeader waits for the acknowledgement of the partition leader
+const AcksLeader Acks = "leader"
+
+// AcksAll waits for the acknowledgement of all the in-sync replicas
+const AcksAll Acks = "all"
+
+// Compression is the compression codec of the written message batches
+type Compression string
+
+const CompressionNone Compression = "none"
+const CompressionGzip Compression = "gzip"
+const CompressionSnappy Compression = "snappy"
+const CompressionLz4 Compression = "lz4"
+const CompressionZstd Compression = "zstd"
+
+// TLSConfig holds the TLS configuration of the broker connections
+// CAFile is the PEM file of the certificate authorities verifying the brokers, the system ones if not set
+// CertFile and KeyFile are the PEM files of the client certificate, for mutual TLS, if set
+type TLSConfig struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// SASLConfig holds the SASL authentication of the broker connections
+type SASLConfig struct {
+	Mechanism SASLMechanism
+	Username  string
+	Password  string
+}
+
+// requiredAcks maps the required acknowledgements to the ones of the Kafka writer
+var requiredAcks = map[Acks]kafka.RequiredAcks{
+	AcksNone:   kafka.RequireNone,
+	AcksLeader: kafka.RequireOne,
+	AcksAll:    kafka.RequireAll,
+}
+
+// compressionCodecs maps the compression codecs to the ones of the Kafka writer
+var compressionCodecs = map[Compression]kafka.Compression{
+	CompressionNone:   0,
+	CompressionGzip:   kafka.Gzip,
+	CompressionSnappy: kafka.Snappy,
+	CompressionLz4:    kafka.Lz4,
+	CompressionZstd:   kafka.Zstd,
+}
+
+// newTLSConfig loads the TLS configuration of the broker connections from its files
+func newTLSConfig(config TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if config.CAFile != "" {
+		caPem, errRead := os.ReadFile(config.CAFile)
+		if errRead != nil {
+			return nil, fmt.Errorf("could not read CA file: %w", errRead)
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caPem) {
+			return nil, errors.New("no certificate found in CA file")
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if config.CertFile != "" || config.KeyFile != "" {
+		certificate, errLoad := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if errLoad != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", errLoad)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// newSASLMechanism returns the SASL mechanism authenticating the broker connections with the given credentials
+func newSASLMechanism(config SASLConfig) (sasl.Mechanism, error) {
+	switch config.Mechanism {
+	case SASLMechanismPlain:
+		return plain.Mechanism{Username: config.Username, Password: config.Password}, nil
+	case SASLMechanismScramSHA256:
+		return scram.Mechanism(scram.SHA256, config.Username, config.Password)
+	case SASLMechanismScramSHA512:
+		return scram.Mechanism(scram.SHA512, config.Username, config.Password)
+	default:
+		return nil, fmt.Errorf("unknown SASL mechanism: %s", config.Mechanism)
+	}
+}
+
+// newWriter creates the Kafka writer of a topic, messages are balanced by key among the topic partitions
+func newWriter(topicName string, config Config) (*kafka.Writer, error) {
+	acks := config.RequiredAcks
+	if acks == "" {
+		acks = AcksAll
+	}
+	writerAcks, okAcks := requiredAcks[acks]
+	if !okAcks {
+		return nil, configError(fmt.Errorf("unknown required acks: %s", acks))
+	}
+
+	compression := config.Compression
+	if compression == "" {
+		compression = CompressionNone
+	}
+	writerCompression, okCompression := compressionCodecs[compression]
+	if !okCompression {
+		return nil, configError(fmt.Errorf("unknown compression: %s", compression))
+	}
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(config.Addresses...),
+		Topic:        topicName,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: writerAcks,
+		Compression:  writerCompression,
+		BatchSize:    config.BatchSize,
+		BatchTimeout: config.BatchTimeout,
+		WriteTimeout: config.WriteTimeout,
+	}
+
+	if config.TLS == nil && config.SASL == nil {
+		return writer, nil
+	}
+
+	transport := &kafka.Transport{}
+	if config.TLS != nil {
+		tlsConfig, errTLS := newTLSConfig(*config.TLS)
+		if errTLS != nil {
+			return nil, configError(errTLS)
+		}
+		transport.TLS = tlsConfig
+	}
+	if config.SASL != nil {
+		mechanism, errSASL := newSASLMechanism(*config.SASL)
+		if errSASL != nil {
+			return nil, configError(errSASL)
+		}
+		transport.SASL = mechanism
+	}
+	writer.Transport = transport
+
+	return writer, nil
+}
+
+// configError logs and returns an error of the Kafka connection configuration
+func configError(err error) error {
+	logger.Log.Error(err)
+
+	return common.NewError(err, common.ErrTypeInternal)
+}
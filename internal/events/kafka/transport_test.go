@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key as PEM files, returning their paths
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "users-service"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	certDer, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDer}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	return certFile, keyFile
+}
+
+func TestNewWriter_Defaults(t *testing.T) {
+	writer, err := newWriter("test-topic", Config{Addresses: []string{"localhost:9092"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "test-topic", writer.Topic)
+	assert.IsType(t, &kafka.Hash{}, writer.Balancer)
+	assert.Equal(t, kafka.RequireAll, writer.RequiredAcks)
+	assert.Equal(t, kafka.Compression(0), writer.Compression)
+	// Plaintext connections use the default transport
+	assert.Nil(t, writer.Transport)
+}
+
+func TestNewWriter_Config(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	writer, err := newWriter("test-topic", Config{
+		Addresses:    []string{"localhost:9092"},
+		TLS:          &TLSConfig{CAFile: certFile, CertFile: certFile, KeyFile: keyFile},
+		SASL:         &SASLConfig{Mechanism: SASLMechanismPlain, Username: "user", Password: "password"},
+		RequiredAcks: AcksLeader,
+		Compression:  CompressionZstd,
+		BatchSize:    500,
+		BatchTimeout: 50 * time.Millisecond,
+		WriteTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, kafka.RequireOne, writer.RequiredAcks)
+	assert.Equal(t, kafka.Zstd, writer.Compression)
+	assert.Equal(t, 500, writer.BatchSize)
+	assert.Equal(t, 50*time.Millisecond, writer.BatchTimeout)
+	assert.Equal(t, 5*time.Second, writer.WriteTimeout)
+
+	transport, ok := writer.Transport.(*kafka.Transport)
+	require.True(t, ok)
+	require.NotNil(t, transport.TLS)
+	assert.NotNil(t, transport.TLS.RootCAs)
+	assert.Len(t, transport.TLS.Certificates, 1)
+	assert.Equal(t, plain.Mechanism{Username: "user", Password: "password"}, transport.SASL)
+}
+
+func TestNewWriter_ConfigError(t *testing.T) {
+	emptyFile := filepath.Join(t.TempDir(), "empty.pem")
+	require.NoError(t, os.WriteFile(emptyFile, nil, 0600))
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name:   "Unknown required acks",
+			config: Config{RequiredAcks: "some"},
+		},
+		{
+			name:   "Unknown compression",
+			config: Config{Compression: "brotli"},
+		},
+		{
+			name:   "Unknown SASL mechanism",
+			config: Config{SASL: &SASLConfig{Mechanism: "gssapi"}},
+		},
+		{
+			name:   "Missing CA file",
+			config: Config{TLS: &TLSConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}},
+		},
+		{
+			name:   "CA file without certificates",
+			config: Config{TLS: &TLSConfig{CAFile: emptyFile}},
+		},
+		{
+			name:   "Client certificate without key",
+			config: Config{TLS: &TLSConfig{CertFile: emptyFile}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Addresses = []string{"localhost:9092"}
+
+			writer, err := newWriter("test-topic", tt.config)
+			assert.Nil(t, writer)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+		})
+	}
+}
+
+func TestNewSASLMechanism_Scram(t *testing.T) {
+	for _, mechanism := range []SASLMechanism{SASLMechanismScramSHA256, SASLMechanismScramSHA512} {
+		saslMechanism, err := newSASLMechanism(SASLConfig{Mechanism: mechanism, Username: "user", Password: "password"})
+		require.NoError(t, err)
+		assert.Equal(t, map[SASLMechanism]string{
+			SASLMechanismScramSHA256: "SCRAM-SHA-256",
+			SASLMechanismScramSHA512: "SCRAM-SHA-512",
+		}[mechanism], saslMechanism.Name())
+	}
+}
@@ -7,23 +7,25 @@ import (
 	"github.com/alenalato/users-service/internal/events"
 	"github.com/alenalato/users-service/internal/logger"
 	"github.com/segmentio/kafka-go"
+	"time"
 )
 
 // EmitUserEvent emits a user event to the Kafka topic
-// It marshals the user event to JSON and sends it as a message
+// It encodes the user event according to the configured encoding and sends it as a message
 // to the Kafka topic using the Kafka writer
-func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserEvent) error {
-	// Marshal user event to JSON
-	userEventBytes, err := json.Marshal(userEvent)
+func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserEvent) (err error) {
+	start := time.Now()
+	defer func() {
+		e.metrics.observe(e.topicName, start, err)
+	}()
+
+	message, err := e.userEventMessage(ctx, userEvent)
 	if err != nil {
-		logger.Log.Errorf("Failed to marshal user event: %v", err)
+		logger.Log.Errorf("Failed to encode user event: %v", err)
 		return err
 	}
 
-	if writeErr := e.writer.WriteMessages(ctx, kafka.Message{
-		Key:   []byte(userEvent.UserId),
-		Value: userEventBytes,
-	}); writeErr != nil {
+	if writeErr := e.writer.WriteMessages(ctx, message); writeErr != nil {
 		logger.Log.Errorf("Failed to emit user event: %v", writeErr)
 
 		return common.NewError(writeErr, common.ErrTypeInternal)
@@ -31,3 +33,26 @@ func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserE
 
 	return nil
 }
+
+// userEventMessage returns the message of a user event, keyed by user ID, according to the configured encoding
+func (e *EventEmitter) userEventMessage(ctx context.Context, userEvent events.UserEvent) (kafka.Message, error) {
+	switch e.encoding {
+	case EncodingCloudEventsStructured:
+		return e.cloudEventsStructuredMessage(userEvent)
+	case EncodingCloudEventsBinary:
+		return e.cloudEventsBinaryMessage(userEvent)
+	case EncodingProtobuf:
+		return e.protobufMessage(ctx, userEvent)
+	}
+
+	// Marshal user event to JSON
+	userEventBytes, err := json.Marshal(userEvent)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	return kafka.Message{
+		Key:   []byte(userEvent.UserId),
+		Value: userEventBytes,
+	}, nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/alenalato/users-service/internal/events"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/segmentio/kafka-go"
 	"github.com/stretchr/testify/assert"
 )
@@ -37,6 +38,8 @@ func TestEmitUserEvent_Success(t *testing.T) {
 	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
 
 	assert.NoError(t, err)
+	assert.Equal(t, float64(1), testutil.ToFloat64(ts.eventEmitter.metrics.emitted.WithLabelValues("test-topic", resultSuccess)))
+	assert.Equal(t, 1, testutil.CollectAndCount(ts.eventEmitter.metrics.duration))
 }
 
 func TestEmitUserEvent_WriteError(t *testing.T) {
@@ -65,4 +68,5 @@ func TestEmitUserEvent_WriteError(t *testing.T) {
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
 	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+	assert.Equal(t, float64(1), testutil.ToFloat64(ts.eventEmitter.metrics.emitted.WithLabelValues("test-topic", resultFailure)))
 }
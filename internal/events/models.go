@@ -7,13 +7,23 @@ type UserEventType string
 const EventTypeCreated UserEventType = "created"
 const EventTypeUpdated UserEventType = "updated"
 const EventTypeDeleted UserEventType = "deleted"
+const EventTypeRestored UserEventType = "restored"
+const EventTypePurged UserEventType = "purged"
+
+// EventTypeSnapshot is the type of the events replaying the current state of a user, emitted by a backfill
+const EventTypeSnapshot UserEventType = "snapshot"
 
 // UserEvent represents a user event
 // It is used to emit events to the event bus
-// EventType is one of the following: created, updated, deleted
+// EventId uniquely identifies the event, redeliveries of the same event share it
+// Sequence numbers the events of a user, it increases by one with every change of the user
+// EventType is one of the following: created, updated, deleted, restored, purged, snapshot
 // EventTime is the time when the event was emitted
 // EventMask is a list of fields that were changed in the user for the updated event
+// Previous holds the values before the update of the fields actually changed by the updated event
 type UserEvent struct {
+	EventId   string        `json:"event_id"`
+	Sequence  int64         `json:"sequence"`
 	EventType UserEventType `json:"event_type"`
 	EventTime time.Time     `json:"event_time"`
 
@@ -26,5 +36,6 @@ type UserEvent struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
-	EventMask []string `json:"event_mask"`
+	EventMask []string          `json:"event_mask"`
+	Previous  map[string]string `json:"previous,omitempty"`
 }
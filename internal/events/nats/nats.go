@@ -0,0 +1,158 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"time"
+)
+
+//go:generate mockgen -destination=nats_mock.go -package=nats github.com/alenalato/users-service/internal/events/nats Publisher
+
+// Publisher is an interface for publishing messages to JetStream
+// It abstracts the underlying JetStream implementation
+// to allow for easier testing and mocking
+type Publisher interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// DefaultSubjectPrefix is the default prefix of the subjects of user events
+const DefaultSubjectPrefix = "users"
+
+// HeaderUserId is the header holding the user ID of the event
+const HeaderUserId = "Users-User-Id"
+
+// Config holds the configuration for the NATS JetStream event emitter
+type Config struct {
+	// URL is the URL of the NATS servers, comma separated, credentials can be part of it
+	URL string
+	// SubjectPrefix is the prefix of the subjects of user events, DefaultSubjectPrefix if not set
+	SubjectPrefix string
+	// Stream is the stream expected to store the user events, the publish fails if another one stores them, if set
+	Stream string
+	// Timeout is the timeout of a publish, waiting for the acknowledgement of the stream
+	Timeout time.Duration
+}
+
+// EventEmitter is a struct that implements the EventEmitter interface using NATS JetStream
+type EventEmitter struct {
+	// subjectPrefix is the prefix of the subjects of user events
+	subjectPrefix string
+	// stream is the stream expected to store the user events, if any
+	stream string
+	// timeout is the timeout of a publish
+	timeout time.Duration
+	// conn is the NATS connection, nil if the publisher is not connected by the emitter
+	conn *nats.Conn
+	// publisher is the JetStream publisher used to publish messages
+	publisher Publisher
+}
+
+var _ events.EventEmitter = new(EventEmitter)
+
+// EmitUserEvent publishes a user event as JSON to the subject of its event type.
+// The event ID is the message ID, so the stream discards redeliveries of an event within its duplicate window.
+func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserEvent) error {
+	data, errMarshal := json.Marshal(userEvent)
+	if errMarshal != nil {
+		logger.Log.Errorf("Error marshalling user event: %v", errMarshal)
+
+		return common.NewError(errMarshal, common.ErrTypeInternal)
+	}
+
+	msg := nats.NewMsg(e.Subject(userEvent.EventType))
+	msg.Data = data
+	msg.Header.Set("Content-Type", "application/json")
+	msg.Header.Set(HeaderUserId, userEvent.UserId)
+	if userEvent.EventId != "" {
+		msg.Header.Set(jetstream.MsgIDHeader, userEvent.EventId)
+	}
+	if e.stream != "" {
+		msg.Header.Set(jetstream.ExpectedStreamHeader, e.stream)
+	}
+
+	publishCtx, cancelPublish := context.WithTimeout(ctx, e.timeout)
+	defer cancelPublish()
+
+	pubAck, errPublish := e.publisher.PublishMsg(publishCtx, msg)
+	if errPublish != nil {
+		logger.Log.Errorf("Error publishing user event: %v", errPublish)
+
+		return common.NewError(errPublish, common.ErrTypeInternal)
+	}
+	if pubAck.Duplicate {
+		logger.Log.Debugf("User event %s already stored in stream %s", userEvent.EventId, pubAck.Stream)
+	}
+
+	return nil
+}
+
+// Subject returns the subject of the user events of the given type, like users.created
+func (e *EventEmitter) Subject(eventType events.UserEventType) string {
+	return e.subjectPrefix + "." + string(eventType)
+}
+
+// Close drains the NATS connection for proper resource cleanup
+func (e *EventEmitter) Close() error {
+	if e.conn == nil {
+		return nil
+	}
+
+	return e.conn.Drain()
+}
+
+// NewEventEmitter creates a new EventEmitter instance, connecting to the NATS servers
+func NewEventEmitter(config Config) (*EventEmitter, error) {
+	if config.URL == "" {
+		err := errors.New("url is empty")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	logger.Log.Debugf("Connecting to NATS servers: %s", config.URL)
+
+	conn, errConnect := nats.Connect(config.URL, nats.Name("users-service"), nats.MaxReconnects(-1))
+	if errConnect != nil {
+		logger.Log.Errorf("Error connecting to NATS servers: %v", errConnect)
+
+		return nil, common.NewError(errConnect, common.ErrTypeInternal)
+	}
+
+	publisher, errJetStream := jetstream.New(conn)
+	if errJetStream != nil {
+		conn.Close()
+		logger.Log.Errorf("Error initializing JetStream: %v", errJetStream)
+
+		return nil, common.NewError(errJetStream, common.ErrTypeInternal)
+	}
+
+	emitter := newEventEmitter(publisher, config)
+	emitter.conn = conn
+
+	return emitter, nil
+}
+
+// newEventEmitter creates a new EventEmitter instance publishing with the given publisher
+func newEventEmitter(publisher Publisher, config Config) *EventEmitter {
+	subjectPrefix := config.SubjectPrefix
+	if subjectPrefix == "" {
+		subjectPrefix = DefaultSubjectPrefix
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	return &EventEmitter{
+		subjectPrefix: subjectPrefix,
+		stream:        config.Stream,
+		timeout:       timeout,
+		publisher:     publisher,
+	}
+}
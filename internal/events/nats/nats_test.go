@@ -0,0 +1,261 @@
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestEventEmitter_EmitUserEvent_Success(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockPublisher := NewMockPublisher(mockCtrl)
+	eventEmitter := newEventEmitter(mockPublisher, Config{Stream: "USERS"})
+
+	userEvent := events.UserEvent{
+		EventId:   "event-id",
+		Sequence:  2,
+		EventType: events.EventTypeUpdated,
+		EventTime: time.Now().UTC(),
+		UserId:    "user-id",
+		Nickname:  "nickname",
+		EventMask: []string{"nickname"},
+	}
+
+	mockPublisher.EXPECT().PublishMsg(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+			_, hasDeadline := ctx.Deadline()
+			assert.True(t, hasDeadline)
+
+			assert.Equal(t, "users.updated", msg.Subject)
+			assert.Equal(t, "application/json", msg.Header.Get("Content-Type"))
+			assert.Equal(t, "user-id", msg.Header.Get(HeaderUserId))
+			assert.Equal(t, "event-id", msg.Header.Get(jetstream.MsgIDHeader))
+			assert.Equal(t, "USERS", msg.Header.Get(jetstream.ExpectedStreamHeader))
+
+			var publishedEvent events.UserEvent
+			require.NoError(t, json.Unmarshal(msg.Data, &publishedEvent))
+			assert.Equal(t, userEvent, publishedEvent)
+
+			return &jetstream.PubAck{Stream: "USERS", Sequence: 1}, nil
+		})
+
+	err := eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	assert.NoError(t, err)
+}
+
+func TestEventEmitter_EmitUserEvent_Duplicate(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockPublisher := NewMockPublisher(mockCtrl)
+	eventEmitter := newEventEmitter(mockPublisher, Config{SubjectPrefix: "accounts"})
+
+	// A redelivered event is discarded by the stream, it is not an error
+	mockPublisher.EXPECT().PublishMsg(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+			assert.Equal(t, "accounts.deleted", msg.Subject)
+			assert.Empty(t, msg.Header.Get(jetstream.ExpectedStreamHeader))
+
+			return &jetstream.PubAck{Stream: "ACCOUNTS", Sequence: 1, Duplicate: true}, nil
+		})
+
+	err := eventEmitter.EmitUserEvent(
+		context.Background(),
+		events.UserEvent{EventId: "event-id", EventType: events.EventTypeDeleted, UserId: "user-id"},
+	)
+	assert.NoError(t, err)
+}
+
+func TestEventEmitter_EmitUserEvent_Error(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockPublisher := NewMockPublisher(mockCtrl)
+	eventEmitter := newEventEmitter(mockPublisher, Config{})
+
+	mockPublisher.EXPECT().PublishMsg(gomock.Any(), gomock.Any()).Return(nil, jetstream.ErrNoStreamResponse)
+
+	err := eventEmitter.EmitUserEvent(
+		context.Background(),
+		events.UserEvent{EventId: "event-id", EventType: events.EventTypeCreated, UserId: "user-id"},
+	)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+	assert.True(t, errors.Is(err, jetstream.ErrNoStreamResponse))
+}
+
+func TestEventEmitter_Subject(t *testing.T) {
+	eventEmitter := newEventEmitter(nil, Config{})
+
+	assert.Equal(t, "users.created", eventEmitter.Subject(events.EventTypeCreated))
+	assert.Equal(t, "users.purged", eventEmitter.Subject(events.EventTypePurged))
+}
+
+func TestNewEventEmitter_Error(t *testing.T) {
+	eventEmitter, err := NewEventEmitter(Config{})
+	assert.Nil(t, eventEmitter)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+
+	// No server is listening
+	eventEmitter, err = NewEventEmitter(Config{URL: "nats://127.0.0.1:1"})
+	assert.Nil(t, eventEmitter)
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+// runTestServer starts an in-process NATS server with JetStream enabled,
+// and returns a JetStream client connected to it
+func runTestServer(t *testing.T) (*server.Server, jetstream.JetStream) {
+	t.Helper()
+
+	natsServer, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go natsServer.Start()
+	require.True(t, natsServer.ReadyForConnections(5*time.Second))
+	t.Cleanup(natsServer.Shutdown)
+
+	conn, err := nats.Connect(natsServer.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+
+	return natsServer, js
+}
+
+// createTestStream creates a stream storing the subjects with the given prefix,
+// discarding duplicate messages within a minute
+func createTestStream(t *testing.T, js jetstream.JetStream, name string, subjectPrefix string) jetstream.Stream {
+	t.Helper()
+
+	stream, err := js.CreateStream(context.Background(), jetstream.StreamConfig{
+		Name:       name,
+		Subjects:   []string{subjectPrefix + ".>"},
+		Duplicates: time.Minute,
+	})
+	require.NoError(t, err)
+
+	return stream
+}
+
+func TestEventEmitter_EmitUserEvent_Server(t *testing.T) {
+	natsServer, js := runTestServer(t)
+	stream := createTestStream(t, js, "ACCOUNTS", "accounts")
+
+	eventEmitter, err := NewEventEmitter(Config{
+		URL:           natsServer.ClientURL(),
+		SubjectPrefix: "accounts",
+		Stream:        "ACCOUNTS",
+		Timeout:       5 * time.Second,
+	})
+	require.NoError(t, err)
+	defer eventEmitter.Close()
+
+	userEvent := events.UserEvent{
+		EventId:   "event-id",
+		Sequence:  1,
+		EventType: events.EventTypeCreated,
+		EventTime: time.Now().UTC(),
+		UserId:    "user-id",
+		Nickname:  "nickname",
+	}
+
+	err = eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	require.NoError(t, err)
+
+	// The event is stored on the subject of its type, with its ID and user ID as headers
+	storedMsg, err := stream.GetLastMsgForSubject(context.Background(), "accounts.created")
+	require.NoError(t, err)
+	assert.Equal(t, "event-id", storedMsg.Header.Get(jetstream.MsgIDHeader))
+	assert.Equal(t, "user-id", storedMsg.Header.Get(HeaderUserId))
+
+	var storedEvent events.UserEvent
+	require.NoError(t, json.Unmarshal(storedMsg.Data, &storedEvent))
+	assert.Equal(t, userEvent, storedEvent)
+}
+
+func TestEventEmitter_EmitUserEvent_ServerDuplicate(t *testing.T) {
+	natsServer, js := runTestServer(t)
+	stream := createTestStream(t, js, "USERS", DefaultSubjectPrefix)
+
+	eventEmitter, err := NewEventEmitter(Config{URL: natsServer.ClientURL(), Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	defer eventEmitter.Close()
+
+	userEvent := events.UserEvent{EventId: "event-id", EventType: events.EventTypeUpdated, UserId: "user-id"}
+
+	// The redelivered event is discarded by the stream within its duplicate window
+	require.NoError(t, eventEmitter.EmitUserEvent(context.Background(), userEvent))
+	require.NoError(t, eventEmitter.EmitUserEvent(context.Background(), userEvent))
+
+	// Another event is stored
+	userEvent.EventId = "other-event-id"
+	require.NoError(t, eventEmitter.EmitUserEvent(context.Background(), userEvent))
+
+	streamInfo, err := stream.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), streamInfo.State.Msgs)
+}
+
+func TestEventEmitter_EmitUserEvent_ServerUnexpectedStream(t *testing.T) {
+	natsServer, js := runTestServer(t)
+	stream := createTestStream(t, js, "USERS", DefaultSubjectPrefix)
+
+	// The user events are stored by another stream than the expected one
+	eventEmitter, err := NewEventEmitter(Config{URL: natsServer.ClientURL(), Stream: "ACCOUNTS", Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	defer eventEmitter.Close()
+
+	err = eventEmitter.EmitUserEvent(
+		context.Background(),
+		events.UserEvent{EventId: "event-id", EventType: events.EventTypeCreated, UserId: "user-id"},
+	)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+
+	streamInfo, err := stream.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), streamInfo.State.Msgs)
+}
+
+func TestEventEmitter_EmitUserEvent_ServerNoStream(t *testing.T) {
+	natsServer, _ := runTestServer(t)
+
+	// No stream stores the user events
+	eventEmitter, err := NewEventEmitter(Config{URL: natsServer.ClientURL(), Timeout: 5 * time.Second})
+	require.NoError(t, err)
+	defer eventEmitter.Close()
+
+	err = eventEmitter.EmitUserEvent(
+		context.Background(),
+		events.UserEvent{EventId: "event-id", EventType: events.EventTypeCreated, UserId: "user-id"},
+	)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+	assert.True(t, errors.Is(err, jetstream.ErrNoStreamResponse))
+}
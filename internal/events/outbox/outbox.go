@@ -0,0 +1,51 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+)
+
+// EventEmitter is a struct that implements the EventEmitter interface storing events in the transactional outbox.
+// Events are stored in the storage transaction carried by the context, if any, and published later by a Relay.
+type EventEmitter struct {
+	// time is a time provider used for timestamping stored events
+	time common.TimeProvider
+	// outboxStorage is the storage of the outbox
+	outboxStorage storage.OutboxStorage
+}
+
+var _ events.EventEmitter = new(EventEmitter)
+
+// EmitUserEvent stores a user event in the outbox, keyed by user ID to be relayed in order per user
+func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserEvent) error {
+	// Marshal user event to JSON
+	userEventBytes, err := json.Marshal(userEvent)
+	if err != nil {
+		logger.Log.Errorf("Failed to marshal user event: %v", err)
+
+		return common.NewError(err, common.ErrTypeInternal)
+	}
+
+	now := e.time.Now().UTC()
+
+	return e.outboxStorage.AddOutboxEvent(ctx, storage.OutboxEvent{
+		ID:            uuid.New().String(),
+		Key:           userEvent.UserId,
+		Payload:       userEventBytes,
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	})
+}
+
+// NewEventEmitter creates a new EventEmitter instance
+func NewEventEmitter(outboxStorage storage.OutboxStorage) *EventEmitter {
+	return &EventEmitter{
+		time:          common.NewTime(),
+		outboxStorage: outboxStorage,
+	}
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+type testSuite struct {
+	mockCtrl          *gomock.Controller
+	mockTimeProvider  *common.MockTimeProvider
+	mockOutboxStorage *storage.MockOutboxStorage
+	mockEventEmitter  *events.MockEventEmitter
+	eventEmitter      *EventEmitter
+	relay             *Relay
+}
+
+func newTestSuite(t *testing.T) *testSuite {
+	mockCtrl := gomock.NewController(t)
+	mockTimeProvider := common.NewMockTimeProvider(mockCtrl)
+	mockOutboxStorage := storage.NewMockOutboxStorage(mockCtrl)
+	mockEventEmitter := events.NewMockEventEmitter(mockCtrl)
+
+	eventEmitter := NewEventEmitter(mockOutboxStorage)
+	eventEmitter.time = mockTimeProvider
+
+	relay := NewRelay(mockOutboxStorage, mockEventEmitter, RelayConfig{
+		Interval:      time.Second,
+		BatchSize:     10,
+		MinBackoff:    time.Second,
+		MaxBackoff:    5 * time.Second,
+		MaxAttempts:   5,
+		LeaseDuration: 3 * time.Second,
+	})
+	relay.owner = "relay-owner"
+	relay.time = mockTimeProvider
+
+	return &testSuite{
+		mockCtrl:          mockCtrl,
+		mockTimeProvider:  mockTimeProvider,
+		mockOutboxStorage: mockOutboxStorage,
+		mockEventEmitter:  mockEventEmitter,
+		eventEmitter:      eventEmitter,
+		relay:             relay,
+	}
+}
+
+func TestEventEmitter_EmitUserEvent_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	userEvent := events.UserEvent{
+		EventType: events.EventTypeCreated,
+		EventTime: now,
+		UserId:    "user-id",
+		Nickname:  "johndoe",
+	}
+	userEventBytes, _ := json.Marshal(userEvent)
+
+	ts.mockOutboxStorage.EXPECT().AddOutboxEvent(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, outboxEvent storage.OutboxEvent) error {
+			assert.NotEmpty(t, outboxEvent.ID)
+			assert.Equal(t, userEvent.UserId, outboxEvent.Key)
+			assert.JSONEq(t, string(userEventBytes), string(outboxEvent.Payload))
+			assert.Equal(t, now, outboxEvent.CreatedAt)
+			assert.Equal(t, now, outboxEvent.NextAttemptAt)
+			assert.Zero(t, outboxEvent.Attempts)
+			assert.Nil(t, outboxEvent.SentAt)
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	assert.NoError(t, err)
+}
+
+func TestEventEmitter_EmitUserEvent_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now())
+
+	ts.mockOutboxStorage.EXPECT().AddOutboxEvent(gomock.Any(), gomock.Any()).
+		Return(common.NewError(nil, common.ErrTypeInternal))
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), events.UserEvent{UserId: "user-id"})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
@@ -0,0 +1,204 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+	"time"
+)
+
+// RelayConfig holds the configuration for the outbox relay
+type RelayConfig struct {
+	// Interval is the period between relay runs
+	Interval time.Duration
+	// BatchSize is the maximum number of pending events read in a single run
+	BatchSize int
+	// MinBackoff is the delay before retrying an event after its first failure, it doubles on every failure
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum delay before retrying a failed event
+	MaxBackoff time.Duration
+	// MaxAttempts is the maximum number of relay attempts of an event,
+	// an event failing every attempt is marked as failed and no longer holds back the following events of its key
+	MaxAttempts int
+	// LeaseDuration is the validity of the outbox lease, renewed before relaying each event.
+	// It must exceed the time to publish an event, whose publication is canceled when the lease expires.
+	LeaseDuration time.Duration
+}
+
+// Relay publishes the events stored in the outbox through an event emitter and marks them as sent.
+// Events with the same key are published in order: an event waiting for a retry holds back the following ones,
+// until it is published or fails every attempt.
+// A lease ensures that a single relay is running among service instances, another one takes over if it stops.
+type Relay struct {
+	// config is the relay configuration
+	config RelayConfig
+	// owner identifies the relay as owner of the outbox lease
+	owner string
+	// time is a time provider used for scheduling retries and leases
+	time common.TimeProvider
+	// outboxStorage is the storage of the outbox
+	outboxStorage storage.OutboxStorage
+	// eventEmitter is the event emitter used to publish the events
+	eventEmitter events.EventEmitter
+}
+
+// Run relays outbox events periodically until the given context is done
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		sent, errRelay := r.Relay(ctx)
+		if errRelay != nil {
+			logger.Log.Errorf("Error relaying outbox events: %v", errRelay)
+		} else if sent > 0 {
+			logger.Log.Debugf("Relayed %d outbox events", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Relay publishes the pending outbox events that are due and returns how many were sent.
+// Nothing is published if the outbox lease is held by another relay, the run stops if the lease is lost.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	_, acquired, errLease := r.renewLease(ctx)
+	if errLease != nil {
+		return 0, errLease
+	}
+	if !acquired {
+		return 0, nil
+	}
+
+	now := r.time.Now().UTC()
+	outboxEvents, errPending := r.outboxStorage.PendingOutboxEvents(ctx, now, r.config.BatchSize)
+	if errPending != nil {
+		return 0, errPending
+	}
+
+	sent := 0
+	// heldKeys are the keys of events not sent in this run, their following events must wait
+	heldKeys := make(map[string]struct{})
+	for _, outboxEvent := range outboxEvents {
+		if _, held := heldKeys[outboxEvent.Key]; held {
+			continue
+		}
+		if outboxEvent.NextAttemptAt.After(now) {
+			heldKeys[outboxEvent.Key] = struct{}{}
+			continue
+		}
+
+		// The lease is renewed before each event, so that it is kept by a slow run,
+		// and the publication is canceled when it expires, so that another relay cannot publish concurrently
+		leaseExpiresAt, acquired, errLease := r.renewLease(ctx)
+		if errLease != nil {
+			return sent, errLease
+		}
+		if !acquired {
+			logger.Log.Warnf("Outbox lease lost, relayed %d events before stopping", sent)
+
+			return sent, nil
+		}
+		publishCtx, cancelPublish := context.WithDeadline(ctx, leaseExpiresAt)
+		errPublish := r.publish(publishCtx, outboxEvent)
+		cancelPublish()
+
+		if errPublish != nil {
+			heldKeys[outboxEvent.Key] = struct{}{}
+			if errMark := r.markFailed(ctx, outboxEvent, errPublish); errMark != nil {
+				return sent, errMark
+			}
+			continue
+		}
+
+		// If marking fails the event is published again, consumers must tolerate duplicates
+		if errMark := r.outboxStorage.MarkOutboxEventSent(ctx, outboxEvent.ID, r.time.Now().UTC()); errMark != nil {
+			return sent, errMark
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// renewLease acquires or renews the outbox lease, it returns when the lease expires and whether it was acquired
+func (r *Relay) renewLease(ctx context.Context) (time.Time, bool, error) {
+	now := r.time.Now().UTC()
+	expiresAt := now.Add(r.config.LeaseDuration)
+
+	acquired, errLease := r.outboxStorage.AcquireOutboxLease(ctx, r.owner, now, expiresAt)
+	if errLease != nil {
+		return time.Time{}, false, errLease
+	}
+
+	return expiresAt, acquired, nil
+}
+
+// markFailed records a failed relay attempt of an outbox event, to be retried after a backoff,
+// or marks the event as failed if it was its last attempt
+func (r *Relay) markFailed(ctx context.Context, outboxEvent storage.OutboxEvent, errPublish error) error {
+	now := r.time.Now().UTC()
+	attempts := outboxEvent.Attempts + 1
+
+	if attempts >= r.config.MaxAttempts {
+		logger.Log.Errorf(
+			"Outbox event %s failed all its %d attempts, it is no longer relayed: %v",
+			outboxEvent.ID,
+			attempts,
+			errPublish,
+		)
+
+		return r.outboxStorage.MarkOutboxEventExhausted(ctx, outboxEvent.ID, now, errPublish.Error())
+	}
+
+	logger.Log.Warnf("Outbox event %s not relayed at attempt %d: %v", outboxEvent.ID, attempts, errPublish)
+
+	return r.outboxStorage.MarkOutboxEventFailed(
+		ctx,
+		outboxEvent.ID,
+		now.Add(r.backoff(outboxEvent.Attempts)),
+		errPublish.Error(),
+	)
+}
+
+// publish decodes an outbox event and emits it
+func (r *Relay) publish(ctx context.Context, outboxEvent storage.OutboxEvent) error {
+	var userEvent events.UserEvent
+	if errDecode := json.Unmarshal(outboxEvent.Payload, &userEvent); errDecode != nil {
+		return common.NewError(errDecode, common.ErrTypeInternal)
+	}
+
+	return r.eventEmitter.EmitUserEvent(ctx, userEvent)
+}
+
+// backoff returns the delay before retrying an event that already failed the given number of times
+func (r *Relay) backoff(attempts int) time.Duration {
+	backoff := r.config.MinBackoff
+	for i := 0; i < attempts && backoff < r.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > r.config.MaxBackoff {
+		backoff = r.config.MaxBackoff
+	}
+
+	return backoff
+}
+
+// NewRelay creates a new Relay instance
+func NewRelay(outboxStorage storage.OutboxStorage, eventEmitter events.EventEmitter, config RelayConfig) *Relay {
+	return &Relay{
+		config:        config,
+		owner:         uuid.New().String(),
+		time:          common.NewTime(),
+		outboxStorage: outboxStorage,
+		eventEmitter:  eventEmitter,
+	}
+}
@@ -0,0 +1,266 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func newTestOutboxEvent(t *testing.T, id string, userEvent events.UserEvent, nextAttemptAt time.Time) storage.OutboxEvent {
+	payload, err := json.Marshal(userEvent)
+	assert.NoError(t, err)
+
+	return storage.OutboxEvent{
+		ID:            id,
+		Key:           userEvent.UserId,
+		Payload:       payload,
+		NextAttemptAt: nextAttemptAt,
+	}
+}
+
+func TestRelay_Relay_LeaseNotAcquired(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(false, nil)
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestRelay_Relay_LeaseError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now)
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(false, common.NewError(nil, common.ErrTypeInternal))
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestRelay_Relay_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	createdEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-1"}
+	updatedEvent := events.UserEvent{EventType: events.EventTypeUpdated, UserId: "user-1"}
+	otherEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-2"}
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(true, nil).Times(4)
+	ts.mockOutboxStorage.EXPECT().PendingOutboxEvents(gomock.Any(), now, 10).Return([]storage.OutboxEvent{
+		newTestOutboxEvent(t, "event-1", createdEvent, now),
+		newTestOutboxEvent(t, "event-2", updatedEvent, now),
+		newTestOutboxEvent(t, "event-3", otherEvent, now),
+	}, nil)
+
+	gomock.InOrder(
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).Return(nil),
+		ts.mockOutboxStorage.EXPECT().MarkOutboxEventSent(gomock.Any(), "event-1", now).Return(nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), updatedEvent).Return(nil),
+		ts.mockOutboxStorage.EXPECT().MarkOutboxEventSent(gomock.Any(), "event-2", now).Return(nil),
+		ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), otherEvent).Return(nil),
+		ts.mockOutboxStorage.EXPECT().MarkOutboxEventSent(gomock.Any(), "event-3", now).Return(nil),
+	)
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, sent)
+}
+
+func TestRelay_Relay_PublishErrorHoldsKey(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	createdEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-1"}
+	updatedEvent := events.UserEvent{EventType: events.EventTypeUpdated, UserId: "user-1"}
+	otherEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-2"}
+
+	failedEvent := newTestOutboxEvent(t, "event-1", createdEvent, now)
+	failedEvent.Attempts = 2
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(true, nil).Times(3)
+	ts.mockOutboxStorage.EXPECT().PendingOutboxEvents(gomock.Any(), now, 10).Return([]storage.OutboxEvent{
+		failedEvent,
+		newTestOutboxEvent(t, "event-2", updatedEvent, now),
+		newTestOutboxEvent(t, "event-3", otherEvent, now),
+	}, nil)
+
+	// The failed event is retried after a backoff of 4 seconds, the following event of the same user waits
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).
+		Return(common.NewError(nil, common.ErrTypeInternal))
+	ts.mockOutboxStorage.EXPECT().
+		MarkOutboxEventFailed(gomock.Any(), "event-1", now.Add(4*time.Second), gomock.Any()).
+		Return(nil)
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), otherEvent).Return(nil)
+	ts.mockOutboxStorage.EXPECT().MarkOutboxEventSent(gomock.Any(), "event-3", now).Return(nil)
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestRelay_Relay_NotDueHoldsKey(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	createdEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-1"}
+	updatedEvent := events.UserEvent{EventType: events.EventTypeUpdated, UserId: "user-1"}
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(true, nil)
+	ts.mockOutboxStorage.EXPECT().PendingOutboxEvents(gomock.Any(), now, 10).Return([]storage.OutboxEvent{
+		newTestOutboxEvent(t, "event-1", createdEvent, now.Add(time.Minute)),
+		newTestOutboxEvent(t, "event-2", updatedEvent, now),
+	}, nil)
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestRelay_Relay_MarkSentError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	createdEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-1"}
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(true, nil).Times(2)
+	ts.mockOutboxStorage.EXPECT().PendingOutboxEvents(gomock.Any(), now, 10).Return([]storage.OutboxEvent{
+		newTestOutboxEvent(t, "event-1", createdEvent, now),
+	}, nil)
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).Return(nil)
+	ts.mockOutboxStorage.EXPECT().MarkOutboxEventSent(gomock.Any(), "event-1", now).
+		Return(common.NewError(nil, common.ErrTypeInternal))
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestRelay_Relay_AttemptsExhausted(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	createdEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-1"}
+
+	exhaustedEvent := newTestOutboxEvent(t, "event-1", createdEvent, now)
+	exhaustedEvent.Attempts = 4
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(true, nil).Times(2)
+	ts.mockOutboxStorage.EXPECT().PendingOutboxEvents(gomock.Any(), now, 10).Return([]storage.OutboxEvent{
+		exhaustedEvent,
+	}, nil)
+
+	// The event fails its last attempt, it is marked as failed instead of being retried
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).
+		Return(common.NewError(nil, common.ErrTypeInternal))
+	ts.mockOutboxStorage.EXPECT().MarkOutboxEventExhausted(gomock.Any(), "event-1", now, gomock.Any()).Return(nil)
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestRelay_Relay_LeaseLost(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	createdEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-1"}
+	otherEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-2"}
+
+	// The lease is taken over by another relay after the first event, the run stops
+	gomock.InOrder(
+		ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+			Return(true, nil).Times(2),
+		ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+			Return(false, nil),
+	)
+	ts.mockOutboxStorage.EXPECT().PendingOutboxEvents(gomock.Any(), now, 10).Return([]storage.OutboxEvent{
+		newTestOutboxEvent(t, "event-1", createdEvent, now),
+		newTestOutboxEvent(t, "event-2", otherEvent, now),
+	}, nil)
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).Return(nil)
+	ts.mockOutboxStorage.EXPECT().MarkOutboxEventSent(gomock.Any(), "event-1", now).Return(nil)
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestRelay_Relay_PublishUntilLeaseExpires(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Now().UTC()
+	ts.mockTimeProvider.EXPECT().Now().Return(now).AnyTimes()
+
+	createdEvent := events.UserEvent{EventType: events.EventTypeCreated, UserId: "user-1"}
+
+	ts.mockOutboxStorage.EXPECT().AcquireOutboxLease(gomock.Any(), "relay-owner", now, now.Add(3*time.Second)).
+		Return(true, nil).Times(2)
+	ts.mockOutboxStorage.EXPECT().PendingOutboxEvents(gomock.Any(), now, 10).Return([]storage.OutboxEvent{
+		newTestOutboxEvent(t, "event-1", createdEvent, now),
+	}, nil)
+	// The publication is bounded by the lease expiration
+	ts.mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), createdEvent).
+		DoAndReturn(func(ctx context.Context, _ events.UserEvent) error {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.Equal(t, now.Add(3*time.Second), deadline)
+
+			return nil
+		})
+	ts.mockOutboxStorage.EXPECT().MarkOutboxEventSent(gomock.Any(), "event-1", now).Return(nil)
+
+	sent, err := ts.relay.Relay(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	relay := NewRelay(nil, nil, RelayConfig{MinBackoff: time.Second, MaxBackoff: 5 * time.Second})
+
+	assert.Equal(t, time.Second, relay.backoff(0))
+	assert.Equal(t, 2*time.Second, relay.backoff(1))
+	assert.Equal(t, 4*time.Second, relay.backoff(2))
+	assert.Equal(t, 5*time.Second, relay.backoff(3))
+	assert.Equal(t, 5*time.Second, relay.backoff(100))
+}
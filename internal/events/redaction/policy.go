@@ -0,0 +1,114 @@
+package redaction
+
+import (
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"slices"
+	"strings"
+)
+
+// Action is the redaction applied to a field of the user events
+type Action string
+
+// ActionPass emits the field as it is
+const ActionPass Action = "pass"
+
+// ActionDrop emits the field empty
+const ActionDrop Action = "drop"
+
+// ActionHash emits the hex encoded HMAC-SHA256 of the field, keyed by the redaction key,
+// a pseudonymous identifier that is the same for the same value
+const ActionHash Action = "hash"
+
+// Field is a personal data field of the user events, named as in the event mask
+type Field string
+
+const FieldFirstName Field = "first_name"
+const FieldLastName Field = "last_name"
+const FieldNickname Field = "nickname"
+const FieldEmail Field = "email"
+const FieldCountry Field = "country"
+
+// fields are the personal data fields of the user events
+var fields = []Field{FieldFirstName, FieldLastName, FieldNickname, FieldEmail, FieldCountry}
+
+// allFields is the field of a policy specification applying an action to the fields not listed
+const allFields = "*"
+
+// Policy is the redaction policy of the user events, the action of each personal data field.
+// Fields without an action are passed.
+type Policy map[Field]Action
+
+// action returns the action of a field
+func (p Policy) action(field Field) Action {
+	if action, ok := p[field]; ok {
+		return action
+	}
+
+	return ActionPass
+}
+
+// hashes tells if the policy hashes any field
+func (p Policy) hashes() bool {
+	for _, action := range p {
+		if action == ActionHash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParsePolicy parses a comma separated list of field actions, a field followed by a colon and its action,
+// like email:hash,first_name:drop,last_name:drop.
+// The * field applies its action to the fields not listed, like *:drop,email:hash.
+func ParsePolicy(value string) (Policy, error) {
+	policy := make(Policy)
+	var defaultAction Action
+	for _, fieldValue := range strings.Split(value, ",") {
+		field, action, found := strings.Cut(strings.TrimSpace(fieldValue), ":")
+		if !found {
+			return nil, policyError(fmt.Errorf("invalid field action: %q", fieldValue))
+		}
+
+		switch Action(action) {
+		case ActionPass, ActionDrop, ActionHash:
+		default:
+			return nil, policyError(fmt.Errorf("field %s has unknown action: %s", field, action))
+		}
+
+		if field == allFields {
+			if defaultAction != "" {
+				return nil, policyError(fmt.Errorf("duplicate field: %s", field))
+			}
+			defaultAction = Action(action)
+
+			continue
+		}
+		if !slices.Contains(fields, Field(field)) {
+			return nil, policyError(fmt.Errorf("unknown field: %s", field))
+		}
+		if _, duplicate := policy[Field(field)]; duplicate {
+			return nil, policyError(fmt.Errorf("duplicate field: %s", field))
+		}
+		policy[Field(field)] = Action(action)
+	}
+
+	if defaultAction != "" {
+		for _, field := range fields {
+			if _, ok := policy[field]; !ok {
+				policy[field] = defaultAction
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// policyError logs and returns an error of the redaction policy
+func policyError(err error) error {
+	logger.Log.Error(err)
+
+	return common.NewError(err, common.ErrTypeInvalidArgument)
+}
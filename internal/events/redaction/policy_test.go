@@ -0,0 +1,55 @@
+package redaction
+
+import (
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("email:hash, first_name:drop,last_name:drop,country:pass")
+	require.NoError(t, err)
+	assert.Equal(t, Policy{
+		FieldEmail:     ActionHash,
+		FieldFirstName: ActionDrop,
+		FieldLastName:  ActionDrop,
+		FieldCountry:   ActionPass,
+	}, policy)
+	assert.Equal(t, ActionPass, policy.action(FieldNickname))
+
+	// The * field applies to the fields not listed
+	policy, err = ParsePolicy("*:drop,email:hash")
+	require.NoError(t, err)
+	assert.Equal(t, Policy{
+		FieldEmail:     ActionHash,
+		FieldFirstName: ActionDrop,
+		FieldLastName:  ActionDrop,
+		FieldNickname:  ActionDrop,
+		FieldCountry:   ActionDrop,
+	}, policy)
+}
+
+func TestParsePolicy_Error(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "Empty", value: ""},
+		{name: "Missing action", value: "email"},
+		{name: "Unknown action", value: "email:encrypt"},
+		{name: "Unknown field", value: "password:drop"},
+		{name: "Duplicate field", value: "email:drop,email:hash"},
+		{name: "Duplicate all fields", value: "*:drop,*:hash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy(tt.value)
+			assert.Nil(t, policy)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+		})
+	}
+}
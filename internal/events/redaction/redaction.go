@@ -0,0 +1,98 @@
+package redaction
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"maps"
+)
+
+// EventEmitter is a struct that implements the EventEmitter interface redacting the personal data of user events
+// according to a redaction policy, before emitting them with another event emitter.
+// The values before an update of the previous map are redacted like the fields they belong to.
+type EventEmitter struct {
+	// eventEmitter is the event emitter of the redacted user events
+	eventEmitter events.EventEmitter
+	// policy is the redaction policy of the user events
+	policy Policy
+	// key is the key of the HMAC of the hashed fields
+	key []byte
+}
+
+var _ events.EventEmitter = new(EventEmitter)
+
+// EmitUserEvent emits the redacted user event
+func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserEvent) error {
+	return e.eventEmitter.EmitUserEvent(ctx, e.Redact(userEvent))
+}
+
+// Redact returns a copy of the user event with its personal data redacted according to the policy
+func (e *EventEmitter) Redact(userEvent events.UserEvent) events.UserEvent {
+	userEvent.FirstName = e.redact(FieldFirstName, userEvent.FirstName)
+	userEvent.LastName = e.redact(FieldLastName, userEvent.LastName)
+	userEvent.Nickname = e.redact(FieldNickname, userEvent.Nickname)
+	userEvent.Email = e.redact(FieldEmail, userEvent.Email)
+	userEvent.Country = e.redact(FieldCountry, userEvent.Country)
+
+	// The previous map is shared with the original event, which may be emitted concurrently elsewhere
+	if len(userEvent.Previous) > 0 {
+		previous := maps.Clone(userEvent.Previous)
+		for field, value := range previous {
+			if e.policy.action(Field(field)) == ActionDrop {
+				delete(previous, field)
+
+				continue
+			}
+			previous[field] = e.redact(Field(field), value)
+		}
+		userEvent.Previous = previous
+	}
+
+	return userEvent
+}
+
+// redact returns the value of a field redacted according to the policy, empty values are left empty
+func (e *EventEmitter) redact(field Field, value string) string {
+	if value == "" {
+		return value
+	}
+
+	switch e.policy.action(field) {
+	case ActionDrop:
+		return ""
+	case ActionHash:
+		mac := hmac.New(sha256.New, e.key)
+		mac.Write([]byte(value))
+
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	return value
+}
+
+// NewEventEmitter creates a new EventEmitter instance, the key is required if the policy hashes any field
+func NewEventEmitter(eventEmitter events.EventEmitter, policy Policy, key []byte) (*EventEmitter, error) {
+	if eventEmitter == nil {
+		err := errors.New("event emitter is nil")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+	if policy.hashes() && len(key) == 0 {
+		err := errors.New("redaction key is required to hash fields")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	return &EventEmitter{
+		eventEmitter: eventEmitter,
+		policy:       policy,
+		key:          key,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package redaction
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("test-key")
+
+// testHash returns the expected hash of a value with the test key
+func testHash(value string) string {
+	mac := hmac.New(sha256.New, testKey)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestUserEvent() events.UserEvent {
+	return events.UserEvent{
+		EventId:   "event-id",
+		Sequence:  2,
+		EventType: events.EventTypeUpdated,
+		EventTime: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		UserId:    "123",
+		FirstName: "John",
+		LastName:  "Doe",
+		Nickname:  "johndoe",
+		Email:     "john.doe@example.com",
+		Country:   "uk",
+		EventMask: []string{"email", "last_name", "country"},
+		Previous: map[string]string{
+			"email":     "john@doe.it",
+			"last_name": "Smith",
+			"country":   "",
+		},
+	}
+}
+
+func TestEventEmitter_EmitUserEvent(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockEventEmitter := events.NewMockEventEmitter(mockCtrl)
+	eventEmitter, err := NewEventEmitter(
+		mockEventEmitter,
+		Policy{FieldEmail: ActionHash, FieldFirstName: ActionDrop, FieldLastName: ActionDrop, FieldNickname: ActionHash},
+		testKey,
+	)
+	require.NoError(t, err)
+
+	userEvent := newTestUserEvent()
+	expected := newTestUserEvent()
+	expected.FirstName = ""
+	expected.LastName = ""
+	expected.Nickname = testHash("johndoe")
+	expected.Email = testHash("john.doe@example.com")
+	// Dropped fields are removed from the previous map, empty values are left empty
+	expected.Previous = map[string]string{
+		"email":   testHash("john@doe.it"),
+		"country": "",
+	}
+
+	mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), expected).Return(nil)
+
+	assert.NoError(t, eventEmitter.EmitUserEvent(context.Background(), userEvent))
+
+	// The original event is not changed
+	assert.Equal(t, newTestUserEvent(), userEvent)
+}
+
+func TestEventEmitter_EmitUserEvent_Error(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockEventEmitter := events.NewMockEventEmitter(mockCtrl)
+	eventEmitter, err := NewEventEmitter(mockEventEmitter, Policy{FieldEmail: ActionDrop}, nil)
+	require.NoError(t, err)
+
+	errEmit := errors.New("emit error")
+	mockEventEmitter.EXPECT().EmitUserEvent(gomock.Any(), gomock.Any()).Return(errEmit)
+
+	assert.ErrorIs(t, eventEmitter.EmitUserEvent(context.Background(), newTestUserEvent()), errEmit)
+}
+
+func TestEventEmitter_Redact_PassThrough(t *testing.T) {
+	eventEmitter, err := NewEventEmitter(events.NopEventEmitter{}, Policy{}, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, newTestUserEvent(), eventEmitter.Redact(newTestUserEvent()))
+}
+
+func TestEventEmitter_Redact_Pseudonymous(t *testing.T) {
+	eventEmitter, err := NewEventEmitter(events.NopEventEmitter{}, Policy{FieldEmail: ActionHash}, testKey)
+	require.NoError(t, err)
+	otherKeyEmitter, err := NewEventEmitter(events.NopEventEmitter{}, Policy{FieldEmail: ActionHash}, []byte("other-key"))
+	require.NoError(t, err)
+
+	// The same value has the same hash, which depends on the key
+	first := eventEmitter.Redact(newTestUserEvent())
+	second := eventEmitter.Redact(newTestUserEvent())
+	assert.Equal(t, first.Email, second.Email)
+	assert.NotEqual(t, first.Email, otherKeyEmitter.Redact(newTestUserEvent()).Email)
+}
+
+func TestNewEventEmitter_Error(t *testing.T) {
+	eventEmitter, err := NewEventEmitter(nil, Policy{}, nil)
+	assert.Error(t, err)
+	assert.Nil(t, eventEmitter)
+
+	// Hashing requires a key
+	eventEmitter, err = NewEventEmitter(events.NopEventEmitter{}, Policy{FieldEmail: ActionHash}, nil)
+	assert.Error(t, err)
+	assert.Nil(t, eventEmitter)
+}
@@ -0,0 +1,244 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentType is the content type of the schema registry API
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// Error codes of the schema registry API
+const (
+	ErrorCodeSubjectNotFound = 40401
+	ErrorCodeSchemaNotFound  = 40403
+)
+
+// SchemaType is the type of a schema
+type SchemaType string
+
+const SchemaTypeProtobuf SchemaType = "PROTOBUF"
+
+// Schema represents a schema of a subject
+type Schema struct {
+	Type   SchemaType
+	Schema string
+}
+
+// Config holds the configuration for the schema registry client
+type Config struct {
+	// URL is the base URL of the schema registry
+	URL string
+	// Username and Password are the basic authentication credentials, if any
+	Username string
+	Password string
+	// Timeout is the timeout of the schema registry requests
+	Timeout time.Duration
+}
+
+// Error is an error returned by the schema registry API
+type Error struct {
+	StatusCode int    `json:"-"`
+	ErrorCode  int    `json:"error_code"`
+	Message    string `json:"message"`
+}
+
+// Error returns the error code and message of the schema registry error
+func (e *Error) Error() string {
+	return fmt.Sprintf("schema registry error %d: %s", e.ErrorCode, e.Message)
+}
+
+// cacheKey identifies a schema of a subject in the cache
+type cacheKey struct {
+	subject string
+	schema  Schema
+}
+
+// schemaRequest is the request body of the schema registration and lookup
+type schemaRequest struct {
+	Schema     string     `json:"schema"`
+	SchemaType SchemaType `json:"schemaType,omitempty"`
+}
+
+// Client is a client of a Confluent compatible schema registry.
+// Schema IDs are cached, since a registered schema never changes its ID.
+type Client struct {
+	// config is the schema registry client configuration
+	config Config
+	// httpClient is the HTTP client used for the schema registry requests
+	httpClient *http.Client
+	// mu guards the cache of schema IDs
+	mu sync.RWMutex
+	// ids is the cache of schema IDs by subject and schema
+	ids map[cacheKey]int
+}
+
+// RegisterSchema registers a schema under a subject, if not registered yet, and returns its ID
+func (c *Client) RegisterSchema(ctx context.Context, subject string, schema Schema) (int, error) {
+	if id, ok := c.cachedID(subject, schema); ok {
+		return id, nil
+	}
+
+	var response struct {
+		ID int `json:"id"`
+	}
+	errRegister := c.do(ctx, "/subjects/"+url.PathEscape(subject)+"/versions", newSchemaRequest(schema), &response)
+	if errRegister != nil {
+		logger.Log.Errorf("Error registering schema of subject %s: %v", subject, errRegister)
+
+		return 0, errRegister
+	}
+	c.cacheID(subject, schema, response.ID)
+
+	return response.ID, nil
+}
+
+// LookupSchema returns the ID of a schema registered under a subject, it fails with a not found error if not registered
+func (c *Client) LookupSchema(ctx context.Context, subject string, schema Schema) (int, error) {
+	if id, ok := c.cachedID(subject, schema); ok {
+		return id, nil
+	}
+
+	var response struct {
+		ID int `json:"id"`
+	}
+	errLookup := c.do(ctx, "/subjects/"+url.PathEscape(subject), newSchemaRequest(schema), &response)
+	if errLookup != nil {
+		return 0, errLookup
+	}
+	c.cacheID(subject, schema, response.ID)
+
+	return response.ID, nil
+}
+
+// CheckCompatibility returns whether a schema is compatible with the latest schema of a subject,
+// according to the compatibility level of the subject. A schema is compatible with a subject without schemas.
+func (c *Client) CheckCompatibility(ctx context.Context, subject string, schema Schema) (bool, error) {
+	var response struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	errCheck := c.do(
+		ctx,
+		"/compatibility/subjects/"+url.PathEscape(subject)+"/versions/latest",
+		newSchemaRequest(schema),
+		&response,
+	)
+	if errCheck != nil {
+		var errRegistry *Error
+		if errors.As(errCheck, &errRegistry) && errRegistry.ErrorCode == ErrorCodeSubjectNotFound {
+			return true, nil
+		}
+		logger.Log.Errorf("Error checking schema compatibility of subject %s: %v", subject, errCheck)
+
+		return false, errCheck
+	}
+
+	return response.IsCompatible, nil
+}
+
+// do posts a request to the schema registry and decodes its response
+func (c *Client) do(ctx context.Context, path string, body interface{}, response interface{}) error {
+	requestBody, errMarshal := json.Marshal(body)
+	if errMarshal != nil {
+		return common.NewError(errMarshal, common.ErrTypeInternal)
+	}
+
+	requestCtx, cancelRequest := context.WithTimeout(ctx, c.config.Timeout)
+	defer cancelRequest()
+
+	request, errRequest := http.NewRequestWithContext(
+		requestCtx,
+		http.MethodPost,
+		strings.TrimSuffix(c.config.URL, "/")+path,
+		bytes.NewReader(requestBody),
+	)
+	if errRequest != nil {
+		return common.NewError(errRequest, common.ErrTypeInternal)
+	}
+	request.Header.Set("Content-Type", contentType)
+	request.Header.Set("Accept", contentType)
+	if c.config.Username != "" {
+		request.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+
+	httpResponse, errDo := c.httpClient.Do(request)
+	if errDo != nil {
+		return common.NewError(errDo, common.ErrTypeInternal)
+	}
+	defer func() {
+		_ = httpResponse.Body.Close()
+	}()
+
+	if httpResponse.StatusCode >= http.StatusMultipleChoices {
+		errRegistry := &Error{StatusCode: httpResponse.StatusCode}
+		if errDecode := json.NewDecoder(httpResponse.Body).Decode(errRegistry); errDecode != nil {
+			errRegistry.Message = http.StatusText(httpResponse.StatusCode)
+		}
+		if httpResponse.StatusCode == http.StatusNotFound {
+			return common.NewError(errRegistry, common.ErrTypeNotFound)
+		}
+
+		return common.NewError(errRegistry, common.ErrTypeInternal)
+	}
+
+	if errDecode := json.NewDecoder(httpResponse.Body).Decode(response); errDecode != nil {
+		return common.NewError(errDecode, common.ErrTypeInternal)
+	}
+
+	return nil
+}
+
+// cachedID returns the cached ID of a schema of a subject
+func (c *Client) cachedID(subject string, schema Schema) (int, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	id, ok := c.ids[cacheKey{subject: subject, schema: schema}]
+
+	return id, ok
+}
+
+// cacheID caches the ID of a schema of a subject
+func (c *Client) cacheID(subject string, schema Schema, id int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ids[cacheKey{subject: subject, schema: schema}] = id
+}
+
+// newSchemaRequest returns the request body of a schema
+func newSchemaRequest(schema Schema) schemaRequest {
+	return schemaRequest{
+		Schema:     schema.Schema,
+		SchemaType: schema.Type,
+	}
+}
+
+// NewClient creates a new schema registry Client instance
+func NewClient(config Config) (*Client, error) {
+	if config.URL == "" {
+		err := errors.New("schema registry URL is empty")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &Client{
+		config:     config,
+		httpClient: &http.Client{},
+		ids:        make(map[cacheKey]int),
+	}, nil
+}
@@ -0,0 +1,188 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry is an in-memory Confluent compatible schema registry
+type fakeRegistry struct {
+	mu sync.Mutex
+	// subjects holds the schemas registered under each subject, in version order
+	subjects map[string][]string
+	// ids holds the IDs of the registered schemas
+	ids map[string]int
+	// compatible is the result of compatibility checks
+	compatible bool
+	// requests is the number of requests served
+	requests int
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		subjects:   make(map[string][]string),
+		ids:        make(map[string]int),
+		compatible: true,
+	}
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	if username, password, ok := r.BasicAuth(); ok && (username != "user" || password != "password") {
+		f.writeError(w, http.StatusUnauthorized, 40101, "unauthorized")
+		return
+	}
+
+	var request schemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.SchemaType != SchemaTypeProtobuf {
+		f.writeError(w, http.StatusUnprocessableEntity, 42201, "invalid schema")
+		return
+	}
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(path) == 3 && path[0] == "subjects" && path[2] == "versions":
+		id, ok := f.ids[request.Schema]
+		if !ok {
+			id = len(f.ids) + 1
+			f.ids[request.Schema] = id
+		}
+		f.subjects[path[1]] = append(f.subjects[path[1]], request.Schema)
+		f.writeResponse(w, map[string]int{"id": id})
+	case len(path) == 2 && path[0] == "subjects":
+		schemas, ok := f.subjects[path[1]]
+		if !ok {
+			f.writeError(w, http.StatusNotFound, ErrorCodeSubjectNotFound, "subject not found")
+			return
+		}
+		for _, schema := range schemas {
+			if schema == request.Schema {
+				f.writeResponse(w, map[string]interface{}{"subject": path[1], "id": f.ids[schema]})
+				return
+			}
+		}
+		f.writeError(w, http.StatusNotFound, ErrorCodeSchemaNotFound, "schema not found")
+	case len(path) == 5 && path[0] == "compatibility":
+		if _, ok := f.subjects[path[2]]; !ok {
+			f.writeError(w, http.StatusNotFound, ErrorCodeSubjectNotFound, "subject not found")
+			return
+		}
+		f.writeResponse(w, map[string]bool{"is_compatible": f.compatible})
+	default:
+		f.writeError(w, http.StatusNotFound, 404, "not found")
+	}
+}
+
+func (f *fakeRegistry) writeResponse(w http.ResponseWriter, response interface{}) {
+	w.Header().Set("Content-Type", contentType)
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeRegistry) writeError(w http.ResponseWriter, statusCode int, errorCode int, message string) {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(Error{ErrorCode: errorCode, Message: message})
+}
+
+func newTestClient(t *testing.T, config Config) (*Client, *fakeRegistry) {
+	registry := newFakeRegistry()
+	server := httptest.NewServer(registry)
+	t.Cleanup(server.Close)
+
+	config.URL = server.URL
+	client, err := NewClient(config)
+	require.NoError(t, err)
+
+	return client, registry
+}
+
+var testSchema = Schema{Type: SchemaTypeProtobuf, Schema: `syntax = "proto3"; message Test {}`}
+
+func TestClient_RegisterSchema_Success(t *testing.T) {
+	client, registry := newTestClient(t, Config{})
+
+	id, err := client.RegisterSchema(context.Background(), "users-value", testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+
+	// The schema ID is cached
+	id, err = client.RegisterSchema(context.Background(), "users-value", testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, 1, registry.requests)
+
+	// A registered schema is found under its subject
+	otherClient, err := NewClient(Config{URL: client.config.URL})
+	require.NoError(t, err)
+	id, err = otherClient.LookupSchema(context.Background(), "users-value", testSchema)
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+}
+
+func TestClient_LookupSchema_NotFound(t *testing.T) {
+	client, _ := newTestClient(t, Config{})
+
+	_, err := client.LookupSchema(context.Background(), "users-value", testSchema)
+	var errCommon common.Error
+	require.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+	var errRegistry *Error
+	require.ErrorAs(t, err, &errRegistry)
+	assert.Equal(t, ErrorCodeSubjectNotFound, errRegistry.ErrorCode)
+}
+
+func TestClient_CheckCompatibility(t *testing.T) {
+	client, registry := newTestClient(t, Config{})
+
+	// A schema is compatible with a subject without schemas
+	compatible, err := client.CheckCompatibility(context.Background(), "users-value", testSchema)
+	require.NoError(t, err)
+	assert.True(t, compatible)
+
+	_, err = client.RegisterSchema(context.Background(), "users-value", testSchema)
+	require.NoError(t, err)
+
+	compatible, err = client.CheckCompatibility(context.Background(), "users-value", testSchema)
+	require.NoError(t, err)
+	assert.True(t, compatible)
+
+	registry.compatible = false
+	compatible, err = client.CheckCompatibility(context.Background(), "users-value", testSchema)
+	require.NoError(t, err)
+	assert.False(t, compatible)
+}
+
+func TestClient_BasicAuth(t *testing.T) {
+	client, _ := newTestClient(t, Config{Username: "user", Password: "wrong"})
+
+	_, err := client.RegisterSchema(context.Background(), "users-value", testSchema)
+	var errCommon common.Error
+	require.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+	var errRegistry *Error
+	require.ErrorAs(t, err, &errRegistry)
+	assert.Equal(t, http.StatusUnauthorized, errRegistry.StatusCode)
+
+	client, _ = newTestClient(t, Config{Username: "user", Password: "password"})
+
+	_, err = client.RegisterSchema(context.Background(), "users-value", testSchema)
+	assert.NoError(t, err)
+}
+
+func TestNewClient_ConfigError(t *testing.T) {
+	_, err := NewClient(Config{})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
@@ -0,0 +1,335 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/google/uuid"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// HeaderEventId is the header holding the ID of the delivered event, for deduplication by the receivers
+const HeaderEventId = "X-Users-Event-Id"
+
+// HeaderEventType is the header holding the type of the delivered event
+const HeaderEventType = "X-Users-Event-Type"
+
+// HeaderTimestamp is the header holding the Unix time in seconds of the delivery attempt, it is part of the signature
+const HeaderTimestamp = "X-Users-Timestamp"
+
+// HeaderSignature is the header holding the signature of the delivery attempt
+const HeaderSignature = "X-Users-Signature"
+
+// signaturePrefix prefixes the hex encoded signature in its header
+const signaturePrefix = "sha256="
+
+// Config holds the configuration for the webhook event emitter
+type Config struct {
+	// Timeout is the timeout of a delivery attempt
+	Timeout time.Duration
+	// MaxAttempts is the number of delivery attempts of an event to an endpoint before the delivery fails
+	MaxAttempts int
+	// MinBackoff is the delay before retrying a delivery after its first failure, it doubles on every failure
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum delay before retrying a failed delivery
+	MaxBackoff time.Duration
+	// AllowHTTP allows posting events to plain HTTP URLs, only HTTPS URLs are posted to otherwise
+	AllowHTTP bool
+	// AllowPrivateNetworks allows posting events to loopback, private and link local addresses,
+	// only public addresses are connected to otherwise
+	AllowPrivateNetworks bool
+}
+
+// errHTTPNotAllowed is the error of a delivery to a plain HTTP URL, when not allowed
+var errHTTPNotAllowed = errors.New("webhook URL is not an HTTPS URL")
+
+// errAddressNotAllowed is the error of a delivery to a non public address, when not allowed
+var errAddressNotAllowed = errors.New("webhook address is not a public address")
+
+// nonPublicPrefixes are the address ranges, besides the private, loopback, link local, multicast and unspecified ones,
+// that are not publicly routable
+var nonPublicPrefixes = []netip.Prefix{
+	// This network
+	netip.MustParsePrefix("0.0.0.0/8"),
+	// Shared address space of carrier grade NATs
+	netip.MustParsePrefix("100.64.0.0/10"),
+	// Protocol assignments
+	netip.MustParsePrefix("192.0.0.0/24"),
+	// Benchmarking
+	netip.MustParsePrefix("198.18.0.0/15"),
+	// Reserved and broadcast
+	netip.MustParsePrefix("240.0.0.0/4"),
+	// IPv4/IPv6 translation, which may reach IPv4 private addresses
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+// EventEmitter is a struct that implements the EventEmitter interface posting events to the subscribed webhooks.
+// Events are delivered concurrently to the endpoints, each delivery is retried with exponential backoff
+// and its outcome recorded in the delivery log.
+type EventEmitter struct {
+	// config is the webhook event emitter configuration
+	config Config
+	// webhookStorage is the storage of the webhook subscriptions and of the delivery log
+	webhookStorage storage.WebhookStorage
+	// client is the HTTP client posting the events
+	client *http.Client
+	// time is the time provider signing the deliveries
+	time common.TimeProvider
+}
+
+var _ events.EventEmitter = new(EventEmitter)
+
+// EmitUserEvent delivers a user event to the webhooks subscribed to its type.
+// Failed deliveries are recorded in the delivery log and do not fail the emission,
+// which only fails if the subscriptions cannot be loaded.
+func (e *EventEmitter) EmitUserEvent(ctx context.Context, userEvent events.UserEvent) error {
+	subscriptions, errList := e.webhookStorage.ListWebhookSubscriptions(ctx, string(userEvent.EventType))
+	if errList != nil {
+		return errList
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	payload, errMarshal := json.Marshal(userEvent)
+	if errMarshal != nil {
+		logger.Log.Errorf("Error marshalling user event: %v", errMarshal)
+
+		return common.NewError(errMarshal, common.ErrTypeInternal)
+	}
+
+	var deliveries sync.WaitGroup
+	for _, subscription := range subscriptions {
+		deliveries.Add(1)
+		go func() {
+			defer deliveries.Done()
+			e.deliver(ctx, subscription, userEvent, payload)
+		}()
+	}
+	deliveries.Wait()
+
+	return nil
+}
+
+// deliver posts a user event to a webhook endpoint, retrying with backoff, and records the delivery outcome
+func (e *EventEmitter) deliver(
+	ctx context.Context,
+	subscription storage.WebhookSubscription,
+	userEvent events.UserEvent,
+	payload []byte,
+) {
+	delivery := storage.WebhookDelivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: subscription.ID,
+		EventID:        userEvent.EventId,
+		EventType:      string(userEvent.EventType),
+		UserID:         userEvent.UserId,
+		URL:            subscription.URL,
+	}
+
+	var errPost error
+	for attempt := 1; ; attempt++ {
+		delivery.Attempts = attempt
+		var retryable bool
+		delivery.StatusCode, retryable, errPost = e.post(ctx, subscription, userEvent, payload)
+		if errPost == nil || !retryable || attempt >= e.config.MaxAttempts {
+			break
+		}
+		logger.Log.Warnf(
+			"User event %s not delivered to webhook %s at attempt %d: %v",
+			userEvent.EventId,
+			subscription.ID,
+			attempt,
+			errPost,
+		)
+		if !wait(ctx, e.backoff(attempt)) {
+			break
+		}
+	}
+
+	delivery.Succeeded = errPost == nil
+	if errPost != nil {
+		delivery.Error = errPost.Error()
+		logger.Log.Errorf("User event %s not delivered to webhook %s: %v", userEvent.EventId, subscription.ID, errPost)
+	}
+	delivery.DeliveredAt = e.time.Now()
+
+	// The delivery is recorded even if the emission is cancelled meanwhile
+	if errAdd := e.webhookStorage.AddWebhookDelivery(context.WithoutCancel(ctx), delivery); errAdd != nil {
+		logger.Log.Errorf("Webhook delivery %s not recorded: %v", delivery.ID, errAdd)
+	}
+}
+
+// post makes a signed delivery attempt of a user event to a webhook endpoint.
+// It returns the response status code, if any, and whether a failed attempt can be retried.
+func (e *EventEmitter) post(
+	ctx context.Context,
+	subscription storage.WebhookSubscription,
+	userEvent events.UserEvent,
+	payload []byte,
+) (int, bool, error) {
+	postCtx, cancelPost := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancelPost()
+
+	request, errRequest := http.NewRequestWithContext(postCtx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if errRequest != nil {
+		return 0, false, errRequest
+	}
+	if request.URL.Scheme != "https" && !e.config.AllowHTTP {
+		return 0, false, errHTTPNotAllowed
+	}
+
+	timestamp := e.time.Now().Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderEventId, userEvent.EventId)
+	request.Header.Set(HeaderEventType, string(userEvent.EventType))
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, payload))
+
+	response, errDo := e.client.Do(request)
+	if errDo != nil {
+		// Network errors and timeouts are retried, unless the emission is cancelled or the address is not allowed
+		return 0, ctx.Err() == nil && !errors.Is(errDo, errAddressNotAllowed), errDo
+	}
+	defer func() {
+		_, _ = io.Copy(io.Discard, response.Body)
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response.StatusCode, false, nil
+	}
+
+	// Client errors are not retried, apart from timeouts and rate limiting
+	retryable := response.StatusCode >= 500 ||
+		response.StatusCode == http.StatusRequestTimeout ||
+		response.StatusCode == http.StatusTooManyRequests
+
+	return response.StatusCode, retryable, fmt.Errorf("unexpected status code %d", response.StatusCode)
+}
+
+// backoff returns the delay before retrying a delivery after the given failed attempt
+func (e *EventEmitter) backoff(attempt int) time.Duration {
+	backoff := e.config.MinBackoff
+	for i := 1; i < attempt && backoff < e.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > e.config.MaxBackoff {
+		backoff = e.config.MaxBackoff
+	}
+
+	return backoff
+}
+
+// wait waits for the given delay, it returns false if the context is done before
+func wait(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// checkPublicAddress fails if the address being connected to is not a public address.
+// It is checked once resolved, right before connecting, so that host names resolving to internal addresses are rejected.
+func checkPublicAddress(_ string, address string, _ syscall.RawConn) error {
+	host, _, errSplit := net.SplitHostPort(address)
+	if errSplit != nil {
+		return errSplit
+	}
+	addr, errParse := netip.ParseAddr(host)
+	if errParse != nil {
+		return errParse
+	}
+	if !isPublicAddress(addr) {
+		return fmt.Errorf("%w: %s", errAddressNotAllowed, addr)
+	}
+
+	return nil
+}
+
+// isPublicAddress returns whether the given address is publicly routable
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// newHTTPClient returns the HTTP client posting the events.
+// Redirects are not followed, so that an endpoint cannot redirect the deliveries elsewhere,
+// and connections are made directly, only to public addresses unless private networks are allowed.
+func newHTTPClient(config Config) *http.Client {
+	dialer := &net.Dialer{Timeout: config.Timeout, KeepAlive: 30 * time.Second}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = checkPublicAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// Sign returns the signature of a delivery, as set in its signature header.
+// It is the hex encoded HMAC-SHA256, keyed by the subscription secret, of the timestamp and the payload joined by a dot.
+// Receivers verify it and reject stale timestamps to prevent replays.
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewEventEmitter creates a new EventEmitter instance
+func NewEventEmitter(webhookStorage storage.WebhookStorage, config Config) (*EventEmitter, error) {
+	if config.Timeout <= 0 || config.MaxAttempts <= 0 {
+		err := errors.New("timeout and max attempts must be positive")
+		logger.Log.Error(err)
+
+		return nil, common.NewError(err, common.ErrTypeInternal)
+	}
+
+	return &EventEmitter{
+		config:         config,
+		webhookStorage: webhookStorage,
+		client:         newHTTPClient(config),
+		time:           common.NewTime(),
+	}, nil
+}
@@ -0,0 +1,357 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/events"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testSuite struct {
+	mockCtrl           *gomock.Controller
+	mockWebhookStorage *storage.MockWebhookStorage
+	mockTimeProvider   *common.MockTimeProvider
+	eventEmitter       *EventEmitter
+}
+
+// newTestSuite returns a test suite whose emitter posts to the local test servers
+func newTestSuite(t *testing.T) *testSuite {
+	return newTestSuiteWithConfig(t, Config{
+		Timeout:              time.Second,
+		MaxAttempts:          3,
+		MinBackoff:           time.Millisecond,
+		MaxBackoff:           5 * time.Millisecond,
+		AllowHTTP:            true,
+		AllowPrivateNetworks: true,
+	})
+}
+
+func newTestSuiteWithConfig(t *testing.T, config Config) *testSuite {
+	mockCtrl := gomock.NewController(t)
+	mockWebhookStorage := storage.NewMockWebhookStorage(mockCtrl)
+	mockTimeProvider := common.NewMockTimeProvider(mockCtrl)
+
+	eventEmitter, err := NewEventEmitter(mockWebhookStorage, config)
+	require.NoError(t, err)
+	eventEmitter.time = mockTimeProvider
+
+	return &testSuite{
+		mockCtrl:           mockCtrl,
+		mockWebhookStorage: mockWebhookStorage,
+		mockTimeProvider:   mockTimeProvider,
+		eventEmitter:       eventEmitter,
+	}
+}
+
+func newTestEvent() events.UserEvent {
+	return events.UserEvent{
+		EventId:   "event-id",
+		Sequence:  1,
+		EventType: events.EventTypeCreated,
+		UserId:    "user-id",
+		Nickname:  "nickname",
+	}
+}
+
+func TestEventEmitter_EmitUserEvent_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	now := time.Unix(1700000000, 0)
+	userEvent := newTestEvent()
+
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		payload, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, userEvent.EventId, r.Header.Get(HeaderEventId))
+		assert.Equal(t, string(userEvent.EventType), r.Header.Get(HeaderEventType))
+		assert.Equal(t, strconv.FormatInt(now.Unix(), 10), r.Header.Get(HeaderTimestamp))
+		assert.Equal(t, Sign("secret", now.Unix(), payload), r.Header.Get(HeaderSignature))
+
+		var receivedEvent events.UserEvent
+		require.NoError(t, json.Unmarshal(payload, &receivedEvent))
+		assert.Equal(t, userEvent, receivedEvent)
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	subscription := storage.WebhookSubscription{ID: "webhook-id", URL: server.URL, Secret: "secret"}
+
+	ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "created").
+		Return([]storage.WebhookSubscription{subscription}, nil)
+	ts.mockTimeProvider.EXPECT().Now().Return(now).Times(2)
+	ts.mockWebhookStorage.EXPECT().AddWebhookDelivery(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, delivery storage.WebhookDelivery) error {
+			assert.NotEmpty(t, delivery.ID)
+			assert.Equal(t, subscription.ID, delivery.SubscriptionID)
+			assert.Equal(t, userEvent.EventId, delivery.EventID)
+			assert.Equal(t, userEvent.UserId, delivery.UserID)
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Equal(t, http.StatusNoContent, delivery.StatusCode)
+			assert.True(t, delivery.Succeeded)
+			assert.Empty(t, delivery.Error)
+			assert.Equal(t, now, delivery.DeliveredAt)
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), userEvent)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), received.Load())
+}
+
+func TestEventEmitter_EmitUserEvent_Retry(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	// The endpoint fails the first attempt
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if received.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "created").
+		Return([]storage.WebhookSubscription{{ID: "webhook-id", URL: server.URL, Secret: "secret"}}, nil)
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+	ts.mockWebhookStorage.EXPECT().AddWebhookDelivery(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, delivery storage.WebhookDelivery) error {
+			assert.Equal(t, 2, delivery.Attempts)
+			assert.Equal(t, http.StatusOK, delivery.StatusCode)
+			assert.True(t, delivery.Succeeded)
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), newTestEvent())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), received.Load())
+}
+
+func TestEventEmitter_EmitUserEvent_Failures(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	// Server errors are retried until the attempts are exhausted
+	var unavailableReceived atomic.Int32
+	unavailableServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		unavailableReceived.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer unavailableServer.Close()
+
+	// Client errors are not retried
+	var goneReceived atomic.Int32
+	goneServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		goneReceived.Add(1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer goneServer.Close()
+
+	ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "created").
+		Return([]storage.WebhookSubscription{
+			{ID: "unavailable", URL: unavailableServer.URL, Secret: "secret"},
+			{ID: "gone", URL: goneServer.URL, Secret: "secret"},
+		}, nil)
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+
+	deliveries := make(chan storage.WebhookDelivery, 2)
+	ts.mockWebhookStorage.EXPECT().AddWebhookDelivery(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, delivery storage.WebhookDelivery) error {
+			deliveries <- delivery
+
+			return nil
+		}).Times(2)
+
+	// Failed deliveries do not fail the emission
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), newTestEvent())
+	assert.NoError(t, err)
+	close(deliveries)
+
+	recorded := make(map[string]storage.WebhookDelivery)
+	for delivery := range deliveries {
+		recorded[delivery.SubscriptionID] = delivery
+	}
+	assert.Equal(t, int32(3), unavailableReceived.Load())
+	assert.Equal(t, 3, recorded["unavailable"].Attempts)
+	assert.Equal(t, http.StatusBadGateway, recorded["unavailable"].StatusCode)
+	assert.False(t, recorded["unavailable"].Succeeded)
+	assert.Equal(t, "unexpected status code 502", recorded["unavailable"].Error)
+
+	assert.Equal(t, int32(1), goneReceived.Load())
+	assert.Equal(t, 1, recorded["gone"].Attempts)
+	assert.Equal(t, http.StatusGone, recorded["gone"].StatusCode)
+	assert.False(t, recorded["gone"].Succeeded)
+}
+
+func TestEventEmitter_EmitUserEvent_Redirect(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	// The endpoint redirects the deliveries to another server
+	var targetReceived atomic.Int32
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		targetReceived.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer targetServer.Close()
+	server := httptest.NewServer(http.RedirectHandler(targetServer.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "created").
+		Return([]storage.WebhookSubscription{{ID: "webhook-id", URL: server.URL, Secret: "secret"}}, nil)
+	ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+	ts.mockWebhookStorage.EXPECT().AddWebhookDelivery(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, delivery storage.WebhookDelivery) error {
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Equal(t, http.StatusTemporaryRedirect, delivery.StatusCode)
+			assert.False(t, delivery.Succeeded)
+
+			return nil
+		})
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), newTestEvent())
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), targetReceived.Load())
+}
+
+func TestEventEmitter_EmitUserEvent_NotAllowed(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      Config
+		expectedErr error
+	}{
+		{
+			name:        "Plain HTTP URL",
+			config:      Config{AllowPrivateNetworks: true},
+			expectedErr: errHTTPNotAllowed,
+		},
+		{
+			name:        "Loopback address",
+			config:      Config{AllowHTTP: true},
+			expectedErr: errAddressNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Timeout = time.Second
+			tt.config.MaxAttempts = 3
+			tt.config.MinBackoff = time.Millisecond
+			tt.config.MaxBackoff = 5 * time.Millisecond
+			ts := newTestSuiteWithConfig(t, tt.config)
+			defer ts.mockCtrl.Finish()
+
+			var received atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				received.Add(1)
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "created").
+				Return([]storage.WebhookSubscription{{ID: "webhook-id", URL: server.URL, Secret: "secret"}}, nil)
+			ts.mockTimeProvider.EXPECT().Now().Return(time.Now()).AnyTimes()
+			ts.mockWebhookStorage.EXPECT().AddWebhookDelivery(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, delivery storage.WebhookDelivery) error {
+					// Deliveries that are not allowed are not retried
+					assert.Equal(t, 1, delivery.Attempts)
+					assert.False(t, delivery.Succeeded)
+					assert.Contains(t, delivery.Error, tt.expectedErr.Error())
+
+					return nil
+				})
+
+			err := ts.eventEmitter.EmitUserEvent(context.Background(), newTestEvent())
+			assert.NoError(t, err)
+			assert.Equal(t, int32(0), received.Load())
+		})
+	}
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		address  string
+		expected bool
+	}{
+		{address: "93.184.215.14", expected: true},
+		{address: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", expected: true},
+		{address: "127.0.0.1", expected: false},
+		{address: "::1", expected: false},
+		{address: "10.1.2.3", expected: false},
+		{address: "172.16.0.1", expected: false},
+		{address: "192.168.1.1", expected: false},
+		{address: "169.254.169.254", expected: false},
+		{address: "fe80::1", expected: false},
+		{address: "fd00:ec2::254", expected: false},
+		{address: "0.0.0.0", expected: false},
+		{address: "::", expected: false},
+		{address: "100.64.0.1", expected: false},
+		{address: "224.0.0.1", expected: false},
+		{address: "255.255.255.255", expected: false},
+		{address: "::ffff:127.0.0.1", expected: false},
+		{address: "64:ff9b::a9fe:a9fe", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isPublicAddress(netip.MustParseAddr(tt.address)))
+		})
+	}
+}
+
+func TestEventEmitter_EmitUserEvent_NoSubscriptions(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "created").Return(nil, nil)
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), newTestEvent())
+	assert.NoError(t, err)
+}
+
+func TestEventEmitter_EmitUserEvent_StorageError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockWebhookStorage.EXPECT().ListWebhookSubscriptions(gomock.Any(), "created").
+		Return(nil, common.NewError(nil, common.ErrTypeInternal))
+
+	err := ts.eventEmitter.EmitUserEvent(context.Background(), newTestEvent())
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign("secret", 1700000000, []byte(`{"event_type":"created"}`))
+	assert.Equal(t, "sha256=", signature[:7])
+	assert.Len(t, signature, 7+64)
+
+	// Any change of secret, timestamp or payload changes the signature
+	assert.NotEqual(t, signature, Sign("other", 1700000000, []byte(`{"event_type":"created"}`)))
+	assert.NotEqual(t, signature, Sign("secret", 1700000001, []byte(`{"event_type":"created"}`)))
+	assert.NotEqual(t, signature, Sign("secret", 1700000000, []byte(`{"event_type":"updated"}`)))
+}
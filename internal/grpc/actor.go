@@ -0,0 +1,27 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/common"
+	"google.golang.org/grpc"
+)
+
+// ActorUnaryInterceptor propagates the actor performing the requested changes to the handler context,
+// so that user changes are recorded along with who performed them.
+// The actor is the subject of authenticated callers, or the identity of their client certificate,
+// otherwise no actor is recorded, since the callers are not verified.
+func ActorUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if claims := auth.ClaimsFromContext(ctx); claims != nil {
+		ctx = common.WithActor(ctx, claims.Subject)
+	} else if identity := auth.PeerIdentityFromContext(ctx); identity != "" {
+		ctx = common.WithActor(ctx, identity)
+	}
+
+	return handler(ctx, req)
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func TestActorUnaryInterceptor(t *testing.T) {
+	// actorMetadataKey is the metadata key clients used to declare the actor with
+	const actorMetadataKey = "x-actor"
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "Unverified actor in metadata",
+			ctx:  metadata.NewIncomingContext(context.Background(), metadata.Pairs(actorMetadataKey, "admin")),
+			want: "",
+		},
+		{
+			name: "Authenticated subject",
+			ctx: auth.WithClaims(
+				metadata.NewIncomingContext(context.Background(), metadata.Pairs(actorMetadataKey, "admin")),
+				&auth.Claims{Subject: "user-123"},
+			),
+			want: "user-123",
+		},
+		{
+			name: "Client certificate identity",
+			ctx: auth.WithPeerIdentity(
+				metadata.NewIncomingContext(context.Background(), metadata.Pairs(actorMetadataKey, "admin")),
+				"spiffe://example.org/billing",
+			),
+			want: "spiffe://example.org/billing",
+		},
+		{
+			name: "No metadata",
+			ctx:  context.Background(),
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			_, err := ActorUnaryInterceptor(tt.ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				got = common.ActorFromContext(ctx)
+
+				return nil, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/common"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// AuthorizationMetadataKey is the request metadata key carrying the bearer token of the caller
+const AuthorizationMetadataKey = "authorization"
+
+// bearerPrefix is the prefix of the bearer token in the authorization metadata
+const bearerPrefix = "bearer "
+
+// errMissingToken is returned when the caller sends no authorization metadata
+var errMissingToken = errors.New("missing bearer token")
+
+// Authenticator authenticates the callers by their bearer token, or by their verified client certificate,
+// the verified claims are put into the handler context for authorization
+type Authenticator struct {
+	// verifier verifies the bearer tokens
+	verifier auth.TokenVerifier
+	// publicMethods are the full method names, or their prefixes, not requiring authentication
+	publicMethods []string
+}
+
+// UnaryInterceptor authenticates the caller of a unary call
+func (a *Authenticator) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	ctx, errAuth := a.authenticate(ctx, info.FullMethod)
+	if errAuth != nil {
+		return nil, errAuth
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authenticates the caller of a streaming call
+func (a *Authenticator) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	ctx, errAuth := a.authenticate(ss.Context(), info.FullMethod)
+	if errAuth != nil {
+		return errAuth
+	}
+
+	return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate verifies the bearer token of the caller and returns a copy of the context carrying its claims,
+// public methods are called without authentication
+func (a *Authenticator) authenticate(ctx context.Context, fullMethod string) (context.Context, error) {
+	for _, publicMethod := range a.publicMethods {
+		if strings.HasPrefix(fullMethod, publicMethod) {
+			return ctx, nil
+		}
+	}
+
+	token, errToken := bearerToken(ctx)
+	if errors.Is(errToken, errMissingToken) && auth.PeerIdentityFromContext(ctx) != "" {
+		// Callers verified by their client certificate are authenticated by it when they send no token
+		return ctx, nil
+	}
+	if errToken != nil {
+		return nil, commonErrorToGRPCError(common.NewError(errToken, common.ErrTypeUnauthenticated))
+	}
+
+	claims, errVerify := a.verifier.Verify(ctx, token)
+	if errVerify != nil {
+		return nil, commonErrorToGRPCError(errVerify)
+	}
+
+	return auth.WithClaims(ctx, claims), nil
+}
+
+// bearerToken returns the bearer token of the authorization metadata
+func bearerToken(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authorizations := md.Get(AuthorizationMetadataKey)
+	if len(authorizations) == 0 {
+		return "", errMissingToken
+	}
+
+	authorization := authorizations[0]
+	if len(authorization) <= len(bearerPrefix) || !strings.EqualFold(authorization[:len(bearerPrefix)], bearerPrefix) {
+		return "", errors.New("invalid authorization, bearer token expected")
+	}
+
+	return strings.TrimSpace(authorization[len(bearerPrefix):]), nil
+}
+
+// contextServerStream is a server stream with a handler context replaced by the interceptors
+type contextServerStream struct {
+	grpc.ServerStream
+	// ctx is the handler context
+	ctx context.Context
+}
+
+// Context returns the handler context
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// NewAuthenticator creates a new Authenticator verifying the bearer tokens with the given verifier,
+// public methods are full method names, like /users.v1.Users/ListUsers, or their prefixes, like /grpc.health.v1.Health/
+func NewAuthenticator(verifier auth.TokenVerifier, publicMethods ...string) *Authenticator {
+	return &Authenticator{
+		verifier:      verifier,
+		publicMethods: publicMethods,
+	}
+}
@@ -0,0 +1,186 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestAuthenticator_UnaryInterceptor(t *testing.T) {
+	claims := &auth.Claims{Subject: "user-123"}
+
+	tests := []struct {
+		name       string
+		fullMethod string
+		md         metadata.MD
+		identity   string
+		mock       func(verifier *auth.MockTokenVerifier)
+		wantClaims *auth.Claims
+		wantCode   codes.Code
+	}{
+		{
+			name:       "Valid token",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.Pairs(AuthorizationMetadataKey, "Bearer valid-token"),
+			mock: func(verifier *auth.MockTokenVerifier) {
+				verifier.EXPECT().Verify(gomock.Any(), "valid-token").Return(claims, nil)
+			},
+			wantClaims: claims,
+			wantCode:   codes.OK,
+		},
+		{
+			name:       "Lowercase scheme",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.Pairs(AuthorizationMetadataKey, "bearer valid-token"),
+			mock: func(verifier *auth.MockTokenVerifier) {
+				verifier.EXPECT().Verify(gomock.Any(), "valid-token").Return(claims, nil)
+			},
+			wantClaims: claims,
+			wantCode:   codes.OK,
+		},
+		{
+			name:       "Invalid token",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.Pairs(AuthorizationMetadataKey, "Bearer invalid-token"),
+			mock: func(verifier *auth.MockTokenVerifier) {
+				verifier.EXPECT().Verify(gomock.Any(), "invalid-token").
+					Return(nil, common.NewError(errors.New("token is expired"), common.ErrTypeUnauthenticated))
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:       "Missing token",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.MD{},
+			wantCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "Basic scheme",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.Pairs(AuthorizationMetadataKey, "Basic dXNlcjpwYXNz"),
+			wantCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "Empty bearer token",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.Pairs(AuthorizationMetadataKey, "Bearer "),
+			wantCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "Client certificate without token",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.MD{},
+			identity:   "spiffe://example.org/billing",
+			wantCode:   codes.OK,
+		},
+		{
+			name:       "Client certificate with invalid token",
+			fullMethod: "/users.v1.Users/ListUsers",
+			md:         metadata.Pairs(AuthorizationMetadataKey, "Bearer invalid-token"),
+			identity:   "spiffe://example.org/billing",
+			mock: func(verifier *auth.MockTokenVerifier) {
+				verifier.EXPECT().Verify(gomock.Any(), "invalid-token").
+					Return(nil, common.NewError(errors.New("token is expired"), common.ErrTypeUnauthenticated))
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:       "Public method",
+			fullMethod: "/grpc.health.v1.Health/Check",
+			md:         metadata.MD{},
+			wantCode:   codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			verifier := auth.NewMockTokenVerifier(ctrl)
+			if tt.mock != nil {
+				tt.mock(verifier)
+			}
+			authenticator := NewAuthenticator(verifier, "/grpc.health.v1.Health/")
+
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			if tt.identity != "" {
+				ctx = auth.WithPeerIdentity(ctx, tt.identity)
+			}
+
+			called := false
+			var gotClaims *auth.Claims
+			_, err := authenticator.UnaryInterceptor(
+				ctx,
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: tt.fullMethod},
+				func(ctx context.Context, _ interface{}) (interface{}, error) {
+					called = true
+					gotClaims = auth.ClaimsFromContext(ctx)
+
+					return nil, nil
+				},
+			)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantCode == codes.OK, called)
+			assert.Equal(t, tt.wantClaims, gotClaims)
+		})
+	}
+}
+
+// testServerStream is a server stream with a given context
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestAuthenticator_StreamInterceptor(t *testing.T) {
+	claims := &auth.Claims{Subject: "user-123"}
+
+	ctrl := gomock.NewController(t)
+	verifier := auth.NewMockTokenVerifier(ctrl)
+	verifier.EXPECT().Verify(gomock.Any(), "valid-token").Return(claims, nil)
+	authenticator := NewAuthenticator(verifier)
+
+	info := &grpc.StreamServerInfo{FullMethod: "/users.v1.Users/WatchUsers"}
+
+	var gotClaims *auth.Claims
+	err := authenticator.StreamInterceptor(
+		nil,
+		&testServerStream{ctx: metadata.NewIncomingContext(
+			context.Background(),
+			metadata.Pairs(AuthorizationMetadataKey, "Bearer valid-token"),
+		)},
+		info,
+		func(_ interface{}, ss grpc.ServerStream) error {
+			gotClaims = auth.ClaimsFromContext(ss.Context())
+
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, claims, gotClaims)
+
+	err = authenticator.StreamInterceptor(
+		nil,
+		&testServerStream{ctx: context.Background()},
+		info,
+		func(_ interface{}, _ grpc.ServerStream) error {
+			t.Fatal("handler called without authentication")
+
+			return nil
+		},
+	)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
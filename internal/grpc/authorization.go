@@ -0,0 +1,83 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/auth"
+	"google.golang.org/grpc"
+	"strings"
+)
+
+// userResource is implemented by the requests acting on a single user
+type userResource interface {
+	GetUserId() string
+}
+
+// Authorizer authorizes the authenticated callers according to the authorization policy.
+// A caller owns the user resource of a request when the user ID is the subject of the caller.
+type Authorizer struct {
+	// policy is the authorization policy of the methods
+	policy *auth.Policy
+	// publicMethods are the full method names, or their prefixes, not requiring authorization
+	publicMethods []string
+}
+
+// UnaryInterceptor authorizes the caller of a unary call
+func (a *Authorizer) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if errAuthorize := a.authorize(ctx, info.FullMethod, req); errAuthorize != nil {
+		return nil, errAuthorize
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamInterceptor authorizes the caller of a streaming call, the caller owns no resource of a stream
+func (a *Authorizer) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if errAuthorize := a.authorize(ss.Context(), info.FullMethod, nil); errAuthorize != nil {
+		return errAuthorize
+	}
+
+	return handler(srv, ss)
+}
+
+// authorize checks that the caller is granted the method on the resource of the request,
+// public methods are called without authorization
+func (a *Authorizer) authorize(ctx context.Context, fullMethod string, req interface{}) error {
+	for _, publicMethod := range a.publicMethods {
+		if strings.HasPrefix(fullMethod, publicMethod) {
+			return nil
+		}
+	}
+
+	claims := auth.ClaimsFromContext(ctx)
+	identity := auth.PeerIdentityFromContext(ctx)
+
+	owner := false
+	if resource, ok := req.(userResource); ok && claims != nil {
+		owner = resource.GetUserId() != "" && resource.GetUserId() == claims.Subject
+	}
+
+	if errAuthorize := a.policy.Authorize(claims, identity, fullMethod, owner); errAuthorize != nil {
+		return commonErrorToGRPCError(errAuthorize)
+	}
+
+	return nil
+}
+
+// NewAuthorizer creates a new Authorizer enforcing the given policy on the authenticated callers,
+// public methods are full method names or their prefixes, like for the Authenticator
+func NewAuthorizer(policy *auth.Policy, publicMethods ...string) *Authorizer {
+	return &Authorizer{
+		policy:        policy,
+		publicMethods: publicMethods,
+	}
+}
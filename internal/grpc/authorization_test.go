@@ -0,0 +1,223 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/auth"
+	protogrpc "github.com/alenalato/users-service/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+// authorizationTest is a call of a method by a caller, and the expected authorization outcome
+type authorizationTest struct {
+	name     string
+	claims   *auth.Claims
+	req      interface{}
+	wantCode codes.Code
+}
+
+var (
+	adminClaims   = &auth.Claims{Subject: "admin-1", Roles: []string{"admin"}}
+	auditorClaims = &auth.Claims{Subject: "auditor-1", Roles: []string{"auditor"}}
+	readerClaims  = &auth.Claims{Subject: "service-1", Scopes: []string{"users:read"}}
+	userClaims    = &auth.Claims{Subject: "user-1"}
+)
+
+// runAuthorizationTests calls the unary interceptor of an Authorizer enforcing the shipped policy
+func runAuthorizationTests(t *testing.T, fullMethod string, tests []authorizationTest) {
+	policy, err := auth.LoadPolicy("../../config/auth-policy.json")
+	require.NoError(t, err)
+	authorizer := NewAuthorizer(policy, "/grpc.health.v1.Health/")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = auth.WithClaims(ctx, tt.claims)
+			}
+
+			called := false
+			_, err := authorizer.UnaryInterceptor(
+				ctx,
+				tt.req,
+				&grpc.UnaryServerInfo{FullMethod: fullMethod},
+				func(_ context.Context, _ interface{}) (interface{}, error) {
+					called = true
+
+					return nil, nil
+				},
+			)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantCode == codes.OK, called)
+		})
+	}
+}
+
+func TestAuthorizer_CreateUser(t *testing.T) {
+	req := &protogrpc.CreateUserRequest{}
+	runAuthorizationTests(t, "/users.v1.Users/CreateUser", []authorizationTest{
+		{name: "Admin", claims: adminClaims, req: req, wantCode: codes.OK},
+		{name: "Reader", claims: readerClaims, req: req, wantCode: codes.PermissionDenied},
+		{name: "User", claims: userClaims, req: req, wantCode: codes.PermissionDenied},
+		{name: "Not authenticated", req: req, wantCode: codes.PermissionDenied},
+	})
+}
+
+func TestAuthorizer_UpdateUser(t *testing.T) {
+	runAuthorizationTests(t, "/users.v1.Users/UpdateUser", []authorizationTest{
+		{name: "Admin", claims: adminClaims, req: &protogrpc.UpdateUserRequest{UserId: "user-1"}, wantCode: codes.OK},
+		{name: "Own user", claims: userClaims, req: &protogrpc.UpdateUserRequest{UserId: "user-1"}, wantCode: codes.OK},
+		{
+			name:     "Other user",
+			claims:   userClaims,
+			req:      &protogrpc.UpdateUserRequest{UserId: "user-2"},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Empty user ID",
+			claims:   &auth.Claims{},
+			req:      &protogrpc.UpdateUserRequest{},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Reader",
+			claims:   readerClaims,
+			req:      &protogrpc.UpdateUserRequest{UserId: "user-1"},
+			wantCode: codes.PermissionDenied,
+		},
+		{name: "Not authenticated", req: &protogrpc.UpdateUserRequest{UserId: "user-1"}, wantCode: codes.PermissionDenied},
+	})
+}
+
+func TestAuthorizer_DeleteUser(t *testing.T) {
+	runAuthorizationTests(t, "/users.v1.Users/DeleteUser", []authorizationTest{
+		{name: "Admin", claims: adminClaims, req: &protogrpc.DeleteUserRequest{UserId: "user-1"}, wantCode: codes.OK},
+		{
+			name:     "Own user",
+			claims:   userClaims,
+			req:      &protogrpc.DeleteUserRequest{UserId: "user-1"},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "Auditor",
+			claims:   auditorClaims,
+			req:      &protogrpc.DeleteUserRequest{UserId: "user-1"},
+			wantCode: codes.PermissionDenied,
+		},
+		{name: "Not authenticated", req: &protogrpc.DeleteUserRequest{UserId: "user-1"}, wantCode: codes.PermissionDenied},
+	})
+}
+
+func TestAuthorizer_UndeleteUser(t *testing.T) {
+	runAuthorizationTests(t, "/users.v1.Users/UndeleteUser", []authorizationTest{
+		{name: "Admin", claims: adminClaims, req: &protogrpc.UndeleteUserRequest{UserId: "user-1"}, wantCode: codes.OK},
+		{
+			name:     "Own user",
+			claims:   userClaims,
+			req:      &protogrpc.UndeleteUserRequest{UserId: "user-1"},
+			wantCode: codes.PermissionDenied,
+		},
+		{name: "Not authenticated", req: &protogrpc.UndeleteUserRequest{UserId: "user-1"}, wantCode: codes.PermissionDenied},
+	})
+}
+
+func TestAuthorizer_ListUsers(t *testing.T) {
+	req := &protogrpc.ListUsersRequest{}
+	runAuthorizationTests(t, "/users.v1.Users/ListUsers", []authorizationTest{
+		{name: "Admin", claims: adminClaims, req: req, wantCode: codes.OK},
+		{name: "Reader", claims: readerClaims, req: req, wantCode: codes.OK},
+		{name: "User", claims: userClaims, req: req, wantCode: codes.PermissionDenied},
+		{name: "Not authenticated", req: req, wantCode: codes.PermissionDenied},
+	})
+}
+
+func TestAuthorizer_GetUserHistory(t *testing.T) {
+	runAuthorizationTests(t, "/users.v1.Users/GetUserHistory", []authorizationTest{
+		{name: "Admin", claims: adminClaims, req: &protogrpc.GetUserHistoryRequest{UserId: "user-1"}, wantCode: codes.OK},
+		{name: "Auditor", claims: auditorClaims, req: &protogrpc.GetUserHistoryRequest{UserId: "user-1"}, wantCode: codes.OK},
+		{name: "Own user", claims: userClaims, req: &protogrpc.GetUserHistoryRequest{UserId: "user-1"}, wantCode: codes.OK},
+		{
+			name:     "Other user",
+			claims:   userClaims,
+			req:      &protogrpc.GetUserHistoryRequest{UserId: "user-2"},
+			wantCode: codes.PermissionDenied,
+		},
+		{name: "Not authenticated", req: &protogrpc.GetUserHistoryRequest{UserId: "user-1"}, wantCode: codes.PermissionDenied},
+	})
+}
+
+func TestAuthorizer_Webhooks(t *testing.T) {
+	for fullMethod, req := range map[string]interface{}{
+		"/users.v1.Webhooks/RegisterWebhook": &protogrpc.RegisterWebhookRequest{},
+		"/users.v1.Webhooks/DeleteWebhook":   &protogrpc.DeleteWebhookRequest{},
+		"/users.v1.Webhooks/ListWebhooks":    &protogrpc.ListWebhooksRequest{},
+	} {
+		t.Run(fullMethod, func(t *testing.T) {
+			runAuthorizationTests(t, fullMethod, []authorizationTest{
+				{name: "Admin", claims: adminClaims, req: req, wantCode: codes.OK},
+				{name: "User", claims: userClaims, req: req, wantCode: codes.PermissionDenied},
+			})
+		})
+	}
+}
+
+func TestAuthorizer_PublicMethod(t *testing.T) {
+	runAuthorizationTests(t, "/grpc.health.v1.Health/Check", []authorizationTest{
+		{name: "Not authenticated", wantCode: codes.OK},
+	})
+}
+
+func TestAuthorizer_StreamInterceptor(t *testing.T) {
+	policy, err := auth.LoadPolicy("../../config/auth-policy.json")
+	require.NoError(t, err)
+	authorizer := NewAuthorizer(policy)
+
+	tests := []struct {
+		name       string
+		fullMethod string
+		claims     *auth.Claims
+		wantCode   codes.Code
+	}{
+		{
+			name:       "Authenticated caller",
+			fullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+			claims:     userClaims,
+			wantCode:   codes.OK,
+		},
+		{
+			name:       "Not authenticated",
+			fullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:       "Method not in policy",
+			fullMethod: "/users.v1.Users/WatchUsers",
+			claims:     adminClaims,
+			wantCode:   codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.claims != nil {
+				ctx = auth.WithClaims(ctx, tt.claims)
+			}
+
+			err := authorizer.StreamInterceptor(
+				nil,
+				&testServerStream{ctx: ctx},
+				&grpc.StreamServerInfo{FullMethod: tt.fullMethod},
+				func(_ interface{}, _ grpc.ServerStream) error {
+					return nil
+				},
+			)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
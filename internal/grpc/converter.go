@@ -14,7 +14,17 @@ type modelConverter interface {
 	fromGrpcCreateUserRequestToModel(ctx context.Context, req *protogrpc.CreateUserRequest) businesslogic.UserDetails
 	fromGrpcUpdateUserRequestToModel(ctx context.Context, req *protogrpc.UpdateUserRequest) businesslogic.UserUpdate
 	fromGrpcListUsersRequestToModel(ctx context.Context, req *protogrpc.ListUsersRequest) businesslogic.UserFilter
+	fromGrpcUserPreconditionToModel(
+		ctx context.Context,
+		precondition *protogrpc.UserPrecondition,
+	) *businesslogic.UserPrecondition
 	fromModelUserToGrpc(ctx context.Context, user businesslogic.User) *protogrpc.User
+	fromModelUserRevisionToGrpc(ctx context.Context, revision businesslogic.UserRevision) *protogrpc.UserRevision
+	fromGrpcRegisterWebhookRequestToModel(
+		ctx context.Context,
+		req *protogrpc.RegisterWebhookRequest,
+	) businesslogic.WebhookDetails
+	fromModelWebhookToGrpc(ctx context.Context, webhook businesslogic.Webhook) *protogrpc.Webhook
 }
 
 type serverModelConverter struct{}
@@ -69,6 +79,21 @@ func (c *serverModelConverter) fromGrpcListUsersRequestToModel(_ context.Context
 	return userFilter
 }
 
+// fromGrpcUserPreconditionToModel converts a gRPC UserPrecondition to a businesslogic.UserPrecondition
+// A nil precondition is converted to nil
+func (c *serverModelConverter) fromGrpcUserPreconditionToModel(
+	_ context.Context,
+	precondition *protogrpc.UserPrecondition,
+) *businesslogic.UserPrecondition {
+	if precondition == nil {
+		return nil
+	}
+
+	return &businesslogic.UserPrecondition{
+		Version: precondition.GetVersion(),
+	}
+}
+
 // fromModelUserToGrpc converts a businesslogic.User to a gRPC User
 func (c *serverModelConverter) fromModelUserToGrpc(_ context.Context, user businesslogic.User) *protogrpc.User {
 	return &protogrpc.User{
@@ -80,6 +105,53 @@ func (c *serverModelConverter) fromModelUserToGrpc(_ context.Context, user busin
 		Country:   user.Country,
 		CreatedAt: timestamppb.New(user.CreatedAt),
 		UpdatedAt: timestamppb.New(user.UpdatedAt),
+		Version:   user.Version,
+	}
+}
+
+// fromModelUserRevisionToGrpc converts a businesslogic.UserRevision to a gRPC UserRevision
+func (c *serverModelConverter) fromModelUserRevisionToGrpc(
+	_ context.Context,
+	revision businesslogic.UserRevision,
+) *protogrpc.UserRevision {
+	var changes []*protogrpc.UserRevision_FieldChange
+	for _, change := range revision.Changes {
+		changes = append(changes, &protogrpc.UserRevision_FieldChange{
+			Field:  change.Field,
+			Before: change.Before,
+			After:  change.After,
+		})
+	}
+
+	return &protogrpc.UserRevision{
+		Id:        revision.ID,
+		UserId:    revision.UserID,
+		Version:   revision.Version,
+		Operation: revision.Operation,
+		Changes:   changes,
+		Actor:     revision.Actor,
+		ChangedAt: timestamppb.New(revision.ChangedAt),
+	}
+}
+
+// fromGrpcRegisterWebhookRequestToModel converts a gRPC RegisterWebhookRequest to a businesslogic.WebhookDetails
+func (c *serverModelConverter) fromGrpcRegisterWebhookRequestToModel(
+	_ context.Context,
+	req *protogrpc.RegisterWebhookRequest,
+) businesslogic.WebhookDetails {
+	return businesslogic.WebhookDetails{
+		URL:        req.GetUrl(),
+		EventTypes: req.GetEventTypes(),
+	}
+}
+
+// fromModelWebhookToGrpc converts a businesslogic.Webhook to a gRPC Webhook, secrets are not part of it
+func (c *serverModelConverter) fromModelWebhookToGrpc(_ context.Context, webhook businesslogic.Webhook) *protogrpc.Webhook {
+	return &protogrpc.Webhook{
+		Id:         webhook.ID,
+		Url:        webhook.URL,
+		EventTypes: webhook.EventTypes,
+		CreatedAt:  timestamppb.New(webhook.CreatedAt),
 	}
 }
 
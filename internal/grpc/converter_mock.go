@@ -70,6 +70,20 @@ func (mr *MockmodelConverterMockRecorder) fromGrpcListUsersRequestToModel(ctx, r
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromGrpcListUsersRequestToModel", reflect.TypeOf((*MockmodelConverter)(nil).fromGrpcListUsersRequestToModel), ctx, req)
 }
 
+// fromGrpcRegisterWebhookRequestToModel mocks base method.
+func (m *MockmodelConverter) fromGrpcRegisterWebhookRequestToModel(ctx context.Context, req *grpc.RegisterWebhookRequest) businesslogic.WebhookDetails {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "fromGrpcRegisterWebhookRequestToModel", ctx, req)
+	ret0, _ := ret[0].(businesslogic.WebhookDetails)
+	return ret0
+}
+
+// fromGrpcRegisterWebhookRequestToModel indicates an expected call of fromGrpcRegisterWebhookRequestToModel.
+func (mr *MockmodelConverterMockRecorder) fromGrpcRegisterWebhookRequestToModel(ctx, req any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromGrpcRegisterWebhookRequestToModel", reflect.TypeOf((*MockmodelConverter)(nil).fromGrpcRegisterWebhookRequestToModel), ctx, req)
+}
+
 // fromGrpcUpdateUserRequestToModel mocks base method.
 func (m *MockmodelConverter) fromGrpcUpdateUserRequestToModel(ctx context.Context, req *grpc.UpdateUserRequest) businesslogic.UserUpdate {
 	m.ctrl.T.Helper()
@@ -84,6 +98,34 @@ func (mr *MockmodelConverterMockRecorder) fromGrpcUpdateUserRequestToModel(ctx,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromGrpcUpdateUserRequestToModel", reflect.TypeOf((*MockmodelConverter)(nil).fromGrpcUpdateUserRequestToModel), ctx, req)
 }
 
+// fromGrpcUserPreconditionToModel mocks base method.
+func (m *MockmodelConverter) fromGrpcUserPreconditionToModel(ctx context.Context, precondition *grpc.UserPrecondition) *businesslogic.UserPrecondition {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "fromGrpcUserPreconditionToModel", ctx, precondition)
+	ret0, _ := ret[0].(*businesslogic.UserPrecondition)
+	return ret0
+}
+
+// fromGrpcUserPreconditionToModel indicates an expected call of fromGrpcUserPreconditionToModel.
+func (mr *MockmodelConverterMockRecorder) fromGrpcUserPreconditionToModel(ctx, precondition any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromGrpcUserPreconditionToModel", reflect.TypeOf((*MockmodelConverter)(nil).fromGrpcUserPreconditionToModel), ctx, precondition)
+}
+
+// fromModelUserRevisionToGrpc mocks base method.
+func (m *MockmodelConverter) fromModelUserRevisionToGrpc(ctx context.Context, revision businesslogic.UserRevision) *grpc.UserRevision {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "fromModelUserRevisionToGrpc", ctx, revision)
+	ret0, _ := ret[0].(*grpc.UserRevision)
+	return ret0
+}
+
+// fromModelUserRevisionToGrpc indicates an expected call of fromModelUserRevisionToGrpc.
+func (mr *MockmodelConverterMockRecorder) fromModelUserRevisionToGrpc(ctx, revision any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromModelUserRevisionToGrpc", reflect.TypeOf((*MockmodelConverter)(nil).fromModelUserRevisionToGrpc), ctx, revision)
+}
+
 // fromModelUserToGrpc mocks base method.
 func (m *MockmodelConverter) fromModelUserToGrpc(ctx context.Context, user businesslogic.User) *grpc.User {
 	m.ctrl.T.Helper()
@@ -97,3 +139,17 @@ func (mr *MockmodelConverterMockRecorder) fromModelUserToGrpc(ctx, user any) *go
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromModelUserToGrpc", reflect.TypeOf((*MockmodelConverter)(nil).fromModelUserToGrpc), ctx, user)
 }
+
+// fromModelWebhookToGrpc mocks base method.
+func (m *MockmodelConverter) fromModelWebhookToGrpc(ctx context.Context, webhook businesslogic.Webhook) *grpc.Webhook {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "fromModelWebhookToGrpc", ctx, webhook)
+	ret0, _ := ret[0].(*grpc.Webhook)
+	return ret0
+}
+
+// fromModelWebhookToGrpc indicates an expected call of fromModelWebhookToGrpc.
+func (mr *MockmodelConverterMockRecorder) fromModelWebhookToGrpc(ctx, webhook any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "fromModelWebhookToGrpc", reflect.TypeOf((*MockmodelConverter)(nil).fromModelWebhookToGrpc), ctx, webhook)
+}
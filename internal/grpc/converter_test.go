@@ -147,6 +147,34 @@ func TestServerModelConverter_FromGrpcListUsersRequestToModel(t *testing.T) {
 	}
 }
 
+func TestServerModelConverter_FromGrpcUserPreconditionToModel(t *testing.T) {
+	converter := newServerModelConverter()
+
+	tests := []struct {
+		name         string
+		precondition *protogrpc.UserPrecondition
+		want         *businesslogic.UserPrecondition
+	}{
+		{
+			name:         "Precondition with version",
+			precondition: &protogrpc.UserPrecondition{Version: 3},
+			want:         &businesslogic.UserPrecondition{Version: 3},
+		},
+		{
+			name:         "No precondition",
+			precondition: nil,
+			want:         nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := converter.fromGrpcUserPreconditionToModel(context.Background(), tt.precondition)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
 func TestServerModelConverter_FromModelUserToGrpc(t *testing.T) {
 	converter := newServerModelConverter()
 
@@ -166,6 +194,7 @@ func TestServerModelConverter_FromModelUserToGrpc(t *testing.T) {
 				Country:   "AU",
 				CreatedAt: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
 				UpdatedAt: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+				Version:   2,
 			},
 			want: &protogrpc.User{
 				Id:        "123",
@@ -176,6 +205,7 @@ func TestServerModelConverter_FromModelUserToGrpc(t *testing.T) {
 				Country:   "AU",
 				CreatedAt: timestamppb.New(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)),
 				UpdatedAt: timestamppb.New(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)),
+				Version:   2,
 			},
 		},
 	}
@@ -187,3 +217,66 @@ func TestServerModelConverter_FromModelUserToGrpc(t *testing.T) {
 		})
 	}
 }
+
+func TestServerModelConverter_FromModelUserRevisionToGrpc(t *testing.T) {
+	converter := newServerModelConverter()
+
+	revision := businesslogic.UserRevision{
+		ID:        "revision-id",
+		UserID:    "123",
+		Version:   3,
+		Operation: "updated",
+		Changes: []businesslogic.UserFieldChange{
+			{Field: "first_name", Before: "Bob", After: "Robert"},
+		},
+		Actor:     "admin",
+		ChangedAt: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC),
+	}
+
+	want := &protogrpc.UserRevision{
+		Id:        "revision-id",
+		UserId:    "123",
+		Version:   3,
+		Operation: "updated",
+		Changes: []*protogrpc.UserRevision_FieldChange{
+			{Field: "first_name", Before: "Bob", After: "Robert"},
+		},
+		Actor:     "admin",
+		ChangedAt: timestamppb.New(time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)),
+	}
+
+	got := converter.fromModelUserRevisionToGrpc(context.Background(), revision)
+	assert.Equal(t, want, got)
+}
+
+func TestServerModelConverter_FromGrpcRegisterWebhookRequestToModel(t *testing.T) {
+	converter := newServerModelConverter()
+
+	webhookDetails := converter.fromGrpcRegisterWebhookRequestToModel(context.Background(), &protogrpc.RegisterWebhookRequest{
+		Url:        "https://example.com/hook",
+		EventTypes: []string{"deleted", "purged"},
+	})
+	assert.Equal(t, businesslogic.WebhookDetails{
+		URL:        "https://example.com/hook",
+		EventTypes: []string{"deleted", "purged"},
+	}, webhookDetails)
+}
+
+func TestServerModelConverter_FromModelWebhookToGrpc(t *testing.T) {
+	converter := newServerModelConverter()
+	now := time.Now()
+
+	grpcWebhook := converter.fromModelWebhookToGrpc(context.Background(), businesslogic.Webhook{
+		ID:         "webhook-id",
+		URL:        "https://example.com/hook",
+		EventTypes: []string{"created"},
+		Secret:     "secret",
+		CreatedAt:  now,
+	})
+	assert.Equal(t, &protogrpc.Webhook{
+		Id:         "webhook-id",
+		Url:        "https://example.com/hook",
+		EventTypes: []string{"created"},
+		CreatedAt:  timestamppb.New(now),
+	}, grpcWebhook)
+}
@@ -8,10 +8,18 @@ import (
 // DeleteUser handles the DeleteUser request
 func (s *UsersServer) DeleteUser(ctx context.Context, req *grpc.DeleteUserRequest) (*grpc.DeleteUserResponse, error) {
 	// Use business logic layer to delete a user
-	errDelete := s.userManager.DeleteUser(ctx, req.GetUserId())
+	user, errDelete := s.userManager.DeleteUser(
+		ctx,
+		req.GetUserId(),
+		// Convert gRPC precondition to business logic precondition model
+		s.converter.fromGrpcUserPreconditionToModel(ctx, req.GetPrecondition()),
+	)
 	if errDelete != nil {
 		return nil, commonErrorToGRPCError(errDelete)
 	}
 
-	return &grpc.DeleteUserResponse{}, nil
+	return &grpc.DeleteUserResponse{
+		// Convert business logic user back to gRPC response's User
+		User: s.converter.fromModelUserToGrpc(ctx, *user),
+	}, nil
 }
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
 	"github.com/alenalato/users-service/internal/common"
 	protogrpc "github.com/alenalato/users-service/pkg/grpc"
 	"github.com/stretchr/testify/assert"
@@ -9,7 +10,9 @@ import (
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"testing"
+	"time"
 )
 
 func TestUsersServer_DeleteUser_Success(t *testing.T) {
@@ -20,12 +23,41 @@ func TestUsersServer_DeleteUser_Success(t *testing.T) {
 		UserId: "123",
 	}
 
-	ts.mockUserManager.EXPECT().DeleteUser(gomock.Any(), "123").Return(nil)
+	ts.mockConverter.EXPECT().fromGrpcUserPreconditionToModel(gomock.Any(), gomock.Nil()).Return(nil)
+
+	user := &businesslogic.User{
+		ID:        "123",
+		FirstName: "John",
+		LastName:  "Doe",
+		Nickname:  "johndoe",
+		Email:     "john@doe.com",
+		Country:   "USA",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Version:   2,
+	}
+
+	grpcUser := &protogrpc.User{
+		Id:        "123",
+		FirstName: "John",
+		LastName:  "Doe",
+		Nickname:  "johndoe",
+		Email:     "john@doe.com",
+		Country:   "USA",
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+		Version:   2,
+	}
+
+	ts.mockUserManager.EXPECT().DeleteUser(gomock.Any(), "123", gomock.Nil()).Return(user, nil)
+
+	ts.mockConverter.EXPECT().fromModelUserToGrpc(gomock.Any(), *user).Return(grpcUser)
 
 	resp, err := ts.usersServer.DeleteUser(context.Background(), req)
 	assert.NoError(t, err)
-	assert.NotNil(t, resp)
-	assert.IsType(t, &protogrpc.DeleteUserResponse{}, resp)
+	assert.Equal(t, &protogrpc.DeleteUserResponse{
+		User: grpcUser,
+	}, resp)
 }
 
 func TestUsersServer_DeleteUser_ManagerError(t *testing.T) {
@@ -36,8 +68,10 @@ func TestUsersServer_DeleteUser_ManagerError(t *testing.T) {
 		UserId: "123",
 	}
 
-	ts.mockUserManager.EXPECT().DeleteUser(gomock.Any(), "123").
-		Return(common.NewError(nil, common.ErrTypeNotFound))
+	ts.mockConverter.EXPECT().fromGrpcUserPreconditionToModel(gomock.Any(), gomock.Nil()).Return(nil)
+
+	ts.mockUserManager.EXPECT().DeleteUser(gomock.Any(), "123", gomock.Nil()).
+		Return(nil, common.NewError(nil, common.ErrTypeNotFound))
 
 	resp, err := ts.usersServer.DeleteUser(context.Background(), req)
 	assert.Nil(t, resp)
@@ -46,3 +80,29 @@ func TestUsersServer_DeleteUser_ManagerError(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, codes.NotFound, errGrpc.Code())
 }
+
+func TestUsersServer_DeleteUser_PreconditionError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.DeleteUserRequest{
+		UserId: "123",
+		Precondition: &protogrpc.UserPrecondition{
+			Version: 5,
+		},
+	}
+	precondition := &businesslogic.UserPrecondition{Version: 5}
+
+	ts.mockConverter.EXPECT().fromGrpcUserPreconditionToModel(gomock.Any(), req.GetPrecondition()).
+		Return(precondition)
+
+	ts.mockUserManager.EXPECT().DeleteUser(gomock.Any(), "123", precondition).
+		Return(nil, common.NewError(nil, common.ErrTypeFailedPrecondition))
+
+	resp, err := ts.usersServer.DeleteUser(context.Background(), req)
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+	errGrpc, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.FailedPrecondition, errGrpc.Code())
+}
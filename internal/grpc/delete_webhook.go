@@ -0,0 +1,23 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/pkg/grpc"
+)
+
+// DeleteWebhook handles the DeleteWebhook request
+func (s *WebhooksServer) DeleteWebhook(
+	ctx context.Context,
+	req *grpc.DeleteWebhookRequest,
+) (*grpc.DeleteWebhookResponse, error) {
+	// Use business logic layer to delete a webhook
+	webhook, errDelete := s.webhookManager.DeleteWebhook(ctx, req.GetWebhookId())
+	if errDelete != nil {
+		return nil, commonErrorToGRPCError(errDelete)
+	}
+
+	return &grpc.DeleteWebhookResponse{
+		// Convert business logic webhook back to gRPC response's Webhook
+		Webhook: s.converter.fromModelWebhookToGrpc(ctx, *webhook),
+	}, nil
+}
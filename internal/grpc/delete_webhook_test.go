@@ -0,0 +1,47 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	protogrpc "github.com/alenalato/users-service/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestWebhooksServer_DeleteWebhook_Success(t *testing.T) {
+	ts := newWebhooksTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	webhook := &businesslogic.Webhook{ID: "webhook-id", URL: "https://example.com/hook"}
+	grpcWebhook := &protogrpc.Webhook{Id: "webhook-id", Url: "https://example.com/hook"}
+
+	ts.mockWebhookManager.EXPECT().DeleteWebhook(gomock.Any(), "webhook-id").Return(webhook, nil)
+	ts.mockConverter.EXPECT().fromModelWebhookToGrpc(gomock.Any(), *webhook).Return(grpcWebhook)
+
+	resp, err := ts.webhooksServer.DeleteWebhook(
+		context.Background(),
+		&protogrpc.DeleteWebhookRequest{WebhookId: "webhook-id"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, grpcWebhook, resp.GetWebhook())
+}
+
+func TestWebhooksServer_DeleteWebhook_NotFound(t *testing.T) {
+	ts := newWebhooksTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockWebhookManager.EXPECT().DeleteWebhook(gomock.Any(), "webhook-id").
+		Return(nil, common.NewError(nil, common.ErrTypeNotFound))
+
+	resp, err := ts.webhooksServer.DeleteWebhook(
+		context.Background(),
+		&protogrpc.DeleteWebhookRequest{WebhookId: "webhook-id"},
+	)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -19,6 +19,14 @@ func commonErrorToGRPCError(err error) error {
 			return status.New(codes.AlreadyExists, err.Error()).Err()
 		case common.ErrTypeInvalidArgument:
 			return status.New(codes.InvalidArgument, err.Error()).Err()
+		case common.ErrTypeFailedPrecondition:
+			return status.New(codes.FailedPrecondition, err.Error()).Err()
+		case common.ErrTypeUnauthenticated:
+			return status.New(codes.Unauthenticated, err.Error()).Err()
+		case common.ErrTypePermissionDenied:
+			return status.New(codes.PermissionDenied, err.Error()).Err()
+		case common.ErrTypeResourceExhausted:
+			return status.New(codes.ResourceExhausted, err.Error()).Err()
 		case common.ErrTypeInternal:
 			return status.New(codes.Internal, err.Error()).Err()
 		default:
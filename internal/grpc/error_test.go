@@ -30,6 +30,26 @@ func TestCommonErrorToGRPCError(t *testing.T) {
 			inputError:   common.NewError(errors.New("invalid argument"), common.ErrTypeInvalidArgument),
 			expectedCode: codes.InvalidArgument,
 		},
+		{
+			name:         "FailedPrecondition error",
+			inputError:   common.NewError(errors.New("version mismatch"), common.ErrTypeFailedPrecondition),
+			expectedCode: codes.FailedPrecondition,
+		},
+		{
+			name:         "Unauthenticated error",
+			inputError:   common.NewError(errors.New("invalid token"), common.ErrTypeUnauthenticated),
+			expectedCode: codes.Unauthenticated,
+		},
+		{
+			name:         "Permission denied error",
+			inputError:   common.NewError(errors.New("caller is not allowed"), common.ErrTypePermissionDenied),
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "Resource exhausted error",
+			inputError:   common.NewError(errors.New("rate limit exceeded"), common.ErrTypeResourceExhausted),
+			expectedCode: codes.ResourceExhausted,
+		},
 		{
 			name:         "Internal error",
 			inputError:   common.NewError(errors.New("internal error"), common.ErrTypeInternal),
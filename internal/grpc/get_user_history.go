@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/pkg/grpc"
+)
+
+// GetUserHistory handles the GetUserHistory request
+func (s *UsersServer) GetUserHistory(
+	ctx context.Context,
+	req *grpc.GetUserHistoryRequest,
+) (*grpc.GetUserHistoryResponse, error) {
+	// Use business logic layer to get the user history
+	revisions, nextPageToken, errHistory := s.userManager.GetUserHistory(
+		ctx,
+		req.GetUserId(),
+		int(req.GetPageSize()),
+		req.GetPageToken(),
+	)
+	if errHistory != nil {
+		return nil, commonErrorToGRPCError(errHistory)
+	}
+
+	var grpcRevisions []*grpc.UserRevision
+	for _, revision := range revisions {
+		// Convert business logic revision back to gRPC response's UserRevision
+		grpcRevisions = append(grpcRevisions, s.converter.fromModelUserRevisionToGrpc(ctx, revision))
+	}
+
+	return &grpc.GetUserHistoryResponse{
+		Revisions:     grpcRevisions,
+		NextPageToken: nextPageToken,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	protogrpc "github.com/alenalato/users-service/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"testing"
+	"time"
+)
+
+func TestUsersServer_GetUserHistory_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.GetUserHistoryRequest{
+		UserId:    "123",
+		PageSize:  10,
+		PageToken: "token123",
+	}
+
+	revisions := []businesslogic.UserRevision{
+		{
+			ID:        "revision-1",
+			UserID:    "123",
+			Version:   1,
+			Operation: "created",
+			Changes: []businesslogic.UserFieldChange{
+				{Field: "nickname", After: "johndoe"},
+			},
+			Actor:     "admin",
+			ChangedAt: time.Now(),
+		},
+	}
+
+	grpcRevisions := []*protogrpc.UserRevision{
+		{
+			Id:        "revision-1",
+			UserId:    "123",
+			Version:   1,
+			Operation: "created",
+			Changes: []*protogrpc.UserRevision_FieldChange{
+				{Field: "nickname", After: "johndoe"},
+			},
+			Actor:     "admin",
+			ChangedAt: timestamppb.New(revisions[0].ChangedAt),
+		},
+	}
+
+	ts.mockUserManager.EXPECT().GetUserHistory(gomock.Any(), "123", int(req.PageSize), req.PageToken).
+		Return(revisions, "nextToken123", nil)
+
+	ts.mockConverter.EXPECT().fromModelUserRevisionToGrpc(gomock.Any(), revisions[0]).Return(grpcRevisions[0])
+
+	resp, err := ts.usersServer.GetUserHistory(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, &protogrpc.GetUserHistoryResponse{
+		Revisions:     grpcRevisions,
+		NextPageToken: "nextToken123",
+	}, resp)
+}
+
+func TestUsersServer_GetUserHistory_ManagerError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.GetUserHistoryRequest{
+		UserId: "",
+	}
+
+	ts.mockUserManager.EXPECT().GetUserHistory(gomock.Any(), "", 0, "").
+		Return(nil, "", common.NewError(nil, common.ErrTypeInvalidArgument))
+
+	resp, err := ts.usersServer.GetUserHistory(context.Background(), req)
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+	errGrpc, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, errGrpc.Code())
+}
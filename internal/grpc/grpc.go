@@ -21,3 +21,20 @@ func NewUsersServer(userManager businesslogic.UserManager) *UsersServer {
 		userManager: userManager,
 	}
 }
+
+// WebhooksServer is the server API for Webhooks service.
+type WebhooksServer struct {
+	grpc.UnimplementedWebhooksServer
+	// converter is used to convert between gRPC and business logic models
+	converter modelConverter
+	// webhookManager is the business logic layer for webhook subscriptions
+	webhookManager businesslogic.WebhookManager
+}
+
+// NewWebhooksServer creates a new WebhooksServer
+func NewWebhooksServer(webhookManager businesslogic.WebhookManager) *WebhooksServer {
+	return &WebhooksServer{
+		converter:      newServerModelConverter(),
+		webhookManager: webhookManager,
+	}
+}
@@ -28,3 +28,26 @@ func newTestSuite(t *testing.T) *testSuite {
 		usersServer:     usersServer,
 	}
 }
+
+type webhooksTestSuite struct {
+	mockCtrl           *gomock.Controller
+	mockConverter      *MockmodelConverter
+	mockWebhookManager *businesslogic.MockWebhookManager
+	webhooksServer     *WebhooksServer
+}
+
+func newWebhooksTestSuite(t *testing.T) *webhooksTestSuite {
+	mockCtrl := gomock.NewController(t)
+	mockModelConverter := NewMockmodelConverter(mockCtrl)
+	mockWebhookManager := businesslogic.NewMockWebhookManager(mockCtrl)
+
+	webhooksServer := NewWebhooksServer(mockWebhookManager)
+	webhooksServer.converter = mockModelConverter
+
+	return &webhooksTestSuite{
+		mockCtrl:           mockCtrl,
+		mockConverter:      mockModelConverter,
+		mockWebhookManager: mockWebhookManager,
+		webhooksServer:     webhooksServer,
+	}
+}
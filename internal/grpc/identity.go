@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/auth/mtls"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// PeerIdentityUnaryInterceptor propagates the SPIFFE ID of the verified client certificate to the handler context,
+// so that callers are authenticated and authorized by their workload identity
+func PeerIdentityUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	return handler(withPeerIdentity(ctx), req)
+}
+
+// PeerIdentityStreamInterceptor propagates the SPIFFE ID of the verified client certificate to the stream context
+func PeerIdentityStreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	_ *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	return handler(srv, &contextServerStream{ServerStream: ss, ctx: withPeerIdentity(ss.Context())})
+}
+
+// withPeerIdentity returns a copy of the context carrying the SPIFFE ID of the client certificate, if any.
+// Only certificates verified against the client certificate authorities are considered.
+func withPeerIdentity(ctx context.Context) context.Context {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return ctx
+	}
+
+	identity := mtls.SPIFFEID(tlsInfo.State.VerifiedChains[0][0])
+	if identity == "" {
+		return ctx
+	}
+
+	return auth.WithPeerIdentity(ctx, identity)
+}
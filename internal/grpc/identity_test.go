@@ -0,0 +1,66 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"net/url"
+	"testing"
+)
+
+func TestPeerIdentityUnaryInterceptor(t *testing.T) {
+	spiffeCertificate := &x509.Certificate{URIs: []*url.URL{{Scheme: "spiffe", Host: "example.org", Path: "/billing"}}}
+	tlsPeer := func(state tls.ConnectionState) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}})
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{
+			name: "Verified client certificate",
+			ctx:  tlsPeer(tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{spiffeCertificate}}}),
+			want: "spiffe://example.org/billing",
+		},
+		{
+			name: "Client certificate not verified",
+			ctx:  tlsPeer(tls.ConnectionState{PeerCertificates: []*x509.Certificate{spiffeCertificate}}),
+			want: "",
+		},
+		{
+			name: "Client certificate without SPIFFE ID",
+			ctx:  tlsPeer(tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}),
+			want: "",
+		},
+		{
+			name: "Plaintext connection",
+			ctx:  peer.NewContext(context.Background(), &peer.Peer{}),
+			want: "",
+		},
+		{
+			name: "No peer",
+			ctx:  context.Background(),
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			_, err := PeerIdentityUnaryInterceptor(tt.ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				got = auth.PeerIdentityFromContext(ctx)
+
+				return nil, nil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
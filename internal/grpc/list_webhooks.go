@@ -0,0 +1,28 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/pkg/grpc"
+)
+
+// ListWebhooks handles the ListWebhooks request
+func (s *WebhooksServer) ListWebhooks(
+	ctx context.Context,
+	req *grpc.ListWebhooksRequest,
+) (*grpc.ListWebhooksResponse, error) {
+	// Use business logic layer to list webhooks
+	webhooks, errList := s.webhookManager.ListWebhooks(ctx, req.GetEventType())
+	if errList != nil {
+		return nil, commonErrorToGRPCError(errList)
+	}
+
+	var grpcWebhooks []*grpc.Webhook
+	for _, webhook := range webhooks {
+		// Convert business logic webhook back to gRPC response's Webhook
+		grpcWebhooks = append(grpcWebhooks, s.converter.fromModelWebhookToGrpc(ctx, webhook))
+	}
+
+	return &grpc.ListWebhooksResponse{
+		Webhooks: grpcWebhooks,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	protogrpc "github.com/alenalato/users-service/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestWebhooksServer_ListWebhooks_Success(t *testing.T) {
+	ts := newWebhooksTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	webhooks := []businesslogic.Webhook{
+		{ID: "first", URL: "https://example.com/first"},
+		{ID: "second", URL: "https://example.com/second", EventTypes: []string{"deleted"}},
+	}
+	grpcWebhooks := []*protogrpc.Webhook{
+		{Id: "first", Url: "https://example.com/first"},
+		{Id: "second", Url: "https://example.com/second", EventTypes: []string{"deleted"}},
+	}
+
+	ts.mockWebhookManager.EXPECT().ListWebhooks(gomock.Any(), "deleted").Return(webhooks, nil)
+	ts.mockConverter.EXPECT().fromModelWebhookToGrpc(gomock.Any(), webhooks[0]).Return(grpcWebhooks[0])
+	ts.mockConverter.EXPECT().fromModelWebhookToGrpc(gomock.Any(), webhooks[1]).Return(grpcWebhooks[1])
+
+	resp, err := ts.webhooksServer.ListWebhooks(context.Background(), &protogrpc.ListWebhooksRequest{EventType: "deleted"})
+	require.NoError(t, err)
+	assert.Equal(t, grpcWebhooks, resp.GetWebhooks())
+}
+
+func TestWebhooksServer_ListWebhooks_Error(t *testing.T) {
+	ts := newWebhooksTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	ts.mockWebhookManager.EXPECT().ListWebhooks(gomock.Any(), "").
+		Return(nil, common.NewError(nil, common.ErrTypeInternal))
+
+	resp, err := ts.webhooksServer.ListWebhooks(context.Background(), &protogrpc.ListWebhooksRequest{})
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
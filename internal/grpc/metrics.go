@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// Metrics records the calls handled by the gRPC server, by full method name and status code
+type Metrics struct {
+	// handled is the number of handled calls, by method and code
+	handled *prometheus.CounterVec
+	// duration is the duration of the handled calls, by method and code
+	duration *prometheus.HistogramVec
+}
+
+// UnaryInterceptor records the unary calls
+func (m *Metrics) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observe(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// StreamInterceptor records the streaming calls, a stream is handled once it ends
+func (m *Metrics) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	m.observe(info.FullMethod, start, err)
+
+	return err
+}
+
+// observe records a call of the given method ended with the given error
+func (m *Metrics) observe(fullMethod string, start time.Time, err error) {
+	code := status.Code(err).String()
+	m.handled.WithLabelValues(fullMethod, code).Inc()
+	m.duration.WithLabelValues(fullMethod, code).Observe(time.Since(start).Seconds())
+}
+
+// NewMetrics creates the metrics of the gRPC server and registers them with the given registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	metrics := &Metrics{
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "users",
+			Subsystem: "grpc_server",
+			Name:      "handled_total",
+			Help:      "Number of calls handled by the gRPC server, by method and status code.",
+		}, []string{"method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "users",
+			Subsystem: "grpc_server",
+			Name:      "handling_seconds",
+			Help:      "Duration of the calls handled by the gRPC server, by method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+	for _, collector := range []prometheus.Collector{metrics.handled, metrics.duration} {
+		if errRegister := registerer.Register(collector); errRegister != nil {
+			return nil, errRegister
+		}
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,62 @@
+package grpc
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestMetrics_UnaryInterceptor(t *testing.T) {
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	info := &grpc.UnaryServerInfo{FullMethod: "/users.v1.Users/GetUser"}
+
+	resp, err := metrics.UnaryInterceptor(context.Background(), nil, info, func(_ context.Context, _ interface{}) (interface{}, error) {
+		return "response", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "response", resp)
+
+	_, err = metrics.UnaryInterceptor(context.Background(), nil, info, func(_ context.Context, _ interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = metrics.UnaryInterceptor(context.Background(), nil, info, func(_ context.Context, _ interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.handled.WithLabelValues("/users.v1.Users/GetUser", "OK")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.handled.WithLabelValues("/users.v1.Users/GetUser", "NotFound")))
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.duration))
+}
+
+func TestMetrics_StreamInterceptor(t *testing.T) {
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	info := &grpc.StreamServerInfo{FullMethod: "/users.v1.Users/GetUserHistory"}
+
+	err = metrics.StreamInterceptor(nil, &testServerStream{ctx: context.Background()}, info, func(_ interface{}, _ grpc.ServerStream) error {
+		return status.Error(codes.PermissionDenied, "caller is not allowed")
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.handled.WithLabelValues("/users.v1.Users/GetUserHistory", "PermissionDenied")))
+	assert.Equal(t, 1, testutil.CollectAndCount(metrics.duration))
+}
+
+func TestNewMetrics_AlreadyRegistered(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	_, err := NewMetrics(registry)
+	require.NoError(t, err)
+
+	metrics, err := NewMetrics(registry)
+	assert.Error(t, err)
+	assert.Nil(t, metrics)
+}
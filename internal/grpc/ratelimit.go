@@ -0,0 +1,130 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// RetryAfterMetadataKey is the response metadata key telling rate limited clients how many seconds to wait before retrying
+const RetryAfterMetadataKey = "retry-after"
+
+// RateLimiter limits the calls of each client to each method, with a token bucket per client and method.
+// Clients are identified by their authenticated subject, their client certificate identity, or their peer address.
+type RateLimiter struct {
+	// limiter holds the token buckets of the clients
+	limiter ratelimit.Limiter
+	// config holds the limits of the methods
+	config ratelimit.Config
+	// exemptMethods are the full method names, or their prefixes, not rate limited
+	exemptMethods []string
+}
+
+// UnaryInterceptor limits the unary calls of the client
+func (l *RateLimiter) UnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if errLimit := l.limit(ctx, info.FullMethod); errLimit != nil {
+		return nil, errLimit
+	}
+
+	return handler(ctx, req)
+}
+
+// StreamInterceptor limits the streaming calls of the client, a stream takes a single token when it starts
+func (l *RateLimiter) StreamInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if errLimit := l.limit(ss.Context(), info.FullMethod); errLimit != nil {
+		return errLimit
+	}
+
+	return handler(srv, ss)
+}
+
+// limit takes a token from the bucket of the client for the method, and rejects the call if the bucket is empty.
+// Calls are allowed if the limiter fails, so that an unavailable shared backend does not stop the service.
+func (l *RateLimiter) limit(ctx context.Context, fullMethod string) error {
+	for _, exemptMethod := range l.exemptMethods {
+		if strings.HasPrefix(fullMethod, exemptMethod) {
+			return nil
+		}
+	}
+	limit, ok := l.config.MethodLimit(fullMethod)
+	if !ok {
+		return nil
+	}
+
+	client := clientKey(ctx)
+	allowed, retryAfter, errAllow := l.limiter.Allow(ctx, fullMethod+" "+client, limit)
+	if errAllow != nil {
+		logger.Log.Errorf("Error rate limiting client %s, allowing the call: %v", client, errAllow)
+
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+
+	logger.Log.Debugf("Client %s rate limited on method %s", client, fullMethod)
+
+	// Clients retry after the whole seconds needed to refill a token
+	retryAfterSeconds := int64(math.Ceil(retryAfter.Seconds()))
+	if retryAfterSeconds > 0 {
+		if errHeader := grpc.SetHeader(ctx, metadata.Pairs(RetryAfterMetadataKey, strconv.FormatInt(retryAfterSeconds, 10))); errHeader != nil {
+			logger.Log.Warnf("Could not set retry-after metadata: %v", errHeader)
+		}
+	}
+
+	return commonErrorToGRPCError(common.NewError(
+		fmt.Errorf("rate limit of %s exceeded, retry after %ds", fullMethod, retryAfterSeconds),
+		common.ErrTypeResourceExhausted,
+	))
+}
+
+// clientKey returns the key identifying the client of the call in the rate limiter
+func clientKey(ctx context.Context) string {
+	if claims := auth.ClaimsFromContext(ctx); claims != nil {
+		return "subject:" + claims.Subject
+	}
+	if identity := auth.PeerIdentityFromContext(ctx); identity != "" {
+		return "identity:" + identity
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		address := p.Addr.String()
+		// The port changes with each connection of a client, so only the host identifies it
+		if host, _, errSplit := net.SplitHostPort(address); errSplit == nil {
+			address = host
+		}
+
+		return "address:" + address
+	}
+
+	return "unknown"
+}
+
+// NewRateLimiter creates a new RateLimiter limiting the calls with the given limiter and configuration,
+// exempt methods are full method names or their prefixes, like for the Authenticator
+func NewRateLimiter(limiter ratelimit.Limiter, config ratelimit.Config, exemptMethods ...string) *RateLimiter {
+	return &RateLimiter{
+		limiter:       limiter,
+		config:        config,
+		exemptMethods: exemptMethods,
+	}
+}
@@ -0,0 +1,166 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/auth"
+	"github.com/alenalato/users-service/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"testing"
+	"time"
+)
+
+// testTransportStream records the response headers of a call
+type testTransportStream struct {
+	header metadata.MD
+}
+
+func (s *testTransportStream) Method() string {
+	return ""
+}
+
+func (s *testTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+
+	return nil
+}
+
+func (s *testTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *testTransportStream) SetTrailer(_ metadata.MD) error {
+	return nil
+}
+
+func TestRateLimiter_UnaryInterceptor(t *testing.T) {
+	createUserLimit := ratelimit.Limit{Rate: 0.5, Burst: 2}
+	defaultLimit := ratelimit.Limit{Rate: 50, Burst: 100}
+	config := ratelimit.Config{
+		Default: &defaultLimit,
+		Methods: map[string]ratelimit.Limit{"/users.v1.Users/CreateUser": createUserLimit},
+	}
+	peerContext := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 51234},
+	})
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		fullMethod     string
+		mock           func(limiter *ratelimit.MockLimiter)
+		wantCode       codes.Code
+		wantRetryAfter []string
+	}{
+		{
+			name:       "Authenticated subject",
+			ctx:        auth.WithClaims(peerContext, &auth.Claims{Subject: "user-1"}),
+			fullMethod: "/users.v1.Users/CreateUser",
+			mock: func(limiter *ratelimit.MockLimiter) {
+				limiter.EXPECT().Allow(gomock.Any(), "/users.v1.Users/CreateUser subject:user-1", createUserLimit).
+					Return(true, time.Duration(0), nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:       "Client certificate identity",
+			ctx:        auth.WithPeerIdentity(peerContext, "spiffe://example.org/batch"),
+			fullMethod: "/users.v1.Users/CreateUser",
+			mock: func(limiter *ratelimit.MockLimiter) {
+				limiter.EXPECT().Allow(gomock.Any(), "/users.v1.Users/CreateUser identity:spiffe://example.org/batch", createUserLimit).
+					Return(true, time.Duration(0), nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:       "Peer address with default limit",
+			ctx:        peerContext,
+			fullMethod: "/users.v1.Users/ListUsers",
+			mock: func(limiter *ratelimit.MockLimiter) {
+				limiter.EXPECT().Allow(gomock.Any(), "/users.v1.Users/ListUsers address:10.0.0.1", defaultLimit).
+					Return(true, time.Duration(0), nil)
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:       "Rate limited",
+			ctx:        peerContext,
+			fullMethod: "/users.v1.Users/CreateUser",
+			mock: func(limiter *ratelimit.MockLimiter) {
+				limiter.EXPECT().Allow(gomock.Any(), "/users.v1.Users/CreateUser address:10.0.0.1", createUserLimit).
+					Return(false, 1500*time.Millisecond, nil)
+			},
+			wantCode:       codes.ResourceExhausted,
+			wantRetryAfter: []string{"2"},
+		},
+		{
+			name:       "Limiter error",
+			ctx:        peerContext,
+			fullMethod: "/users.v1.Users/CreateUser",
+			mock: func(limiter *ratelimit.MockLimiter) {
+				limiter.EXPECT().Allow(gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(false, time.Duration(0), errors.New("backend unavailable"))
+			},
+			wantCode: codes.OK,
+		},
+		{
+			name:       "Exempt method",
+			ctx:        peerContext,
+			fullMethod: "/grpc.health.v1.Health/Check",
+			wantCode:   codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			limiter := ratelimit.NewMockLimiter(ctrl)
+			if tt.mock != nil {
+				tt.mock(limiter)
+			}
+			rateLimiter := NewRateLimiter(limiter, config, "/grpc.health.v1.Health/")
+
+			stream := &testTransportStream{}
+			called := false
+			_, err := rateLimiter.UnaryInterceptor(
+				grpc.NewContextWithServerTransportStream(tt.ctx, stream),
+				nil,
+				&grpc.UnaryServerInfo{FullMethod: tt.fullMethod},
+				func(_ context.Context, _ interface{}) (interface{}, error) {
+					called = true
+
+					return nil, nil
+				},
+			)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantCode == codes.OK, called)
+			assert.Equal(t, tt.wantRetryAfter, stream.header.Get(RetryAfterMetadataKey))
+		})
+	}
+}
+
+func TestRateLimiter_UnlimitedMethod(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	limiter := ratelimit.NewMockLimiter(ctrl)
+	rateLimiter := NewRateLimiter(limiter, ratelimit.Config{
+		Methods: map[string]ratelimit.Limit{"/users.v1.Users/CreateUser": {Rate: 1, Burst: 1}},
+	})
+
+	err := rateLimiter.StreamInterceptor(
+		nil,
+		&testServerStream{ctx: context.Background()},
+		&grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"},
+		func(_ interface{}, _ grpc.ServerStream) error {
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,29 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/pkg/grpc"
+)
+
+// RegisterWebhook handles the RegisterWebhook request
+func (s *WebhooksServer) RegisterWebhook(
+	ctx context.Context,
+	req *grpc.RegisterWebhookRequest,
+) (*grpc.RegisterWebhookResponse, error) {
+	// Use business logic layer to register a webhook
+	webhook, errRegister := s.webhookManager.RegisterWebhook(
+		ctx,
+		// Convert gRPC request to business logic webhook details model
+		s.converter.fromGrpcRegisterWebhookRequestToModel(ctx, req),
+	)
+	if errRegister != nil {
+		return nil, commonErrorToGRPCError(errRegister)
+	}
+
+	return &grpc.RegisterWebhookResponse{
+		// Convert business logic webhook back to gRPC response's Webhook
+		Webhook: s.converter.fromModelWebhookToGrpc(ctx, *webhook),
+		// The secret is only returned on registration
+		Secret: webhook.Secret,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	protogrpc "github.com/alenalato/users-service/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestWebhooksServer_RegisterWebhook_Success(t *testing.T) {
+	ts := newWebhooksTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.RegisterWebhookRequest{Url: "https://example.com/hook"}
+	webhookDetails := businesslogic.WebhookDetails{URL: "https://example.com/hook"}
+	webhook := &businesslogic.Webhook{ID: "webhook-id", URL: "https://example.com/hook", Secret: "secret"}
+	grpcWebhook := &protogrpc.Webhook{Id: "webhook-id", Url: "https://example.com/hook"}
+
+	ts.mockConverter.EXPECT().fromGrpcRegisterWebhookRequestToModel(gomock.Any(), req).Return(webhookDetails)
+	ts.mockWebhookManager.EXPECT().RegisterWebhook(gomock.Any(), webhookDetails).Return(webhook, nil)
+	ts.mockConverter.EXPECT().fromModelWebhookToGrpc(gomock.Any(), *webhook).Return(grpcWebhook)
+
+	resp, err := ts.webhooksServer.RegisterWebhook(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, grpcWebhook, resp.GetWebhook())
+	assert.Equal(t, "secret", resp.GetSecret())
+}
+
+func TestWebhooksServer_RegisterWebhook_Error(t *testing.T) {
+	ts := newWebhooksTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.RegisterWebhookRequest{Url: "not a url"}
+	webhookDetails := businesslogic.WebhookDetails{URL: "not a url"}
+
+	ts.mockConverter.EXPECT().fromGrpcRegisterWebhookRequestToModel(gomock.Any(), req).Return(webhookDetails)
+	ts.mockWebhookManager.EXPECT().RegisterWebhook(gomock.Any(), webhookDetails).
+		Return(nil, common.NewError(nil, common.ErrTypeInvalidArgument))
+
+	resp, err := ts.webhooksServer.RegisterWebhook(context.Background(), req)
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
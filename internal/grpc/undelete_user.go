@@ -0,0 +1,20 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/pkg/grpc"
+)
+
+// UndeleteUser handles the UndeleteUser request
+func (s *UsersServer) UndeleteUser(ctx context.Context, req *grpc.UndeleteUserRequest) (*grpc.UndeleteUserResponse, error) {
+	// Use business logic layer to restore a soft deleted user
+	user, errUndelete := s.userManager.UndeleteUser(ctx, req.GetUserId())
+	if errUndelete != nil {
+		return nil, commonErrorToGRPCError(errUndelete)
+	}
+
+	return &grpc.UndeleteUserResponse{
+		// Convert business logic user back to gRPC response's User
+		User: s.converter.fromModelUserToGrpc(ctx, *user),
+	}, nil
+}
@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/businesslogic"
+	"github.com/alenalato/users-service/internal/common"
+	protogrpc "github.com/alenalato/users-service/pkg/grpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"testing"
+	"time"
+)
+
+func TestUsersServer_UndeleteUser_Success(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.UndeleteUserRequest{
+		UserId: "123",
+	}
+
+	restoredUser := &businesslogic.User{
+		ID:        "123",
+		Nickname:  "johndoe",
+		Email:     "john@doe.com",
+		CreatedAt: time.Now().Add(-24 * time.Hour),
+		UpdatedAt: time.Now(),
+		Version:   3,
+	}
+
+	grpcUser := &protogrpc.User{
+		Id:        "123",
+		Nickname:  "johndoe",
+		Email:     "john@doe.com",
+		CreatedAt: timestamppb.New(restoredUser.CreatedAt),
+		UpdatedAt: timestamppb.New(restoredUser.UpdatedAt),
+		Version:   3,
+	}
+
+	ts.mockUserManager.EXPECT().UndeleteUser(gomock.Any(), "123").Return(restoredUser, nil)
+
+	ts.mockConverter.EXPECT().fromModelUserToGrpc(gomock.Any(), *restoredUser).Return(grpcUser)
+
+	resp, err := ts.usersServer.UndeleteUser(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, &protogrpc.UndeleteUserResponse{
+		User: grpcUser,
+	}, resp)
+}
+
+func TestUsersServer_UndeleteUser_ManagerError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.UndeleteUserRequest{
+		UserId: "123",
+	}
+
+	ts.mockUserManager.EXPECT().UndeleteUser(gomock.Any(), "123").
+		Return(nil, common.NewError(nil, common.ErrTypeFailedPrecondition))
+
+	resp, err := ts.usersServer.UndeleteUser(context.Background(), req)
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+	errGrpc, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.FailedPrecondition, errGrpc.Code())
+}
@@ -12,6 +12,8 @@ func (s *UsersServer) UpdateUser(ctx context.Context, req *grpc.UpdateUserReques
 		req.GetUserId(),
 		// Convert gRPC request to business logic update model
 		s.converter.fromGrpcUpdateUserRequestToModel(ctx, req),
+		// Convert gRPC precondition to business logic precondition model
+		s.converter.fromGrpcUserPreconditionToModel(ctx, req.GetPrecondition()),
 	)
 	if errUpdate != nil {
 		return nil, commonErrorToGRPCError(errUpdate)
@@ -63,7 +63,9 @@ func TestUsersServer_UpdateUser_Success(t *testing.T) {
 
 	ts.mockConverter.EXPECT().fromGrpcUpdateUserRequestToModel(gomock.Any(), req).Return(userUpdate)
 
-	ts.mockUserManager.EXPECT().UpdateUser(gomock.Any(), "123", userUpdate).Return(updatedUser, nil)
+	ts.mockConverter.EXPECT().fromGrpcUserPreconditionToModel(gomock.Any(), gomock.Nil()).Return(nil)
+
+	ts.mockUserManager.EXPECT().UpdateUser(gomock.Any(), "123", userUpdate, gomock.Nil()).Return(updatedUser, nil)
 
 	ts.mockConverter.EXPECT().fromModelUserToGrpc(gomock.Any(), *updatedUser).Return(grpcUser)
 
@@ -99,7 +101,9 @@ func TestUsersServer_UpdateUser_ManagerError(t *testing.T) {
 
 	ts.mockConverter.EXPECT().fromGrpcUpdateUserRequestToModel(gomock.Any(), req).Return(userUpdate)
 
-	ts.mockUserManager.EXPECT().UpdateUser(gomock.Any(), "123", userUpdate).
+	ts.mockConverter.EXPECT().fromGrpcUserPreconditionToModel(gomock.Any(), gomock.Nil()).Return(nil)
+
+	ts.mockUserManager.EXPECT().UpdateUser(gomock.Any(), "123", userUpdate, gomock.Nil()).
 		Return(nil, common.NewError(nil, common.ErrTypeNotFound))
 
 	resp, err := ts.usersServer.UpdateUser(context.Background(), req)
@@ -109,3 +113,42 @@ func TestUsersServer_UpdateUser_ManagerError(t *testing.T) {
 	require.True(t, ok)
 	assert.Equal(t, codes.NotFound, errGrpc.Code())
 }
+
+func TestUsersServer_UpdateUser_PreconditionError(t *testing.T) {
+	ts := newTestSuite(t)
+	defer ts.mockCtrl.Finish()
+
+	req := &protogrpc.UpdateUserRequest{
+		UserId: "123",
+		Update: &protogrpc.UpdateUserRequest_Update{
+			FirstName: "Jane",
+		},
+		Precondition: &protogrpc.UserPrecondition{
+			Version: 2,
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{
+			Paths: []string{"first_name"},
+		},
+	}
+
+	userUpdate := businesslogic.UserUpdate{
+		FirstName:  "Jane",
+		UpdateMask: []string{"first_name"},
+	}
+	precondition := &businesslogic.UserPrecondition{Version: 2}
+
+	ts.mockConverter.EXPECT().fromGrpcUpdateUserRequestToModel(gomock.Any(), req).Return(userUpdate)
+
+	ts.mockConverter.EXPECT().fromGrpcUserPreconditionToModel(gomock.Any(), req.GetPrecondition()).
+		Return(precondition)
+
+	ts.mockUserManager.EXPECT().UpdateUser(gomock.Any(), "123", userUpdate, precondition).
+		Return(nil, common.NewError(nil, common.ErrTypeFailedPrecondition))
+
+	resp, err := ts.usersServer.UpdateUser(context.Background(), req)
+	assert.Nil(t, resp)
+	assert.Error(t, err)
+	errGrpc, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.FailedPrecondition, errGrpc.Code())
+}
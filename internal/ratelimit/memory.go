@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+// bucket is the token bucket of a key
+type bucket struct {
+	limiter *rate.Limiter
+	limit   Limit
+}
+
+// MemoryLimiter is a Limiter keeping the token buckets in memory, limiting the calls to a single instance
+type MemoryLimiter struct {
+	mu sync.Mutex
+	// time provides the current time
+	time common.TimeProvider
+	// buckets are the token buckets by key
+	buckets map[string]*bucket
+}
+
+var _ Limiter = new(MemoryLimiter)
+
+// Allow takes a token from the bucket of the given key, the bucket is created full on the first call.
+// The bucket is replaced if its limit changed.
+func (l *MemoryLimiter) Allow(_ context.Context, key string, limit Limit) (bool, time.Duration, error) {
+	now := l.time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok || b.limit != limit {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(limit.Rate), limit.Burst), limit: limit}
+		l.buckets[key] = b
+	}
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, 0, nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// The token is given back, the call is rejected instead of waiting for it
+		reservation.CancelAt(now)
+
+		return false, delay, nil
+	}
+
+	return true, 0, nil
+}
+
+// Run removes the full buckets periodically until the given context is done,
+// a full bucket is the same as a new one so that idle clients do not grow the memory
+func (l *MemoryLimiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		l.removeFullBuckets()
+	}
+}
+
+// removeFullBuckets removes the buckets with all their tokens
+func (l *MemoryLimiter) removeFullBuckets() {
+	now := l.time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, b := range l.buckets {
+		if b.limiter.TokensAt(now) >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// NewMemoryLimiter creates a new MemoryLimiter instance
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		time:    common.NewTime(),
+		buckets: make(map[string]*bucket),
+	}
+}
@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeProvider := common.NewMockTimeProvider(ctrl)
+	timeProvider.EXPECT().Now().DoAndReturn(func() time.Time { return now }).AnyTimes()
+
+	limiter := NewMemoryLimiter()
+	limiter.time = timeProvider
+	limit := Limit{Rate: 0.5, Burst: 2}
+	ctx := context.Background()
+
+	// The burst is allowed at once
+	for i := 0; i < 2; i++ {
+		allowed, _, err := limiter.Allow(ctx, "client-1", limit)
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	// The bucket is empty, a token is added every 2 seconds
+	allowed, retryAfter, err := limiter.Allow(ctx, "client-1", limit)
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Equal(t, 2*time.Second, retryAfter)
+
+	// Rejected calls take no token
+	now = now.Add(time.Second)
+	allowed, retryAfter, _ = limiter.Allow(ctx, "client-1", limit)
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// Other clients have their own bucket
+	allowed, _, _ = limiter.Allow(ctx, "client-2", limit)
+	assert.True(t, allowed)
+
+	now = now.Add(time.Second)
+	allowed, _, _ = limiter.Allow(ctx, "client-1", limit)
+	assert.True(t, allowed)
+
+	// A changed limit replaces the bucket
+	allowed, _, _ = limiter.Allow(ctx, "client-1", Limit{Rate: 1, Burst: 1})
+	assert.True(t, allowed)
+	allowed, retryAfter, _ = limiter.Allow(ctx, "client-1", Limit{Rate: 1, Burst: 1})
+	assert.False(t, allowed)
+	assert.Equal(t, time.Second, retryAfter)
+}
+
+func TestMemoryLimiter_removeFullBuckets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeProvider := common.NewMockTimeProvider(ctrl)
+	timeProvider.EXPECT().Now().DoAndReturn(func() time.Time { return now }).AnyTimes()
+
+	limiter := NewMemoryLimiter()
+	limiter.time = timeProvider
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	_, _, _ = limiter.Allow(ctx, "client-1", limit)
+	_, _, _ = limiter.Allow(ctx, "client-1", limit)
+	_, _, _ = limiter.Allow(ctx, "client-2", limit)
+
+	now = now.Add(time.Second)
+	limiter.removeFullBuckets()
+	assert.Len(t, limiter.buckets, 1)
+	assert.Contains(t, limiter.buckets, "client-1")
+
+	now = now.Add(time.Second)
+	limiter.removeFullBuckets()
+	assert.Empty(t, limiter.buckets)
+}
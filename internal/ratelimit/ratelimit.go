@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"os"
+	"strings"
+	"time"
+)
+
+//go:generate mockgen -source=./ratelimit.go -destination=./ratelimit_mock.go -package=ratelimit Limiter
+
+// Limiter is an interface for the token buckets limiting the calls of the clients.
+// It is implemented in memory, for a single instance, or by a shared backend limiting the calls across instances.
+type Limiter interface {
+	// Allow takes a token from the bucket of the given key, with the given limit,
+	// it returns how long to wait before retrying if the bucket is empty
+	Allow(ctx context.Context, key string, limit Limit) (bool, time.Duration, error)
+}
+
+// Limit is the limit of a token bucket
+// Rate is the number of tokens added to the bucket per second, the sustained rate of calls
+// Burst is the capacity of the bucket, the number of calls allowed at once
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Config holds the limits of the calls of each client, by full method name like /users.v1.Users/CreateUser
+// Default is the limit of the methods not listed, they are not limited if not set
+type Config struct {
+	Default *Limit           `json:"default,omitempty"`
+	Methods map[string]Limit `json:"methods"`
+}
+
+// MethodLimit returns the limit of the given method, false if the method is not limited
+func (c Config) MethodLimit(method string) (Limit, bool) {
+	if limit, ok := c.Methods[method]; ok {
+		return limit, true
+	}
+	if c.Default != nil {
+		return *c.Default, true
+	}
+
+	return Limit{}, false
+}
+
+// ParseConfig parses a JSON rate limit configuration, like
+// {"default": {"rate": 50, "burst": 100}, "methods": {"/users.v1.Users/CreateUser": {"rate": 5, "burst": 10}}}
+func ParseConfig(content []byte) (Config, error) {
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.DisallowUnknownFields()
+
+	var config Config
+	if errDecode := decoder.Decode(&config); errDecode != nil {
+		return Config{}, configError(errDecode)
+	}
+	if config.Default != nil {
+		if errLimit := validateLimit(*config.Default); errLimit != nil {
+			return Config{}, configError(fmt.Errorf("default: %w", errLimit))
+		}
+	}
+	for method, limit := range config.Methods {
+		service, name, ok := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+		if !strings.HasPrefix(method, "/") || !ok || service == "" || name == "" || strings.Contains(name, "/") {
+			return Config{}, configError(fmt.Errorf("invalid method %q, expected /<service>/<method>", method))
+		}
+		if errLimit := validateLimit(limit); errLimit != nil {
+			return Config{}, configError(fmt.Errorf("%s: %w", method, errLimit))
+		}
+	}
+
+	return config, nil
+}
+
+// LoadConfig reads and parses the JSON rate limit configuration of the given file
+func LoadConfig(path string) (Config, error) {
+	content, errRead := os.ReadFile(path)
+	if errRead != nil {
+		logger.Log.Errorf("Error reading rate limit configuration %s: %v", path, errRead)
+
+		return Config{}, common.NewError(errRead, common.ErrTypeInternal)
+	}
+
+	return ParseConfig(content)
+}
+
+// validateLimit checks that a limit allows calls
+func validateLimit(limit Limit) error {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return fmt.Errorf("rate and burst must be positive, got rate %v and burst %d", limit.Rate, limit.Burst)
+	}
+
+	return nil
+}
+
+// configError returns the invalid argument error of an invalid rate limit configuration
+func configError(err error) error {
+	err = fmt.Errorf("invalid rate limit configuration: %w", err)
+	logger.Log.Error(err)
+
+	return common.NewError(err, common.ErrTypeInvalidArgument)
+}
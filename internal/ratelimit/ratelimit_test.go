@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfig_MethodLimit(t *testing.T) {
+	config, err := ParseConfig([]byte(`{
+		"default": {"rate": 50, "burst": 100},
+		"methods": {"/users.v1.Users/CreateUser": {"rate": 0.5, "burst": 2}}
+	}`))
+	require.NoError(t, err)
+
+	limit, ok := config.MethodLimit("/users.v1.Users/CreateUser")
+	assert.True(t, ok)
+	assert.Equal(t, Limit{Rate: 0.5, Burst: 2}, limit)
+
+	limit, ok = config.MethodLimit("/users.v1.Users/ListUsers")
+	assert.True(t, ok)
+	assert.Equal(t, Limit{Rate: 50, Burst: 100}, limit)
+
+	config.Default = nil
+	_, ok = config.MethodLimit("/users.v1.Users/ListUsers")
+	assert.False(t, ok)
+}
+
+func TestParseConfig_Error(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "Invalid JSON", content: `{"methods":`},
+		{name: "Unknown field", content: `{"methods": {"/users.v1.Users/CreateUser": {"rate": 1, "bucket": 2}}}`},
+		{name: "Invalid method", content: `{"methods": {"CreateUser": {"rate": 1, "burst": 2}}}`},
+		{name: "Zero rate", content: `{"methods": {"/users.v1.Users/CreateUser": {"rate": 0, "burst": 2}}}`},
+		{name: "Zero burst", content: `{"methods": {"/users.v1.Users/CreateUser": {"rate": 1}}}`},
+		{name: "Invalid default", content: `{"default": {"rate": -1, "burst": 2}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tt.content))
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rate-limits.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"methods": {"/users.v1.Users/CreateUser": {"rate": 1, "burst": 2}}}`), 0o600))
+
+	config, err := LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{"/users.v1.Users/CreateUser": {Rate: 1, Burst: 2}}, config.Methods)
+
+	_, err = LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+}
@@ -13,6 +13,7 @@ type UserDetails struct {
 	Country      string    `bson:"country,omitempty"`
 	CreatedAt    time.Time `bson:"created_at,omitempty"`
 	UpdatedAt    time.Time `bson:"updated_at,omitempty"`
+	Version      int64     `bson:"version,omitempty"`
 }
 
 // User represents a user output from the storage
@@ -25,6 +26,16 @@ type User struct {
 	Country   string    `bson:"country,omitempty"`
 	CreatedAt time.Time `bson:"created_at,omitempty"`
 	UpdatedAt time.Time `bson:"updated_at,omitempty"`
+	// Version is bumped by every change of the user, it numbers the user events
+	Version int64 `bson:"version,omitempty"`
+	// DeletedAt is set only for soft deleted users
+	DeletedAt *time.Time `bson:"deleted_at,omitempty"`
+}
+
+// UserKey is the position of a user in the creation order of the users, its ID orders the users created at the same time
+type UserKey struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
 }
 
 // UserUpdate represents the input details of a user to be updated
@@ -38,6 +49,11 @@ type UserUpdate struct {
 	UpdatedAt *time.Time `bson:"updated_at,omitempty"`
 }
 
+// UserPrecondition represents the expected state of a user for a write operation to be applied
+type UserPrecondition struct {
+	Version int64
+}
+
 // UserFilter represents the input filter criteria for listing users
 // If a field is nil, it will not be used in the filter
 type UserFilter struct {
@@ -45,3 +61,88 @@ type UserFilter struct {
 	LastName  *string `json:"last_name" bson:"last_name,omitempty"`
 	Country   *string `json:"country" bson:"country,omitempty"`
 }
+
+type UserOperation string
+
+const OperationCreated UserOperation = "created"
+const OperationUpdated UserOperation = "updated"
+const OperationDeleted UserOperation = "deleted"
+const OperationRestored UserOperation = "restored"
+const OperationPurged UserOperation = "purged"
+
+// UserRevision represents an immutable record of a change applied to a user
+// Version is the user version resulting from the change
+// Changes holds the values before and after the change of every changed field
+type UserRevision struct {
+	ID        string            `bson:"_id"`
+	UserID    string            `bson:"user_id"`
+	Version   int64             `bson:"version"`
+	Operation UserOperation     `bson:"operation"`
+	Changes   []UserFieldChange `bson:"changes"`
+	Actor     string            `bson:"actor,omitempty"`
+	ChangedAt time.Time         `bson:"changed_at"`
+}
+
+// UserFieldChange represents the change of a user field
+// Before is empty for fields set by the change, After is empty for fields cleared by the change
+type UserFieldChange struct {
+	Field  string `bson:"field"`
+	Before string `bson:"before,omitempty"`
+	After  string `bson:"after,omitempty"`
+}
+
+// OutboxEvent represents an event stored in the transactional outbox, waiting to be relayed
+// Key is the ordering key, events with the same key are relayed in creation order
+// Payload is the encoded event
+// SentAt is set once the event has been relayed
+type OutboxEvent struct {
+	ID            string     `bson:"_id"`
+	Key           string     `bson:"key"`
+	Payload       []byte     `bson:"payload"`
+	CreatedAt     time.Time  `bson:"created_at"`
+	Attempts      int        `bson:"attempts"`
+	NextAttemptAt time.Time  `bson:"next_attempt_at"`
+	LastError     string     `bson:"last_error,omitempty"`
+	SentAt        *time.Time `bson:"sent_at,omitempty"`
+	FailedAt      *time.Time `bson:"failed_at,omitempty"`
+}
+
+// UserChange represents a change applied to a user, as observed on the user storage
+// ID identifies the change, it is the same when the change is observed again
+// User holds the user after the change, it only holds the user ID for purged and hard deleted users
+// ChangedFields holds the user fields changed by an update
+type UserChange struct {
+	ID            string
+	Operation     UserOperation
+	User          User
+	ChangedFields []string
+	ChangedAt     time.Time
+}
+
+// WebhookSubscription represents a webhook endpoint subscribed to user events
+// Secret is the key signing the deliveries to the endpoint
+// EventTypes holds the user event types delivered to the endpoint, all of them if empty
+type WebhookSubscription struct {
+	ID         string    `bson:"_id"`
+	URL        string    `bson:"url"`
+	Secret     string    `bson:"secret"`
+	EventTypes []string  `bson:"event_types"`
+	CreatedAt  time.Time `bson:"created_at"`
+}
+
+// WebhookDelivery represents the outcome of the delivery of a user event to a webhook endpoint
+// StatusCode is the status code of the last attempt response, zero if no response was received
+// Error is the error of the last attempt, empty if the delivery succeeded
+type WebhookDelivery struct {
+	ID             string    `bson:"_id"`
+	SubscriptionID string    `bson:"subscription_id"`
+	EventID        string    `bson:"event_id"`
+	EventType      string    `bson:"event_type"`
+	UserID         string    `bson:"user_id"`
+	URL            string    `bson:"url"`
+	Attempts       int       `bson:"attempts"`
+	StatusCode     int       `bson:"status_code,omitempty"`
+	Succeeded      bool      `bson:"succeeded"`
+	Error          string    `bson:"error,omitempty"`
+	DeliveredAt    time.Time `bson:"delivered_at"`
+}
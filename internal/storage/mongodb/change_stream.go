@@ -0,0 +1,278 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"slices"
+	"time"
+)
+
+const ChangeStreamTokenCollection = "change_stream_token"
+
+// changeStreamToken is the resume token of a change stream watcher, the position of the last handled change.
+// It holds the lease of the watcher too, granting a single owner the right to handle the changes and save the token.
+type changeStreamToken struct {
+	ID        string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expires_at"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// userChangeEvent is a change event of the user collection
+type userChangeEvent struct {
+	// ID is the resume token of the change, its data uniquely identifies the change
+	ID struct {
+		Data string `bson:"_data"`
+	} `bson:"_id"`
+	OperationType string         `bson:"operationType"`
+	ClusterTime   bson.Timestamp `bson:"clusterTime"`
+	DocumentKey   struct {
+		ID string `bson:"_id"`
+	} `bson:"documentKey"`
+	// FullDocument is the current user document, looked up for updates, it is nil if the user no longer exists
+	FullDocument *deletedUser `bson:"fullDocument"`
+	// FullDocumentBeforeChange is the pre-image of the user document, it is nil if pre-images are not recorded
+	FullDocumentBeforeChange *deletedUser `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// userChangeStreamPipeline limits the change stream to the user document changes
+var userChangeStreamPipeline = mongo.Pipeline{
+	{{Key: "$match", Value: bson.D{{Key: "operationType", Value: bson.D{
+		{Key: "$in", Value: bson.A{"insert", "update", "replace", "delete"}},
+	}}}}},
+}
+
+var _ storage.UserChangeStorage = new(MongoDB)
+
+// WatchUserChanges tails the changes of the user collection and handles them in order, until the context is done.
+// The watcher resumes after the last change it handled, its resume token is stored after every handled change
+// as long as the given owner holds the lease of the watcher, otherwise the watch stops.
+// If handling a change fails, the watch stops and the change is handled again when the watcher resumes.
+// Updated users are looked up when the change is observed, so they may already include later changes.
+func (m *MongoDB) WatchUserChanges(
+	ctx context.Context,
+	watcherId string,
+	owner string,
+	handle func(ctx context.Context, change storage.UserChange) error,
+) error {
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+
+	resumeToken, errToken := m.changeStreamToken(ctx, watcherId)
+	if errToken != nil {
+		return errToken
+	}
+	if resumeToken != nil {
+		opts.SetResumeAfter(resumeToken)
+	}
+
+	stream, errWatch := m.database.Collection(UserCollection).Watch(ctx, userChangeStreamPipeline, opts)
+	if errWatch != nil {
+		logger.Log.Errorf("Error watching user changes: %v", errWatch)
+
+		return common.NewError(errWatch, common.ErrTypeInternal)
+	}
+	defer func() {
+		_ = stream.Close(context.Background())
+	}()
+
+	for stream.Next(ctx) {
+		var event userChangeEvent
+		if errDecode := stream.Decode(&event); errDecode != nil {
+			logger.Log.Errorf("Error decoding user change: %v", errDecode)
+
+			return common.NewError(errDecode, common.ErrTypeInternal)
+		}
+
+		if errHandle := handle(ctx, m.toUserChange(event)); errHandle != nil {
+			return errHandle
+		}
+
+		if errSave := m.saveChangeStreamToken(ctx, watcherId, owner, stream.ResumeToken()); errSave != nil {
+			return errSave
+		}
+	}
+
+	errStream := stream.Err()
+	if errStream != nil && ctx.Err() == nil {
+		logger.Log.Errorf("Error watching user changes: %v", errStream)
+
+		return common.NewError(errStream, common.ErrTypeInternal)
+	}
+
+	return nil
+}
+
+// toUserChange converts a change event of the user collection to a user change.
+// Soft deletions and restores are updates of the deletion timestamp, while removals are purges.
+// Without soft delete, removals are deletions.
+// The version of updates is the one set by the change, even if the looked up user includes later changes,
+// while removed users are taken from the pre-image of the removal, with the version following it.
+// Without pre-images, removed users only have their ID.
+func (m *MongoDB) toUserChange(event userChangeEvent) storage.UserChange {
+	change := storage.UserChange{
+		ID:        event.ID.Data,
+		User:      storage.User{ID: event.DocumentKey.ID},
+		ChangedAt: time.Unix(int64(event.ClusterTime.T), 0).UTC(),
+	}
+	if event.FullDocument != nil {
+		change.User = event.FullDocument.toStorageUser()
+	}
+	// Removed users are the ones before the removal, which sets the next version
+	if event.OperationType == "delete" && event.FullDocumentBeforeChange != nil {
+		change.User = event.FullDocumentBeforeChange.toStorageUser()
+		change.User.Version++
+	}
+
+	updatedFields := event.UpdateDescription.UpdatedFields
+	removedFields := event.UpdateDescription.RemovedFields
+
+	switch version := updatedFields["version"].(type) {
+	case int32:
+		change.User.Version = int64(version)
+	case int64:
+		change.User.Version = version
+	}
+
+	switch {
+	case event.OperationType == "insert":
+		change.Operation = storage.OperationCreated
+	case event.OperationType == "delete" && m.config.SoftDelete:
+		change.Operation = storage.OperationPurged
+	case event.OperationType == "delete":
+		change.Operation = storage.OperationDeleted
+	case updatedFields["deleted_at"] != nil:
+		change.Operation = storage.OperationDeleted
+	case slices.Contains(removedFields, "deleted_at"):
+		change.Operation = storage.OperationRestored
+	default:
+		change.Operation = storage.OperationUpdated
+		// Replaced users do not describe the changed fields
+		if event.OperationType == "update" {
+			change.ChangedFields = changedUserFields(updatedFields, removedFields)
+		}
+	}
+
+	return change
+}
+
+// changedUserFields returns the user fields among the updated and removed fields of a change
+func changedUserFields(updatedFields bson.M, removedFields []string) []string {
+	changedFields := make([]string, 0)
+	for _, field := range userFields {
+		if _, updated := updatedFields[field]; updated || slices.Contains(removedFields, field) {
+			changedFields = append(changedFields, field)
+		}
+	}
+
+	return changedFields
+}
+
+// changeStreamToken returns the stored resume token of a change stream watcher, or nil if it has none
+func (m *MongoDB) changeStreamToken(ctx context.Context, watcherId string) (bson.Raw, error) {
+	findCtx, cancelFind := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelFind()
+
+	var token changeStreamToken
+	errFind := m.database.Collection(ChangeStreamTokenCollection).FindOne(
+		findCtx,
+		bson.D{{Key: "_id", Value: watcherId}},
+	).Decode(&token)
+	if errFind != nil {
+		if errors.Is(errFind, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		logger.Log.Errorf("Error finding change stream token: %v", errFind)
+
+		return nil, common.NewError(errFind, common.ErrTypeInternal)
+	}
+
+	return token.Token, nil
+}
+
+// saveChangeStreamToken stores the resume token of a change stream watcher, if the given owner holds its lease
+func (m *MongoDB) saveChangeStreamToken(ctx context.Context, watcherId string, owner string, resumeToken bson.Raw) error {
+	saveCtx, cancelSave := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelSave()
+
+	saveRes, errSave := m.database.Collection(ChangeStreamTokenCollection).UpdateOne(
+		saveCtx,
+		bson.D{{Key: "_id", Value: watcherId}, {Key: "owner", Value: owner}},
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "token", Value: resumeToken},
+			{Key: "updated_at", Value: time.Now().UTC()},
+		}}},
+	)
+	if errSave != nil {
+		logger.Log.Errorf("Error saving change stream token: %v", errSave)
+
+		return common.NewError(errSave, common.ErrTypeInternal)
+	}
+	if saveRes.MatchedCount == 0 {
+		logger.Log.Warnf("Lease of change stream watcher %s lost by %s", watcherId, owner)
+
+		return common.NewError(
+			fmt.Errorf("lease of change stream watcher %s lost", watcherId),
+			common.ErrTypeFailedPrecondition,
+		)
+	}
+
+	return nil
+}
+
+// AcquireUserChangesLease acquires or renews until the given expiration the lease of a change stream watcher,
+// granting the given owner the right to watch user changes and save the resume token of the watcher.
+// It returns false if the lease is held by another owner and not expired yet.
+func (m *MongoDB) AcquireUserChangesLease(
+	ctx context.Context,
+	watcherId string,
+	owner string,
+	now time.Time,
+	expiresAt time.Time,
+) (bool, error) {
+	filter := bson.D{
+		{Key: "_id", Value: watcherId},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "owner", Value: owner}},
+			bson.D{{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "owner", Value: owner},
+		{Key: "expires_at", Value: expiresAt},
+	}}}
+
+	leaseCtx, cancelLease := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelLease()
+
+	// A lease held by another owner does not match, so the upsert fails on the watcher ID
+	_, errLease := m.database.Collection(ChangeStreamTokenCollection).UpdateOne(
+		leaseCtx,
+		filter,
+		update,
+		options.UpdateOne().SetUpsert(true),
+	)
+	if errLease != nil {
+		if mongo.IsDuplicateKeyError(errLease) {
+			return false, nil
+		}
+		logger.Log.Errorf("Error acquiring change stream lease: %v", errLease)
+
+		return false, common.NewError(errLease, common.ErrTypeInternal)
+	}
+
+	return true, nil
+}
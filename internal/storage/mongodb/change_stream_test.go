@@ -0,0 +1,295 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"testing"
+	"time"
+)
+
+func TestMongoDB_ToUserChange(t *testing.T) {
+	now := time.Now().UTC()
+	clusterTime := bson.Timestamp{T: uint32(now.Unix())}
+
+	fullDocument := &deletedUser{
+		User:             storage.User{ID: "user-id", FirstName: "John", Nickname: "deleted:user-id", Version: 5},
+		ReleasedNickname: "johndoe",
+	}
+
+	newEvent := func(operationType string, updatedFields bson.M, removedFields []string) userChangeEvent {
+		event := userChangeEvent{
+			OperationType: operationType,
+			ClusterTime:   clusterTime,
+			FullDocument:  fullDocument,
+		}
+		event.ID.Data = "change-id"
+		event.DocumentKey.ID = "user-id"
+		event.UpdateDescription.UpdatedFields = updatedFields
+		event.UpdateDescription.RemovedFields = removedFields
+
+		return event
+	}
+	newDeleteEvent := func(preImage *deletedUser) userChangeEvent {
+		event := userChangeEvent{
+			OperationType:            "delete",
+			ClusterTime:              clusterTime,
+			FullDocumentBeforeChange: preImage,
+		}
+		event.ID.Data = "change-id"
+		event.DocumentKey.ID = "user-id"
+
+		return event
+	}
+	originalUser := storage.User{ID: "user-id", FirstName: "John", Nickname: "johndoe", Version: 5}
+	// The version of an update is the one it set, not the one of the looked up user
+	updatedUser := originalUser
+	updatedUser.Version = 4
+	// The version of a removal is the one following its pre-image
+	removedUser := originalUser
+	removedUser.Version = 6
+
+	tests := []struct {
+		name       string
+		softDelete bool
+		event      userChangeEvent
+		want       storage.UserChange
+	}{
+		{
+			name:  "Inserted user",
+			event: newEvent("insert", nil, nil),
+			want:  storage.UserChange{Operation: storage.OperationCreated, User: originalUser},
+		},
+		{
+			name: "Updated user",
+			event: newEvent(
+				"update",
+				bson.M{"first_name": "John", "version": int64(4), "updated_at": now},
+				[]string{"country"},
+			),
+			want: storage.UserChange{
+				Operation:     storage.OperationUpdated,
+				User:          updatedUser,
+				ChangedFields: []string{"first_name", "country"},
+			},
+		},
+		{
+			name:  "Replaced user",
+			event: newEvent("replace", nil, nil),
+			want:  storage.UserChange{Operation: storage.OperationUpdated, User: originalUser},
+		},
+		{
+			name:       "Soft deleted user",
+			softDelete: true,
+			event:      newEvent("update", bson.M{"deleted_at": now, "nickname": "deleted:user-id"}, nil),
+			want:       storage.UserChange{Operation: storage.OperationDeleted, User: originalUser},
+		},
+		{
+			name:       "Restored user",
+			softDelete: true,
+			event:      newEvent("update", bson.M{"version": int32(5)}, []string{"deleted_at", "released_nickname"}),
+			want:       storage.UserChange{Operation: storage.OperationRestored, User: originalUser},
+		},
+		{
+			name:       "Purged user",
+			softDelete: true,
+			event:      newDeleteEvent(fullDocument),
+			want:       storage.UserChange{Operation: storage.OperationPurged, User: removedUser},
+		},
+		{
+			name:  "Hard deleted user",
+			event: newDeleteEvent(&deletedUser{User: originalUser}),
+			want:  storage.UserChange{Operation: storage.OperationDeleted, User: removedUser},
+		},
+		{
+			name:  "Deleted user without pre-image",
+			event: newDeleteEvent(nil),
+			want:  storage.UserChange{Operation: storage.OperationDeleted, User: storage.User{ID: "user-id"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MongoDB{config: Config{SoftDelete: tt.softDelete}}
+
+			tt.want.ID = "change-id"
+			tt.want.ChangedAt = time.Unix(now.Unix(), 0).UTC()
+
+			assert.Equal(t, tt.want, m.toUserChange(tt.event))
+		})
+	}
+}
+
+func TestMongoDB_WatchUserChanges_Success(t *testing.T) {
+	softDeleteStorage := newTestSoftDeleteStorage(false)
+	watcherId := "test-watcher"
+	owner := "test-owner"
+	errStop := errors.New("stop watching")
+
+	now := time.Now().UTC()
+	acquired, err := softDeleteStorage.AcquireUserChangesLease(context.Background(), watcherId, owner, now, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// watchChanges watches user changes until the given number of changes is handled, the last one fails
+	watchChanges := func(count int) (<-chan []storage.UserChange, <-chan error) {
+		changesCh := make(chan []storage.UserChange, 1)
+		errCh := make(chan error, 1)
+
+		go func() {
+			changes := make([]storage.UserChange, 0, count)
+			errWatch := softDeleteStorage.WatchUserChanges(
+				context.Background(),
+				watcherId,
+				owner,
+				func(_ context.Context, change storage.UserChange) error {
+					changes = append(changes, change)
+					if len(changes) == count {
+						return errStop
+					}
+
+					return nil
+				},
+			)
+			changesCh <- changes
+			errCh <- errWatch
+		}()
+
+		return changesCh, errCh
+	}
+
+	changesCh, errCh := watchChanges(4)
+	// the change stream starts when the watch is opened
+	time.Sleep(time.Second)
+
+	userDetails := storage.UserDetails{
+		ID:        "watcheduser",
+		FirstName: "John",
+		Nickname:  "watchednickname",
+		Email:     "watched@email.com",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+	_, err = softDeleteStorage.CreateUser(context.Background(), userDetails)
+	require.NoError(t, err)
+
+	firstName := "Johnny"
+	_, _, err = softDeleteStorage.UpdateUser(
+		context.Background(),
+		userDetails.ID,
+		storage.UserUpdate{FirstName: &firstName, UpdatedAt: &now},
+		nil,
+	)
+	require.NoError(t, err)
+
+	// the user is deleted long ago, so that no other user is purged along with it
+	deletedAt := time.Unix(1, 0).UTC()
+	_, err = softDeleteStorage.DeleteUser(context.Background(), userDetails.ID, deletedAt, nil)
+	require.NoError(t, err)
+
+	purgedUsers, err := softDeleteStorage.PurgeDeletedUsers(context.Background(), deletedAt.Add(time.Second), now, 1)
+	require.NoError(t, err)
+	require.Len(t, purgedUsers, 1)
+
+	var changes []storage.UserChange
+	select {
+	case changes = <-changesCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("user changes not watched")
+	}
+	assert.ErrorIs(t, <-errCh, errStop)
+
+	require.Len(t, changes, 4)
+	assert.Equal(t, storage.OperationCreated, changes[0].Operation)
+	assert.Equal(t, userDetails.ID, changes[0].User.ID)
+	assert.Equal(t, storage.OperationUpdated, changes[1].Operation)
+	assert.Equal(t, []string{"first_name"}, changes[1].ChangedFields)
+	assert.Equal(t, storage.OperationDeleted, changes[2].Operation)
+	assert.Equal(t, "watchednickname", changes[2].User.Nickname)
+	// the purged user is taken from the pre-image of the purge
+	assert.Equal(t, storage.OperationPurged, changes[3].Operation)
+	assert.Equal(t, "watchednickname", changes[3].User.Nickname)
+	assert.Equal(t, "watched@email.com", changes[3].User.Email)
+	assert.Equal(t, purgedUsers[0].Version, changes[3].User.Version)
+
+	// the watch resumes from the change whose handling failed
+	changesCh, errCh = watchChanges(1)
+
+	select {
+	case changes = <-changesCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("user changes not resumed")
+	}
+	assert.ErrorIs(t, <-errCh, errStop)
+
+	require.Len(t, changes, 1)
+	assert.Equal(t, storage.OperationPurged, changes[0].Operation)
+	assert.Equal(t, userDetails.ID, changes[0].User.ID)
+
+	// another owner takes over the expired lease, the previous owner cannot save the token anymore
+	acquired, err = softDeleteStorage.AcquireUserChangesLease(
+		context.Background(),
+		watcherId,
+		"other-owner",
+		now.Add(2*time.Hour),
+		now.Add(3*time.Hour),
+	)
+	require.NoError(t, err)
+	require.True(t, acquired)
+	err = softDeleteStorage.saveChangeStreamToken(context.Background(), watcherId, owner, bson.Raw{})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+
+	// clean up the test user, its history and the watcher token
+	_, errDelete := testMongoStorage.Database().Collection(UserCollection).DeleteOne(
+		context.Background(),
+		bson.D{{Key: "_id", Value: userDetails.ID}},
+	)
+	require.NoError(t, errDelete)
+	_, errDelete = testMongoStorage.Database().Collection(UserHistoryCollection).DeleteMany(
+		context.Background(),
+		bson.D{{Key: "user_id", Value: userDetails.ID}},
+	)
+	require.NoError(t, errDelete)
+	_, errDelete = testMongoStorage.Database().Collection(ChangeStreamTokenCollection).DeleteOne(
+		context.Background(),
+		bson.D{{Key: "_id", Value: watcherId}},
+	)
+	require.NoError(t, errDelete)
+}
+
+func TestMongoDB_AcquireUserChangesLease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	watcherId := "test-lease-watcher"
+
+	acquired, err := testMongoStorage.AcquireUserChangesLease(ctx, watcherId, "owner-1", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// the owner renews its lease
+	acquired, err = testMongoStorage.AcquireUserChangesLease(ctx, watcherId, "owner-1", now.Add(time.Second), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// another owner cannot acquire the lease until it expires
+	acquired, err = testMongoStorage.AcquireUserChangesLease(ctx, watcherId, "owner-2", now.Add(time.Second), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	acquired, err = testMongoStorage.AcquireUserChangesLease(ctx, watcherId, "owner-2", now.Add(time.Minute), now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	_, errDelete := testMongoStorage.Database().Collection(ChangeStreamTokenCollection).DeleteOne(
+		ctx,
+		bson.D{{Key: "_id", Value: watcherId}},
+	)
+	require.NoError(t, errDelete)
+}
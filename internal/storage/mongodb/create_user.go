@@ -14,38 +14,44 @@ import (
 func (m *MongoDB) CreateUser(ctx context.Context, userDetails storage.UserDetails) (*storage.User, error) {
 	collection := m.database.Collection(UserCollection)
 
-	insertCtx, cancelInsert := context.WithTimeout(ctx, 10*time.Second)
-	defer cancelInsert()
+	createCtx, cancelCreate := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelCreate()
 
-	insertRes, insertErr := collection.InsertOne(insertCtx, userDetails)
-	if insertErr != nil {
+	var user storage.User
+
+	// Create the user and record its revision in the same transaction
+	createErr := m.withTransaction(createCtx, func(ctx context.Context) error {
+		insertRes, insertErr := collection.InsertOne(ctx, userDetails)
+		if insertErr != nil {
+			return insertErr
+		}
+
+		// Find the created user to return
+		filter := bson.D{{Key: "_id", Value: insertRes.InsertedID}}
+		findErr := collection.FindOne(ctx, filter).Decode(&user)
+		if findErr != nil {
+			return findErr
+		}
+
+		return m.recordRevision(
+			ctx,
+			newUserRevision(ctx, storage.OperationCreated, user.ID, user.Version, nil, &user, user.CreatedAt),
+		)
+	})
+	if createErr != nil {
 		// Duplicate key error handling
-		if mongo.IsDuplicateKeyError(insertErr) {
-			logger.Log.Debugf("Error creating user: %v", insertErr)
-			insertErr = common.NewError(
+		if mongo.IsDuplicateKeyError(createErr) {
+			logger.Log.Debugf("Error creating user: %v", createErr)
+			createErr = common.NewError(
 				errors.New("another user with same nickname or email already exists"),
 				common.ErrTypeAlreadyExists,
 			)
 		} else {
-			logger.Log.Errorf("Error creating user: %v", insertErr)
-			insertErr = common.NewError(insertErr, common.ErrTypeInternal)
+			logger.Log.Errorf("Error creating user: %v", createErr)
+			createErr = common.NewError(createErr, common.ErrTypeInternal)
 		}
 
-		return nil, insertErr
-	}
-
-	// Find the created user to return
-	findCtx, cancelFind := context.WithTimeout(ctx, 5*time.Second)
-	defer cancelFind()
-
-	filter := bson.D{{Key: "_id", Value: insertRes.InsertedID}}
-
-	var user storage.User
-	findErr := collection.FindOne(findCtx, filter).Decode(&user)
-	if findErr != nil {
-		logger.Log.Errorf("Error decoding created user: %v", findErr)
-
-		return nil, common.NewError(findErr, common.ErrTypeInternal)
+		return nil, createErr
 	}
 
 	return &user, nil
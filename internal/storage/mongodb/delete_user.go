@@ -5,31 +5,94 @@ import (
 	"errors"
 	"github.com/alenalato/users-service/internal/common"
 	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
 	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"time"
 )
 
-func (m *MongoDB) DeleteUser(ctx context.Context, userId string) error {
+// DeleteUser deletes the user with the given ID and returns the deleted user.
+// If soft delete is enabled, the user is only marked as deleted at the given time and hidden from reads,
+// otherwise it is removed from the collection.
+func (m *MongoDB) DeleteUser(
+	ctx context.Context,
+	userId string,
+	deletedAt time.Time,
+	precondition *storage.UserPrecondition,
+) (*storage.User, error) {
 	collection := m.database.Collection(UserCollection)
 
 	deleteCtx, cancelDelete := context.WithTimeout(ctx, 10*time.Second)
 	defer cancelDelete()
 
-	filter := bson.D{{Key: "_id", Value: userId}}
+	filter := userFilterWithPrecondition(userId, precondition)
 
-	deleteRes, errDelete := collection.DeleteOne(deleteCtx, filter)
+	var user deletedUser
+
+	// Delete the user and record its revision in the same transaction
+	errDelete := m.withTransaction(deleteCtx, func(ctx context.Context) error {
+		var deleteRes *mongo.SingleResult
+		if m.config.SoftDelete {
+			opts := options.FindOneAndUpdate().
+				SetUpsert(false).
+				SetReturnDocument(options.After)
+			deleteRes = collection.FindOneAndUpdate(ctx, filter, m.softDeleteUpdate(userId, deletedAt), opts)
+		} else {
+			deleteRes = collection.FindOneAndDelete(ctx, filter)
+		}
+		errDecode := deleteRes.Decode(&user)
+		if errDecode != nil {
+			return errDecode
+		}
+		if !m.config.SoftDelete {
+			// The removed document holds the version of the previous change, the removal is the next one
+			user.Version++
+		}
+
+		// Soft deletion keeps the user fields, apart from released identifiers
+		storageUser := user.toStorageUser()
+
+		return m.recordRevision(
+			ctx,
+			newUserRevision(ctx, storage.OperationDeleted, userId, user.Version, &storageUser, nil, deletedAt),
+		)
+	})
 	if errDelete != nil {
+		// Check if the user was matched, if not, return a not found or failed precondition error
+		if errors.Is(errDelete, mongo.ErrNoDocuments) {
+			errDelete = m.unmatchedUserError(ctx, userId, precondition)
+			logger.Log.Errorf("Error deleting user: %v", errDelete)
+
+			return nil, errDelete
+		}
 		logger.Log.Errorf("Error deleting user: %v", errDelete)
 
-		return common.NewError(errDelete, common.ErrTypeInternal)
+		return nil, common.NewError(errDelete, common.ErrTypeInternal)
 	}
-	// Check if the user was found and deleted, if not, return a not found error
-	if deleteRes.DeletedCount == 0 {
-		errDelete = errors.New("user not found")
-		logger.Log.Errorf("Error deleting user: %v", errDelete)
 
-		return common.NewError(errDelete, common.ErrTypeNotFound)
+	// Return the deleted user with its original identifiers, even if released
+	storageUser := user.toStorageUser()
+
+	return &storageUser, nil
+}
+
+// softDeleteUpdate returns the pipeline update marking a user as deleted.
+// If configured, nickname and email are moved aside and replaced by placeholders to free them.
+func (m *MongoDB) softDeleteUpdate(userId string, deletedAt time.Time) mongo.Pipeline {
+	set := bson.D{
+		{Key: "deleted_at", Value: deletedAt},
+		{Key: "version", Value: incrementedVersion},
+	}
+	if m.config.ReleaseDeletedIdentifiers {
+		// Expressions are evaluated on the document before the stage, so original values are preserved
+		set = append(set,
+			bson.E{Key: "released_nickname", Value: "$nickname"},
+			bson.E{Key: "released_email", Value: "$email"},
+			bson.E{Key: "nickname", Value: releasedIdentifier(userId)},
+			bson.E{Key: "email", Value: releasedIdentifier(userId)},
+		)
 	}
 
-	return nil
+	return mongo.Pipeline{{{Key: "$set", Value: set}}}
 }
@@ -9,12 +9,14 @@ import (
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"testing"
+	"time"
 )
 
 func TestMongoDB_DeleteUser_NotFoundError(t *testing.T) {
 	userId := "notpresent"
-	err := testMongoStorage.DeleteUser(context.Background(), userId)
+	deletedUser, err := testMongoStorage.DeleteUser(context.Background(), userId, time.Now().UTC(), nil)
 
+	assert.Nil(t, deletedUser)
 	assert.Error(t, err)
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
@@ -31,12 +33,19 @@ func TestMongoDB_DeleteUser_Success(t *testing.T) {
 		context.Background(),
 		bson.D{
 			{Key: "_id", Value: userId},
+			{Key: "nickname", Value: "present"},
+			{Key: "version", Value: 1},
 		},
 	)
 	require.NoError(t, errIns)
 
-	err := testMongoStorage.DeleteUser(context.Background(), userId)
+	deletedUser, err := testMongoStorage.DeleteUser(context.Background(), userId, time.Now().UTC(), nil)
 	assert.NoError(t, err)
+	require.NotNil(t, deletedUser)
+	assert.Equal(t, userId, deletedUser.ID)
+	assert.Equal(t, "present", deletedUser.Nickname)
+	// the deletion is the change following the stored version
+	assert.Equal(t, int64(2), deletedUser.Version)
 
 	// verify that the user is actually deleted
 	user := &storage.User{}
@@ -49,3 +58,31 @@ func TestMongoDB_DeleteUser_Success(t *testing.T) {
 	assert.Error(t, errFind)
 	assert.ErrorIs(t, errFind, mongo.ErrNoDocuments)
 }
+
+func TestMongoDB_DeleteUser_FailedPreconditionError(t *testing.T) {
+	userId := "presentversioned"
+
+	// create a user to ensure it exists
+	collection := testMongoStorage.Database().Collection(UserCollection)
+
+	_, errIns := collection.InsertOne(
+		context.Background(),
+		storage.UserDetails{
+			ID:      userId,
+			Version: 2,
+		},
+	)
+	require.NoError(t, errIns)
+
+	_, err := testMongoStorage.DeleteUser(context.Background(), userId, time.Now().UTC(), &storage.UserPrecondition{Version: 1})
+	assert.Error(t, err)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+
+	// delete with the matching version
+	deletedUser, err := testMongoStorage.DeleteUser(context.Background(), userId, time.Now().UTC(), &storage.UserPrecondition{Version: 2})
+	assert.NoError(t, err)
+	require.NotNil(t, deletedUser)
+	assert.Equal(t, int64(3), deletedUser.Version)
+}
@@ -0,0 +1,69 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// GetUserHistory returns a page of the revisions of the user with the given ID, oldest first.
+// History is kept for deleted and purged users too.
+func (m *MongoDB) GetUserHistory(
+	ctx context.Context,
+	userId string,
+	pageSize int,
+	pageToken string,
+) ([]storage.UserRevision, string, error) {
+	collection := m.database.Collection(UserHistoryCollection)
+
+	// Coerce the page size to a valid value
+	if pageSize <= 0 || pageSize > storage.MaxPageSize {
+		pageSize = storage.MaxPageSize
+	}
+
+	var skipSize int64
+	if pageToken != "" {
+		// Decode the page token to get cursor skip size
+		var errTok error
+		skipSize, errTok = parseHistoryPageToken(pageToken, userId)
+		if errTok != nil {
+			return nil, "", errTok
+		}
+	}
+
+	// Add 1 to the requested pageSize to tell if there is a next page
+	limit := int64(pageSize) + 1
+
+	opts := options.Find().
+		SetSkip(skipSize).
+		SetLimit(limit).
+		SetSort(bson.D{{Key: "changed_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, errFind := collection.Find(ctx, bson.D{{Key: "user_id", Value: userId}}, opts)
+	if errFind != nil {
+		logger.Log.Errorf("Error getting user history: %v", errFind)
+
+		return nil, "", common.NewError(errFind, common.ErrTypeInternal)
+	}
+
+	var revisions []storage.UserRevision
+	if errCurs := cursor.All(ctx, &revisions); errCurs != nil {
+		logger.Log.Errorf("Error decoding user revisions: %v", errCurs)
+
+		return nil, "", common.NewError(errCurs, common.ErrTypeInternal)
+	}
+
+	nextPageToken := ""
+	// There is the extra element at the end of the result, generate the next page token
+	if len(revisions) > pageSize {
+		nextPageToken = generateHistoryPageToken(userId, skipSize+int64(pageSize))
+
+		// Remove the last element from the list
+		revisions = revisions[:pageSize]
+	}
+
+	return revisions, nextPageToken, nil
+}
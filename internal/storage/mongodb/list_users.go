@@ -5,6 +5,7 @@ import (
 	"github.com/alenalato/users-service/internal/common"
 	"github.com/alenalato/users-service/internal/logger"
 	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
@@ -39,7 +40,13 @@ func (m *MongoDB) ListUsers(
 		SetLimit(limit).
 		SetSort(map[string]interface{}{"created_at": 1}) // Default fixed sort by created_at in ascending order
 
-	cursor, errFind := collection.Find(ctx, userFilter, opts)
+	// Soft deleted users are never listed
+	filter := bson.D{{Key: "$and", Value: bson.A{
+		userFilter,
+		bson.D{notDeletedFilter},
+	}}}
+
+	cursor, errFind := collection.Find(ctx, filter, opts)
 	if errFind != nil {
 		logger.Log.Errorf("Error listing users: %v", errFind)
 
@@ -73,3 +80,52 @@ func (m *MongoDB) ListUsers(
 
 	return users, nextPageToken, nil
 }
+
+// ListUsersAfter lists the users following the given key in creation order, the users created at the same time
+// are ordered by ID, so that the key of the last listed user is where the next call resumes
+func (m *MongoDB) ListUsersAfter(
+	ctx context.Context,
+	userFilter storage.UserFilter,
+	after *storage.UserKey,
+	limit int,
+) ([]storage.User, error) {
+	// Coerce the limit to a valid value
+	if limit <= 0 || limit > storage.MaxPageSize {
+		limit = storage.MaxPageSize
+	}
+
+	opts := options.Find().
+		SetLimit(int64(limit)).
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	// Soft deleted users are never listed
+	conditions := bson.A{
+		userFilter,
+		bson.D{notDeletedFilter},
+	}
+	if after != nil {
+		conditions = append(conditions, bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "created_at", Value: bson.D{{Key: "$gt", Value: after.CreatedAt}}}},
+			bson.D{
+				{Key: "created_at", Value: after.CreatedAt},
+				{Key: "_id", Value: bson.D{{Key: "$gt", Value: after.ID}}},
+			},
+		}}})
+	}
+
+	cursor, errFind := m.database.Collection(UserCollection).Find(ctx, bson.D{{Key: "$and", Value: conditions}}, opts)
+	if errFind != nil {
+		logger.Log.Errorf("Error listing users: %v", errFind)
+
+		return nil, common.NewError(errFind, common.ErrTypeInternal)
+	}
+
+	var users []storage.User
+	if errCurs := cursor.All(ctx, &users); errCurs != nil {
+		logger.Log.Errorf("Error decoding users: %v", errCurs)
+
+		return nil, common.NewError(errCurs, common.ErrTypeInternal)
+	}
+
+	return users, nil
+}
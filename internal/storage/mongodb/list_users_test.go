@@ -219,3 +219,42 @@ func TestMongoDB_ListUsers_SuccessInvalidPageSize(t *testing.T) {
 	})
 	require.NoError(t, err)
 }
+
+func TestMongoDB_ListUsersAfter_Success(t *testing.T) {
+	collection := testMongoStorage.Database().Collection(UserCollection)
+	createdAt := testTimeForStorage(time.Now().Add(-time.Hour))
+	users := []storage.User{
+		{ID: "user-after-1", Email: "after1@example.com", Nickname: "after1", CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: "user-after-2", Email: "after2@example.com", Nickname: "after2", CreatedAt: createdAt, UpdatedAt: createdAt},
+		{ID: "user-after-3", Email: "after3@example.com", Nickname: "after3", CreatedAt: createdAt, UpdatedAt: createdAt},
+	}
+	for _, user := range users {
+		_, err := collection.InsertOne(context.Background(), user)
+		require.NoError(t, err)
+	}
+
+	// Users sharing the same creation time are ordered by ID
+	result, err := testMongoStorage.ListUsersAfter(context.Background(), storage.UserFilter{}, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, users[0], result[0])
+	assert.Equal(t, users[1], result[1])
+
+	// Deleting an already listed user does not shift the next page
+	_, err = collection.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: users[0].ID}})
+	require.NoError(t, err)
+
+	after := &storage.UserKey{CreatedAt: result[1].CreatedAt, ID: result[1].ID}
+	result, err = testMongoStorage.ListUsersAfter(context.Background(), storage.UserFilter{}, after, 2)
+	require.NoError(t, err)
+	require.Len(t, result, 1)
+	assert.Equal(t, users[2], result[0])
+
+	// Clean up test data
+	_, err = collection.DeleteMany(context.Background(), bson.D{
+		{Key: "_id", Value: bson.D{
+			{Key: "$in", Value: []string{"user-after-2", "user-after-3"}},
+		}},
+	})
+	require.NoError(t, err)
+}
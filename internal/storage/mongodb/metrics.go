@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.mongodb.org/mongo-driver/v2/event"
+)
+
+// Metrics holds the metrics of the MongoDB commands sent by the storage
+type Metrics struct {
+	// duration is the duration of the commands, by command name
+	duration *prometheus.HistogramVec
+	// errors is the number of failed commands, by command name
+	errors *prometheus.CounterVec
+}
+
+// CommandMonitor returns the MongoDB command monitor recording the commands, nil if the metrics are nil
+func (m *Metrics) CommandMonitor() *event.CommandMonitor {
+	if m == nil {
+		return nil
+	}
+
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, succeeded *event.CommandSucceededEvent) {
+			m.duration.WithLabelValues(succeeded.CommandName).Observe(succeeded.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, failed *event.CommandFailedEvent) {
+			m.duration.WithLabelValues(failed.CommandName).Observe(failed.Duration.Seconds())
+			m.errors.WithLabelValues(failed.CommandName).Inc()
+		},
+	}
+}
+
+// NewMetrics creates the metrics of the MongoDB commands and registers them with the given registerer
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	metrics := &Metrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "users",
+			Subsystem: "mongodb",
+			Name:      "command_duration_seconds",
+			Help:      "Duration of the MongoDB commands, by command name.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"command"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "users",
+			Subsystem: "mongodb",
+			Name:      "command_errors_total",
+			Help:      "Number of failed MongoDB commands, by command name.",
+		}, []string{"command"}),
+	}
+	for _, collector := range []prometheus.Collector{metrics.duration, metrics.errors} {
+		if errRegister := registerer.Register(collector); errRegister != nil {
+			return nil, errRegister
+		}
+	}
+
+	return metrics, nil
+}
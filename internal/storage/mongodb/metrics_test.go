@@ -0,0 +1,36 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/event"
+	"testing"
+	"time"
+)
+
+func TestMetrics_CommandMonitor(t *testing.T) {
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	require.NoError(t, err)
+	monitor := metrics.CommandMonitor()
+	require.NotNil(t, monitor)
+
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", Duration: time.Millisecond},
+	})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", Duration: time.Millisecond},
+	})
+
+	assert.Equal(t, 2, testutil.CollectAndCount(metrics.duration))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.errors.WithLabelValues("find")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.errors.WithLabelValues("insert")))
+}
+
+func TestMetrics_CommandMonitor_Nil(t *testing.T) {
+	var metrics *Metrics
+
+	assert.Nil(t, metrics.CommandMonitor())
+}
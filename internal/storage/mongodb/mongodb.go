@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/alenalato/users-service/internal/logger"
 	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 	"os"
@@ -11,12 +12,26 @@ import (
 
 const UserCollection = "user"
 
+// Config holds the configuration for the MongoDB storage
+type Config struct {
+	// SoftDelete keeps deleted users hidden in the collection, with a deletion timestamp, until they are purged
+	SoftDelete bool
+	// ReleaseDeletedIdentifiers frees nickname and email of soft deleted users, so they can be taken by other users
+	ReleaseDeletedIdentifiers bool
+	// ChangeStreamPreImages records the pre-images of the user documents, the change stream builds removed users from them
+	ChangeStreamPreImages bool
+	// Metrics records the commands of the client created by the storage, they are not recorded if not set
+	Metrics *Metrics
+}
+
 // MongoDB is the MongoDB storage implementation of the UserStorage interface
 type MongoDB struct {
 	// client is the internal MongoDB client
 	client *mongo.Client
 	// database is the internal MongoDB database
 	database *mongo.Database
+	// config is the storage configuration
+	config Config
 }
 
 var _ storage.UserStorage = new(MongoDB)
@@ -33,12 +48,14 @@ func (m *MongoDB) Database() *mongo.Database {
 
 // NewMongoDB creates a new MongoDB storage.
 // If client is nil, it creates a new client using the MONGODB_URI environment variable and connects to the database with the given name.
-// It also creates unique indexes for user email and nickname.
-func NewMongoDB(client *mongo.Client, databaseName string) (*MongoDB, error) {
+// It also creates unique indexes for user email and nickname, the indexes for user history and events outbox, and the ones for webhooks.
+// Since changes are recorded in the user history within transactions, MongoDB must be deployed as a replica set.
+// If change stream pre-images are enabled in the config, MongoDB 6.0 or later is required.
+func NewMongoDB(client *mongo.Client, databaseName string, config Config) (*MongoDB, error) {
 	if client == nil {
 		logger.Log.Debugf("Creating new MongoDB client with URI: %s", os.Getenv("MONGODB_URI"))
 		var newClientErr error
-		client, newClientErr = NewMongoDBClient(os.Getenv("MONGODB_URI"))
+		client, newClientErr = NewMongoDBClient(os.Getenv("MONGODB_URI"), config.Metrics)
 		if newClientErr != nil {
 			return nil, newClientErr
 		}
@@ -87,14 +104,124 @@ func NewMongoDB(client *mongo.Client, databaseName string) (*MongoDB, error) {
 		return nil, indexErr
 	}
 
+	// Create index for user deleted_at
+	_, indexErr = database.Collection(UserCollection).Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: map[string]interface{}{
+				"deleted_at": 1,
+			},
+			Options: options.Index().SetName("deleted-at").SetUnique(false),
+		},
+	)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
+	// Record the pre-images of the user documents, so the change stream can publish the removed users
+	if config.ChangeStreamPreImages {
+		collModErr := database.RunCommand(
+			context.Background(),
+			bson.D{
+				{Key: "collMod", Value: UserCollection},
+				{Key: "changeStreamPreAndPostImages", Value: bson.D{{Key: "enabled", Value: true}}},
+			},
+		).Err()
+		if collModErr != nil {
+			return nil, collModErr
+		}
+	}
+
+	// Create index for user history, revisions are listed per user in chronological order
+	_, indexErr = database.Collection(UserHistoryCollection).Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "user_id", Value: 1},
+				{Key: "changed_at", Value: 1},
+			},
+			Options: options.Index().SetName("user-id-changed-at").SetUnique(false),
+		},
+	)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
+	// Create index for pending outbox events, they are relayed in creation order
+	_, indexErr = database.Collection(OutboxCollection).Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: bson.D{
+				{Key: "sent_at", Value: 1},
+				{Key: "created_at", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+			Options: options.Index().SetName("sent-at-created-at").SetUnique(false),
+		},
+	)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
+	// Create TTL index for sent outbox events, only documents with a sent_at field expire
+	_, indexErr = database.Collection(OutboxCollection).Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: map[string]interface{}{
+				"sent_at": 1,
+			},
+			Options: options.Index().
+				SetName("sent-at-ttl").
+				SetExpireAfterSeconds(int32(outboxSentRetention.Seconds())),
+		},
+	)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
+	// Create index for webhook subscriptions, they are listed by event type
+	_, indexErr = database.Collection(WebhookSubscriptionCollection).Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: map[string]interface{}{
+				"event_types": 1,
+			},
+			Options: options.Index().SetName("event-types").SetUnique(false),
+		},
+	)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
+	// Create TTL index for webhook deliveries, the delivery log is kept for a retention period
+	_, indexErr = database.Collection(WebhookDeliveryCollection).Indexes().CreateOne(
+		context.Background(),
+		mongo.IndexModel{
+			Keys: map[string]interface{}{
+				"delivered_at": 1,
+			},
+			Options: options.Index().
+				SetName("delivered-at-ttl").
+				SetExpireAfterSeconds(int32(webhookDeliveryRetention.Seconds())),
+		},
+	)
+	if indexErr != nil {
+		return nil, indexErr
+	}
+
 	return &MongoDB{
 		client:   client,
 		database: database,
+		config:   config,
 	}, nil
 }
 
-func NewMongoDBClient(uri string) (*mongo.Client, error) {
+// NewMongoDBClient creates a new MongoDB client connected to the given URI, recording its commands in the metrics, if any
+func NewMongoDBClient(uri string, metrics *Metrics) (*mongo.Client, error) {
 	clientOptions := options.Client().ApplyURI(uri)
+	if monitor := metrics.CommandMonitor(); monitor != nil {
+		clientOptions.SetMonitor(monitor)
+	}
 
 	return mongo.Connect(clientOptions)
 }
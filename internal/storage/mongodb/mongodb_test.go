@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/alenalato/users-service/internal/storage"
 	"github.com/google/uuid"
@@ -33,9 +34,11 @@ func TestMain(m *testing.M) {
 	}
 
 	// pull mongodb docker image
+	// run as a single node replica set, required by transactions, 6.0 records change stream pre-images
 	resource, err := pool.RunWithOptions(&dockertest.RunOptions{
 		Repository: "mongo",
-		Tag:        "4.4",
+		Tag:        "6.0",
+		Cmd:        []string{"--replSet", "rs0"},
 	}, func(config *docker.HostConfig) {
 		config.AutoRemove = true
 		config.RestartPolicy = docker.RestartPolicy{
@@ -51,21 +54,24 @@ func TestMain(m *testing.M) {
 	// exponential backoff-retry
 	err = pool.Retry(func() error {
 		dbClient, err = NewMongoDBClient(fmt.Sprintf(
-			"mongodb://%s:%s",
+			"mongodb://%s:%s/?directConnection=true",
 			resource.Container.NetworkSettings.Gateway,
 			resource.GetPort("27017/tcp"),
-		))
+		), nil)
 		if err != nil {
 			return err
 		}
-		return dbClient.Ping(context.TODO(), nil)
+		if err = dbClient.Ping(context.TODO(), nil); err != nil {
+			return err
+		}
+		return initiateReplicaSet(dbClient)
 	})
 
 	if err != nil {
 		log.Fatalf("Could not connect to docker: %s", err)
 	}
 
-	testMongoStorage, err = NewMongoDB(dbClient, testDbName)
+	testMongoStorage, err = NewMongoDB(dbClient, testDbName, Config{ChangeStreamPreImages: true})
 
 	defer func() {
 		// kill and remove the container
@@ -142,3 +148,29 @@ func TestMongoDB_UniqueIndexes(t *testing.T) {
 		},
 	)
 }
+
+// initiateReplicaSet initiates the single node replica set of the test container,
+// it returns an error until the node is elected primary
+func initiateReplicaSet(client *mongo.Client) error {
+	admin := client.Database("admin")
+
+	if errStatus := admin.RunCommand(context.TODO(), bson.D{{Key: "replSetGetStatus", Value: 1}}).Err(); errStatus != nil {
+		errInit := admin.RunCommand(context.TODO(), bson.D{{Key: "replSetInitiate", Value: bson.D{}}}).Err()
+		if errInit != nil {
+			return errInit
+		}
+	}
+
+	var isMaster struct {
+		IsMaster bool `bson:"ismaster"`
+	}
+	errIsMaster := admin.RunCommand(context.TODO(), bson.D{{Key: "isMaster", Value: 1}}).Decode(&isMaster)
+	if errIsMaster != nil {
+		return errIsMaster
+	}
+	if !isMaster.IsMaster {
+		return errors.New("replica set primary not elected yet")
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"time"
+)
+
+const OutboxCollection = "outbox"
+const OutboxLeaseCollection = "outbox_lease"
+
+// outboxSentRetention is the period sent events are kept in the outbox before expiring
+const outboxSentRetention = 7 * 24 * time.Hour
+
+// outboxLeaseId is the ID of the single lease granting the right to relay outbox events
+const outboxLeaseId = "relay"
+
+// pendingOutboxFilter is the filter matching outbox events not sent yet and not failed
+var pendingOutboxFilter = bson.D{
+	{Key: "sent_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	{Key: "failed_at", Value: bson.D{{Key: "$exists", Value: false}}},
+}
+
+var _ storage.OutboxStorage = new(MongoDB)
+
+// AddOutboxEvent stores an event in the outbox, it is part of the transaction carried by the context if any
+func (m *MongoDB) AddOutboxEvent(ctx context.Context, outboxEvent storage.OutboxEvent) error {
+	insertCtx, cancelInsert := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelInsert()
+
+	_, errInsert := m.database.Collection(OutboxCollection).InsertOne(insertCtx, outboxEvent)
+	if errInsert != nil {
+		logger.Log.Errorf("Error adding outbox event: %v", errInsert)
+
+		return common.NewError(errInsert, common.ErrTypeInternal)
+	}
+
+	return nil
+}
+
+// PendingOutboxEvents returns up to limit events not sent yet, in creation order by key.
+// The events of a key are only returned if its oldest pending event is due at the given time,
+// so that a key waiting for a retry holds back its following events without holding back the other keys.
+func (m *MongoDB) PendingOutboxEvents(ctx context.Context, now time.Time, limit int) ([]storage.OutboxEvent, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: pendingOutboxFilter}},
+		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: "$key"},
+			{Key: "head", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+			{Key: "events", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "head.next_attempt_at", Value: bson.D{{Key: "$lte", Value: now}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "head.created_at", Value: 1}, {Key: "head._id", Value: 1}}}},
+		{{Key: "$limit", Value: limit}},
+		{{Key: "$project", Value: bson.D{{Key: "events", Value: bson.D{{Key: "$slice", Value: bson.A{"$events", limit}}}}}}},
+		{{Key: "$unwind", Value: "$events"}},
+		{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$events"}}}},
+		{{Key: "$limit", Value: limit}},
+	}
+
+	findCtx, cancelFind := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelFind()
+
+	cursor, errFind := m.database.Collection(OutboxCollection).Aggregate(
+		findCtx,
+		pipeline,
+		options.Aggregate().SetAllowDiskUse(true),
+	)
+	if errFind != nil {
+		logger.Log.Errorf("Error finding pending outbox events: %v", errFind)
+
+		return nil, common.NewError(errFind, common.ErrTypeInternal)
+	}
+
+	var outboxEvents []storage.OutboxEvent
+	if errCurs := cursor.All(findCtx, &outboxEvents); errCurs != nil {
+		logger.Log.Errorf("Error decoding pending outbox events: %v", errCurs)
+
+		return nil, common.NewError(errCurs, common.ErrTypeInternal)
+	}
+
+	return outboxEvents, nil
+}
+
+// MarkOutboxEventSent marks an outbox event as sent, it expires after a retention period
+func (m *MongoDB) MarkOutboxEventSent(ctx context.Context, eventId string, sentAt time.Time) error {
+	return m.updateOutboxEvent(ctx, eventId, bson.D{
+		{Key: "$set", Value: bson.D{{Key: "sent_at", Value: sentAt}}},
+		{Key: "$unset", Value: bson.D{{Key: "last_error", Value: ""}}},
+	})
+}
+
+// MarkOutboxEventFailed records a failed relay attempt of an outbox event and when it can be attempted again
+func (m *MongoDB) MarkOutboxEventFailed(
+	ctx context.Context,
+	eventId string,
+	nextAttemptAt time.Time,
+	lastError string,
+) error {
+	return m.updateOutboxEvent(ctx, eventId, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "next_attempt_at", Value: nextAttemptAt},
+			{Key: "last_error", Value: lastError},
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+	})
+}
+
+// MarkOutboxEventExhausted records the last failed relay attempt of an outbox event and marks it as failed,
+// it is no longer relayed nor holds back the following events of its key, and it is kept for inspection
+func (m *MongoDB) MarkOutboxEventExhausted(
+	ctx context.Context,
+	eventId string,
+	failedAt time.Time,
+	lastError string,
+) error {
+	return m.updateOutboxEvent(ctx, eventId, bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "failed_at", Value: failedAt},
+			{Key: "last_error", Value: lastError},
+		}},
+		{Key: "$inc", Value: bson.D{{Key: "attempts", Value: 1}}},
+	})
+}
+
+// updateOutboxEvent applies the given update to a pending outbox event
+func (m *MongoDB) updateOutboxEvent(ctx context.Context, eventId string, update bson.D) error {
+	updateCtx, cancelUpdate := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelUpdate()
+
+	filter := append(bson.D{{Key: "_id", Value: eventId}}, pendingOutboxFilter...)
+	updateRes, errUpdate := m.database.Collection(OutboxCollection).UpdateOne(updateCtx, filter, update)
+	if errUpdate != nil {
+		logger.Log.Errorf("Error updating outbox event: %v", errUpdate)
+
+		return common.NewError(errUpdate, common.ErrTypeInternal)
+	}
+	if updateRes.MatchedCount == 0 {
+		return common.NewError(nil, common.ErrTypeNotFound)
+	}
+
+	return nil
+}
+
+// AcquireOutboxLease acquires or renews until the given expiration the lease granting the right to relay outbox events.
+// It returns false if the lease is held by another owner and not expired yet.
+func (m *MongoDB) AcquireOutboxLease(
+	ctx context.Context,
+	owner string,
+	now time.Time,
+	expiresAt time.Time,
+) (bool, error) {
+	filter := bson.D{
+		{Key: "_id", Value: outboxLeaseId},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "owner", Value: owner}},
+			bson.D{{Key: "expires_at", Value: bson.D{{Key: "$lte", Value: now}}}},
+		}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "owner", Value: owner},
+		{Key: "expires_at", Value: expiresAt},
+	}}}
+
+	leaseCtx, cancelLease := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelLease()
+
+	// A lease held by another owner does not match, so the upsert fails on the lease ID
+	_, errLease := m.database.Collection(OutboxLeaseCollection).UpdateOne(
+		leaseCtx,
+		filter,
+		update,
+		options.UpdateOne().SetUpsert(true),
+	)
+	if errLease != nil {
+		if mongo.IsDuplicateKeyError(errLease) {
+			return false, nil
+		}
+		logger.Log.Errorf("Error acquiring outbox lease: %v", errLease)
+
+		return false, common.NewError(errLease, common.ErrTypeInternal)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,206 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"testing"
+	"time"
+)
+
+func TestMongoDB_Outbox_Success(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	firstEvent := storage.OutboxEvent{
+		ID:            "outbox-event-1",
+		Key:           "outboxuser",
+		Payload:       []byte(`{"event_type":"created"}`),
+		CreatedAt:     now,
+		NextAttemptAt: now,
+	}
+	secondEvent := storage.OutboxEvent{
+		ID:            "outbox-event-2",
+		Key:           "outboxuser",
+		Payload:       []byte(`{"event_type":"updated"}`),
+		CreatedAt:     now.Add(time.Second),
+		NextAttemptAt: now.Add(time.Second),
+	}
+
+	// events are added in reverse order and returned in creation order
+	require.NoError(t, testMongoStorage.AddOutboxEvent(ctx, secondEvent))
+	require.NoError(t, testMongoStorage.AddOutboxEvent(ctx, firstEvent))
+
+	pending, err := testMongoStorage.PendingOutboxEvents(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, firstEvent.ID, pending[0].ID)
+	assert.Equal(t, firstEvent.Payload, pending[0].Payload)
+	assert.Equal(t, secondEvent.ID, pending[1].ID)
+
+	// a failed attempt is recorded with the next attempt time
+	nextAttemptAt := now.Add(time.Minute)
+	require.NoError(t, testMongoStorage.MarkOutboxEventFailed(ctx, firstEvent.ID, nextAttemptAt, "publish error"))
+
+	// the key waits for the retry of its oldest event, it holds back the following event
+	pending, err = testMongoStorage.PendingOutboxEvents(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	pending, err = testMongoStorage.PendingOutboxEvents(ctx, nextAttemptAt, 1)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Equal(t, "publish error", pending[0].LastError)
+	assert.Equal(t, nextAttemptAt.UnixMilli(), pending[0].NextAttemptAt.UnixMilli())
+
+	// sent events are not pending anymore
+	require.NoError(t, testMongoStorage.MarkOutboxEventSent(ctx, firstEvent.ID, now))
+	require.NoError(t, testMongoStorage.MarkOutboxEventSent(ctx, secondEvent.ID, now))
+
+	pending, err = testMongoStorage.PendingOutboxEvents(ctx, nextAttemptAt, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	err = testMongoStorage.MarkOutboxEventSent(ctx, firstEvent.ID, now)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+
+	_, errDelete := testMongoStorage.Database().Collection(OutboxCollection).DeleteMany(
+		ctx,
+		bson.D{{Key: "key", Value: "outboxuser"}},
+	)
+	require.NoError(t, errDelete)
+}
+
+func TestMongoDB_Outbox_Exhausted(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	poisonEvent := storage.OutboxEvent{
+		ID:            "outbox-poison-event",
+		Key:           "outboxpoisonuser",
+		CreatedAt:     now,
+		NextAttemptAt: now.Add(time.Minute),
+	}
+	followingEvent := storage.OutboxEvent{
+		ID:            "outbox-following-event",
+		Key:           "outboxpoisonuser",
+		CreatedAt:     now.Add(time.Second),
+		NextAttemptAt: now.Add(time.Second),
+	}
+	otherEvent := storage.OutboxEvent{
+		ID:            "outbox-other-event",
+		Key:           "outboxotheruser",
+		CreatedAt:     now.Add(2 * time.Second),
+		NextAttemptAt: now.Add(2 * time.Second),
+	}
+	require.NoError(t, testMongoStorage.AddOutboxEvent(ctx, poisonEvent))
+	require.NoError(t, testMongoStorage.AddOutboxEvent(ctx, followingEvent))
+	require.NoError(t, testMongoStorage.AddOutboxEvent(ctx, otherEvent))
+
+	// the key waiting for a retry does not hold back the other keys
+	pending, err := testMongoStorage.PendingOutboxEvents(ctx, now.Add(10*time.Second), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, otherEvent.ID, pending[0].ID)
+
+	// a failed event no longer holds back the following events of its key
+	require.NoError(t, testMongoStorage.MarkOutboxEventExhausted(ctx, poisonEvent.ID, now, "message too large"))
+
+	pending, err = testMongoStorage.PendingOutboxEvents(ctx, now.Add(10*time.Second), 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, followingEvent.ID, pending[0].ID)
+	assert.Equal(t, otherEvent.ID, pending[1].ID)
+
+	var failedEvent storage.OutboxEvent
+	require.NoError(t, testMongoStorage.Database().Collection(OutboxCollection).FindOne(
+		ctx,
+		bson.D{{Key: "_id", Value: poisonEvent.ID}},
+	).Decode(&failedEvent))
+	assert.NotNil(t, failedEvent.FailedAt)
+	assert.Equal(t, 1, failedEvent.Attempts)
+	assert.Equal(t, "message too large", failedEvent.LastError)
+
+	_, errDelete := testMongoStorage.Database().Collection(OutboxCollection).DeleteMany(
+		ctx,
+		bson.D{{Key: "key", Value: bson.D{{Key: "$in", Value: bson.A{"outboxpoisonuser", "outboxotheruser"}}}}},
+	)
+	require.NoError(t, errDelete)
+}
+
+func TestMongoDB_AcquireOutboxLease(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	acquired, err := testMongoStorage.AcquireOutboxLease(ctx, "owner-1", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// the owner renews its lease
+	acquired, err = testMongoStorage.AcquireOutboxLease(ctx, "owner-1", now.Add(time.Second), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// another owner cannot acquire the lease until it expires
+	acquired, err = testMongoStorage.AcquireOutboxLease(ctx, "owner-2", now.Add(time.Second), now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	acquired, err = testMongoStorage.AcquireOutboxLease(ctx, "owner-2", now.Add(time.Minute), now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	_, errDelete := testMongoStorage.Database().Collection(OutboxLeaseCollection).DeleteMany(ctx, bson.D{})
+	require.NoError(t, errDelete)
+}
+
+func TestMongoDB_WithTransaction_Rollback(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	userDetails := storage.UserDetails{
+		ID:        "transactionuser",
+		Nickname:  "transactionnickname",
+		Email:     "transaction@email.com",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+
+	// an error of the function rolls back the user creation and the outbox event
+	err := testMongoStorage.WithTransaction(ctx, func(ctx context.Context) error {
+		if _, errCreate := testMongoStorage.CreateUser(ctx, userDetails); errCreate != nil {
+			return errCreate
+		}
+		if errAdd := testMongoStorage.AddOutboxEvent(ctx, storage.OutboxEvent{
+			ID:            "transaction-event",
+			Key:           userDetails.ID,
+			CreatedAt:     now,
+			NextAttemptAt: now,
+		}); errAdd != nil {
+			return errAdd
+		}
+
+		return common.NewError(nil, common.ErrTypeInternal)
+	})
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInternal, errCommon.Type())
+
+	count, err := testMongoStorage.Database().Collection(UserCollection).CountDocuments(
+		ctx,
+		bson.D{{Key: "_id", Value: userDetails.ID}},
+	)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	pending, err := testMongoStorage.PendingOutboxEvents(ctx, now, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
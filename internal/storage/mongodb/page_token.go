@@ -87,3 +87,42 @@ func deserializeFilter(data string) (storage.UserFilter, error) {
 	}
 	return filter, nil
 }
+
+// generateHistoryPageToken generates the next page token for the pagination of a user history.
+// The token is a base64 encoded string that contains the user ID and the skip size, separated by a delimiter.
+func generateHistoryPageToken(userId string, skipSize int64) string {
+	return common.Base64Encode(fmt.Sprintf("%s%s%d", userId, tokenDelimiter, skipSize))
+}
+
+// parseHistoryPageToken parses the page token of a user history and returns the skip size.
+// The token must have been generated for the given user ID.
+func parseHistoryPageToken(pageToken string, userId string) (int64, error) {
+	// Decode base64
+	plainToken := common.Base64Decode(pageToken)
+	if plainToken == "" {
+		err := fmt.Errorf("cannot decode page token: %s", pageToken)
+		logger.Log.Error(err)
+
+		return 0, common.NewError(err, common.ErrTypeInvalidArgument)
+	}
+
+	// Split the token, the skip size follows the last delimiter
+	separator := strings.LastIndex(plainToken, tokenDelimiter)
+	if separator < 0 || plainToken[:separator] != userId {
+		err := fmt.Errorf("invalid page token for user %s: %s", userId, plainToken)
+		logger.Log.Error(err)
+
+		return 0, common.NewError(err, common.ErrTypeInvalidArgument)
+	}
+
+	// Parse the skip size
+	skipSize, err := strconv.ParseInt(plainToken[separator+1:], 10, 64)
+	if err != nil || skipSize < 0 {
+		err = fmt.Errorf("cannot parse skip size from page token: %s", plainToken)
+		logger.Log.Error(err)
+
+		return 0, common.NewError(err, common.ErrTypeInvalidArgument)
+	}
+
+	return skipSize, nil
+}
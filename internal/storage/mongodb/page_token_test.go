@@ -234,3 +234,47 @@ func TestParsePageToken(t *testing.T) {
 		})
 	}
 }
+
+func TestHistoryPageToken(t *testing.T) {
+	token := generateHistoryPageToken("user#id", 20)
+
+	skipSize, err := parseHistoryPageToken(token, "user#id")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20), skipSize)
+
+	tests := []struct {
+		name   string
+		token  string
+		userId string
+	}{
+		{
+			name:   "Token of another user",
+			token:  token,
+			userId: "user",
+		},
+		{
+			name:   "Invalid base64",
+			token:  "!!!",
+			userId: "user#id",
+		},
+		{
+			name:   "Invalid skip size",
+			token:  common.Base64Encode("user#id#abc"),
+			userId: "user#id",
+		},
+		{
+			name:   "Negative skip size",
+			token:  common.Base64Encode("user#id#-1"),
+			userId: "user#id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseHistoryPageToken(tt.token, tt.userId)
+			var errCommon common.Error
+			assert.ErrorAs(t, err, &errCommon)
+			assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"time"
+)
+
+// userFilterWithPrecondition returns the filter matching the not deleted user with the given ID
+// and, if a precondition is provided, the expected version
+func userFilterWithPrecondition(userId string, precondition *storage.UserPrecondition) bson.D {
+	filter := bson.D{
+		{Key: "_id", Value: userId},
+		notDeletedFilter,
+	}
+	if precondition != nil {
+		filter = append(filter, versionFilter(precondition.Version))
+	}
+
+	return filter
+}
+
+// versionFilter returns the filter matching the users with the given version.
+// Users stored before versioning have no version field and are read as version 0, so version 0 matches them too.
+func versionFilter(version int64) bson.E {
+	if version == 0 {
+		return bson.E{Key: "version", Value: bson.D{{Key: "$in", Value: bson.A{0, nil}}}}
+	}
+
+	return bson.E{Key: "version", Value: version}
+}
+
+// unmatchedUserError returns the error for a write operation that did not match any user.
+// Without a precondition the user does not exist, otherwise the user existence is checked
+// to tell a missing user from a version mismatch.
+func (m *MongoDB) unmatchedUserError(
+	ctx context.Context,
+	userId string,
+	precondition *storage.UserPrecondition,
+) error {
+	if precondition == nil {
+		return common.NewError(errors.New("user not found"), common.ErrTypeNotFound)
+	}
+
+	countCtx, cancelCount := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelCount()
+
+	count, errCount := m.database.Collection(UserCollection).CountDocuments(
+		countCtx,
+		bson.D{
+			{Key: "_id", Value: userId},
+			notDeletedFilter,
+		},
+	)
+	if errCount != nil {
+		logger.Log.Errorf("Error checking user existence: %v", errCount)
+
+		return common.NewError(errCount, common.ErrTypeInternal)
+	}
+	if count == 0 {
+		return common.NewError(errors.New("user not found"), common.ErrTypeNotFound)
+	}
+
+	return common.NewError(
+		errors.New("user version does not match the precondition"),
+		common.ErrTypeFailedPrecondition,
+	)
+}
@@ -0,0 +1,63 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"time"
+)
+
+// PurgeDeletedUsers permanently removes up to limit users soft deleted before the given time,
+// oldest deletions first, recording their purge at purgedAt, and returns the removed users with their original identifiers
+func (m *MongoDB) PurgeDeletedUsers(
+	ctx context.Context,
+	deletedBefore time.Time,
+	purgedAt time.Time,
+	limit int,
+) ([]storage.User, error) {
+	collection := m.database.Collection(UserCollection)
+
+	filter := bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$lt", Value: deletedBefore}}}}
+	opts := options.FindOneAndDelete().SetSort(bson.D{{Key: "deleted_at", Value: 1}})
+
+	var users []storage.User
+	// Users are removed one by one to return each removed document atomically
+	for len(users) < limit {
+		purgeCtx, cancelPurge := context.WithTimeout(ctx, 10*time.Second)
+
+		var user deletedUser
+		// Remove the user and record its revision in the same transaction
+		purgeErr := m.withTransaction(purgeCtx, func(ctx context.Context) error {
+			errDelete := collection.FindOneAndDelete(ctx, filter, opts).Decode(&user)
+			if errDelete != nil {
+				return errDelete
+			}
+			// The removed document holds the version of the deletion, the purge is the next change
+			user.Version++
+
+			// The user is already deleted, the purge does not change any field
+			return m.recordRevision(
+				ctx,
+				newUserRevision(ctx, storage.OperationPurged, user.ID, user.Version, nil, nil, purgedAt),
+			)
+		})
+		cancelPurge()
+		if purgeErr != nil {
+			if errors.Is(purgeErr, mongo.ErrNoDocuments) {
+				break
+			}
+			logger.Log.Errorf("Error purging deleted users: %v", purgeErr)
+
+			return users, common.NewError(purgeErr, common.ErrTypeInternal)
+		}
+
+		users = append(users, user.toStorageUser())
+	}
+
+	return users, nil
+}
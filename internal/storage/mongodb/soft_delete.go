@@ -0,0 +1,42 @@
+package mongodb
+
+import (
+	"fmt"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// notDeletedFilter is the filter element excluding soft deleted users
+var notDeletedFilter = bson.E{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}}
+
+// deletedUser is a soft deleted user document, along with the identifiers released on deletion
+type deletedUser struct {
+	storage.User     `bson:",inline"`
+	ReleasedNickname string `bson:"released_nickname,omitempty"`
+	ReleasedEmail    string `bson:"released_email,omitempty"`
+}
+
+// toStorageUser returns the storage user with its original identifiers
+func (u deletedUser) toStorageUser() storage.User {
+	user := u.User
+	if u.ReleasedNickname != "" {
+		user.Nickname = u.ReleasedNickname
+	}
+	if u.ReleasedEmail != "" {
+		user.Email = u.ReleasedEmail
+	}
+
+	return user
+}
+
+// releasedIdentifier returns the placeholder replacing a released nickname or email of a soft deleted user.
+// It is unique because it is derived from the user ID.
+func releasedIdentifier(userId string) string {
+	return fmt.Sprintf("deleted:%s", userId)
+}
+
+// incrementedVersion is the aggregation expression incrementing the user version in pipeline updates
+var incrementedVersion = bson.D{{Key: "$add", Value: bson.A{
+	bson.D{{Key: "$ifNull", Value: bson.A{"$version", 0}}},
+	1,
+}}}
@@ -0,0 +1,251 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"testing"
+	"time"
+)
+
+// newTestSoftDeleteStorage returns a storage sharing the test database with soft delete enabled
+func newTestSoftDeleteStorage(releaseIdentifiers bool) *MongoDB {
+	return &MongoDB{
+		client:   testMongoStorage.client,
+		database: testMongoStorage.database,
+		config: Config{
+			SoftDelete:                true,
+			ReleaseDeletedIdentifiers: releaseIdentifiers,
+		},
+	}
+}
+
+func TestMongoDB_SoftDeleteUser_Success(t *testing.T) {
+	softDeleteStorage := newTestSoftDeleteStorage(false)
+
+	now := time.Now().UTC()
+	userDetails := storage.UserDetails{
+		ID:        "softdeleteduser",
+		Nickname:  "softdeletednickname",
+		Email:     "softdeleted@email.com",
+		Country:   "IT",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+
+	_, err := softDeleteStorage.CreateUser(context.Background(), userDetails)
+	require.NoError(t, err)
+
+	deletedUser, err := softDeleteStorage.DeleteUser(context.Background(), userDetails.ID, now, nil)
+	require.NoError(t, err)
+	require.NotNil(t, deletedUser)
+	assert.Equal(t, userDetails.ID, deletedUser.ID)
+	assert.Equal(t, userDetails.Nickname, deletedUser.Nickname)
+	require.NotNil(t, deletedUser.DeletedAt)
+	assert.Equal(t, now.UnixMilli(), deletedUser.DeletedAt.UnixMilli())
+	assert.Equal(t, userDetails.Version+1, deletedUser.Version)
+
+	// the document is kept with the deletion timestamp
+	collection := testMongoStorage.Database().Collection(UserCollection)
+	var foundUser storage.User
+	errFind := collection.FindOne(
+		context.Background(),
+		bson.D{{Key: "_id", Value: userDetails.ID}},
+	).Decode(&foundUser)
+	require.NoError(t, errFind)
+	require.NotNil(t, foundUser.DeletedAt)
+	assert.Equal(t, now.UnixMilli(), foundUser.DeletedAt.UnixMilli())
+	assert.Equal(t, userDetails.Nickname, foundUser.Nickname)
+	assert.Equal(t, userDetails.Version+1, foundUser.Version)
+
+	// the deleted user is hidden from reads and writes
+	users, _, err := softDeleteStorage.ListUsers(
+		context.Background(),
+		storage.UserFilter{Country: &userDetails.Country},
+		10,
+		"",
+	)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	firstName := "FirstName"
+	_, _, err = softDeleteStorage.UpdateUser(
+		context.Background(),
+		userDetails.ID,
+		storage.UserUpdate{FirstName: &firstName},
+		nil,
+	)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+
+	_, err = softDeleteStorage.DeleteUser(context.Background(), userDetails.ID, now, nil)
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+
+	// identifiers are retained
+	_, err = softDeleteStorage.CreateUser(context.Background(), storage.UserDetails{
+		ID:       "anothersoftdeleteduser",
+		Nickname: userDetails.Nickname,
+		Email:    "another@email.com",
+	})
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeAlreadyExists, errCommon.Type())
+
+	// clean up the test user
+	_, errDelete := collection.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: userDetails.ID}})
+	require.NoError(t, errDelete)
+}
+
+func TestMongoDB_UndeleteUser_ReleasedIdentifiers(t *testing.T) {
+	softDeleteStorage := newTestSoftDeleteStorage(true)
+
+	now := time.Now().UTC()
+	userDetails := storage.UserDetails{
+		ID:        "releaseduser",
+		Nickname:  "releasednickname",
+		Email:     "released@email.com",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+
+	_, err := softDeleteStorage.CreateUser(context.Background(), userDetails)
+	require.NoError(t, err)
+
+	deletedUser, err := softDeleteStorage.DeleteUser(context.Background(), userDetails.ID, now, nil)
+	require.NoError(t, err)
+	// the deleted user is returned with its original identifiers
+	require.NotNil(t, deletedUser)
+	assert.Equal(t, userDetails.Nickname, deletedUser.Nickname)
+	assert.Equal(t, userDetails.Email, deletedUser.Email)
+
+	// identifiers are released and can be taken by another user
+	otherUser, err := softDeleteStorage.CreateUser(context.Background(), storage.UserDetails{
+		ID:       "releasedidentifiersowner",
+		Nickname: userDetails.Nickname,
+		Email:    userDetails.Email,
+	})
+	require.NoError(t, err)
+
+	// restore fails while identifiers are taken
+	restoredAt := now.Add(time.Minute)
+	restoredUser, err := softDeleteStorage.UndeleteUser(
+		context.Background(),
+		userDetails.ID,
+		now.Add(-time.Hour),
+		restoredAt,
+	)
+	assert.Nil(t, restoredUser)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeAlreadyExists, errCommon.Type())
+
+	_, err = softDeleteStorage.DeleteUser(context.Background(), otherUser.ID, now, nil)
+	require.NoError(t, err)
+
+	// restore fails after the grace period
+	restoredUser, err = softDeleteStorage.UndeleteUser(
+		context.Background(),
+		userDetails.ID,
+		now.Add(time.Second),
+		restoredAt,
+	)
+	assert.Nil(t, restoredUser)
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+
+	// restore succeeds with original identifiers
+	restoredUser, err = softDeleteStorage.UndeleteUser(
+		context.Background(),
+		userDetails.ID,
+		now.Add(-time.Hour),
+		restoredAt,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, userDetails.Nickname, restoredUser.Nickname)
+	assert.Equal(t, userDetails.Email, restoredUser.Email)
+	assert.Nil(t, restoredUser.DeletedAt)
+	assert.Equal(t, restoredAt.UnixMilli(), restoredUser.UpdatedAt.UnixMilli())
+	assert.Equal(t, userDetails.Version+2, restoredUser.Version)
+
+	// restore fails for a user that is not deleted
+	_, err = softDeleteStorage.UndeleteUser(context.Background(), userDetails.ID, now.Add(-time.Hour), restoredAt)
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+
+	// restore fails for a missing user
+	_, err = softDeleteStorage.UndeleteUser(context.Background(), "missinguser", now.Add(-time.Hour), restoredAt)
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+
+	// clean up the test users
+	_, errDelete := testMongoStorage.Database().Collection(UserCollection).DeleteMany(
+		context.Background(),
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: []string{userDetails.ID, otherUser.ID}}}}},
+	)
+	require.NoError(t, errDelete)
+}
+
+func TestMongoDB_PurgeDeletedUsers_Success(t *testing.T) {
+	softDeleteStorage := newTestSoftDeleteStorage(true)
+
+	now := time.Now().UTC()
+	for i, userId := range []string{"purgeduser1", "purgeduser2", "keptuser"} {
+		_, err := softDeleteStorage.CreateUser(context.Background(), storage.UserDetails{
+			ID:       userId,
+			Nickname: userId,
+			Email:    userId + "@email.com",
+			Version:  1,
+		})
+		require.NoError(t, err)
+
+		// the last user is deleted within the retention window
+		deletedAt := now.Add(time.Duration(i-2) * time.Hour)
+		if userId == "keptuser" {
+			deletedAt = now
+		}
+		_, err = softDeleteStorage.DeleteUser(context.Background(), userId, deletedAt, nil)
+		require.NoError(t, err)
+	}
+
+	purgedAt := now.Add(time.Minute)
+	purgedUsers, err := softDeleteStorage.PurgeDeletedUsers(context.Background(), now.Add(-time.Minute), purgedAt, 1)
+	require.NoError(t, err)
+	require.Len(t, purgedUsers, 1)
+	assert.Equal(t, "purgeduser1", purgedUsers[0].ID)
+	// original identifiers are returned
+	assert.Equal(t, "purgeduser1", purgedUsers[0].Nickname)
+	assert.Equal(t, "purgeduser1@email.com", purgedUsers[0].Email)
+	// the purge follows the creation and the deletion
+	assert.Equal(t, int64(3), purgedUsers[0].Version)
+
+	// the purge is recorded at the given time
+	revisions, _, err := softDeleteStorage.GetUserHistory(context.Background(), "purgeduser1", 10, "")
+	require.NoError(t, err)
+	require.Len(t, revisions, 3)
+	assert.Equal(t, storage.OperationPurged, revisions[2].Operation)
+	assert.Equal(t, int64(3), revisions[2].Version)
+	assert.Equal(t, purgedAt.UnixMilli(), revisions[2].ChangedAt.UnixMilli())
+
+	purgedUsers, err = softDeleteStorage.PurgeDeletedUsers(context.Background(), now.Add(-time.Minute), purgedAt, 10)
+	require.NoError(t, err)
+	require.Len(t, purgedUsers, 1)
+	assert.Equal(t, "purgeduser2", purgedUsers[0].ID)
+
+	collection := testMongoStorage.Database().Collection(UserCollection)
+	count, err := collection.CountDocuments(
+		context.Background(),
+		bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: []string{"purgeduser1", "purgeduser2", "keptuser"}}}}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// clean up the test user
+	_, errDelete := collection.DeleteOne(context.Background(), bson.D{{Key: "_id", Value: "keptuser"}})
+	require.NoError(t, errDelete)
+}
@@ -0,0 +1,48 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// WithTransaction runs the given function in a transaction, storage operations using the context it receives are part of it.
+// Storage operations running their own transaction join the given one, so either all or none of them are applied.
+func (m *MongoDB) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	errTransaction := m.withTransaction(ctx, fn)
+	if errTransaction != nil {
+		// Errors of the function are returned as they are, transaction errors are internal
+		var errCommon common.Error
+		if errors.As(errTransaction, &errCommon) {
+			return errTransaction
+		}
+		logger.Log.Errorf("Error running transaction: %v", errTransaction)
+
+		return common.NewError(errTransaction, common.ErrTypeInternal)
+	}
+
+	return nil
+}
+
+// withTransaction runs the given function in a transaction, along with every operation using the context it receives.
+// If the context already carries a transaction, the function joins it.
+// Transient transaction errors are retried, other errors are returned as they are.
+func (m *MongoDB) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+
+	session, errSession := m.client.StartSession()
+	if errSession != nil {
+		return errSession
+	}
+	defer session.EndSession(ctx)
+
+	_, errTransaction := session.WithTransaction(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+
+	return errTransaction
+}
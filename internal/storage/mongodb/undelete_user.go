@@ -0,0 +1,108 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"time"
+)
+
+// UndeleteUser restores a soft deleted user if it was deleted since the given time.
+// Released nickname and email are restored too, failing if they have been taken by another user in the meantime.
+func (m *MongoDB) UndeleteUser(
+	ctx context.Context,
+	userId string,
+	deletedSince time.Time,
+	restoredAt time.Time,
+) (*storage.User, error) {
+	collection := m.database.Collection(UserCollection)
+
+	filter := bson.D{
+		{Key: "_id", Value: userId},
+		{Key: "deleted_at", Value: bson.D{{Key: "$gte", Value: deletedSince}}},
+	}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "nickname", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$released_nickname", "$nickname"}}}},
+			{Key: "email", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$released_email", "$email"}}}},
+			{Key: "updated_at", Value: restoredAt},
+			{Key: "version", Value: incrementedVersion},
+		}}},
+		{{Key: "$unset", Value: bson.A{"deleted_at", "released_nickname", "released_email"}}},
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(false).
+		SetReturnDocument(options.After)
+
+	undeleteCtx, cancelUndelete := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelUndelete()
+
+	var user storage.User
+
+	// Restore the user and record its revision in the same transaction
+	undeleteErr := m.withTransaction(undeleteCtx, func(ctx context.Context) error {
+		errUpdate := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&user)
+		if errUpdate != nil {
+			return errUpdate
+		}
+
+		return m.recordRevision(
+			ctx,
+			newUserRevision(ctx, storage.OperationRestored, userId, user.Version, nil, &user, restoredAt),
+		)
+	})
+	if undeleteErr != nil {
+		if errors.Is(undeleteErr, mongo.ErrNoDocuments) {
+			logger.Log.Debugf("Error undeleting user: %v", undeleteErr)
+
+			return nil, m.unmatchedDeletedUserError(ctx, userId)
+		} else if mongo.IsDuplicateKeyError(undeleteErr) {
+			logger.Log.Debugf("Error undeleting user: %v", undeleteErr)
+
+			return nil, common.NewError(
+				errors.New("another user with same nickname or email already exists"),
+				common.ErrTypeAlreadyExists,
+			)
+		} else {
+			logger.Log.Errorf("Error undeleting user: %v", undeleteErr)
+
+			return nil, common.NewError(undeleteErr, common.ErrTypeInternal)
+		}
+	}
+
+	return &user, nil
+}
+
+// unmatchedDeletedUserError returns the error for an undelete operation that did not match any user,
+// telling a missing user from a user that is not deleted or deleted before the restore grace period
+func (m *MongoDB) unmatchedDeletedUserError(ctx context.Context, userId string) error {
+	findCtx, cancelFind := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelFind()
+
+	var user storage.User
+	findErr := m.database.Collection(UserCollection).FindOne(
+		findCtx,
+		bson.D{{Key: "_id", Value: userId}},
+	).Decode(&user)
+	if findErr != nil {
+		if errors.Is(findErr, mongo.ErrNoDocuments) {
+			return common.NewError(errors.New("user not found"), common.ErrTypeNotFound)
+		}
+		logger.Log.Errorf("Error checking deleted user: %v", findErr)
+
+		return common.NewError(findErr, common.ErrTypeInternal)
+	}
+	if user.DeletedAt == nil {
+		return common.NewError(errors.New("user is not deleted"), common.ErrTypeFailedPrecondition)
+	}
+
+	return common.NewError(
+		errors.New("user was deleted before the restore grace period"),
+		common.ErrTypeFailedPrecondition,
+	)
+}
@@ -12,41 +12,92 @@ import (
 	"time"
 )
 
-func (m *MongoDB) UpdateUser(ctx context.Context, userId string, userUpdate storage.UserUpdate) (*storage.User, error) {
+// UpdateUser updates the user with the given ID and returns it along with the user before the update.
+// If the update does not change any field, the user is left untouched, without bumping its version.
+func (m *MongoDB) UpdateUser(
+	ctx context.Context,
+	userId string,
+	userUpdate storage.UserUpdate,
+	precondition *storage.UserPrecondition,
+) (*storage.User, *storage.User, error) {
 	collection := m.database.Collection(UserCollection)
 
-	filter := bson.D{{Key: "_id", Value: userId}}
-	update := bson.D{{Key: "$set", Value: userUpdate}}
+	filter := userFilterWithPrecondition(userId, precondition)
+	update := bson.D{
+		{Key: "$set", Value: userUpdate},
+		// Every change bumps the user version
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
 	opts := options.FindOneAndUpdate().
-		SetUpsert(false).                // Do not create a new document if the filter does not match
-		SetReturnDocument(options.After) // Return the updated document
+		SetUpsert(false).                 // Do not create a new document if the filter does not match
+		SetReturnDocument(options.Before) // Return the document before the update, to record the changes
 
 	updateCtx, cancelUpdate := context.WithTimeout(ctx, 10*time.Second)
 	defer cancelUpdate()
 
-	var user storage.User
+	var user, before storage.User
+
+	// Update the user in the database and record its revision in the same transaction
+	updateErr := m.withTransaction(updateCtx, func(ctx context.Context) error {
+		// Find the current user first, to leave it untouched if the update changes nothing
+		errFind := collection.FindOne(ctx, filter).Decode(&before)
+		if errFind != nil {
+			return errFind
+		}
+		if !changesUser(before, userUpdate) {
+			user = before
+
+			return nil
+		}
+
+		errUpdate := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&before)
+		if errUpdate != nil {
+			return errUpdate
+		}
 
-	// Update the user in the database and return the updated document in a single operation
-	updateRes := collection.FindOneAndUpdate(updateCtx, filter, update, opts)
-	updateErr := updateRes.Decode(&user)
+		// Find the updated user to return, the transaction reads its own writes
+		errFind = collection.FindOne(ctx, bson.D{{Key: "_id", Value: userId}}).Decode(&user)
+		if errFind != nil {
+			return errFind
+		}
+
+		return m.recordRevision(
+			ctx,
+			newUserRevision(ctx, storage.OperationUpdated, userId, user.Version, &before, &user, user.UpdatedAt),
+		)
+	})
 	if updateErr != nil {
-		if errors.Is(updateErr, mongo.ErrNoDocuments) { // Check if the error is due to the user not being found
+		if errors.Is(updateErr, mongo.ErrNoDocuments) { // Check if the error is due to the user not being matched
 			logger.Log.Debugf("Error updating user: %v", updateErr)
 
-			return nil, common.NewError(errors.New("user not found"), common.ErrTypeNotFound)
+			return nil, nil, m.unmatchedUserError(ctx, userId, precondition)
 		} else if mongo.IsDuplicateKeyError(updateErr) { // Check for duplicate key error
 			logger.Log.Debugf("Error updating user: %v", updateErr)
 
-			return nil, common.NewError(
+			return nil, nil, common.NewError(
 				errors.New("another user with same nickname or email already exists"),
 				common.ErrTypeAlreadyExists,
 			)
 		} else {
 			logger.Log.Errorf("Error updating user: %v", updateErr)
 
-			return nil, common.NewError(updateErr, common.ErrTypeInternal)
+			return nil, nil, common.NewError(updateErr, common.ErrTypeInternal)
 		}
 	}
 
-	return &user, nil
+	return &user, &before, nil
+}
+
+// changesUser tells if a user update sets any field to a value different from the current one of the user.
+// The update timestamp is not considered, since it is set by every update.
+func changesUser(user storage.User, userUpdate storage.UserUpdate) bool {
+	changes := func(value *string, current string) bool {
+		return value != nil && *value != current
+	}
+
+	return changes(userUpdate.FirstName, user.FirstName) ||
+		changes(userUpdate.LastName, user.LastName) ||
+		changes(userUpdate.Nickname, user.Nickname) ||
+		changes(userUpdate.Email, user.Email) ||
+		changes(userUpdate.Country, user.Country)
 }
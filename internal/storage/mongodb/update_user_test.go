@@ -26,6 +26,7 @@ func TestMongoDB_UpdateUser_Success(t *testing.T) {
 		Country:      "country",
 		CreatedAt:    createdAt,
 		UpdatedAt:    createdAt,
+		Version:      1,
 	}
 
 	// create a user to ensure it exists
@@ -46,9 +47,15 @@ func TestMongoDB_UpdateUser_Success(t *testing.T) {
 		UpdatedAt: &updatedAt,
 	}
 
-	updatedUser, err := testMongoStorage.UpdateUser(context.Background(), userId, userUpdate)
+	updatedUser, previousUser, err := testMongoStorage.UpdateUser(context.Background(), userId, userUpdate, nil)
 	require.NoError(t, err)
 
+	// the previous user holds the values before the update
+	require.NotNil(t, previousUser)
+	assert.Equal(t, userDetails.FirstName, previousUser.FirstName)
+	assert.Equal(t, userDetails.LastName, previousUser.LastName)
+	assert.Equal(t, userDetails.Version, previousUser.Version)
+
 	assert.NotNil(t, updatedUser)
 	assert.Equal(t, userId, updatedUser.ID)
 	assert.Equal(t, *userUpdate.FirstName, updatedUser.FirstName)
@@ -58,6 +65,7 @@ func TestMongoDB_UpdateUser_Success(t *testing.T) {
 	assert.Equal(t, userDetails.Country, updatedUser.Country)
 	assert.Equal(t, userDetails.CreatedAt.UnixMilli(), updatedUser.CreatedAt.UnixMilli())
 	assert.Equal(t, userUpdate.UpdatedAt.UnixMilli(), updatedUser.UpdatedAt.UnixMilli())
+	assert.Equal(t, userDetails.Version+1, updatedUser.Version)
 
 	// verify the user is updated in the database
 	var foundUser storage.User
@@ -82,6 +90,49 @@ func TestMongoDB_UpdateUser_Success(t *testing.T) {
 	require.NoError(t, errDelete)
 }
 
+func TestMongoDB_UpdateUser_NoChanges(t *testing.T) {
+	userId := "unchangeduser"
+
+	createdAt := time.Now().UTC().Add(-time.Hour)
+
+	userDetails := storage.UserDetails{
+		ID:        userId,
+		FirstName: "FirstName",
+		Nickname:  "unchangednickname",
+		Email:     "unchanged@email.com",
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Version:   1,
+	}
+
+	_, err := testMongoStorage.CreateUser(context.Background(), userDetails)
+	require.NoError(t, err)
+
+	// the update sets the current values only
+	updatedAt := createdAt.Add(time.Hour)
+	userUpdate := storage.UserUpdate{
+		FirstName: &userDetails.FirstName,
+		Email:     &userDetails.Email,
+		UpdatedAt: &updatedAt,
+	}
+
+	updatedUser, previousUser, err := testMongoStorage.UpdateUser(context.Background(), userId, userUpdate, nil)
+	require.NoError(t, err)
+	require.NotNil(t, updatedUser)
+	assert.Equal(t, previousUser, updatedUser)
+
+	// the user is left untouched
+	assert.Equal(t, userDetails.Version, updatedUser.Version)
+	assert.Equal(t, createdAt.UnixMilli(), updatedUser.UpdatedAt.UnixMilli())
+
+	// clean up the test user
+	_, errDelete := testMongoStorage.Database().Collection(UserCollection).DeleteOne(
+		context.Background(),
+		bson.D{{Key: "_id", Value: userId}},
+	)
+	require.NoError(t, errDelete)
+}
+
 func TestMongoDB_UpdateUser_AlreadyExistsError(t *testing.T) {
 	userId := "existinguser"
 
@@ -121,7 +172,7 @@ func TestMongoDB_UpdateUser_AlreadyExistsError(t *testing.T) {
 	userUpdate.Nickname = &userDetails2.Nickname
 	userUpdate.UpdatedAt = &now
 
-	user, err := testMongoStorage.UpdateUser(context.Background(), userId, userUpdate)
+	user, _, err := testMongoStorage.UpdateUser(context.Background(), userId, userUpdate, nil)
 	assert.Error(t, err)
 	assert.Nil(t, user)
 	var errCommon common.Error
@@ -146,10 +197,129 @@ func TestMongoDB_UpdateUser_NotFoundError(t *testing.T) {
 
 	userUpdate := storage.UserUpdate{}
 
-	updatedUser, err := testMongoStorage.UpdateUser(context.Background(), userId, userUpdate)
+	updatedUser, _, err := testMongoStorage.UpdateUser(context.Background(), userId, userUpdate, nil)
+	assert.Error(t, err)
+	assert.Nil(t, updatedUser)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+}
+
+func TestMongoDB_UpdateUser_FailedPreconditionError(t *testing.T) {
+	userId := "versioneduser"
+
+	now := time.Now().UTC()
+
+	userDetails := storage.UserDetails{
+		ID:        userId,
+		FirstName: "FirstName",
+		Nickname:  "versionednickname",
+		Email:     "versioned@email.com",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+
+	_, err := testMongoStorage.CreateUser(context.Background(), userDetails)
+	require.NoError(t, err)
+
+	firstName := "NewFirstName"
+	userUpdate := storage.UserUpdate{
+		FirstName: &firstName,
+		UpdatedAt: &now,
+	}
+
+	// update with the current version succeeds and bumps the version
+	updatedUser, _, err := testMongoStorage.UpdateUser(
+		context.Background(),
+		userId,
+		userUpdate,
+		&storage.UserPrecondition{Version: 1},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updatedUser.Version)
+
+	// update with the stale version fails
+	updatedUser, _, err = testMongoStorage.UpdateUser(
+		context.Background(),
+		userId,
+		userUpdate,
+		&storage.UserPrecondition{Version: 1},
+	)
 	assert.Error(t, err)
 	assert.Nil(t, updatedUser)
 	var errCommon common.Error
 	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+
+	// update of a missing user with a precondition is still not found
+	updatedUser, _, err = testMongoStorage.UpdateUser(
+		context.Background(),
+		"missingversioneduser",
+		userUpdate,
+		&storage.UserPrecondition{Version: 1},
+	)
+	assert.Error(t, err)
+	assert.Nil(t, updatedUser)
+	assert.ErrorAs(t, err, &errCommon)
 	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+
+	// clean up the test user
+	_, errDelete := testMongoStorage.Database().Collection(UserCollection).DeleteOne(
+		context.Background(),
+		bson.D{{Key: "_id", Value: userId}},
+	)
+	require.NoError(t, errDelete)
+}
+
+func TestMongoDB_UpdateUser_PreconditionUnversionedUser(t *testing.T) {
+	userId := "unversioneduser"
+
+	now := time.Now().UTC()
+
+	// the user is stored as before versioning, without a version field
+	_, err := testMongoStorage.Database().Collection(UserCollection).InsertOne(context.Background(), bson.D{
+		{Key: "_id", Value: userId},
+		{Key: "first_name", Value: "FirstName"},
+		{Key: "nickname", Value: "unversionednickname"},
+		{Key: "email", Value: "unversioned@email.com"},
+		{Key: "created_at", Value: now},
+		{Key: "updated_at", Value: now},
+	})
+	require.NoError(t, err)
+
+	firstName := "NewFirstName"
+	userUpdate := storage.UserUpdate{
+		FirstName: &firstName,
+		UpdatedAt: &now,
+	}
+
+	// the user is read as version 0, which matches the precondition
+	updatedUser, _, err := testMongoStorage.UpdateUser(
+		context.Background(),
+		userId,
+		userUpdate,
+		&storage.UserPrecondition{Version: 0},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), updatedUser.Version)
+
+	// once versioned, version 0 is stale
+	updatedUser, _, err = testMongoStorage.UpdateUser(
+		context.Background(),
+		userId,
+		userUpdate,
+		&storage.UserPrecondition{Version: 0},
+	)
+	assert.Nil(t, updatedUser)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeFailedPrecondition, errCommon.Type())
+
+	// clean up the test user
+	_, errDelete := testMongoStorage.Database().Collection(UserCollection).DeleteOne(
+		context.Background(),
+		bson.D{{Key: "_id", Value: userId}},
+	)
+	require.NoError(t, errDelete)
 }
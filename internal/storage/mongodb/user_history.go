@@ -0,0 +1,69 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"time"
+)
+
+const UserHistoryCollection = "user_history"
+
+// userFields are the names of the user fields tracked by the history and change events, the password is not tracked
+var userFields = []string{"first_name", "last_name", "nickname", "email", "country"}
+
+// recordRevision stores the revision of a user change, it is meant to be called in the transaction of the change
+func (m *MongoDB) recordRevision(ctx context.Context, revision storage.UserRevision) error {
+	_, errInsert := m.database.Collection(UserHistoryCollection).InsertOne(ctx, revision)
+
+	return errInsert
+}
+
+// newUserRevision returns the revision of a user change, performed by the actor carried by the context.
+// A nil before or after user stands for a user that does not exist or is deleted.
+func newUserRevision(
+	ctx context.Context,
+	operation storage.UserOperation,
+	userId string,
+	version int64,
+	before *storage.User,
+	after *storage.User,
+	changedAt time.Time,
+) storage.UserRevision {
+	return storage.UserRevision{
+		ID:        bson.NewObjectID().Hex(),
+		UserID:    userId,
+		Version:   version,
+		Operation: operation,
+		Changes:   userChanges(before, after),
+		Actor:     common.ActorFromContext(ctx),
+		ChangedAt: changedAt,
+	}
+}
+
+// userChanges returns the changes of the user fields between the before and after users.
+// The password is never part of the history.
+func userChanges(before *storage.User, after *storage.User) []storage.UserFieldChange {
+	fields := func(user *storage.User) []string {
+		if user == nil {
+			return make([]string, 5)
+		}
+
+		return []string{user.FirstName, user.LastName, user.Nickname, user.Email, user.Country}
+	}
+	beforeFields, afterFields := fields(before), fields(after)
+
+	changes := make([]storage.UserFieldChange, 0)
+	for i, name := range userFields {
+		if beforeFields[i] != afterFields[i] {
+			changes = append(changes, storage.UserFieldChange{
+				Field:  name,
+				Before: beforeFields[i],
+				After:  afterFields[i],
+			})
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,193 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"testing"
+	"time"
+)
+
+func TestUserChanges(t *testing.T) {
+	user := &storage.User{
+		ID:        "user-id",
+		FirstName: "John",
+		Nickname:  "johndoe",
+		Email:     "john@doe.com",
+		Version:   1,
+	}
+	updatedUser := &storage.User{
+		ID:       "user-id",
+		LastName: "Doe",
+		Nickname: "johndoe",
+		Email:    "john.doe@doe.com",
+		Version:  2,
+	}
+
+	tests := []struct {
+		name   string
+		before *storage.User
+		after  *storage.User
+		want   []storage.UserFieldChange
+	}{
+		{
+			name:  "Created user",
+			after: user,
+			want: []storage.UserFieldChange{
+				{Field: "first_name", After: "John"},
+				{Field: "nickname", After: "johndoe"},
+				{Field: "email", After: "john@doe.com"},
+			},
+		},
+		{
+			name:   "Updated user",
+			before: user,
+			after:  updatedUser,
+			want: []storage.UserFieldChange{
+				{Field: "first_name", Before: "John"},
+				{Field: "last_name", After: "Doe"},
+				{Field: "email", Before: "john@doe.com", After: "john.doe@doe.com"},
+			},
+		},
+		{
+			name:   "Deleted user",
+			before: user,
+			want: []storage.UserFieldChange{
+				{Field: "first_name", Before: "John"},
+				{Field: "nickname", Before: "johndoe"},
+				{Field: "email", Before: "john@doe.com"},
+			},
+		},
+		{
+			name:   "Unchanged user",
+			before: user,
+			after:  user,
+			want:   []storage.UserFieldChange{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, userChanges(tt.before, tt.after))
+		})
+	}
+}
+
+func TestMongoDB_UserHistory_Success(t *testing.T) {
+	softDeleteStorage := newTestSoftDeleteStorage(true)
+	ctx := common.WithActor(context.Background(), "admin")
+
+	now := time.Now().UTC()
+	userDetails := storage.UserDetails{
+		ID:        "historyuser",
+		FirstName: "John",
+		Nickname:  "historynickname",
+		Email:     "history@email.com",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+
+	_, err := softDeleteStorage.CreateUser(ctx, userDetails)
+	require.NoError(t, err)
+
+	// a rejected change is not recorded
+	firstName := "Johnny"
+	_, _, err = softDeleteStorage.UpdateUser(
+		ctx,
+		userDetails.ID,
+		storage.UserUpdate{FirstName: &firstName},
+		&storage.UserPrecondition{Version: 5},
+	)
+	require.Error(t, err)
+
+	updatedAt := now.Add(time.Minute)
+	_, _, err = softDeleteStorage.UpdateUser(
+		ctx,
+		userDetails.ID,
+		storage.UserUpdate{FirstName: &firstName, UpdatedAt: &updatedAt},
+		nil,
+	)
+	require.NoError(t, err)
+
+	_, err = softDeleteStorage.DeleteUser(ctx, userDetails.ID, now.Add(2*time.Minute), nil)
+	require.NoError(t, err)
+
+	// changes of the service itself are recorded with the system actor
+	_, err = softDeleteStorage.UndeleteUser(
+		common.WithActor(context.Background(), common.SystemActor),
+		userDetails.ID,
+		now,
+		now.Add(3*time.Minute),
+	)
+	require.NoError(t, err)
+
+	// the history is paginated in chronological order
+	firstPage, nextPageToken, err := softDeleteStorage.GetUserHistory(context.Background(), userDetails.ID, 3, "")
+	require.NoError(t, err)
+	require.Len(t, firstPage, 3)
+	require.NotEmpty(t, nextPageToken)
+
+	secondPage, lastPageToken, err := softDeleteStorage.GetUserHistory(
+		context.Background(),
+		userDetails.ID,
+		3,
+		nextPageToken,
+	)
+	require.NoError(t, err)
+	require.Len(t, secondPage, 1)
+	assert.Empty(t, lastPageToken)
+
+	revisions := append(firstPage, secondPage...)
+
+	assert.Equal(t, storage.OperationCreated, revisions[0].Operation)
+	assert.Equal(t, int64(1), revisions[0].Version)
+	assert.Equal(t, "admin", revisions[0].Actor)
+	assert.Equal(t, now.UnixMilli(), revisions[0].ChangedAt.UnixMilli())
+	assert.Equal(t, []storage.UserFieldChange{
+		{Field: "first_name", After: "John"},
+		{Field: "nickname", After: "historynickname"},
+		{Field: "email", After: "history@email.com"},
+	}, revisions[0].Changes)
+
+	assert.Equal(t, storage.OperationUpdated, revisions[1].Operation)
+	assert.Equal(t, int64(2), revisions[1].Version)
+	assert.Equal(t, updatedAt.UnixMilli(), revisions[1].ChangedAt.UnixMilli())
+	assert.Equal(t, []storage.UserFieldChange{
+		{Field: "first_name", Before: "John", After: "Johnny"},
+	}, revisions[1].Changes)
+
+	// the original identifiers are recorded even if released
+	assert.Equal(t, storage.OperationDeleted, revisions[2].Operation)
+	assert.Equal(t, int64(3), revisions[2].Version)
+	assert.Equal(t, []storage.UserFieldChange{
+		{Field: "first_name", Before: "Johnny"},
+		{Field: "nickname", Before: "historynickname"},
+		{Field: "email", Before: "history@email.com"},
+	}, revisions[2].Changes)
+
+	assert.Equal(t, storage.OperationRestored, revisions[3].Operation)
+	assert.Equal(t, int64(4), revisions[3].Version)
+	assert.Equal(t, common.SystemActor, revisions[3].Actor)
+
+	// the page token is bound to the user
+	_, _, err = softDeleteStorage.GetUserHistory(context.Background(), "anotheruser", 3, nextPageToken)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeInvalidArgument, errCommon.Type())
+
+	// clean up the test user and its history
+	_, errDelete := testMongoStorage.Database().Collection(UserCollection).DeleteOne(
+		context.Background(),
+		bson.D{{Key: "_id", Value: userDetails.ID}},
+	)
+	require.NoError(t, errDelete)
+	_, errDelete = testMongoStorage.Database().Collection(UserHistoryCollection).DeleteMany(
+		context.Background(),
+		bson.D{{Key: "user_id", Value: userDetails.ID}},
+	)
+	require.NoError(t, errDelete)
+}
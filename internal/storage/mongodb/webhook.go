@@ -0,0 +1,128 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/logger"
+	"github.com/alenalato/users-service/internal/storage"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"time"
+)
+
+const WebhookSubscriptionCollection = "webhook_subscription"
+const WebhookDeliveryCollection = "webhook_delivery"
+
+// webhookDeliveryRetention is the period deliveries are kept in the delivery log before expiring
+const webhookDeliveryRetention = 30 * 24 * time.Hour
+
+var _ storage.WebhookStorage = new(MongoDB)
+
+// CreateWebhookSubscription stores a webhook subscription and returns it
+func (m *MongoDB) CreateWebhookSubscription(
+	ctx context.Context,
+	subscription storage.WebhookSubscription,
+) (*storage.WebhookSubscription, error) {
+	insertCtx, cancelInsert := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelInsert()
+
+	if subscription.EventTypes == nil {
+		// Store an empty array, so subscriptions to all event types are matched by size
+		subscription.EventTypes = []string{}
+	}
+
+	_, errInsert := m.database.Collection(WebhookSubscriptionCollection).InsertOne(insertCtx, subscription)
+	if errInsert != nil {
+		if mongo.IsDuplicateKeyError(errInsert) {
+			logger.Log.Debugf("Error creating webhook subscription: %v", errInsert)
+
+			return nil, common.NewError(
+				errors.New("another webhook subscription with same ID already exists"),
+				common.ErrTypeAlreadyExists,
+			)
+		}
+		logger.Log.Errorf("Error creating webhook subscription: %v", errInsert)
+
+		return nil, common.NewError(errInsert, common.ErrTypeInternal)
+	}
+
+	return &subscription, nil
+}
+
+// ListWebhookSubscriptions returns the subscriptions receiving the given event type in creation order.
+// Subscriptions without event types receive all of them, all subscriptions are returned if the event type is empty.
+func (m *MongoDB) ListWebhookSubscriptions(
+	ctx context.Context,
+	eventType string,
+) ([]storage.WebhookSubscription, error) {
+	filter := bson.D{}
+	if eventType != "" {
+		filter = bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "event_types", Value: eventType}},
+			bson.D{{Key: "event_types", Value: bson.D{{Key: "$size", Value: 0}}}},
+		}}}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	findCtx, cancelFind := context.WithTimeout(ctx, 10*time.Second)
+	defer cancelFind()
+
+	cursor, errFind := m.database.Collection(WebhookSubscriptionCollection).Find(findCtx, filter, opts)
+	if errFind != nil {
+		logger.Log.Errorf("Error finding webhook subscriptions: %v", errFind)
+
+		return nil, common.NewError(errFind, common.ErrTypeInternal)
+	}
+
+	var subscriptions []storage.WebhookSubscription
+	if errCurs := cursor.All(findCtx, &subscriptions); errCurs != nil {
+		logger.Log.Errorf("Error decoding webhook subscriptions: %v", errCurs)
+
+		return nil, common.NewError(errCurs, common.ErrTypeInternal)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteWebhookSubscription deletes the webhook subscription with the given ID and returns it
+func (m *MongoDB) DeleteWebhookSubscription(
+	ctx context.Context,
+	subscriptionId string,
+) (*storage.WebhookSubscription, error) {
+	deleteCtx, cancelDelete := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelDelete()
+
+	var subscription storage.WebhookSubscription
+	errDelete := m.database.Collection(WebhookSubscriptionCollection).
+		FindOneAndDelete(deleteCtx, bson.D{{Key: "_id", Value: subscriptionId}}).
+		Decode(&subscription)
+	if errDelete != nil {
+		if errors.Is(errDelete, mongo.ErrNoDocuments) {
+			logger.Log.Debugf("Webhook subscription not found: %s", subscriptionId)
+
+			return nil, common.NewError(errors.New("webhook subscription not found"), common.ErrTypeNotFound)
+		}
+		logger.Log.Errorf("Error deleting webhook subscription: %v", errDelete)
+
+		return nil, common.NewError(errDelete, common.ErrTypeInternal)
+	}
+
+	return &subscription, nil
+}
+
+// AddWebhookDelivery records a delivery in the delivery log, it expires after a retention period
+func (m *MongoDB) AddWebhookDelivery(ctx context.Context, delivery storage.WebhookDelivery) error {
+	insertCtx, cancelInsert := context.WithTimeout(ctx, 5*time.Second)
+	defer cancelInsert()
+
+	_, errInsert := m.database.Collection(WebhookDeliveryCollection).InsertOne(insertCtx, delivery)
+	if errInsert != nil {
+		logger.Log.Errorf("Error adding webhook delivery: %v", errInsert)
+
+		return common.NewError(errInsert, common.ErrTypeInternal)
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/alenalato/users-service/internal/common"
+	"github.com/alenalato/users-service/internal/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"testing"
+	"time"
+)
+
+func TestMongoDB_WebhookSubscriptions_Success(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	allEvents := storage.WebhookSubscription{
+		ID:        "webhook-all",
+		URL:       "https://example.com/all",
+		Secret:    "secret-all",
+		CreatedAt: now,
+	}
+	deletedEvents := storage.WebhookSubscription{
+		ID:         "webhook-deleted",
+		URL:        "https://example.com/deleted",
+		Secret:     "secret-deleted",
+		EventTypes: []string{"deleted", "purged"},
+		CreatedAt:  now.Add(time.Second),
+	}
+
+	created, err := testMongoStorage.CreateWebhookSubscription(ctx, deletedEvents)
+	require.NoError(t, err)
+	assert.Equal(t, deletedEvents, *created)
+	_, err = testMongoStorage.CreateWebhookSubscription(ctx, allEvents)
+	require.NoError(t, err)
+
+	_, err = testMongoStorage.CreateWebhookSubscription(ctx, allEvents)
+	var errCommon common.Error
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeAlreadyExists, errCommon.Type())
+
+	// all subscriptions are listed in creation order
+	subscriptions, err := testMongoStorage.ListWebhookSubscriptions(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 2)
+	assert.Equal(t, allEvents.ID, subscriptions[0].ID)
+	assert.Equal(t, deletedEvents.ID, subscriptions[1].ID)
+
+	// subscriptions without event types receive all of them
+	subscriptions, err = testMongoStorage.ListWebhookSubscriptions(ctx, "created")
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, allEvents.ID, subscriptions[0].ID)
+
+	subscriptions, err = testMongoStorage.ListWebhookSubscriptions(ctx, "purged")
+	require.NoError(t, err)
+	assert.Len(t, subscriptions, 2)
+
+	deleted, err := testMongoStorage.DeleteWebhookSubscription(ctx, deletedEvents.ID)
+	require.NoError(t, err)
+	assert.Equal(t, deletedEvents.URL, deleted.URL)
+
+	_, err = testMongoStorage.DeleteWebhookSubscription(ctx, deletedEvents.ID)
+	assert.ErrorAs(t, err, &errCommon)
+	assert.Equal(t, common.ErrTypeNotFound, errCommon.Type())
+
+	_, err = testMongoStorage.DeleteWebhookSubscription(ctx, allEvents.ID)
+	require.NoError(t, err)
+}
+
+func TestMongoDB_AddWebhookDelivery(t *testing.T) {
+	ctx := context.Background()
+
+	delivery := storage.WebhookDelivery{
+		ID:             "webhook-delivery-1",
+		SubscriptionID: "webhook-all",
+		EventID:        "event-1",
+		EventType:      "created",
+		UserID:         "webhookuser",
+		URL:            "https://example.com/all",
+		Attempts:       3,
+		StatusCode:     503,
+		Error:          "unexpected status code 503",
+		DeliveredAt:    time.Now().UTC(),
+	}
+	require.NoError(t, testMongoStorage.AddWebhookDelivery(ctx, delivery))
+
+	var stored storage.WebhookDelivery
+	require.NoError(t, testMongoStorage.Database().Collection(WebhookDeliveryCollection).FindOne(
+		ctx,
+		bson.D{{Key: "_id", Value: delivery.ID}},
+	).Decode(&stored))
+	assert.Equal(t, delivery.Attempts, stored.Attempts)
+	assert.Equal(t, delivery.StatusCode, stored.StatusCode)
+	assert.False(t, stored.Succeeded)
+
+	_, errDelete := testMongoStorage.Database().Collection(WebhookDeliveryCollection).DeleteMany(ctx, bson.D{})
+	require.NoError(t, errDelete)
+}
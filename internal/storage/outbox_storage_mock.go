@@ -0,0 +1,128 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/alenalato/users-service/internal/storage (interfaces: OutboxStorage)
+//
+// Generated by this command:
+//
+//	mockgen -destination=outbox_storage_mock.go -package=storage github.com/alenalato/users-service/internal/storage OutboxStorage
+//
+
+// Package storage is a generated GoMock package.
+package storage
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOutboxStorage is a mock of OutboxStorage interface.
+type MockOutboxStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxStorageMockRecorder
+	isgomock struct{}
+}
+
+// MockOutboxStorageMockRecorder is the mock recorder for MockOutboxStorage.
+type MockOutboxStorageMockRecorder struct {
+	mock *MockOutboxStorage
+}
+
+// NewMockOutboxStorage creates a new mock instance.
+func NewMockOutboxStorage(ctrl *gomock.Controller) *MockOutboxStorage {
+	mock := &MockOutboxStorage{ctrl: ctrl}
+	mock.recorder = &MockOutboxStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxStorage) EXPECT() *MockOutboxStorageMockRecorder {
+	return m.recorder
+}
+
+// AcquireOutboxLease mocks base method.
+func (m *MockOutboxStorage) AcquireOutboxLease(ctx context.Context, owner string, now, expiresAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireOutboxLease", ctx, owner, now, expiresAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireOutboxLease indicates an expected call of AcquireOutboxLease.
+func (mr *MockOutboxStorageMockRecorder) AcquireOutboxLease(ctx, owner, now, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireOutboxLease", reflect.TypeOf((*MockOutboxStorage)(nil).AcquireOutboxLease), ctx, owner, now, expiresAt)
+}
+
+// AddOutboxEvent mocks base method.
+func (m *MockOutboxStorage) AddOutboxEvent(ctx context.Context, outboxEvent OutboxEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddOutboxEvent", ctx, outboxEvent)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddOutboxEvent indicates an expected call of AddOutboxEvent.
+func (mr *MockOutboxStorageMockRecorder) AddOutboxEvent(ctx, outboxEvent any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddOutboxEvent", reflect.TypeOf((*MockOutboxStorage)(nil).AddOutboxEvent), ctx, outboxEvent)
+}
+
+// MarkOutboxEventExhausted mocks base method.
+func (m *MockOutboxStorage) MarkOutboxEventExhausted(ctx context.Context, eventId string, failedAt time.Time, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventExhausted", ctx, eventId, failedAt, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxEventExhausted indicates an expected call of MarkOutboxEventExhausted.
+func (mr *MockOutboxStorageMockRecorder) MarkOutboxEventExhausted(ctx, eventId, failedAt, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventExhausted", reflect.TypeOf((*MockOutboxStorage)(nil).MarkOutboxEventExhausted), ctx, eventId, failedAt, lastError)
+}
+
+// MarkOutboxEventFailed mocks base method.
+func (m *MockOutboxStorage) MarkOutboxEventFailed(ctx context.Context, eventId string, nextAttemptAt time.Time, lastError string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventFailed", ctx, eventId, nextAttemptAt, lastError)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxEventFailed indicates an expected call of MarkOutboxEventFailed.
+func (mr *MockOutboxStorageMockRecorder) MarkOutboxEventFailed(ctx, eventId, nextAttemptAt, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventFailed", reflect.TypeOf((*MockOutboxStorage)(nil).MarkOutboxEventFailed), ctx, eventId, nextAttemptAt, lastError)
+}
+
+// MarkOutboxEventSent mocks base method.
+func (m *MockOutboxStorage) MarkOutboxEventSent(ctx context.Context, eventId string, sentAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventSent", ctx, eventId, sentAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkOutboxEventSent indicates an expected call of MarkOutboxEventSent.
+func (mr *MockOutboxStorageMockRecorder) MarkOutboxEventSent(ctx, eventId, sentAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventSent", reflect.TypeOf((*MockOutboxStorage)(nil).MarkOutboxEventSent), ctx, eventId, sentAt)
+}
+
+// PendingOutboxEvents mocks base method.
+func (m *MockOutboxStorage) PendingOutboxEvents(ctx context.Context, now time.Time, limit int) ([]OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PendingOutboxEvents", ctx, now, limit)
+	ret0, _ := ret[0].([]OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PendingOutboxEvents indicates an expected call of PendingOutboxEvents.
+func (mr *MockOutboxStorageMockRecorder) PendingOutboxEvents(ctx, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PendingOutboxEvents", reflect.TypeOf((*MockOutboxStorage)(nil).PendingOutboxEvents), ctx, now, limit)
+}
@@ -1,6 +1,9 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 const MaxPageSize = 100
 
@@ -9,7 +12,60 @@ const MaxPageSize = 100
 // UserStorage is the repository interface for user storage
 type UserStorage interface {
 	CreateUser(ctx context.Context, userDetails UserDetails) (*User, error)
-	UpdateUser(ctx context.Context, userId string, userUpdate UserUpdate) (*User, error)
-	DeleteUser(ctx context.Context, userId string) error
+	// UpdateUser returns the updated user and the user as it was before the update.
+	// An update that changes no field is not applied, both returned users are then the current user.
+	UpdateUser(
+		ctx context.Context,
+		userId string,
+		userUpdate UserUpdate,
+		precondition *UserPrecondition,
+	) (user *User, previous *User, err error)
+	DeleteUser(ctx context.Context, userId string, deletedAt time.Time, precondition *UserPrecondition) (*User, error)
+	UndeleteUser(ctx context.Context, userId string, deletedSince time.Time, restoredAt time.Time) (*User, error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time, purgedAt time.Time, limit int) ([]User, error)
 	ListUsers(ctx context.Context, userFilter UserFilter, pageSize int, pageToken string) ([]User, string, error)
+	// ListUsersAfter returns up to limit users following the given key in creation order, from the first user if nil.
+	// Unlike page tokens, keys are not shifted by the users deleted between two calls.
+	ListUsersAfter(ctx context.Context, userFilter UserFilter, after *UserKey, limit int) ([]User, error)
+	GetUserHistory(ctx context.Context, userId string, pageSize int, pageToken string) ([]UserRevision, string, error)
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+//go:generate mockgen -destination=outbox_storage_mock.go -package=storage github.com/alenalato/users-service/internal/storage OutboxStorage
+
+// OutboxStorage is the repository interface for the transactional outbox of events
+type OutboxStorage interface {
+	AddOutboxEvent(ctx context.Context, outboxEvent OutboxEvent) error
+	// PendingOutboxEvents returns the pending events of the keys whose oldest pending event is due at the given time
+	PendingOutboxEvents(ctx context.Context, now time.Time, limit int) ([]OutboxEvent, error)
+	MarkOutboxEventSent(ctx context.Context, eventId string, sentAt time.Time) error
+	MarkOutboxEventFailed(ctx context.Context, eventId string, nextAttemptAt time.Time, lastError string) error
+	// MarkOutboxEventExhausted marks an event failing its last attempt as failed, it is no longer pending
+	MarkOutboxEventExhausted(ctx context.Context, eventId string, failedAt time.Time, lastError string) error
+	AcquireOutboxLease(ctx context.Context, owner string, now time.Time, expiresAt time.Time) (bool, error)
+}
+
+//go:generate mockgen -destination=user_change_storage_mock.go -package=storage github.com/alenalato/users-service/internal/storage UserChangeStorage
+
+// UserChangeStorage is the repository interface for watching the changes applied to users.
+// A lease grants a single owner the right to watch the changes of a watcher and save its resume token.
+type UserChangeStorage interface {
+	AcquireUserChangesLease(ctx context.Context, watcherId string, owner string, now time.Time, expiresAt time.Time) (bool, error)
+	WatchUserChanges(
+		ctx context.Context,
+		watcherId string,
+		owner string,
+		handle func(ctx context.Context, change UserChange) error,
+	) error
+}
+
+//go:generate mockgen -destination=webhook_storage_mock.go -package=storage github.com/alenalato/users-service/internal/storage WebhookStorage
+
+// WebhookStorage is the repository interface for webhook subscriptions and their delivery log
+type WebhookStorage interface {
+	CreateWebhookSubscription(ctx context.Context, subscription WebhookSubscription) (*WebhookSubscription, error)
+	// ListWebhookSubscriptions returns the subscriptions receiving the given event type, all of them if empty
+	ListWebhookSubscriptions(ctx context.Context, eventType string) ([]WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, subscriptionId string) (*WebhookSubscription, error)
+	AddWebhookDelivery(ctx context.Context, delivery WebhookDelivery) error
 }
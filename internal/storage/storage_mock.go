@@ -12,6 +12,7 @@ package storage
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -56,17 +57,34 @@ func (mr *MockUserStorageMockRecorder) CreateUser(ctx, userDetails any) *gomock.
 }
 
 // DeleteUser mocks base method.
-func (m *MockUserStorage) DeleteUser(ctx context.Context, userId string) error {
+func (m *MockUserStorage) DeleteUser(ctx context.Context, userId string, deletedAt time.Time, precondition *UserPrecondition) (*User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteUser", ctx, userId)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "DeleteUser", ctx, userId, deletedAt, precondition)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // DeleteUser indicates an expected call of DeleteUser.
-func (mr *MockUserStorageMockRecorder) DeleteUser(ctx, userId any) *gomock.Call {
+func (mr *MockUserStorageMockRecorder) DeleteUser(ctx, userId, deletedAt, precondition any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserStorage)(nil).DeleteUser), ctx, userId, deletedAt, precondition)
+}
+
+// GetUserHistory mocks base method.
+func (m *MockUserStorage) GetUserHistory(ctx context.Context, userId string, pageSize int, pageToken string) ([]UserRevision, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserHistory", ctx, userId, pageSize, pageToken)
+	ret0, _ := ret[0].([]UserRevision)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetUserHistory indicates an expected call of GetUserHistory.
+func (mr *MockUserStorageMockRecorder) GetUserHistory(ctx, userId, pageSize, pageToken any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockUserStorage)(nil).DeleteUser), ctx, userId)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserHistory", reflect.TypeOf((*MockUserStorage)(nil).GetUserHistory), ctx, userId, pageSize, pageToken)
 }
 
 // ListUsers mocks base method.
@@ -85,17 +103,77 @@ func (mr *MockUserStorageMockRecorder) ListUsers(ctx, userFilter, pageSize, page
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUserStorage)(nil).ListUsers), ctx, userFilter, pageSize, pageToken)
 }
 
-// UpdateUser mocks base method.
-func (m *MockUserStorage) UpdateUser(ctx context.Context, userId string, userUpdate UserUpdate) (*User, error) {
+// ListUsersAfter mocks base method.
+func (m *MockUserStorage) ListUsersAfter(ctx context.Context, userFilter UserFilter, after *UserKey, limit int) ([]User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "UpdateUser", ctx, userId, userUpdate)
+	ret := m.ctrl.Call(m, "ListUsersAfter", ctx, userFilter, after, limit)
+	ret0, _ := ret[0].([]User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsersAfter indicates an expected call of ListUsersAfter.
+func (mr *MockUserStorageMockRecorder) ListUsersAfter(ctx, userFilter, after, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsersAfter", reflect.TypeOf((*MockUserStorage)(nil).ListUsersAfter), ctx, userFilter, after, limit)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockUserStorage) PurgeDeletedUsers(ctx context.Context, deletedBefore, purgedAt time.Time, limit int) ([]User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", ctx, deletedBefore, purgedAt, limit)
+	ret0, _ := ret[0].([]User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockUserStorageMockRecorder) PurgeDeletedUsers(ctx, deletedBefore, purgedAt, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockUserStorage)(nil).PurgeDeletedUsers), ctx, deletedBefore, purgedAt, limit)
+}
+
+// UndeleteUser mocks base method.
+func (m *MockUserStorage) UndeleteUser(ctx context.Context, userId string, deletedSince, restoredAt time.Time) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UndeleteUser", ctx, userId, deletedSince, restoredAt)
 	ret0, _ := ret[0].(*User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
+// UndeleteUser indicates an expected call of UndeleteUser.
+func (mr *MockUserStorageMockRecorder) UndeleteUser(ctx, userId, deletedSince, restoredAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UndeleteUser", reflect.TypeOf((*MockUserStorage)(nil).UndeleteUser), ctx, userId, deletedSince, restoredAt)
+}
+
+// UpdateUser mocks base method.
+func (m *MockUserStorage) UpdateUser(ctx context.Context, userId string, userUpdate UserUpdate, precondition *UserPrecondition) (*User, *User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", ctx, userId, userUpdate, precondition)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(*User)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
 // UpdateUser indicates an expected call of UpdateUser.
-func (mr *MockUserStorageMockRecorder) UpdateUser(ctx, userId, userUpdate any) *gomock.Call {
+func (mr *MockUserStorageMockRecorder) UpdateUser(ctx, userId, userUpdate, precondition any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserStorage)(nil).UpdateUser), ctx, userId, userUpdate, precondition)
+}
+
+// WithTransaction mocks base method.
+func (m *MockUserStorage) WithTransaction(ctx context.Context, fn func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTransaction", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTransaction indicates an expected call of WithTransaction.
+func (mr *MockUserStorageMockRecorder) WithTransaction(ctx, fn any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockUserStorage)(nil).UpdateUser), ctx, userId, userUpdate)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTransaction", reflect.TypeOf((*MockUserStorage)(nil).WithTransaction), ctx, fn)
 }
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/alenalato/users-service/internal/storage (interfaces: UserChangeStorage)
+//
+// Generated by this command:
+//
+//	mockgen -destination=user_change_storage_mock.go -package=storage github.com/alenalato/users-service/internal/storage UserChangeStorage
+//
+
+// Package storage is a generated GoMock package.
+package storage
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockUserChangeStorage is a mock of UserChangeStorage interface.
+type MockUserChangeStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserChangeStorageMockRecorder
+	isgomock struct{}
+}
+
+// MockUserChangeStorageMockRecorder is the mock recorder for MockUserChangeStorage.
+type MockUserChangeStorageMockRecorder struct {
+	mock *MockUserChangeStorage
+}
+
+// NewMockUserChangeStorage creates a new mock instance.
+func NewMockUserChangeStorage(ctrl *gomock.Controller) *MockUserChangeStorage {
+	mock := &MockUserChangeStorage{ctrl: ctrl}
+	mock.recorder = &MockUserChangeStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserChangeStorage) EXPECT() *MockUserChangeStorageMockRecorder {
+	return m.recorder
+}
+
+// AcquireUserChangesLease mocks base method.
+func (m *MockUserChangeStorage) AcquireUserChangesLease(ctx context.Context, watcherId, owner string, now, expiresAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcquireUserChangesLease", ctx, watcherId, owner, now, expiresAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AcquireUserChangesLease indicates an expected call of AcquireUserChangesLease.
+func (mr *MockUserChangeStorageMockRecorder) AcquireUserChangesLease(ctx, watcherId, owner, now, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcquireUserChangesLease", reflect.TypeOf((*MockUserChangeStorage)(nil).AcquireUserChangesLease), ctx, watcherId, owner, now, expiresAt)
+}
+
+// WatchUserChanges mocks base method.
+func (m *MockUserChangeStorage) WatchUserChanges(ctx context.Context, watcherId, owner string, handle func(context.Context, UserChange) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WatchUserChanges", ctx, watcherId, owner, handle)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WatchUserChanges indicates an expected call of WatchUserChanges.
+func (mr *MockUserChangeStorageMockRecorder) WatchUserChanges(ctx, watcherId, owner, handle any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WatchUserChanges", reflect.TypeOf((*MockUserChangeStorage)(nil).WatchUserChanges), ctx, watcherId, owner, handle)
+}
@@ -0,0 +1,242 @@
+// Package consumer consumes the user events published to Kafka by the users service.
+// It decodes every encoding the service writes into the published events.UserEvent message,
+// dispatches events to handlers by type, commits them once handled and skips redeliveries by event ID.
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/pkg/events"
+	"github.com/segmentio/kafka-go"
+	"io"
+	"time"
+)
+
+// EventTypeCreated is the type of the events of created users
+const EventTypeCreated = "created"
+
+// EventTypeUpdated is the type of the events of updated users
+const EventTypeUpdated = "updated"
+
+// EventTypeDeleted is the type of the events of deleted users
+const EventTypeDeleted = "deleted"
+
+// EventTypeRestored is the type of the events of restored users
+const EventTypeRestored = "restored"
+
+// EventTypePurged is the type of the events of purged users
+const EventTypePurged = "purged"
+
+// EventTypeSnapshot is the type of the events replaying the current state of a user
+const EventTypeSnapshot = "snapshot"
+
+// defaultMaxAttempts is the default number of attempts of a handler
+const defaultMaxAttempts = 5
+
+// defaultMinBackoff is the default delay before the second attempt of a handler
+const defaultMinBackoff = 100 * time.Millisecond
+
+// defaultMaxBackoff is the default maximum delay between the attempts of a handler
+const defaultMaxBackoff = 10 * time.Second
+
+// Handler handles a user event, an error makes the consumer attempt it again
+type Handler func(ctx context.Context, userEvent *events.UserEvent) error
+
+//go:generate mockgen -destination=reader_mock.go -package=consumer github.com/alenalato/users-service/pkg/events/consumer Reader
+
+// Reader is the interface of the Kafka reader of a consumer, as implemented by kafka.Reader
+type Reader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// Config holds the configuration of a consumer
+type Config struct {
+	// Brokers are the addresses of the Kafka brokers
+	Brokers []string
+	// Topic is the topic of the user events
+	Topic string
+	// GroupID is the consumer group, partitions are balanced across its consumers and offsets are committed to it
+	GroupID string
+	// Dialer is the dialer of the broker connections, to configure TLS and SASL, the default dialer if not set
+	Dialer *kafka.Dialer
+	// MaxAttempts is the number of attempts of a handler before the consumer stops, 5 if not set
+	MaxAttempts int
+	// MinBackoff is the delay before the second attempt of a handler, doubling at every attempt, 100ms if not set
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum delay between the attempts of a handler, 10s if not set
+	MaxBackoff time.Duration
+	// SkipInvalid commits the messages that cannot be decoded, instead of stopping the consumer
+	SkipInvalid bool
+	// Deduplicator remembers the handled events, a MemoryDeduplicator of DefaultDeduplicationSize events if not set
+	Deduplicator Deduplicator
+}
+
+// Consumer consumes user events from a Kafka consumer group with at-least-once semantics:
+// a message is committed only after its event has been handled, so events may be handled again after a restart,
+// and redeliveries already handled are skipped by the deduplicator.
+// Events are handled one at a time, in the order of their partition.
+type Consumer struct {
+	// reader is the Kafka reader of the consumer group
+	reader Reader
+	// config is the consumer configuration
+	config Config
+	// handlers are the event handlers by event type
+	handlers map[string]Handler
+	// defaultHandler handles the events of the types without a handler, if any
+	defaultHandler Handler
+}
+
+// Handle registers the handler of the events of the given type, it must be called before Run
+func (c *Consumer) Handle(eventType string, handler Handler) {
+	c.handlers[eventType] = handler
+}
+
+// HandleDefault registers the handler of the events of the types without a handler, it must be called before Run.
+// Events without a handler are committed without being handled.
+func (c *Consumer) HandleDefault(handler Handler) {
+	c.defaultHandler = handler
+}
+
+// Run consumes user events until the given context is done or the reader is closed, then it returns nil.
+// It stops with an error if a handler fails every attempt, if a message cannot be decoded, unless skipped,
+// or if the reader fails, the failed message is not committed and is consumed again on restart.
+func (c *Consumer) Run(ctx context.Context) error {
+	for {
+		message, errFetch := c.reader.FetchMessage(ctx)
+		if errFetch != nil {
+			if ctx.Err() != nil || errors.Is(errFetch, io.EOF) {
+				return nil
+			}
+
+			return fmt.Errorf("fetching message: %w", errFetch)
+		}
+
+		if errConsume := c.consume(ctx, message); errConsume != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return errConsume
+		}
+
+		if errCommit := c.reader.CommitMessages(ctx, message); errCommit != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("committing message at offset %d of partition %d: %w", message.Offset, message.Partition, errCommit)
+		}
+	}
+}
+
+// consume decodes and handles a message, skipping the events already handled
+func (c *Consumer) consume(ctx context.Context, message kafka.Message) error {
+	userEvent, errDecode := Decode(message)
+	if errDecode != nil {
+		if c.config.SkipInvalid {
+			return nil
+		}
+
+		return fmt.Errorf("decoding message at offset %d of partition %d: %w", message.Offset, message.Partition, errDecode)
+	}
+
+	handler, ok := c.handlers[userEvent.GetEventType()]
+	if !ok {
+		handler = c.defaultHandler
+	}
+	if handler == nil {
+		return nil
+	}
+
+	// Events without ID, emitted by older versions of the service, are never deduplicated
+	eventId := userEvent.GetEventId()
+	if eventId != "" {
+		seen, errSeen := c.config.Deduplicator.Seen(ctx, eventId)
+		if errSeen != nil {
+			return fmt.Errorf("deduplicating event %s: %w", eventId, errSeen)
+		}
+		if seen {
+			return nil
+		}
+	}
+
+	if errHandle := c.handle(ctx, handler, userEvent); errHandle != nil {
+		return fmt.Errorf("handling %s event %s: %w", userEvent.GetEventType(), eventId, errHandle)
+	}
+
+	if eventId != "" {
+		if errMark := c.config.Deduplicator.Mark(ctx, eventId); errMark != nil {
+			return fmt.Errorf("deduplicating event %s: %w", eventId, errMark)
+		}
+	}
+
+	return nil
+}
+
+// handle runs a handler until it succeeds or fails every attempt, waiting an exponential backoff between attempts
+func (c *Consumer) handle(ctx context.Context, handler Handler, userEvent *events.UserEvent) error {
+	backoff := c.config.MinBackoff
+	for attempt := 1; ; attempt++ {
+		errHandle := handler(ctx, userEvent)
+		if errHandle == nil {
+			return nil
+		}
+		if attempt >= c.config.MaxAttempts {
+			return errHandle
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, c.config.MaxBackoff)
+	}
+}
+
+// Close closes the reader of the consumer, leaving the consumer group
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+// NewConsumer creates a new Consumer reading the user events of the configured topic in the configured consumer group
+func NewConsumer(config Config) (*Consumer, error) {
+	if len(config.Brokers) == 0 || config.Topic == "" || config.GroupID == "" {
+		return nil, errors.New("brokers, topic and group ID are required")
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: config.Brokers,
+		Topic:   config.Topic,
+		GroupID: config.GroupID,
+		Dialer:  config.Dialer,
+	})
+
+	return NewConsumerFromReader(reader, config), nil
+}
+
+// NewConsumerFromReader creates a new Consumer reading the user events from the given reader,
+// the brokers, topic, group and dialer of the configuration are ignored
+func NewConsumerFromReader(reader Reader, config Config) *Consumer {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.Deduplicator == nil {
+		config.Deduplicator = NewMemoryDeduplicator(DefaultDeduplicationSize)
+	}
+
+	return &Consumer{
+		reader:   reader,
+		config:   config,
+		handlers: make(map[string]Handler),
+	}
+}
@@ -0,0 +1,238 @@
+package consumer_test
+
+import (
+	"context"
+	"errors"
+	"github.com/alenalato/users-service/pkg/events"
+	"github.com/alenalato/users-service/pkg/events/consumer"
+	"github.com/alenalato/users-service/pkg/events/consumer/consumertest"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"testing"
+	"time"
+)
+
+// testConfig retries handlers without delay
+var testConfig = consumer.Config{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  time.Millisecond,
+}
+
+// committedOffsets returns the offsets of the messages committed to a reader
+func committedOffsets(reader *consumertest.Reader) []int64 {
+	var offsets []int64
+	for _, message := range reader.Committed() {
+		offsets = append(offsets, message.Offset)
+	}
+
+	return offsets
+}
+
+func TestConsumer_Run_HandlersByType(t *testing.T) {
+	encodings := []consumer.Encoding{
+		consumer.EncodingJSON,
+		consumer.EncodingCloudEventsStructured,
+		consumer.EncodingCloudEventsBinary,
+		consumer.EncodingProtobuf,
+	}
+	for _, encoding := range encodings {
+		t.Run(string(encoding), func(t *testing.T) {
+			reader := consumertest.NewReader()
+			require.NoError(t, reader.FeedEvents(
+				encoding,
+				&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated, UserId: "user-1"},
+				&events.UserEvent{EventId: "event-2", EventType: consumer.EventTypeUpdated, UserId: "user-1"},
+				&events.UserEvent{EventId: "event-3", EventType: consumer.EventTypeDeleted, UserId: "user-1"},
+				&events.UserEvent{EventId: "event-4", EventType: consumer.EventTypeSnapshot, UserId: "user-2"},
+			))
+			require.NoError(t, reader.Close())
+
+			var created, others []string
+			userConsumer := consumer.NewConsumerFromReader(reader, testConfig)
+			userConsumer.Handle(consumer.EventTypeCreated, func(_ context.Context, userEvent *events.UserEvent) error {
+				created = append(created, userEvent.GetEventId())
+				return nil
+			})
+			userConsumer.HandleDefault(func(_ context.Context, userEvent *events.UserEvent) error {
+				others = append(others, userEvent.GetEventId())
+				return nil
+			})
+
+			assert.NoError(t, userConsumer.Run(context.Background()))
+			assert.Equal(t, []string{"event-1"}, created)
+			assert.Equal(t, []string{"event-2", "event-3", "event-4"}, others)
+			assert.Equal(t, []int64{0, 1, 2, 3}, committedOffsets(reader))
+		})
+	}
+}
+
+func TestConsumer_Run_UnhandledTypes(t *testing.T) {
+	reader := consumertest.NewReader()
+	require.NoError(t, reader.FeedEvents(
+		consumer.EncodingJSON,
+		&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+		&events.UserEvent{EventId: "event-2", EventType: consumer.EventTypePurged},
+	))
+	require.NoError(t, reader.Close())
+
+	var purged []string
+	userConsumer := consumer.NewConsumerFromReader(reader, testConfig)
+	userConsumer.Handle(consumer.EventTypePurged, func(_ context.Context, userEvent *events.UserEvent) error {
+		purged = append(purged, userEvent.GetEventId())
+		return nil
+	})
+
+	// Events without a handler are committed
+	assert.NoError(t, userConsumer.Run(context.Background()))
+	assert.Equal(t, []string{"event-2"}, purged)
+	assert.Equal(t, []int64{0, 1}, committedOffsets(reader))
+}
+
+func TestConsumer_Run_Deduplication(t *testing.T) {
+	reader := consumertest.NewReader()
+	require.NoError(t, reader.FeedEvents(
+		consumer.EncodingJSON,
+		&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+		&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+		&events.UserEvent{EventType: consumer.EventTypeCreated},
+		&events.UserEvent{EventType: consumer.EventTypeCreated},
+	))
+	require.NoError(t, reader.Close())
+
+	handled := 0
+	userConsumer := consumer.NewConsumerFromReader(reader, testConfig)
+	userConsumer.HandleDefault(func(_ context.Context, _ *events.UserEvent) error {
+		handled++
+		return nil
+	})
+
+	// Redeliveries are skipped and committed, events without ID are never deduplicated
+	assert.NoError(t, userConsumer.Run(context.Background()))
+	assert.Equal(t, 3, handled)
+	assert.Equal(t, []int64{0, 1, 2, 3}, committedOffsets(reader))
+}
+
+func TestConsumer_Run_HandlerRetry(t *testing.T) {
+	reader := consumertest.NewReader()
+	require.NoError(t, reader.FeedEvents(
+		consumer.EncodingJSON,
+		&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+	))
+	require.NoError(t, reader.Close())
+
+	attempts := 0
+	userConsumer := consumer.NewConsumerFromReader(reader, testConfig)
+	userConsumer.HandleDefault(func(_ context.Context, _ *events.UserEvent) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("handler error")
+		}
+		return nil
+	})
+
+	assert.NoError(t, userConsumer.Run(context.Background()))
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, []int64{0}, committedOffsets(reader))
+}
+
+func TestConsumer_Run_HandlerFailure(t *testing.T) {
+	reader := consumertest.NewReader()
+	require.NoError(t, reader.FeedEvents(
+		consumer.EncodingJSON,
+		&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+		&events.UserEvent{EventId: "event-2", EventType: consumer.EventTypeCreated},
+		&events.UserEvent{EventId: "event-3", EventType: consumer.EventTypeCreated},
+	))
+	require.NoError(t, reader.Close())
+
+	errHandler := errors.New("handler error")
+	userConsumer := consumer.NewConsumerFromReader(reader, testConfig)
+	userConsumer.HandleDefault(func(_ context.Context, userEvent *events.UserEvent) error {
+		if userEvent.GetEventId() == "event-2" {
+			return errHandler
+		}
+		return nil
+	})
+
+	// The consumer stops at the failed event, which is not committed
+	assert.ErrorIs(t, userConsumer.Run(context.Background()), errHandler)
+	assert.Equal(t, []int64{0}, committedOffsets(reader))
+}
+
+func TestConsumer_Run_InvalidMessage(t *testing.T) {
+	newReader := func() *consumertest.Reader {
+		reader := consumertest.NewReader()
+		reader.Feed(kafka.Message{Value: []byte("not json")})
+		require.NoError(t, reader.FeedEvents(
+			consumer.EncodingJSON,
+			&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+		))
+		require.NoError(t, reader.Close())
+
+		return reader
+	}
+	handler := func(_ context.Context, _ *events.UserEvent) error {
+		return nil
+	}
+
+	reader := newReader()
+	userConsumer := consumer.NewConsumerFromReader(reader, testConfig)
+	userConsumer.HandleDefault(handler)
+	assert.ErrorIs(t, userConsumer.Run(context.Background()), consumer.ErrInvalidMessage)
+	assert.Empty(t, committedOffsets(reader))
+
+	// Invalid messages are committed when skipped
+	config := testConfig
+	config.SkipInvalid = true
+	reader = newReader()
+	userConsumer = consumer.NewConsumerFromReader(reader, config)
+	userConsumer.HandleDefault(handler)
+	assert.NoError(t, userConsumer.Run(context.Background()))
+	assert.Equal(t, []int64{0, 1}, committedOffsets(reader))
+}
+
+func TestConsumer_Run_ContextDone(t *testing.T) {
+	reader := consumertest.NewReader()
+	require.NoError(t, reader.FeedEvents(
+		consumer.EncodingJSON,
+		&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+	))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	userConsumer := consumer.NewConsumerFromReader(reader, testConfig)
+	userConsumer.HandleDefault(func(_ context.Context, _ *events.UserEvent) error {
+		// The consumer waits for new messages until the context is done
+		cancel()
+		return nil
+	})
+
+	assert.NoError(t, userConsumer.Run(ctx))
+}
+
+func TestConsumer_Run_CommitError(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	message, err := consumertest.Message(
+		&events.UserEvent{EventId: "event-1", EventType: consumer.EventTypeCreated},
+		consumer.EncodingJSON,
+	)
+	require.NoError(t, err)
+
+	errCommit := errors.New("commit error")
+	mockReader := consumer.NewMockReader(mockCtrl)
+	mockReader.EXPECT().FetchMessage(gomock.Any()).Return(message, nil)
+	mockReader.EXPECT().CommitMessages(gomock.Any(), message).Return(errCommit)
+
+	userConsumer := consumer.NewConsumerFromReader(mockReader, testConfig)
+	assert.ErrorIs(t, userConsumer.Run(context.Background()), errCommit)
+}
+
+func TestNewConsumer_InvalidConfig(t *testing.T) {
+	userConsumer, err := consumer.NewConsumer(consumer.Config{Topic: "users"})
+	assert.Error(t, err)
+	assert.Nil(t, userConsumer)
+}
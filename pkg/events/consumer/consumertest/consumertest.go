@@ -0,0 +1,204 @@
+// Package consumertest provides a fake Kafka reader for testing the handlers of user events consumers.
+// Fake user events are fed to the reader in any encoding written by the users service,
+// a consumer created with consumer.NewConsumerFromReader consumes them, and the commits are recorded.
+package consumertest
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/alenalato/users-service/pkg/events"
+	"github.com/alenalato/users-service/pkg/events/consumer"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"sync"
+)
+
+// Topic is the topic of the messages fed to the reader
+const Topic = "users"
+
+// jsonMarshalOptions encode the JSON user events with the field names written by the users service
+var jsonMarshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+// Reader is a fake consumer.Reader serving the fed messages in order and recording the committed ones.
+// Once closed, it serves the remaining messages and then reports the end of the stream,
+// so that a consumer run returns after consuming every fed message.
+type Reader struct {
+	mu sync.Mutex
+	// pending are the fed messages not fetched yet
+	pending []kafka.Message
+	// committed are the committed messages
+	committed []kafka.Message
+	// offset is the offset of the next fed message
+	offset int64
+	// closed is set once the reader is closed
+	closed bool
+	// notify signals fetches waiting for messages
+	notify chan struct{}
+}
+
+var _ consumer.Reader = new(Reader)
+
+// Feed feeds messages to the reader, their topic and offset are set by the reader
+func (r *Reader) Feed(messages ...kafka.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, message := range messages {
+		message.Topic = Topic
+		message.Offset = r.offset
+		r.offset++
+		r.pending = append(r.pending, message)
+	}
+	r.signal()
+}
+
+// FeedEvents feeds user events to the reader, encoded with the given encoding
+func (r *Reader) FeedEvents(encoding consumer.Encoding, userEvents ...*events.UserEvent) error {
+	messages := make([]kafka.Message, 0, len(userEvents))
+	for _, userEvent := range userEvents {
+		message, err := Message(userEvent, encoding)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+	r.Feed(messages...)
+
+	return nil
+}
+
+// FetchMessage returns the next fed message, waiting for one if none is pending,
+// it returns io.EOF once the reader is closed and every message has been fetched
+func (r *Reader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	for {
+		r.mu.Lock()
+		if len(r.pending) > 0 {
+			message := r.pending[0]
+			r.pending = r.pending[1:]
+			r.mu.Unlock()
+
+			return message, nil
+		}
+		closed := r.closed
+		r.mu.Unlock()
+
+		if closed {
+			return kafka.Message{}, io.EOF
+		}
+
+		select {
+		case <-ctx.Done():
+			return kafka.Message{}, ctx.Err()
+		case <-r.notify:
+		}
+	}
+}
+
+// CommitMessages records the given messages as committed
+func (r *Reader) CommitMessages(_ context.Context, messages ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.committed = append(r.committed, messages...)
+
+	return nil
+}
+
+// Committed returns the committed messages, in commit order
+func (r *Reader) Committed() []kafka.Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]kafka.Message(nil), r.committed...)
+}
+
+// Close closes the reader, the remaining messages are still served
+func (r *Reader) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.signal()
+
+	return nil
+}
+
+// signal wakes up a fetch waiting for messages, the lock must be held
+func (r *Reader) signal() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// NewReader creates a new Reader without messages
+func NewReader() *Reader {
+	return &Reader{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// Message returns the message of a user event, keyed by user ID, as written by the users service with the given encoding.
+// Protobuf user events are written in the schema registry wire format, with schema ID 1.
+func Message(userEvent *events.UserEvent, encoding consumer.Encoding) (kafka.Message, error) {
+	message := kafka.Message{Key: []byte(userEvent.GetUserId())}
+
+	switch encoding {
+	case consumer.EncodingProtobuf:
+		value, err := proto.Marshal(userEvent)
+		if err != nil {
+			return kafka.Message{}, err
+		}
+		header := binary.BigEndian.AppendUint32([]byte{0}, 1)
+		message.Value = append(append(header, 0), value...)
+		message.Headers = []kafka.Header{
+			{Key: "content-type", Value: []byte("application/x-protobuf; messageType=users.events.UserEvent")},
+		}
+	case consumer.EncodingCloudEventsStructured:
+		data, err := jsonMarshalOptions.Marshal(userEvent)
+		if err != nil {
+			return kafka.Message{}, err
+		}
+		value, err := json.Marshal(map[string]any{
+			"specversion":     "1.0",
+			"id":              userEvent.GetEventId(),
+			"source":          "users-service",
+			"type":            "users.v1.user." + userEvent.GetEventType(),
+			"subject":         userEvent.GetUserId(),
+			"datacontenttype": "application/json",
+			"data":            json.RawMessage(data),
+		})
+		if err != nil {
+			return kafka.Message{}, err
+		}
+		message.Value = value
+		message.Headers = []kafka.Header{
+			{Key: "content-type", Value: []byte("application/cloudevents+json")},
+		}
+	case consumer.EncodingCloudEventsBinary:
+		value, err := jsonMarshalOptions.Marshal(userEvent)
+		if err != nil {
+			return kafka.Message{}, err
+		}
+		message.Value = value
+		message.Headers = []kafka.Header{
+			{Key: "ce_specversion", Value: []byte("1.0")},
+			{Key: "ce_id", Value: []byte(userEvent.GetEventId())},
+			{Key: "ce_source", Value: []byte("users-service")},
+			{Key: "ce_type", Value: []byte("users.v1.user." + userEvent.GetEventType())},
+			{Key: "ce_subject", Value: []byte(userEvent.GetUserId())},
+			{Key: "content-type", Value: []byte("application/json")},
+		}
+	default:
+		value, err := jsonMarshalOptions.Marshal(userEvent)
+		if err != nil {
+			return kafka.Message{}, err
+		}
+		message.Value = value
+	}
+
+	return message, nil
+}
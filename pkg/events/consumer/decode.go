@@ -0,0 +1,141 @@
+package consumer
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alenalato/users-service/pkg/events"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"strings"
+)
+
+// Encoding is the encoding of the user events read from Kafka
+type Encoding string
+
+// EncodingJSON is the encoding of user events written as plain JSON
+const EncodingJSON Encoding = "json"
+
+// EncodingCloudEventsStructured is the encoding of user events written as JSON CloudEvents, envelope and data in the message value
+const EncodingCloudEventsStructured Encoding = "cloudevents-structured"
+
+// EncodingCloudEventsBinary is the encoding of user events written as CloudEvents, envelope in the message headers and data in the message value
+const EncodingCloudEventsBinary Encoding = "cloudevents-binary"
+
+// EncodingProtobuf is the encoding of user events written as protobuf messages, optionally in the schema registry wire format
+const EncodingProtobuf Encoding = "protobuf"
+
+// ErrInvalidMessage is returned when a message cannot be decoded as a user event
+var ErrInvalidMessage = errors.New("invalid user event message")
+
+// wireFormatMagicByte is the first byte of the messages in the schema registry wire format,
+// a plain protobuf message never starts with it since zero is not a valid field number
+const wireFormatMagicByte = 0
+
+// jsonUnmarshalOptions decode the JSON user events, accepting the fields added by newer versions of the service
+var jsonUnmarshalOptions = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// protobufUnmarshalOptions decode the protobuf user events
+var protobufUnmarshalOptions = proto.UnmarshalOptions{DiscardUnknown: true}
+
+// cloudEvent is the envelope of a structured CloudEvent carrying a user event as data
+type cloudEvent struct {
+	ID   string          `json:"id"`
+	Data json.RawMessage `json:"data"`
+}
+
+// MessageEncoding returns the encoding of a message, detected from its headers
+func MessageEncoding(message kafka.Message) Encoding {
+	contentType := header(message, "content-type")
+	switch {
+	case strings.HasPrefix(contentType, "application/cloudevents+json"):
+		return EncodingCloudEventsStructured
+	case header(message, "ce_specversion") != "":
+		return EncodingCloudEventsBinary
+	case strings.HasPrefix(contentType, "application/x-protobuf"):
+		return EncodingProtobuf
+	}
+
+	return EncodingJSON
+}
+
+// Decode decodes a message as a user event, whatever the encoding it was written with.
+// Events without an event ID take the CloudEvents ID, if any.
+func Decode(message kafka.Message) (*events.UserEvent, error) {
+	userEvent := &events.UserEvent{}
+
+	switch MessageEncoding(message) {
+	case EncodingCloudEventsStructured:
+		var envelope cloudEvent
+		if err := json.Unmarshal(message.Value, &envelope); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		if err := jsonUnmarshalOptions.Unmarshal(envelope.Data, userEvent); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		if userEvent.EventId == "" {
+			userEvent.EventId = envelope.ID
+		}
+	case EncodingCloudEventsBinary:
+		if err := jsonUnmarshalOptions.Unmarshal(message.Value, userEvent); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+		if userEvent.EventId == "" {
+			userEvent.EventId = header(message, "ce_id")
+		}
+	case EncodingProtobuf:
+		value, errWire := stripWireFormat(message.Value)
+		if errWire != nil {
+			return nil, errWire
+		}
+		if err := protobufUnmarshalOptions.Unmarshal(value, userEvent); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+	default:
+		if err := jsonUnmarshalOptions.Unmarshal(message.Value, userEvent); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+		}
+	}
+
+	return userEvent, nil
+}
+
+// stripWireFormat returns a protobuf user event without its schema registry wire format header, if any:
+// the magic byte, the 4 bytes schema ID and the message indexes, a zigzag varint count followed by the indexes,
+// or a single zero byte for the first message of the schema
+func stripWireFormat(value []byte) ([]byte, error) {
+	if len(value) == 0 || value[0] != wireFormatMagicByte {
+		return value, nil
+	}
+	if len(value) < 6 {
+		return nil, fmt.Errorf("%w: truncated wire format header", ErrInvalidMessage)
+	}
+
+	value = value[5:]
+	count, n := binary.Varint(value)
+	if n <= 0 || count < 0 {
+		return nil, fmt.Errorf("%w: invalid wire format message indexes", ErrInvalidMessage)
+	}
+	value = value[n:]
+	for i := int64(0); i < count; i++ {
+		if _, n = binary.Varint(value); n <= 0 {
+			return nil, fmt.Errorf("%w: invalid wire format message indexes", ErrInvalidMessage)
+		}
+		value = value[n:]
+	}
+
+	return value, nil
+}
+
+// header returns the value of the message header with the given key, empty if missing
+func header(message kafka.Message, key string) string {
+	for _, h := range message.Headers {
+		if strings.EqualFold(h.Key, key) {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,137 @@
+package consumer
+
+import (
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// testUserEventJSON is a user event as written in JSON by the users service
+const testUserEventJSON = `{
+	"event_id": "event-id",
+	"sequence": 2,
+	"event_type": "updated",
+	"event_time": "2025-01-02T03:04:05.123Z",
+	"user_id": "123",
+	"first_name": "John",
+	"last_name": "Doe",
+	"nickname": "",
+	"email": "john.doe@example.com",
+	"country": "uk",
+	"created_at": "0001-01-01T00:00:00Z",
+	"updated_at": "2025-01-02T03:04:05.123Z",
+	"event_mask": ["first_name"],
+	"previous": {"first_name": "Jack"},
+	"added_by_newer_version": true
+}`
+
+func TestDecode_JSON(t *testing.T) {
+	userEvent, err := Decode(kafka.Message{Value: []byte(testUserEventJSON)})
+	require.NoError(t, err)
+
+	assert.Equal(t, "event-id", userEvent.GetEventId())
+	assert.Equal(t, int64(2), userEvent.GetSequence())
+	assert.Equal(t, EventTypeUpdated, userEvent.GetEventType())
+	assert.Equal(t, time.Date(2025, 1, 2, 3, 4, 5, 123000000, time.UTC), userEvent.GetEventTime().AsTime())
+	assert.Equal(t, "123", userEvent.GetUserId())
+	assert.Equal(t, "John", userEvent.GetFirstName())
+	assert.Equal(t, "uk", userEvent.GetCountry())
+	assert.Equal(t, time.Time{}, userEvent.GetCreatedAt().AsTime())
+	assert.Equal(t, []string{"first_name"}, userEvent.GetEventMask())
+	assert.Equal(t, map[string]string{"first_name": "Jack"}, userEvent.GetPrevious())
+}
+
+func TestDecode_JSONNullEventMask(t *testing.T) {
+	userEvent, err := Decode(kafka.Message{Value: []byte(`{"event_type":"created","user_id":"123","event_mask":null}`)})
+	require.NoError(t, err)
+
+	assert.Equal(t, EventTypeCreated, userEvent.GetEventType())
+	assert.Empty(t, userEvent.GetEventMask())
+}
+
+func TestDecode_CloudEventsStructured(t *testing.T) {
+	userEvent, err := Decode(kafka.Message{
+		Value: []byte(`{"specversion":"1.0","id":"ce-id","type":"users.v1.user.created",` +
+			`"data":{"event_type":"created","user_id":"123"}}`),
+		Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/cloudevents+json")}},
+	})
+	require.NoError(t, err)
+
+	// The CloudEvents ID is taken by events without event ID
+	assert.Equal(t, "ce-id", userEvent.GetEventId())
+	assert.Equal(t, EventTypeCreated, userEvent.GetEventType())
+	assert.Equal(t, "123", userEvent.GetUserId())
+}
+
+func TestDecode_CloudEventsBinary(t *testing.T) {
+	userEvent, err := Decode(kafka.Message{
+		Value: []byte(`{"event_id":"event-id","event_type":"deleted","user_id":"123"}`),
+		Headers: []kafka.Header{
+			{Key: "ce_specversion", Value: []byte("1.0")},
+			{Key: "ce_id", Value: []byte("event-id")},
+			{Key: "content-type", Value: []byte("application/json")},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "event-id", userEvent.GetEventId())
+	assert.Equal(t, EventTypeDeleted, userEvent.GetEventType())
+}
+
+func TestDecode_ProtobufWireFormat(t *testing.T) {
+	// event_type (field 10) "purged" and user_id (field 30) "123"
+	value := []byte{0x52, 6, 'p', 'u', 'r', 'g', 'e', 'd', 0xf2, 0x01, 3, '1', '2', '3'}
+	headers := []kafka.Header{{Key: "content-type", Value: []byte("application/x-protobuf")}}
+
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{"plain", nil},
+		{"first message", []byte{0, 0, 0, 0, 42, 0}},
+		// Two message indexes, 1 and 0, zigzag encoded
+		{"message indexes", []byte{0, 0, 0, 0, 42, 4, 2, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userEvent, err := Decode(kafka.Message{
+				Value:   append(append([]byte{}, tt.header...), value...),
+				Headers: headers,
+			})
+			require.NoError(t, err)
+
+			assert.Equal(t, EventTypePurged, userEvent.GetEventType())
+			assert.Equal(t, "123", userEvent.GetUserId())
+		})
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		message kafka.Message
+	}{
+		{"json", kafka.Message{Value: []byte("not json")}},
+		{"cloudevents structured", kafka.Message{
+			Value:   []byte(`{"data":"not an event"}`),
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/cloudevents+json")}},
+		}},
+		{"truncated wire format", kafka.Message{
+			Value:   []byte{0, 0, 0},
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/x-protobuf")}},
+		}},
+		{"protobuf", kafka.Message{
+			Value:   []byte{0x52, 10, 'x'},
+			Headers: []kafka.Header{{Key: "content-type", Value: []byte("application/x-protobuf")}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userEvent, err := Decode(tt.message)
+			assert.ErrorIs(t, err, ErrInvalidMessage)
+			assert.Nil(t, userEvent)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package consumer
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// DefaultDeduplicationSize is the default number of event IDs remembered by the memory deduplicator
+const DefaultDeduplicationSize = 10000
+
+// Deduplicator remembers the IDs of the handled user events, so that redeliveries of an event are skipped.
+// Implementations backed by a shared store deduplicate events across consumer instances and restarts.
+type Deduplicator interface {
+	// Seen tells if the event with the given ID has already been handled
+	Seen(ctx context.Context, eventId string) (bool, error)
+	// Mark records that the event with the given ID has been handled
+	Mark(ctx context.Context, eventId string) error
+}
+
+// MemoryDeduplicator is a Deduplicator remembering the most recently handled event IDs in memory
+type MemoryDeduplicator struct {
+	mu sync.Mutex
+	// size is the maximum number of remembered event IDs
+	size int
+	// order holds the remembered event IDs, the most recent first
+	order *list.List
+	// eventIds indexes the elements of order by event ID
+	eventIds map[string]*list.Element
+}
+
+var _ Deduplicator = new(MemoryDeduplicator)
+
+// Seen tells if the event with the given ID is among the remembered ones
+func (d *MemoryDeduplicator) Seen(_ context.Context, eventId string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	_, seen := d.eventIds[eventId]
+
+	return seen, nil
+}
+
+// Mark remembers the given event ID, forgetting the least recent one if the deduplicator is full
+func (d *MemoryDeduplicator) Mark(_ context.Context, eventId string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if element, seen := d.eventIds[eventId]; seen {
+		d.order.MoveToFront(element)
+
+		return nil
+	}
+
+	d.eventIds[eventId] = d.order.PushFront(eventId)
+	if d.order.Len() > d.size {
+		oldest := d.order.Back()
+		d.order.Remove(oldest)
+		delete(d.eventIds, oldest.Value.(string))
+	}
+
+	return nil
+}
+
+// NewMemoryDeduplicator creates a new MemoryDeduplicator remembering up to size event IDs,
+// DefaultDeduplicationSize if size is not positive
+func NewMemoryDeduplicator(size int) *MemoryDeduplicator {
+	if size <= 0 {
+		size = DefaultDeduplicationSize
+	}
+
+	return &MemoryDeduplicator{
+		size:     size,
+		order:    list.New(),
+		eventIds: make(map[string]*list.Element),
+	}
+}
@@ -0,0 +1,38 @@
+package consumer
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMemoryDeduplicator(t *testing.T) {
+	ctx := context.Background()
+	deduplicator := NewMemoryDeduplicator(2)
+
+	seen, err := deduplicator.Seen(ctx, "event-1")
+	require.NoError(t, err)
+	assert.False(t, seen)
+
+	require.NoError(t, deduplicator.Mark(ctx, "event-1"))
+	require.NoError(t, deduplicator.Mark(ctx, "event-2"))
+	seen, _ = deduplicator.Seen(ctx, "event-1")
+	assert.True(t, seen)
+
+	// Marking an event again makes it the most recent one
+	require.NoError(t, deduplicator.Mark(ctx, "event-1"))
+
+	// The least recent event is forgotten once the deduplicator is full
+	require.NoError(t, deduplicator.Mark(ctx, "event-3"))
+	seen, _ = deduplicator.Seen(ctx, "event-2")
+	assert.False(t, seen)
+	seen, _ = deduplicator.Seen(ctx, "event-1")
+	assert.True(t, seen)
+	seen, _ = deduplicator.Seen(ctx, "event-3")
+	assert.True(t, seen)
+}
+
+func TestNewMemoryDeduplicator_DefaultSize(t *testing.T) {
+	assert.Equal(t, DefaultDeduplicationSize, NewMemoryDeduplicator(0).size)
+}
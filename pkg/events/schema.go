@@ -0,0 +1,11 @@
+package events
+
+import (
+	_ "embed"
+)
+
+// UserEventSchema is the published Protocol Buffers schema of the UserEvent message, as registered in schema registries.
+// It is a copy of proto/events/user_event.proto made by the code generator.
+//
+//go:embed user_event.proto
+var UserEventSchema string
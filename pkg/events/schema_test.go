@@ -0,0 +1,16 @@
+package events
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestUserEventSchema_UpToDate(t *testing.T) {
+	// The embedded schema must match its source, run the code generator otherwise
+	source, err := os.ReadFile("../../proto/events/user_event.proto")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(source), UserEventSchema)
+}
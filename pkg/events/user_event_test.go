@@ -0,0 +1,60 @@
+package events
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"testing"
+)
+
+// publishedUserEventFields are the fields of the published UserEvent schema.
+// Consumers rely on them: fields can be added, but never removed, renamed, renumbered or retyped.
+var publishedUserEventFields = []struct {
+	name        protoreflect.Name
+	number      protoreflect.FieldNumber
+	kind        protoreflect.Kind
+	cardinality protoreflect.Cardinality
+}{
+	{"event_type", 10, protoreflect.StringKind, protoreflect.Optional},
+	{"event_time", 20, protoreflect.MessageKind, protoreflect.Optional},
+	{"user_id", 30, protoreflect.StringKind, protoreflect.Optional},
+	{"first_name", 40, protoreflect.StringKind, protoreflect.Optional},
+	{"last_name", 50, protoreflect.StringKind, protoreflect.Optional},
+	{"nickname", 60, protoreflect.StringKind, protoreflect.Optional},
+	{"email", 70, protoreflect.StringKind, protoreflect.Optional},
+	{"country", 80, protoreflect.StringKind, protoreflect.Optional},
+	{"created_at", 90, protoreflect.MessageKind, protoreflect.Optional},
+	{"updated_at", 100, protoreflect.MessageKind, protoreflect.Optional},
+	{"event_mask", 110, protoreflect.StringKind, protoreflect.Repeated},
+	{"event_id", 120, protoreflect.StringKind, protoreflect.Optional},
+	{"sequence", 130, protoreflect.Int64Kind, protoreflect.Optional},
+	{"previous", 140, protoreflect.MessageKind, protoreflect.Repeated},
+}
+
+func TestUserEvent_SchemaCompatibility(t *testing.T) {
+	descriptor := (&UserEvent{}).ProtoReflect().Descriptor()
+	assert.Equal(t, protoreflect.FullName("users.events.UserEvent"), descriptor.FullName())
+
+	fields := descriptor.Fields()
+	for _, published := range publishedUserEventFields {
+		t.Run(string(published.name), func(t *testing.T) {
+			field := fields.ByName(published.name)
+			require.NotNil(t, field, "published field removed or renamed")
+			assert.Equal(t, published.number, field.Number(), "published field renumbered")
+			assert.Equal(t, published.kind, field.Kind(), "published field retyped")
+			assert.Equal(t, published.cardinality, field.Cardinality(), "published field cardinality changed")
+		})
+	}
+
+	// New fields must not reuse the number of a published field
+	publishedNumbers := make(map[protoreflect.FieldNumber]protoreflect.Name)
+	for _, published := range publishedUserEventFields {
+		publishedNumbers[published.number] = published.name
+	}
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if name, ok := publishedNumbers[field.Number()]; ok {
+			assert.Equal(t, name, field.Name(), "published field number reused")
+		}
+	}
+}
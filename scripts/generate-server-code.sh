@@ -15,3 +15,19 @@ docker run \
   --go-grpc_opt paths=import \
   --go-grpc_opt module=github.com/alenalato/users-service/pkg/grpc \
   ./proto/*.proto;
+
+# This generates the user events code from the proto files.
+
+mkdir -p ./pkg/events
+docker run \
+	-v "$(pwd)":/defs \
+	--rm \
+	namely/protoc:1.42_2 \
+	-I ./proto \
+	--go_out ./pkg/events \
+	--go_opt paths=import \
+	--go_opt module=github.com/alenalato/users-service/pkg/events \
+  ./proto/events/*.proto;
+
+# The user event schema is embedded in the pkg/events package, to be registered in schema registries
+cp ./proto/events/user_event.proto ./pkg/events/user_event.proto